@@ -0,0 +1,221 @@
+package arkaineparser
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// ToolCall is a call to a tool parsed from an agent step.
+type ToolCall struct {
+	Name string `json:"name"`
+	// The arguments converted to their data types, or nil when they are
+	// neither validated by a ToolRegistry nor a JSON object
+	Arguments Result `json:"arguments,omitempty"`
+	Input     string `json:"input,omitempty"` // The arguments as the model wrote them
+}
+
+// Observation is what a tool call returned, to be shown to the model.
+type Observation struct {
+	Text    string `json:"text"`
+	IsError bool   `json:"is_error,omitempty"` // The call failed; Text says why
+}
+
+// Executor runs tool calls for an agent, e.g. by dispatching on call.Name.
+type Executor interface {
+	Execute(ctx context.Context, call ToolCall) (Observation, error)
+}
+
+// ExecutorFunc adapts a function to the Executor interface.
+type ExecutorFunc func(ctx context.Context, call ToolCall) (Observation, error)
+
+// Execute calls f.
+func (f ExecutorFunc) Execute(ctx context.Context, call ToolCall) (Observation, error) {
+	return f(ctx, call)
+}
+
+// Step is the outcome of RunStep.
+type Step struct {
+	Details Details   // The parsed completion
+	Call    *ToolCall // The tool call, or nil when the completion calls no tool
+	// The decisions of the step's policies that denied or rewrote the call
+	Decisions []PolicyDecision
+	// The observation of the call; empty when there is none
+	Observation Observation
+	// The completion's model labels followed by the observation, as labeled
+	// text to append to the scratchpad of the next prompt
+	Scratchpad string
+}
+
+// StepOption configures RunStep.
+type StepOption func(*stepConfig)
+
+// stepConfig holds the settings of RunStep.
+type stepConfig struct {
+	action      string // Lowercase label naming the tool
+	input       string // Lowercase label holding the arguments
+	observation string // Lowercase label the observation is written under
+	tools       *ToolRegistry
+	policies    []Policy
+}
+
+// WithStepLabels sets the labels of the tool name, its arguments and the
+// observation (default "Action", "Action Input" and "Observation").
+func WithStepLabels(action, input, observation string) StepOption {
+	return func(c *stepConfig) {
+		c.action = strings.ToLower(strings.TrimSpace(action))
+		c.input = strings.ToLower(strings.TrimSpace(input))
+		c.observation = strings.ToLower(strings.TrimSpace(observation))
+	}
+}
+
+// WithStepTools validates tool calls against tools before executing them:
+// the tool must be registered and its arguments, as a JSON object or as
+// labeled lines, must parse without errors.
+func WithStepTools(tools *ToolRegistry) StepOption {
+	return func(c *stepConfig) {
+		c.tools = tools
+	}
+}
+
+// WithStepPolicies evaluates policies on every tool call, after its
+// arguments are validated, before it runs (see ApplyPolicies). A denied call
+// does not run; its observation gives the reason.
+func WithStepPolicies(policies ...Policy) StepOption {
+	return func(c *stepConfig) {
+		c.policies = append(c.policies, policies...)
+	}
+}
+
+// RunStep runs one step of an agent loop: it parses completion with p, takes
+// the tool call from its action and action input labels, validates and
+// executes it, and renders the observation back as scratchpad text. A
+// completion without an action calls no tool, e.g. when it holds the final
+// answer.
+//
+// Problems with the call itself, such as an unknown tool, invalid arguments, a
+// policy denial or an error from the executor, become an error Observation so the model can
+// correct itself on the next step. RunStep returns an error only if a step
+// label is not defined, the completion has parse errors (Step.Details tells
+// which, e.g. for ReAskPrompt), or ctx is done; a tool never runs once ctx
+// is done.
+func RunStep(ctx context.Context, p *Parser, completion string, executor Executor, opts ...StepOption) (Step, error) {
+	config := stepConfig{action: "action", input: "action input", observation: "observation"}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	for _, label := range []*string{&config.action, &config.input, &config.observation} {
+		canonical, ok := p.names[*label]
+		if !ok {
+			return Step{}, wrapError(ErrUndefinedLabel, "Label '"+*label+"' is not defined")
+		}
+		*label = canonical
+	}
+
+	// Step 1: Parse the completion
+	step := Step{Details: p.ParseDetailed(completion)}
+	if err := step.Details.Err(); err != nil {
+		return step, err
+	}
+	values := p.flattenValues(step.Details.Result)
+
+	// Step 2: Build and validate the tool call, if any
+	name, _ := values[p.resultKey(config.action)].(string)
+	if name = strings.TrimSpace(name); name != "" {
+		call, problem := config.toolCall(name, values[p.resultKey(config.input)])
+		if problem == "" && len(config.policies) > 0 {
+			var allowed bool
+			call, step.Decisions, allowed = ApplyPolicies(call, config.policies...)
+			if !allowed {
+				problem = "Error: Tool call denied: " + step.Decisions[len(step.Decisions)-1].Reason
+			}
+		}
+		step.Call = &call
+		if problem != "" {
+			step.Observation = Observation{Text: problem, IsError: true}
+		} else {
+			// Step 3: Execute the call, unless the step was cancelled already
+			if err := ctx.Err(); err != nil {
+				return step, err
+			}
+			observation, err := executor.Execute(ctx, call)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return step, ctxErr
+			}
+			if err != nil {
+				observation = NewObservation(err)
+			}
+			step.Observation = observation
+		}
+	}
+
+	// Step 4: Render the scratchpad
+	scratchpad, err := p.stepScratchpad(values, config.observation, step)
+	if err != nil {
+		return step, err
+	}
+	step.Scratchpad = scratchpad
+	return step, nil
+}
+
+// toolCall builds the call of tool name with the value of the input label,
+// validated against the ToolRegistry if any. Returns the reason the call is
+// invalid, or "".
+func (c stepConfig) toolCall(name string, input interface{}) (ToolCall, string) {
+	call := ToolCall{Name: name}
+	switch v := input.(type) {
+	case string:
+		call.Input = strings.TrimSpace(v)
+	case nil:
+	default:
+		encoded, _ := json.Marshal(v)
+		call.Input = string(encoded)
+	}
+	if object, ok := input.(map[string]interface{}); ok {
+		call.Arguments = Result(object)
+	}
+	if c.tools == nil {
+		return call, ""
+	}
+
+	// Arguments written as a JSON object are validated like a native call
+	var details Details
+	var err error
+	if strings.HasPrefix(call.Input, "{") {
+		details, err = c.tools.ValidateArguments(name, call.Input)
+	} else {
+		details, err = c.tools.ParseArguments(name, call.Input)
+	}
+	if err != nil {
+		return call, "Error: " + err.Error()
+	}
+	if len(details.Errors) > 0 {
+		return call, "Error: Invalid arguments for tool '" + name + "': " + strings.Join(errorStrings(details.Errors), "; ")
+	}
+	call.Arguments = details.Result
+	return call, ""
+}
+
+// stepScratchpad renders the non-empty values of the model labels of a step
+// followed by its observation, if any.
+func (p *Parser) stepScratchpad(values map[string]interface{}, observation string, step Step) (string, error) {
+	model := make(map[string]interface{})
+	for key, value := range values {
+		if p.labelMap[key].Source == SourceSystem || value == nil || value == "" {
+			continue
+		}
+		model[key] = value
+	}
+	text, err := p.Format(model)
+	if err != nil || step.Call == nil {
+		return text, err
+	}
+	rendered, err := p.FormatObservation(observation, step.Observation)
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return rendered, nil
+	}
+	return text + "\n" + rendered, nil
+}
@@ -0,0 +1,120 @@
+package arkaineparser
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// reactParser is a ReAct step parser with a system-sourced observation.
+func reactParser(t *testing.T) *Parser {
+	t.Helper()
+	parser, err := NewParser([]Label{
+		{Name: "Thought"},
+		{Name: "Action"},
+		{Name: "Action Input"},
+		{Name: "Observation", Source: SourceSystem},
+		{Name: "Final Answer"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	return parser
+}
+
+// TestRunStep checks a validated call, its execution and the scratchpad.
+func TestRunStep(t *testing.T) {
+	parser := reactParser(t)
+	registry := NewToolRegistry()
+	registry.Register(weatherTool())
+	var calls []ToolCall
+	executor := ExecutorFunc(func(ctx context.Context, call ToolCall) (Observation, error) {
+		calls = append(calls, call)
+		if call.Arguments["city"] == "Atlantis" {
+			return Observation{}, errors.New("no such city")
+		}
+		return Observation{Text: "Sunny, 21C"}, nil
+	})
+
+	completion := "Thought: check the weather\nAction: get_weather\nAction Input: {\"city\": \"Paris\", \"days\": 2}\nObservation: invented"
+	step, err := RunStep(context.Background(), parser, completion, executor, WithStepTools(registry))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := ToolCall{Name: "get_weather", Arguments: Result{"city": "Paris", "days": 2, "units": ""}, Input: `{"city": "Paris", "days": 2}`}
+	if len(calls) != 1 || !reflect.DeepEqual(calls[0], expected) || !reflect.DeepEqual(*step.Call, expected) {
+		t.Errorf("unexpected calls: %#v", calls)
+	}
+	scratchpad := "Thought: check the weather\nAction: get_weather\nAction Input: {\"city\": \"Paris\", \"days\": 2}\nObservation: Sunny, 21C"
+	if step.Scratchpad != scratchpad {
+		t.Errorf("unexpected scratchpad: %q", step.Scratchpad)
+	}
+	if result, _ := parser.Parse(step.Scratchpad); result["observation"] != "" || result["action"] != "get_weather" {
+		t.Errorf("scratchpad does not parse back: %#v", result)
+	}
+
+	// Invalid calls and executor errors become error observations
+	for completion, observation := range map[string]string{
+		"Action: get_weather\nAction Input: city: Atlantis": "Error: no such city",
+		"Action: get_weather\nAction Input: days: 2":        "Error: Invalid arguments for tool 'get_weather': 'city' is required",
+		"Action: search\nAction Input: go":                  "Error: Tool 'search' is not registered",
+	} {
+		step, err := RunStep(context.Background(), parser, completion, executor, WithStepTools(registry))
+		if err != nil || !step.Observation.IsError || step.Observation.Text != observation {
+			t.Errorf("%q: unexpected observation: %#v, %v", completion, step.Observation, err)
+		}
+	}
+	if len(calls) != 2 {
+		t.Errorf("expected invalid calls not to run, got %d calls", len(calls))
+	}
+
+	// A completion without an action calls no tool
+	step, err = RunStep(context.Background(), parser, "Thought: done\nFinal Answer: 42", executor)
+	if err != nil || step.Call != nil || step.Scratchpad != "Thought: done\nFinal Answer: 42" {
+		t.Errorf("unexpected step: %#v, %v", step, err)
+	}
+}
+
+// TestRunStepErrors checks the errors RunStep returns.
+func TestRunStepErrors(t *testing.T) {
+	parser := reactParser(t)
+	executor := ExecutorFunc(func(ctx context.Context, call ToolCall) (Observation, error) {
+		return Observation{Text: "ok"}, nil
+	})
+	if _, err := RunStep(context.Background(), parser, "Action: x", executor, WithStepLabels("Tool", "Args", "Result")); !errors.Is(err, ErrUndefinedLabel) {
+		t.Errorf("expected ErrUndefinedLabel, got %v", err)
+	}
+
+	strict, _ := NewParser([]Label{{Name: "Action", Required: true}, {Name: "Action Input"}, {Name: "Observation"}})
+	step, err := RunStep(context.Background(), strict, "Thought: hmm", executor)
+	if !errors.Is(err, ErrMissingRequired) || len(step.Details.Errors) != 1 {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ran := false
+	tracked := ExecutorFunc(func(ctx context.Context, call ToolCall) (Observation, error) {
+		ran = true
+		return Observation{Text: "ok"}, nil
+	})
+	if _, err := RunStep(ctx, parser, "Action: search\nAction Input: go", tracked); !errors.Is(err, context.Canceled) || ran {
+		t.Errorf("expected context.Canceled without running the tool, got %v, ran %v", err, ran)
+	}
+
+	// Aliased step labels and arguments without a registry
+	custom, _ := NewParser([]Label{{Name: "Tool", Aliases: []string{"Call"}}, {Name: "Args", IsJSON: true}, {Name: "Result"}})
+	step, err = RunStep(context.Background(), custom, "Tool: search\nArgs: {\"q\": \"go\"}", executor, WithStepLabels("call", "args", "result"))
+	if err != nil || !strings.HasSuffix(step.Scratchpad, "\nResult: ok") || step.Call.Arguments["q"] != "go" {
+		t.Errorf("unexpected step: %#v, %v", step, err)
+	}
+
+	// Result keys in declared casing
+	declared, _ := NewParser([]Label{{Name: "Action"}, {Name: "Action Input"}, {Name: "Observation"}}, WithDeclaredKeys())
+	step, err = RunStep(context.Background(), declared, "Action: search\nAction Input: go", executor)
+	if err != nil || step.Call == nil || step.Call.Input != "go" {
+		t.Errorf("unexpected step: %#v, %v", step, err)
+	}
+}
@@ -0,0 +1,120 @@
+package arkaineparser
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// scratch holds buffers reused by consecutive parses on one goroutine, such as
+// the items of a batch handled by one worker. Its buffers only live until the
+// next parse, so nothing built from them may be kept. A nil *scratch
+// allocates fresh buffers.
+type scratch struct {
+	scanned []scannedLine
+	matches []lineMatch
+}
+
+// scannedLines returns a zeroed slice of n scanned lines.
+func (s *scratch) scannedLines(n int) []scannedLine {
+	if s == nil {
+		return make([]scannedLine, n)
+	}
+	if cap(s.scanned) < n {
+		s.scanned = make([]scannedLine, n)
+	}
+	s.scanned = s.scanned[:n]
+	clear(s.scanned)
+	return s.scanned
+}
+
+// lineMatches returns a zeroed slice of n line matches.
+func (s *scratch) lineMatches(n int) []lineMatch {
+	if s == nil {
+		return make([]lineMatch, n)
+	}
+	if cap(s.matches) < n {
+		s.matches = make([]lineMatch, n)
+	}
+	s.matches = s.matches[:n]
+	clear(s.matches)
+	return s.matches
+}
+
+// BatchResult is the outcome of parsing one text of a batch.
+type BatchResult struct {
+	Details                // The parse, as ParseDetailed returns it
+	Duration time.Duration // Time spent parsing the text
+}
+
+// BatchOption configures ParseBatch.
+type BatchOption func(*batchConfig)
+
+// batchConfig holds the configuration of a single ParseBatch call.
+type batchConfig struct {
+	workers int
+}
+
+// WithWorkers parses the batch on n goroutines. Values below 1 use one
+// worker per CPU (runtime.GOMAXPROCS). By default the batch is parsed on the
+// calling goroutine.
+func WithWorkers(n int) BatchOption {
+	return func(c *batchConfig) {
+		if n < 1 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		c.workers = n
+	}
+}
+
+// ParseBatch parses many texts like ParseDetailed, returning one BatchResult
+// per text in the same order. Each worker reuses its internal buffers from one
+// text to the next, which saves allocations over calling ParseDetailed in a
+// loop when parsing large datasets. The returned slice is never nil.
+func (p *Parser) ParseBatch(texts []string, opts ...BatchOption) []BatchResult {
+	config := batchConfig{workers: 1}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	results := make([]BatchResult, len(texts))
+	// parse handles the texts whose indices arrive on next, reusing one scratch
+	parse := func(next func() (int, bool)) {
+		s := &scratch{}
+		for i, ok := next(); ok; i, ok = next() {
+			start := time.Now()
+			details := p.parseDetailed(texts[i], s)
+			results[i] = BatchResult{Details: details, Duration: time.Since(start)}
+			if p.journal != nil {
+				p.journal.record(texts[i], []Details{details}, false)
+			}
+		}
+	}
+
+	workers := min(config.workers, len(texts))
+	if workers <= 1 {
+		i := 0
+		parse(func() (int, bool) {
+			i++
+			return i - 1, i <= len(texts)
+		})
+		return results
+	}
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			parse(func() (int, bool) {
+				i, ok := <-indices
+				return i, ok
+			})
+		}()
+	}
+	for i := range texts {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,70 @@
+package arkaineparser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseBatch checks that batches parse like ParseDetailed, in order.
+func TestParseBatch(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought", Required: true},
+		{Name: "Action", RequiredWith: []string{"Action Input"}},
+		{Name: "Action Input", IsJSON: true},
+		{Name: "Count", DataType: "integer"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	var texts []string
+	for i := 0; i < 50; i++ {
+		switch i % 3 {
+		case 0:
+			texts = append(texts, fmt.Sprintf("Thought: step %d\nAction: search\nAction Input: {\"page\": %d}", i, i))
+		case 1:
+			texts = append(texts, fmt.Sprintf("Count: %d\nAction: search\n```json\n{\"unclosed\": \n```", i))
+		default:
+			texts = append(texts, strings.Repeat("Thought: long\ncontinued\n", i))
+		}
+	}
+	for _, opts := range [][]BatchOption{nil, {WithWorkers(4)}, {WithWorkers(0)}} {
+		results := parser.ParseBatch(texts, opts...)
+		if len(results) != len(texts) {
+			t.Fatalf("expected %d results, got %d", len(texts), len(results))
+		}
+		for i, result := range results {
+			if expected := parser.ParseDetailed(texts[i]); !reflect.DeepEqual(result.Details, expected) {
+				t.Errorf("item %d: got %#v, want %#v", i, result.Details, expected)
+			}
+			if result.Duration <= 0 {
+				t.Errorf("item %d: missing duration", i)
+			}
+		}
+	}
+	if results := parser.ParseBatch(nil, WithWorkers(4)); results == nil || len(results) != 0 {
+		t.Errorf("expected an empty non-nil batch, got %#v", results)
+	}
+}
+
+// BenchmarkParseBatch measures parsing many short outputs in one batch.
+func BenchmarkParseBatch(b *testing.B) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought"},
+		{Name: "Action", RequiredWith: []string{"Action Input"}},
+		{Name: "Action Input", IsJSON: true},
+	})
+	if err != nil {
+		b.Fatalf("failed to create parser: %v", err)
+	}
+	texts := make([]string, 1000)
+	for i := range texts {
+		texts[i] = "Thought: I should look this up\nand think it over\nAction: search\nAction Input: {\"query\": \"step\"}"
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser.ParseBatch(texts)
+	}
+}
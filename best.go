@@ -0,0 +1,63 @@
+package arkaineparser
+
+// ParseBest parses several sampled completions of the same prompt and returns
+// the best one, as used by self-consistency and best-of-n pipelines. Candidates
+// are ranked by, in order: fewest errors, most required labels with a value,
+// most labels with a value, and fewest repairs; ties go to the earliest candidate.
+// Returns the chosen result, its index, and its errors, or (nil, -1, nil) when
+// there are no candidates.
+func (p *Parser) ParseBest(candidates []string) (Result, int, []ParseError) {
+	best := -1
+	var (
+		bestDetails Details
+		bestScore   candidateScore
+	)
+	for i, candidate := range candidates {
+		details := p.ParseDetailed(candidate)
+		score := p.scoreCandidate(details)
+		if best < 0 || score.betterThan(bestScore) {
+			best, bestDetails, bestScore = i, details, score
+		}
+	}
+	if best < 0 {
+		return nil, -1, nil
+	}
+	return bestDetails.Result, best, bestDetails.Errors
+}
+
+// candidateScore holds the ranking criteria of a parsed candidate.
+type candidateScore struct {
+	errors   int // Number of errors
+	required int // Required labels with a value
+	filled   int // Labels with a value
+	repairs  int // Number of repairs
+}
+
+// scoreCandidate computes the ranking criteria of a parsed candidate.
+func (p *Parser) scoreCandidate(details Details) candidateScore {
+	score := candidateScore{errors: len(details.Errors), repairs: len(details.Repairs)}
+	for _, label := range p.labels {
+		if value, ok := details.Result[p.resultKey(label.Name)]; !ok || value == "" {
+			continue
+		}
+		score.filled++
+		if label.Required {
+			score.required++
+		}
+	}
+	return score
+}
+
+// betterThan reports whether s ranks strictly above other.
+func (s candidateScore) betterThan(other candidateScore) bool {
+	if s.errors != other.errors {
+		return s.errors < other.errors
+	}
+	if s.required != other.required {
+		return s.required > other.required
+	}
+	if s.filled != other.filled {
+		return s.filled > other.filled
+	}
+	return s.repairs < other.repairs
+}
@@ -0,0 +1,29 @@
+package arkaineparser
+
+import "testing"
+
+// TestParseBest checks that the candidate with the best validation score wins.
+func TestParseBest(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Answer", Required: true},
+		{Name: "Reason"},
+		{Name: "Arguments", IsJSON: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	candidates := []string{
+		"Reason: no answer given",
+		"Answer: 42\nArguments: {broken",
+		"Answer: 41",
+		"Answer: 42\nReason: computed it",
+	}
+	result, index, errList := parser.ParseBest(candidates)
+	if index != 3 || result["answer"] != "42" || len(errList) != 0 {
+		t.Errorf("unexpected best candidate %d: %#v %v", index, result, errList)
+	}
+
+	if result, index, _ := parser.ParseBest(nil); result != nil || index != -1 {
+		t.Errorf("expected no result without candidates, got %d %#v", index, result)
+	}
+}
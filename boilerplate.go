@@ -0,0 +1,74 @@
+package arkaineparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// preamblePatterns match the chat boilerplate assistants open with, each up to
+// the end of its sentence or line.
+var preamblePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^(?:sure(?: thing)?|certainly|of course|absolutely|okay|ok|alright|great|no problem|happy to help)\b[^\n]*?(?:[!.:]|$)`),
+	regexp.MustCompile(`(?i)^here(?:'s| is| are)\b[^\n]*?(?:[:.!]|$)`),
+	regexp.MustCompile(`(?i)^(?:i'm sorry|i am sorry|sorry|i apologi[sz]e|apologies|my apologies)\b[^\n]*?(?:[.!]|$)`),
+	regexp.MustCompile(`(?i)^as an ai(?: language model| assistant)?\b[^\n]*?(?:[.!]|$)`),
+}
+
+// signOffPatterns match the closing lines assistants end with.
+var signOffPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^(?:let me know|feel free|i hope this helps|hope this helps|happy to help|good luck|if you (?:have|need)|is there anything else|please let me know)\b`),
+}
+
+// stripBoilerplate removes chat preambles from the start of text, up to the
+// first label, and sign-off lines from its end. Each removal is reported as a
+// RepairBoilerplate repair.
+func (p *Parser) stripBoilerplate(text string, repairs *[]Repair) string {
+	preambles := append(append([]*regexp.Regexp(nil), preamblePatterns...), p.boilerplate...)
+	signOffs := append(append([]*regexp.Regexp(nil), signOffPatterns...), p.boilerplate...)
+
+	// Step 1: Strip preambles, one sentence at a time, until a label or other text
+	for {
+		rest := strings.TrimLeft(text, " \t\r\n")
+		if label, _, _ := p.parseLine(rest); label != "" {
+			break
+		}
+		end := 0
+		for _, pattern := range preambles {
+			if loc := pattern.FindStringIndex(rest); loc != nil && loc[0] == 0 && loc[1] > 0 {
+				end = loc[1]
+				break
+			}
+		}
+		if end == 0 {
+			break
+		}
+		*repairs = append(*repairs, Repair{Kind: RepairBoilerplate, Before: rest[:end]})
+		text = rest[end:]
+	}
+
+	// Step 2: Strip sign-off lines from the end, keeping the first line of text
+	lines := strings.Split(strings.TrimRight(text, " \t\r\n"), "\n")
+	for len(lines) > 1 {
+		last := strings.TrimSpace(lines[len(lines)-1])
+		if last == "" {
+			lines = lines[:len(lines)-1]
+			continue
+		}
+		if label, _, _ := p.parseLine(last); label != "" || !matchesAtStart(signOffs, last) {
+			break
+		}
+		*repairs = append(*repairs, Repair{Kind: RepairBoilerplate, Before: last})
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// matchesAtStart reports whether any of patterns matches at the start of text.
+func matchesAtStart(patterns []*regexp.Regexp, text string) bool {
+	for _, pattern := range patterns {
+		if loc := pattern.FindStringIndex(text); loc != nil && loc[0] == 0 {
+			return true
+		}
+	}
+	return false
+}
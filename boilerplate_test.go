@@ -0,0 +1,64 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// TestBoilerplateStripping checks that chat preambles and sign-offs are
+// removed before labels are matched.
+func TestBoilerplateStripping(t *testing.T) {
+	labels := []Label{{Name: "Thought"}, {Name: "Final Answer"}}
+	parser, err := NewParser(labels, WithBoilerplateStripping(regexp.MustCompile(`(?i)^\[end of response\]`)))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	tests := []struct {
+		text     string
+		expected Result
+		removed  []string
+	}{
+		{
+			"Sure! Here is my analysis: Thought: add them\nFinal Answer: 42\n\nLet me know if you need anything else!\n[End of response]",
+			Result{"thought": "add them", "final answer": "42"},
+			[]string{"Sure!", "Here is my analysis:", "[End of response]", "Let me know if you need anything else!"},
+		},
+		{
+			"I'm sorry for the confusion earlier.\nCertainly.\nThought: retry\nFinal Answer: 7\nHope this helps.",
+			Result{"thought": "retry", "final answer": "7"},
+			[]string{"I'm sorry for the confusion earlier.", "Certainly.", "Hope this helps."},
+		},
+		{
+			// Text that merely starts like boilerplate inside a value is kept
+			"Thought: sure, it works\nFinal Answer: feel free to ask",
+			Result{"thought": "sure, it works", "final answer": "feel free to ask"},
+			nil,
+		},
+	}
+	for _, test := range tests {
+		details := parser.ParseDetailed(test.text)
+		if len(details.Errors) > 0 {
+			t.Errorf("unexpected errors for %q: %v", test.text, details.Errors)
+		}
+		if !reflect.DeepEqual(details.Result, test.expected) {
+			t.Errorf("for %q got %#v", test.text, details.Result)
+		}
+		var removed []string
+		for _, repair := range details.Repairs {
+			if repair.Kind == RepairBoilerplate {
+				removed = append(removed, repair.Before)
+			}
+		}
+		if !reflect.DeepEqual(removed, test.removed) {
+			t.Errorf("for %q removed %#v", test.text, removed)
+		}
+	}
+
+	// Without the option the sign-off is glued to the last value
+	plain, _ := NewParser(labels)
+	result, _ := plain.Parse("Final Answer: 42\nHope this helps!")
+	if result["final answer"] != "42\nHope this helps!" {
+		t.Errorf("unexpected answer without the option: %q", result["final answer"])
+	}
+}
@@ -0,0 +1,136 @@
+package arkaineparser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Tokenizer counts the tokens of a text for a particular model.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc adapts a counting function to the Tokenizer interface.
+type TokenizerFunc func(text string) int
+
+// CountTokens calls f(text).
+func (f TokenizerFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// ApproxTokenizer estimates tokens as one per four bytes, rounding up, which
+// is close enough for English text when no real tokenizer is at hand.
+var ApproxTokenizer Tokenizer = TokenizerFunc(func(text string) int {
+	return (len(text) + 3) / 4
+})
+
+// TruncationPolicy chooses how FormatSteps fits steps into a token budget.
+type TruncationPolicy string
+
+const (
+	// DropOldest drops the oldest steps until the rest fit (the default)
+	DropOldest TruncationPolicy = "drop-oldest"
+	// SummarizeOldest replaces the oldest steps with a summary from Budget.Summarize
+	SummarizeOldest TruncationPolicy = "summarize-oldest"
+)
+
+// Budget limits the size of formatted text.
+type Budget struct {
+	MaxTokens int       // Largest number of tokens allowed
+	Tokenizer Tokenizer // Counts tokens; ApproxTokenizer if nil
+	Policy    TruncationPolicy
+	// Summarize condenses the formatted text of the dropped steps, e.g. with a
+	// call to a cheap model. Required by SummarizeOldest; it may be called
+	// several times with more steps until the summary and the rest fit.
+	Summarize func(dropped string) (string, error)
+}
+
+// FormatSteps formats each step with Format, oldest first, one step per
+// paragraph, fitting the result into budget: the oldest steps are dropped or
+// summarized until the text fits. The newest step is always kept whole.
+// Returns the text and the number of steps dropped or summarized, or an error
+// if a step cannot be formatted, Summarize fails, or the newest step alone
+// exceeds the budget.
+func (p *Parser) FormatSteps(steps []map[string]interface{}, budget Budget) (string, int, error) {
+	tokenizer := budget.Tokenizer
+	if tokenizer == nil {
+		tokenizer = ApproxTokenizer
+	}
+	if budget.Policy == SummarizeOldest && budget.Summarize == nil {
+		return "", 0, errors.New("SummarizeOldest policy requires a Summarize function")
+	}
+	// Step 1: Format every step
+	texts := make([]string, len(steps))
+	for i, step := range steps {
+		text, err := p.Format(step)
+		if err != nil {
+			return "", 0, err
+		}
+		texts[i] = text
+	}
+	fits := func(text string) bool {
+		return budget.MaxTokens <= 0 || tokenizer.CountTokens(text) <= budget.MaxTokens
+	}
+
+	// Step 2: Drop or summarize the oldest steps until the rest fit
+	for dropped := 0; dropped < len(texts); dropped++ {
+		text := strings.Join(texts[dropped:], "\n\n")
+		if dropped > 0 && budget.Policy == SummarizeOldest {
+			summary, err := budget.Summarize(strings.Join(texts[:dropped], "\n\n"))
+			if err != nil {
+				return "", 0, err
+			}
+			text = summary + "\n\n" + text
+		}
+		if fits(text) {
+			return text, dropped, nil
+		}
+	}
+	if len(texts) == 0 {
+		return "", 0, nil
+	}
+	return "", 0, errors.New("Newest step needs " + strconv.Itoa(tokenizer.CountTokens(texts[len(texts)-1])) +
+		" tokens, more than the budget of " + strconv.Itoa(budget.MaxTokens))
+}
+
+// TruncateToBudget shortens text to fit budget.MaxTokens by cutting whole
+// lines from its start, keeping the most recent lines, e.g. for a rendered
+// trace or transcript. Returns the text and whether it was truncated.
+func TruncateToBudget(text string, budget Budget) (string, bool) {
+	tokenizer := budget.Tokenizer
+	if tokenizer == nil {
+		tokenizer = ApproxTokenizer
+	}
+	if budget.MaxTokens <= 0 || tokenizer.CountTokens(text) <= budget.MaxTokens {
+		return text, false
+	}
+	lines := strings.Split(text, "\n")
+	for start := 1; start < len(lines); start++ {
+		if rest := strings.Join(lines[start:], "\n"); tokenizer.CountTokens(rest) <= budget.MaxTokens {
+			return rest, true
+		}
+	}
+	return "", true
+}
+
+// countTokens counts the tokens of each label's values and of the matched
+// lines as a whole.
+func (p *Parser) countTokens(matches []lineMatch, data map[string][]string) *TokenCounts {
+	counts := &TokenCounts{Fields: make(map[string]int)}
+	for label, entries := range data {
+		total := 0
+		for _, entry := range entries {
+			total += p.tokenizer.CountTokens(entry)
+		}
+		if total > 0 {
+			counts.Fields[label] = total
+		}
+	}
+	lines := make([]string, len(matches))
+	for i, match := range matches {
+		lines[i] = match.line
+	}
+	counts.Total = p.tokenizer.CountTokens(strings.Join(lines, "\n"))
+	return counts
+}
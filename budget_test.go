@@ -0,0 +1,78 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestFormatSteps checks the truncation policies of FormatSteps.
+func TestFormatSteps(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Thought"}, {Name: "Observation"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	steps := []map[string]interface{}{
+		{"thought": "one", "observation": "first result"},
+		{"thought": "two", "observation": "second result"},
+		{"thought": "three", "observation": "third result"},
+	}
+	// Count words as tokens to keep the test readable
+	words := TokenizerFunc(func(text string) int { return len(strings.Fields(text)) })
+
+	text, dropped, err := parser.FormatSteps(steps, Budget{MaxTokens: 100, Tokenizer: words})
+	if err != nil || dropped != 0 || strings.Count(text, "Thought:") != 3 {
+		t.Errorf("unexpected output within budget: %q %d %v", text, dropped, err)
+	}
+
+	text, dropped, err = parser.FormatSteps(steps, Budget{MaxTokens: 10, Tokenizer: words})
+	expected := "Thought: two\nObservation: second result\n\nThought: three\nObservation: third result"
+	if err != nil || dropped != 1 || text != expected {
+		t.Errorf("unexpected dropped output: %q %d %v", text, dropped, err)
+	}
+
+	calls := 0
+	text, dropped, err = parser.FormatSteps(steps, Budget{MaxTokens: 8, Tokenizer: words, Policy: SummarizeOldest,
+		Summarize: func(dropped string) (string, error) {
+			calls++
+			return "Earlier: " + strings.Repeat("step ", strings.Count(dropped, "Thought:")), nil
+		}})
+	expected = "Earlier: step step \n\nThought: three\nObservation: third result"
+	if err != nil || dropped != 2 || calls != 2 || text != expected {
+		t.Errorf("unexpected summarized output: %q %d %d %v", text, dropped, calls, err)
+	}
+
+	_, _, err = parser.FormatSteps(steps, Budget{MaxTokens: 3, Tokenizer: words})
+	if err == nil || err.Error() != "Newest step needs 5 tokens, more than the budget of 3" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	truncated, ok := TruncateToBudget("a b\nc d\ne f", Budget{MaxTokens: 4, Tokenizer: words})
+	if !ok || truncated != "c d\ne f" {
+		t.Errorf("unexpected truncation: %q %v", truncated, ok)
+	}
+}
+
+// TestWithTokenizer checks the token counts reported by parses.
+func TestWithTokenizer(t *testing.T) {
+	words := TokenizerFunc(func(text string) int { return len(strings.Fields(text)) })
+	parser, err := NewParser([]Label{{Name: "Thought"}, {Name: "Answer"}, {Name: "Task", IsBlockStart: true}}, WithTokenizer(words))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	details := parser.ParseDetailed("Thought: think it over\nThought: again\nAnswer: forty two")
+	expected := &TokenCounts{Fields: map[string]int{"thought": 4, "answer": 2}, Total: 9}
+	if !reflect.DeepEqual(details.Tokens, expected) {
+		t.Errorf("tokens mismatch.\nGot: %#v\nExpected: %#v", details.Tokens, expected)
+	}
+	blocks, _ := parser.ParseBlocksDetailed("Task: one\nAnswer: 1\nTask: two\nAnswer: 2 and 3")
+	if len(blocks) != 2 || blocks[1].Tokens.Total != 6 || blocks[1].Tokens.Fields["answer"] != 3 {
+		t.Errorf("unexpected block tokens: %#v", blocks)
+	}
+
+	// Without a tokenizer nothing is counted
+	plain, _ := NewParser([]Label{{Name: "Answer"}})
+	if plain.ParseDetailed("Answer: 42").Tokens != nil {
+		t.Errorf("expected no token counts")
+	}
+}
@@ -0,0 +1,237 @@
+package arkaineparser
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// DefaultCacheSize is the number of results a CachedParser keeps when created
+// with a non-positive size.
+const DefaultCacheSize = 1024
+
+// CachedParser wraps a Parser with an LRU cache keyed by a hash of the input
+// text, so identical outputs (retries, deduplication pipelines) skip parsing.
+// Cached results are deep-copied on the way out, so callers may modify them
+// freely. A CachedParser is safe for concurrent use.
+type CachedParser struct {
+	parser   *Parser
+	capacity int
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List // Most recently used entry at the front
+	hits    uint64
+	misses  uint64
+}
+
+// cacheEntry is a single cached parse.
+type cacheEntry struct {
+	key     [sha256.Size]byte
+	details Details
+}
+
+// CacheStats reports the effectiveness of a CachedParser.
+type CacheStats struct {
+	Hits     uint64 // Lookups answered from the cache
+	Misses   uint64 // Lookups that required parsing
+	Size     int    // Results currently cached
+	Capacity int    // Maximum number of cached results
+}
+
+// HitRate returns the fraction of lookups answered from the cache,
+// or 0 when there were no lookups.
+func (s CacheStats) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// NewCachedParser creates a CachedParser around p keeping at most size results.
+// A non-positive size uses DefaultCacheSize.
+func NewCachedParser(p *Parser, size int) *CachedParser {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &CachedParser{
+		parser:   p,
+		capacity: size,
+		entries:  make(map[[sha256.Size]byte]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Parse behaves like Parser.Parse, answering repeated inputs from the cache.
+func (c *CachedParser) Parse(text string) (map[string]interface{}, []string) {
+	details := c.ParseDetailed(text)
+	return map[string]interface{}(details.Result), errorStrings(details.Errors)
+}
+
+// ParseDetailed behaves like Parser.ParseDetailed, answering repeated inputs
+// from the cache.
+func (c *CachedParser) ParseDetailed(text string) Details {
+	key := sha256.Sum256([]byte(text))
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.hits++
+		c.order.MoveToFront(elem)
+		details := elem.Value.(*cacheEntry).details
+		c.mu.Unlock()
+		return copyDetails(details)
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// Parse outside the lock; Parsers are safe for concurrent use
+	details := c.parser.ParseDetailed(text)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Another goroutine may have cached the same input meanwhile
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.order.PushFront(&cacheEntry{key: key, details: copyDetails(details)})
+		// Evict the least recently used results beyond capacity
+		for c.order.Len() > c.capacity {
+			oldest := c.order.Back()
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*cacheEntry).key)
+		}
+	}
+	return details
+}
+
+// Stats returns the current cache statistics.
+func (c *CachedParser) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Size: c.order.Len(), Capacity: c.capacity}
+}
+
+// Reset empties the cache and clears its statistics.
+func (c *CachedParser) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[[sha256.Size]byte]*list.Element)
+	c.order.Init()
+	c.hits, c.misses = 0, 0
+}
+
+// copyDetails returns a deep copy of details, so cached values are never
+// shared with callers.
+func copyDetails(details Details) Details {
+	copied := Details{
+		Errors:   copyErrors(append([]ParseError{}, details.Errors...)),
+		Repairs:  append([]Repair(nil), details.Repairs...),
+		Warnings: copyErrors(append([]ParseError(nil), details.Warnings...)),
+	}
+	if details.Result != nil {
+		copied.Result = copyValue(map[string]interface{}(details.Result)).(map[string]interface{})
+	}
+	if details.Occurrences != nil {
+		copied.Occurrences = make(map[string][]interface{}, len(details.Occurrences))
+		for label, values := range details.Occurrences {
+			copied.Occurrences[label] = copyValue(values).([]interface{})
+		}
+	}
+	if details.Provenance != nil {
+		copied.Provenance = make(map[string][]Provenance, len(details.Provenance))
+		for label, entries := range details.Provenance {
+			copied.Provenance[label] = append([]Provenance{}, entries...)
+		}
+	}
+	if details.Cleaning != nil {
+		cleaning := Cleaning{Text: details.Cleaning.Text, Removals: append([]Removal{}, details.Cleaning.Removals...)}
+		copied.Cleaning = &cleaning
+	}
+	if details.Tokens != nil {
+		tokens := TokenCounts{Fields: make(map[string]int, len(details.Tokens.Fields)), Total: details.Tokens.Total}
+		for label, count := range details.Tokens.Fields {
+			tokens.Fields[label] = count
+		}
+		copied.Tokens = &tokens
+	}
+	return copied
+}
+
+// copyErrors gives each of the (already copied) errList its own Params.
+func copyErrors(errList []ParseError) []ParseError {
+	for i, err := range errList {
+		if err.Params != nil {
+			params := make(map[string]string, len(err.Params))
+			for key, value := range err.Params {
+				params[key] = value
+			}
+			errList[i].Params = params
+		}
+	}
+	return errList
+}
+
+// copyValue deep-copies the maps and slices produced by parsing and JSON decoding.
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	case []byte:
+		return append([]byte(nil), v...)
+	case []string:
+		return append([]string(nil), v...)
+	case [][]string:
+		copied := make([][]string, len(v))
+		for i, row := range v {
+			copied[i] = append([]string(nil), row...)
+		}
+		return copied
+	case []ListItem:
+		copied := make([]ListItem, len(v))
+		for i, item := range v {
+			copied[i] = ListItem{Text: item.Text}
+			if item.Children != nil {
+				copied[i].Children = copyValue(item.Children).([]ListItem)
+			}
+		}
+		return copied
+	case []map[string]interface{}:
+		copied := make([]map[string]interface{}, len(v))
+		for i, record := range v {
+			copied[i] = copyValue(record).(map[string]interface{})
+		}
+		return copied
+	case []map[string]string:
+		copied := make([]map[string]string, len(v))
+		for i, record := range v {
+			copied[i] = make(map[string]string, len(record))
+			for key, field := range record {
+				copied[i][key] = field
+			}
+		}
+		return copied
+	case Patch:
+		copied := Patch{Files: make([]FilePatch, len(v.Files))}
+		for i, file := range v.Files {
+			copied.Files[i] = file
+			copied.Files[i].Hunks = make([]Hunk, len(file.Hunks))
+			for j, hunk := range file.Hunks {
+				copied.Files[i].Hunks[j] = hunk
+				copied.Files[i].Hunks[j].Lines = append([]string(nil), hunk.Lines...)
+			}
+		}
+		return copied
+	default:
+		return value
+	}
+}
@@ -0,0 +1,52 @@
+package arkaineparser
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestCachedParser checks hits, eviction, and that cached results are isolated from callers.
+func TestCachedParser(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Tool"}, {Name: "Arguments", IsJSON: true}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	cache := NewCachedParser(parser, 2)
+	input := "Tool: search\nArguments: {\"q\": \"go\"}"
+
+	result, _ := cache.Parse(input)
+	// Mutating a returned result must not affect later hits
+	result["arguments"].(map[string]interface{})["q"] = "changed"
+	result, _ = cache.Parse(input)
+	if q := result["arguments"].(map[string]interface{})["q"]; q != "go" {
+		t.Errorf("cached result was modified by a caller: %v", q)
+	}
+
+	// Two new inputs evict the least recently used one
+	cache.Parse("Tool: a")
+	cache.Parse("Tool: b")
+	cache.Parse(input)
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 4 || stats.Size != 2 || stats.Capacity != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if rate := stats.HitRate(); rate != 0.2 {
+		t.Errorf("unexpected hit rate: %v", rate)
+	}
+
+	// Concurrent use is safe
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				cache.Parse(input)
+			}
+		}()
+	}
+	wg.Wait()
+	if stats := cache.Stats(); stats.Hits+stats.Misses != 405 {
+		t.Errorf("unexpected lookup count: %+v", stats)
+	}
+}
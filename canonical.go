@@ -0,0 +1,110 @@
+package arkaineparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Canonicalize returns a normalized copy of result for snapshot comparisons
+// that must not flake across Go versions and refactors:
+//   - numbers of every Go type become json.Number, so int 7 and float64 7
+//     compare equal;
+//   - times become RFC 3339 strings in UTC;
+//   - structs such as Quantity, slices of any type and nested maps become the
+//     map[string]interface{} and []interface{} values their JSON encodes to;
+//   - the values of the unordered labels, when a slice, are sorted by their
+//     JSON encoding.
+//
+// Values that cannot be encoded as JSON (NaN, channels) become their fmt.Sprint
+// string. See Parser.Canonicalize to sort the labels marked Unordered.
+func Canonicalize(result Result, unordered ...string) Result {
+	sorted := make(map[string]bool, len(unordered))
+	for _, name := range unordered {
+		sorted[strings.ToLower(name)] = true
+	}
+	canonical := make(Result, len(result))
+	for key, value := range result {
+		value = canonicalValue(value)
+		if list, ok := value.([]interface{}); ok && sorted[strings.ToLower(key)] {
+			sortCanonical(list)
+		}
+		canonical[key] = value
+	}
+	return canonical
+}
+
+// Canonicalize returns the canonical form of a result of this parser, as the
+// package-level Canonicalize does, sorting the values of labels marked
+// Unordered.
+func (p *Parser) Canonicalize(result Result) Result {
+	var unordered []string
+	for _, label := range p.labels {
+		if label.Unordered {
+			unordered = append(unordered, label.Name)
+		}
+	}
+	return Canonicalize(result, unordered...)
+}
+
+// canonicalValue converts a value to its canonical form.
+func canonicalValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, string, bool, json.Number:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case Result:
+		return canonicalValue(map[string]interface{}(v))
+	case map[string]interface{}:
+		canonical := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			canonical[key] = canonicalValue(item)
+		}
+		return canonical
+	case []interface{}:
+		canonical := make([]interface{}, len(v))
+		for i, item := range v {
+			canonical[i] = canonicalValue(item)
+		}
+		return canonical
+	}
+	// Anything else takes the shape of its JSON encoding, with numbers kept exact
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return fmt.Sprint(value)
+	}
+	return decoded
+}
+
+// sortCanonical sorts canonical values by their JSON encoding.
+func sortCanonical(list []interface{}) {
+	keys := make([]string, len(list))
+	for i, item := range list {
+		encoded, _ := marshalStable(item)
+		keys[i] = string(encoded)
+	}
+	sort.Sort(byKey{keys, list})
+}
+
+// byKey sorts values by parallel string keys.
+type byKey struct {
+	keys   []string
+	values []interface{}
+}
+
+func (b byKey) Len() int           { return len(b.keys) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+}
@@ -0,0 +1,60 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestCanonicalize checks that equivalent results have one canonical form.
+func TestCanonicalize(t *testing.T) {
+	parser, err := NewSchema().
+		Label("Count").DataType("integer").
+		Label("Tags").Unordered().
+		Label("Steps").
+		Label("Price").DataType("currency").
+		JSON("Meta").
+		Label("When").DataType("datetime").
+		Parser()
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	parsed, errList := parser.Parse("Count: 7\nTags: b\nTags: a\nSteps: two\nSteps: one\nPrice: $5\nMeta: {\"n\": 7, \"xs\": [1.5]}\nWhen: 2024-01-02T03:04:05+02:00")
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	// The same values, built by hand with other Go types and orders
+	manual := Result{
+		"count": 7.0,
+		"tags":  []string{"a", "b"},
+		"steps": []interface{}{"two", "one"},
+		"price": map[string]interface{}{"value": 5, "currency": "USD"},
+		"meta":  map[string]interface{}{"xs": []float64{1.5}, "n": int64(7)},
+		"when":  time.Date(2024, 1, 2, 1, 4, 5, 0, time.UTC),
+	}
+	got, want := parser.Canonicalize(parsed), parser.Canonicalize(manual)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("canonical forms differ:\n%#v\n%#v", got, want)
+	}
+	expected := Result{
+		"count": json.Number("7"),
+		"tags":  []interface{}{"a", "b"},
+		"steps": []interface{}{"two", "one"},
+		"price": map[string]interface{}{"value": json.Number("5"), "currency": "USD"},
+		"meta":  map[string]interface{}{"xs": []interface{}{json.Number("1.5")}, "n": json.Number("7")},
+		"when":  "2024-01-02T01:04:05Z",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected canonical form: %#v", got)
+	}
+
+	// Without the label marked, order is kept; the input is not modified
+	tags := []interface{}{"b", "a"}
+	if canonical := Canonicalize(Result{"tags": tags}); !reflect.DeepEqual(canonical["tags"], []interface{}{"b", "a"}) {
+		t.Errorf("unexpected unsorted tags: %#v", canonical["tags"])
+	}
+	if canonical := Canonicalize(Result{"tags": tags}, "Tags"); !reflect.DeepEqual(canonical["tags"], []interface{}{"a", "b"}) || tags[0] != "b" {
+		t.Errorf("unexpected sorted tags: %#v, input %#v", canonical["tags"], tags)
+	}
+}
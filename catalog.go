@@ -0,0 +1,125 @@
+package arkaineparser
+
+import (
+	"errors"
+	"strings"
+)
+
+// MessageCatalog supplies localized MessageTemplates, e.g. from a translation
+// system, for WithLanguage.
+type MessageCatalog interface {
+	// Templates returns the templates of language, and whether the catalog
+	// knows the language.
+	Templates(language string) (MessageTemplates, bool)
+}
+
+// Catalog is a MessageCatalog of templates keyed by lowercase language tag,
+// such as "de" or "pt-br". A region tag missing from the catalog falls back to
+// its base language, so "de-AT" finds "de".
+type Catalog map[string]MessageTemplates
+
+// Templates implements MessageCatalog.
+func (c Catalog) Templates(language string) (MessageTemplates, bool) {
+	language = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(language, "_", "-")))
+	if templates, ok := c[language]; ok {
+		return templates, true
+	}
+	if base, _, found := strings.Cut(language, "-"); found {
+		templates, ok := c[base]
+		return templates, ok
+	}
+	return nil, false
+}
+
+// DefaultCatalog holds the messages the parser ships with: English ("en", the
+// default messages), German ("de"), Spanish ("es") and French ("fr"). Errors
+// reported by the JSON decoder and data type converters ({error}) and front
+// matter problems ({problem}) stay in English.
+var DefaultCatalog = Catalog{
+	"en": {},
+	"de": {
+		SubjectTemplate:           "'{label}'",
+		OccurrenceSubjectTemplate: "'{label}' (Vorkommen {occurrence})",
+		KindRequired:              "'{label}' ist erforderlich",
+		KindDependency:            "{subject} erfordert '{dependency}'",
+		KindJSON:                  "JSON-Fehler in '{label}': {error}",
+		"json:empty":              "JSON-Fehler in '{label}': der Wert ist leer",
+		KindType:                  "Ungültiger Wert vom Typ {type} in '{label}': {error}",
+		"range:number":            "{subject} muss eine Zahl sein",
+		"range:min":               "{subject} muss mindestens {bound} sein, erhalten: {value}",
+		"range:max":               "{subject} darf höchstens {bound} sein, erhalten: {value}",
+		KindPattern:               "{subject} muss dem Muster '{pattern}' entsprechen, erhalten: '{value}'",
+		KindChoice:                "{subject} muss einer der Werte '{choices}' sein, erhalten: '{value}'",
+		KindFrontMatter:           "Ungültiges Front Matter in Zeile {line}: {problem}",
+		KindMiddleware:            "Middleware-Fehler in {subject}: {error}",
+		KindSystemLabel:           "'{label}' ist ein reines System-Label; {count} vom Modell erzeugte(r) Wert(e) entfernt",
+		KindUngrounded:            "'{label}' wird von der Quelle nicht gestützt: '{sentence}' (Ähnlichkeit {score})",
+		KindDuplicateBlock:        "Block {block} wurde {repeats} Mal wiederholt; die Wiederholungen wurden zusammengefasst",
+		KindAmbiguousLabel:        "Zeile '{text}' passt auch auf {others}; '{label}' wurde als längste Übereinstimmung gewählt",
+	},
+	"es": {
+		SubjectTemplate:           "'{label}'",
+		OccurrenceSubjectTemplate: "'{label}' (aparición {occurrence})",
+		KindRequired:              "'{label}' es obligatorio",
+		KindDependency:            "{subject} requiere '{dependency}'",
+		KindJSON:                  "Error de JSON en '{label}': {error}",
+		"json:empty":              "Error de JSON en '{label}': el valor está vacío",
+		KindType:                  "Valor {type} no válido en '{label}': {error}",
+		"range:number":            "{subject} debe ser un número",
+		"range:min":               "{subject} debe ser como mínimo {bound}, se obtuvo {value}",
+		"range:max":               "{subject} debe ser como máximo {bound}, se obtuvo {value}",
+		KindPattern:               "{subject} debe coincidir con el patrón '{pattern}', se obtuvo '{value}'",
+		KindChoice:                "{subject} debe ser uno de '{choices}', se obtuvo '{value}'",
+		KindFrontMatter:           "Front matter no válido en la línea {line}: {problem}",
+		KindMiddleware:            "Error de middleware en {subject}: {error}",
+		KindSystemLabel:           "'{label}' es una etiqueta exclusiva del sistema; se eliminaron {count} valor(es) producidos por el modelo",
+		KindUngrounded:            "'{label}' no está respaldado por la fuente: '{sentence}' (similitud {score})",
+		KindDuplicateBlock:        "El bloque {block} se repitió {repeats} vez/veces; las repeticiones se fusionaron",
+		KindAmbiguousLabel:        "La línea '{text}' también coincide con {others}; se tomó '{label}' como la coincidencia más larga",
+	},
+	"fr": {
+		SubjectTemplate:           "'{label}'",
+		OccurrenceSubjectTemplate: "'{label}' (occurrence {occurrence})",
+		KindRequired:              "'{label}' est obligatoire",
+		KindDependency:            "{subject} nécessite '{dependency}'",
+		KindJSON:                  "Erreur JSON dans '{label}' : {error}",
+		"json:empty":              "Erreur JSON dans '{label}' : la valeur est vide",
+		KindType:                  "Valeur {type} invalide dans '{label}' : {error}",
+		"range:number":            "{subject} doit être un nombre",
+		"range:min":               "{subject} doit être au moins {bound}, reçu {value}",
+		"range:max":               "{subject} doit être au plus {bound}, reçu {value}",
+		KindPattern:               "{subject} doit correspondre au motif '{pattern}', reçu '{value}'",
+		KindChoice:                "{subject} doit être l'une des valeurs '{choices}', reçu '{value}'",
+		KindFrontMatter:           "Front matter invalide à la ligne {line} : {problem}",
+		KindMiddleware:            "Erreur de middleware dans {subject} : {error}",
+		KindSystemLabel:           "'{label}' est une étiquette réservée au système ; {count} valeur(s) produite(s) par le modèle supprimée(s)",
+		KindUngrounded:            "'{label}' n'est pas étayé par la source : '{sentence}' (similarité {score})",
+		KindDuplicateBlock:        "Le bloc {block} a été répété {repeats} fois ; les répétitions ont été fusionnées",
+		KindAmbiguousLabel:        "La ligne '{text}' correspond aussi à {others} ; '{label}' a été retenu comme la correspondance la plus longue",
+	},
+}
+
+// resolveLanguage merges the templates of the parser's language, from the
+// first of its catalogs that knows it, under its own templates.
+func (p *Parser) resolveLanguage() error {
+	catalogs := p.catalogs
+	if len(catalogs) == 0 {
+		catalogs = []MessageCatalog{DefaultCatalog}
+	}
+	for _, catalog := range catalogs {
+		templates, ok := catalog.Templates(p.language)
+		if !ok {
+			continue
+		}
+		merged := make(MessageTemplates, len(templates)+len(p.messageTemplates))
+		for kind, template := range templates {
+			merged[kind] = template
+		}
+		for kind, template := range p.messageTemplates {
+			merged[kind] = template
+		}
+		p.messageTemplates = merged
+		return nil
+	}
+	return errors.New("Unknown message language '" + p.language + "'")
+}
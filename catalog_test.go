@@ -0,0 +1,81 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestWithLanguage checks that messages are reported in the chosen language.
+func TestWithLanguage(t *testing.T) {
+	maximum := 10.0
+	labels := []Label{
+		{Name: "Action", Required: true},
+		{Name: "Action Input", RequiredWith: []string{"Action"}},
+		{Name: "Score", DataType: "integer", Max: &maximum},
+	}
+	parser, err := NewParser(labels, WithLanguage("de-AT"))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	_, errList := parser.Parse("Action Input: a\nAction Input: b\nScore: 12")
+	expected := []string{
+		"'score' darf höchstens 10 sein, erhalten: 12",
+		"'action' ist erforderlich",
+		"'action input' (Vorkommen 1) erfordert 'Action'",
+		"'action input' (Vorkommen 2) erfordert 'Action'",
+	}
+	if !reflect.DeepEqual(errList, expected) {
+		t.Errorf("unexpected errors: %#v", errList)
+	}
+
+	// Explicit templates win over the catalog's
+	parser, _ = NewParser(labels, WithLanguage("fr"), WithMessageTemplates(MessageTemplates{KindRequired: "Il manque {label}."}))
+	_, errList = parser.Parse("Score: x")
+	if len(errList) != 2 || errList[1] != "Il manque action." {
+		t.Errorf("unexpected errors: %#v", errList)
+	}
+
+	if _, err := NewParser(labels, WithLanguage("xx")); err == nil || err.Error() != "Unknown message language 'xx'" {
+		t.Errorf("expected an unknown language error, got %v", err)
+	}
+}
+
+// TestCustomCatalog checks that catalogs are looked up in order.
+func TestCustomCatalog(t *testing.T) {
+	pirate := Catalog{"en-pirate": {KindRequired: "Arr, ye forgot {label}!"}}
+	parser, err := NewParser([]Label{{Name: "Answer", Required: true}}, WithLanguage("en_PIRATE", pirate, DefaultCatalog))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	if _, errList := parser.Parse(""); !reflect.DeepEqual(errList, []string{"Arr, ye forgot answer!"}) {
+		t.Errorf("unexpected errors: %#v", errList)
+	}
+	// Languages the first catalog lacks come from the next
+	parser, _ = NewParser([]Label{{Name: "Answer", Required: true}}, WithLanguage("es", pirate, DefaultCatalog))
+	if _, errList := parser.Parse(""); !reflect.DeepEqual(errList, []string{"'answer' es obligatorio"}) {
+		t.Errorf("unexpected errors: %#v", errList)
+	}
+}
+
+// TestDefaultCatalogComplete checks that every shipped language phrases every
+// kind of error and warning.
+func TestDefaultCatalogComplete(t *testing.T) {
+	keys := []ErrorKind{
+		SubjectTemplate, OccurrenceSubjectTemplate, KindRequired, KindDependency, KindJSON, "json:empty",
+		KindType, "range:number", "range:min", "range:max", KindPattern, KindChoice, KindFrontMatter,
+		KindMiddleware, KindSystemLabel, KindUngrounded, KindDuplicateBlock, KindAmbiguousLabel,
+	}
+	for language, templates := range DefaultCatalog {
+		if language == "en" {
+			continue
+		}
+		if len(templates) != len(keys) {
+			t.Errorf("%s: expected %d templates, got %d", language, len(keys), len(templates))
+		}
+		for _, key := range keys {
+			if templates[key] == "" {
+				t.Errorf("%s: missing template for %s", language, key)
+			}
+		}
+	}
+}
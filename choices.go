@@ -0,0 +1,176 @@
+package arkaineparser
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// checkChoices normalizes a non-empty parsed value of a label with Choices to
+// the matching choice, recording an error in details when none matches.
+// Values of "list" labels are checked element by element.
+func (p *Parser) checkChoices(labelDef Label, value interface{}, occurrence int, details *Details) interface{} {
+	if len(labelDef.Choices) == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case string:
+		choice, ok := matchChoice(labelDef.Choices, v)
+		if !ok {
+			details.Errors = append(details.Errors, newChoiceError(labelDef.Name, labelDef.Choices, v, occurrence))
+			return value
+		}
+		return choice
+	case []string:
+		normalized := make([]string, 0, len(v))
+		for _, item := range v {
+			choice, ok := matchChoice(labelDef.Choices, item)
+			if !ok {
+				details.Errors = append(details.Errors, newChoiceError(labelDef.Name, labelDef.Choices, item, occurrence))
+				return value
+			}
+			// A choice named twice counts once
+			if !slices.Contains(normalized, choice) {
+				normalized = append(normalized, choice)
+			}
+		}
+		return normalized
+	}
+	return value
+}
+
+// matchChoice finds the choice a value names. It tries, in order: the same
+// text ignoring case, punctuation and separators ("Not-Spam!" is "not spam");
+// a value starting with a single choice as a whole word ("Spam, clearly");
+// and a single choice within a small edit distance of the value ("spma").
+func matchChoice(choices []string, value string) (string, bool) {
+	key := choiceKey(value)
+	if key == "" {
+		return "", false
+	}
+	for _, choice := range choices {
+		if choiceKey(choice) == key {
+			return choice, true
+		}
+	}
+	if choice, ok := uniqueChoice(choices, func(choiceKey string) bool {
+		return strings.HasPrefix(key+" ", choiceKey+" ")
+	}); ok {
+		return choice, true
+	}
+	return uniqueChoice(choices, func(choiceKey string) bool {
+		distance := levenshtein(key, choiceKey)
+		return distance <= 2 && distance*3 <= len([]rune(choiceKey))
+	})
+}
+
+// uniqueChoice returns the only choice whose key satisfies match.
+func uniqueChoice(choices []string, match func(choiceKey string) bool) (string, bool) {
+	found := ""
+	count := 0
+	for _, choice := range choices {
+		if match(choiceKey(choice)) {
+			found = choice
+			count++
+		}
+	}
+	return found, count == 1
+}
+
+// choiceSeparatorPattern matches runs of anything but letters and digits.
+var choiceSeparatorPattern = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// choiceKey lowercases a choice and reduces punctuation and separators to
+// single spaces, so variants of the same choice compare equal.
+func choiceKey(value string) string {
+	value = choiceSeparatorPattern.ReplaceAllString(strings.ToLower(value), " ")
+	return strings.TrimFunc(value, unicode.IsSpace)
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// Patterns locating a multiple-choice token, tried in order: after words such
+// as "answer is" or "option"; at the start of the value ("B)", "(b)", "B."); and
+// as the whole value.
+var choiceTokenPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(?:answer|option|choice)(?:\s+is)?\s*[:\-]?\s*[(\[]?([A-Za-z0-9]{1,3})\b[)\]]?`),
+	regexp.MustCompile(`^\s*[(\[]?([A-Za-z0-9]{1,3})[)\].:\-](?:\s|$)`),
+	regexp.MustCompile(`^\s*[(\[]?([A-Za-z0-9]{1,3})[)\]]?\s*$`),
+}
+
+// choiceWordPattern matches a standalone word or number.
+var choiceWordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// defaultChoiceTokens are the tokens "choice" labels accept without Choices.
+var defaultChoiceTokens = []string{"A", "B", "C", "D"}
+
+// NormalizeChoice extracts the choice token of a multiple-choice answer such
+// as "The answer is (B)", "b) Paris" or "Option B: Paris" and returns it as
+// written in choices, comparing case-insensitively. As a last resort, an
+// answer naming exactly one choice as a standalone word in the same case
+// ("I'd go with B") is accepted. Returns false if no single choice is found.
+func NormalizeChoice(answer string, choices []string) (string, bool) {
+	find := func(token string) (string, bool) {
+		for _, choice := range choices {
+			if strings.EqualFold(choice, token) {
+				return choice, true
+			}
+		}
+		return "", false
+	}
+	for _, pattern := range choiceTokenPatterns {
+		if m := pattern.FindStringSubmatch(answer); m != nil {
+			if choice, ok := find(m[1]); ok {
+				return choice, true
+			}
+		}
+	}
+	found := ""
+	for _, word := range choiceWordPattern.FindAllString(answer, -1) {
+		if !slices.Contains(choices, word) || word == found {
+			continue
+		}
+		if found != "" {
+			// More than one choice is named
+			return "", false
+		}
+		found = word
+	}
+	return found, found != ""
+}
+
+// parseChoiceValue normalizes a multiple-choice answer to its choice token,
+// one of the label's Choices (A to D by default).
+func parseChoiceValue(p *Parser, label Label, value string) (interface{}, error) {
+	choices := defaultChoiceTokens
+	if len(label.Choices) > 0 {
+		choices = label.Choices
+	}
+	choice, ok := NormalizeChoice(value, choices)
+	if !ok {
+		return nil, errors.New("no choice of '" + strings.Join(choices, "', '") + "' found in '" + value + "'")
+	}
+	return choice, nil
+}
@@ -0,0 +1,131 @@
+package arkaineparser
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hlfshell/go-arkaine-parser/parsec"
+)
+
+// Span is a byte range [Start, End) within a text.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Citation is a reference to a source found in a label's value.
+type Citation struct {
+	Ref  string // Reference marker without brackets (e.g. "1"), or "" for inline sources
+	URL  string // Source URL, if one was given inline or in a footnote definition
+	Span Span   // Location of the citation in the original text
+}
+
+// Patterns used to find citations
+var (
+	// Footnote definitions on their own line: "[1]: text", "[^note]: text", "[2] https://..."
+	footnotePattern = regexp.MustCompile(`(?m)^[ \t]*\[\^?([\w-]+)\](?::[ \t]*(.*)|[ \t]+(https?://.*))$`)
+	// Inline source attributions: "(source: https://...)", "(Sources: a, b)"
+	sourcePattern = regexp.MustCompile(`(?i)\((?:sources?|src|via)\s*:\s*([^)]*)\)`)
+	// Inline references: "[1]", "[1, 2]", "[^note]"
+	refPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*|\^[\w-]+)\]`)
+	urlPattern = regexp.MustCompile(`https?://[^\s)\]>]+`)
+	// Whitespace left before punctuation once a citation is removed
+	spaceBeforePunctPattern = regexp.MustCompile(`[ \t]+([.,;:!?])`)
+	multiSpacePattern       = regexp.MustCompile(`[ \t]{2,}`)
+)
+
+// ExtractCitations separates citations from prose, e.g. an answer label's value.
+// It recognizes inline references ("[1]", "[1, 2]", "[^note]"), inline sources
+// ("(source: https://...)"), and footnote definition lines ("[1]: https://...").
+// References take the URL of their footnote definition when one exists.
+// Returns the text with every citation removed and the citations in the order
+// they appear.
+func ExtractCitations(text string) (string, []Citation) {
+	var (
+		citations []Citation
+		removals  []Span
+	)
+
+	// Step 1: Footnote definitions, removed with their whole line
+	urls := make(map[string]string)
+	for _, m := range footnotePattern.FindAllStringSubmatchIndex(text, -1) {
+		ref := text[m[2]:m[3]]
+		url := ""
+		for _, group := range [][2]int{{m[4], m[5]}, {m[6], m[7]}} {
+			if group[0] >= 0 {
+				url = urlPattern.FindString(text[group[0]:group[1]])
+			}
+		}
+		if url != "" {
+			urls[ref] = url
+		}
+		citations = append(citations, Citation{Ref: ref, URL: url, Span: Span{m[0], m[1]}})
+		removals = append(removals, Span{m[0], m[1]})
+	}
+	insideDefinition := func(pos int) bool {
+		for _, r := range removals {
+			if pos >= r.Start && pos < r.End {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Step 2: Inline sources
+	for _, m := range sourcePattern.FindAllStringSubmatchIndex(text, -1) {
+		if insideDefinition(m[0]) {
+			continue
+		}
+		source := strings.TrimSpace(text[m[2]:m[3]])
+		url := urlPattern.FindString(source)
+		if url == "" {
+			url = source
+		}
+		citations = append(citations, Citation{URL: url, Span: Span{m[0], m[1]}})
+		removals = append(removals, Span{m[0], m[1]})
+	}
+
+	// Step 3: Inline references, skipping markdown links ("[1](url)")
+	for _, m := range refPattern.FindAllStringSubmatchIndex(text, -1) {
+		if insideDefinition(m[0]) || (m[1] < len(text) && text[m[1]] == '(') {
+			continue
+		}
+		for _, ref := range strings.Split(strings.TrimPrefix(text[m[2]:m[3]], "^"), ",") {
+			ref = strings.TrimSpace(ref)
+			citations = append(citations, Citation{Ref: ref, Span: Span{m[0], m[1]}})
+		}
+		removals = append(removals, Span{m[0], m[1]})
+	}
+
+	// Step 4: Resolve reference URLs from footnote definitions
+	for i := range citations {
+		if citations[i].URL == "" {
+			citations[i].URL = urls[citations[i].Ref]
+		}
+	}
+	sort.SliceStable(citations, func(i, j int) bool {
+		return citations[i].Span.Start < citations[j].Span.Start
+	})
+	return removeSpans(text, removals), citations
+}
+
+// removeSpans removes non-overlapping spans from text and tidies the
+// whitespace they leave behind.
+func removeSpans(text string, spans []Span) string {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if span.Start < last {
+			continue
+		}
+		b.WriteString(text[last:span.Start])
+		last = span.End
+	}
+	b.WriteString(text[last:])
+	cleaned := spaceBeforePunctPattern.ReplaceAllString(b.String(), "$1")
+	cleaned = multiSpacePattern.ReplaceAllString(cleaned, " ")
+	lines := parsec.SplitLines(cleaned)
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,37 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestExtractCitations checks inline references, inline sources and footnote definitions.
+func TestExtractCitations(t *testing.T) {
+	input := "Go was released in 2009 [1]. It has generics [1, 2] (source: https://go.dev/blog).\n\n[1]: https://go.dev/doc\n[2] https://go.dev/spec"
+	prose, citations := ExtractCitations(input)
+	if prose != "Go was released in 2009. It has generics." {
+		t.Errorf("unexpected prose: %q", prose)
+	}
+	expected := []Citation{
+		{Ref: "1", URL: "https://go.dev/doc", Span: Span{24, 27}},
+		{Ref: "1", URL: "https://go.dev/doc", Span: Span{45, 51}},
+		{Ref: "2", URL: "https://go.dev/spec", Span: Span{45, 51}},
+		{URL: "https://go.dev/blog", Span: Span{52, 81}},
+		{Ref: "1", URL: "https://go.dev/doc", Span: Span{84, 107}},
+		{Ref: "2", URL: "https://go.dev/spec", Span: Span{108, 131}},
+	}
+	if !reflect.DeepEqual(citations, expected) {
+		t.Errorf("citations mismatch.\nGot: %#v\nExpected: %#v", citations, expected)
+	}
+	for _, c := range citations[:3] {
+		if marker := input[c.Span.Start:c.Span.End]; marker != "[1]" && marker != "[1, 2]" {
+			t.Errorf("unexpected span text: %q", marker)
+		}
+	}
+
+	// Markdown links are not citations
+	prose, citations = ExtractCitations("See [docs](https://go.dev).")
+	if prose != "See [docs](https://go.dev)." || len(citations) != 0 {
+		t.Errorf("unexpected extraction: %q %v", prose, citations)
+	}
+}
@@ -0,0 +1,64 @@
+package arkaineparser
+
+// Classification is the parsed output of a classification task.
+type Classification struct {
+	// Classes holds the chosen class, or every chosen class in multi-label mode,
+	// each normalized to the class list
+	Classes []string `json:"classes"`
+	// Confidence is the model's confidence between 0 and 1, or nil if not given
+	Confidence *float64 `json:"confidence,omitempty"`
+}
+
+// Class returns the first chosen class, or "" if there is none.
+func (c Classification) Class() string {
+	if len(c.Classes) == 0 {
+		return ""
+	}
+	return c.Classes[0]
+}
+
+// ClassificationLabels returns the labels of a classification output: a
+// required "Label" (or "Category", "Class") restricted to classes, and an
+// optional "Confidence". In multi-label mode the label holds a list of classes,
+// one per line or separated by commas.
+func ClassificationLabels(classes []string, multi bool) []Label {
+	label := Label{Name: "Label", Required: true, Aliases: []string{"Category", "Class"}, Choices: classes}
+	if multi {
+		label.DataType = "list"
+		label.Aliases = append(label.Aliases, "Labels", "Categories", "Classes")
+	}
+	return []Label{label, ConfidenceLabel("Confidence")}
+}
+
+// Classifier parses classification outputs into Classifications.
+type Classifier struct {
+	parser *Parser
+}
+
+// NewClassifier creates a Classifier for classes using ClassificationLabels.
+func NewClassifier(classes []string, multi bool, opts ...Option) (*Classifier, error) {
+	parser, err := NewParser(ClassificationLabels(classes, multi), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Classifier{parser: parser}, nil
+}
+
+// Parse returns the classification found in text, with the errors Parse
+// reports, such as a class outside the class list.
+func (c *Classifier) Parse(text string) (Classification, []string) {
+	details := c.parser.ParseDetailed(text)
+	var classification Classification
+	switch v := details.Result["label"].(type) {
+	case string:
+		if v != "" {
+			classification.Classes = []string{v}
+		}
+	case []string:
+		classification.Classes = v
+	}
+	if confidence, ok := Confidence(details.Result, "confidence"); ok {
+		classification.Confidence = &confidence
+	}
+	return classification, errorStrings(details.Errors)
+}
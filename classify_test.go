@@ -0,0 +1,49 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestClassifier checks class normalization in single and multi-label mode.
+func TestClassifier(t *testing.T) {
+	classes := []string{"Spam", "Not Spam", "Phishing"}
+	single, err := NewClassifier(classes, false)
+	if err != nil {
+		t.Fatalf("failed to create classifier: %v", err)
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Label: spam", "Spam"},
+		{"Category: **NOT-SPAM**.", "Not Spam"},
+		{"Class: Phishing - it asks for a password", "Phishing"},
+		{"Label: phising", "Phishing"},
+	}
+	for _, tt := range tests {
+		classification, errList := single.Parse(tt.input)
+		if len(errList) > 0 || classification.Class() != tt.expected {
+			t.Errorf("unexpected classification for %q: %#v %v", tt.input, classification, errList)
+		}
+	}
+	classification, errList := single.Parse("Label: newsletter\nConfidence: 90%")
+	if !reflect.DeepEqual(errList, []string{"'label' must be one of 'Spam', 'Not Spam', 'Phishing', got 'newsletter'"}) {
+		t.Errorf("unexpected errors: %#v", errList)
+	}
+	if classification.Confidence == nil || *classification.Confidence != 0.9 {
+		t.Errorf("unexpected confidence: %v", classification.Confidence)
+	}
+
+	multi, err := NewClassifier(classes, true)
+	if err != nil {
+		t.Fatalf("failed to create classifier: %v", err)
+	}
+	classification, errList = multi.Parse("Labels:\n- phishing\n- spam\n- Spam")
+	if len(errList) > 0 || !reflect.DeepEqual(classification.Classes, []string{"Phishing", "Spam"}) {
+		t.Errorf("unexpected classification: %#v %v", classification, errList)
+	}
+	if classification.Confidence != nil {
+		t.Errorf("expected no confidence, got %v", *classification.Confidence)
+	}
+}
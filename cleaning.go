@@ -0,0 +1,88 @@
+package arkaineparser
+
+import "strings"
+
+// CleaningMode chooses how code fences outside label values are cleaned
+// before labels are matched (see WithCleaningMode).
+type CleaningMode string
+
+const (
+	// CleanStrip removes the fence markers and scans the fence content for
+	// labels like any other text. This is the default.
+	CleanStrip CleaningMode = "strip"
+	// CleanPlaceholders hides each closed fence behind a placeholder while
+	// labels are matched and puts it back, markers included, into the value
+	// it belongs to, so fence content is never misread as labels and is fully
+	// preserved.
+	CleanPlaceholders CleaningMode = "placeholders"
+)
+
+// Cleaning describes how the input was cleaned before labels were matched
+// (see WithCleaningReport).
+type Cleaning struct {
+	Text     string    `json:"text"`     // The cleaned text labels were matched in
+	Removals []Removal `json:"removals"` // What cleaning removed, in the order it happened
+}
+
+// Removal is a code fence or inline code span removed by cleaning.
+type Removal struct {
+	Kind RepairKind `json:"kind"` // RepairCodeFence or RepairInlineCode
+	// Offset is the byte offset of Removed in the input text, or -1 when it no
+	// longer appears there verbatim, e.g. after WithHTML converted the text
+	Offset  int    `json:"offset"`
+	Removed string `json:"removed"` // The original text, markers included
+	Kept    string `json:"kept"`    // The content left in its place
+}
+
+// End returns the byte offset just past the removed text in the input, or -1.
+func (r Removal) End() int {
+	if r.Offset < 0 {
+		return -1
+	}
+	return r.Offset + len(r.Removed)
+}
+
+// locateRemovals turns the code fence and inline code repairs into removals,
+// finding each one in text. Repairs of one kind are reported in order of
+// appearance, so each is searched for after the previous one of its kind
+// first; fences held by labels are reported before the others, so a fence not
+// found there is searched for from the start. An offset is never given twice.
+func locateRemovals(text string, repairs []Repair) []Removal {
+	removals := []Removal{}
+	cursor := make(map[RepairKind]int)
+	used := make(map[int]bool)
+	for _, repair := range repairs {
+		if repair.Kind != RepairCodeFence && repair.Kind != RepairInlineCode {
+			continue
+		}
+		offset := indexUnused(text, repair.Before, cursor[repair.Kind], used)
+		if offset < 0 {
+			offset = indexUnused(text, repair.Before, 0, used)
+		}
+		if offset >= 0 {
+			used[offset] = true
+			cursor[repair.Kind] = offset + len(repair.Before)
+		}
+		removals = append(removals, Removal{Kind: repair.Kind, Offset: offset, Removed: repair.Before, Kept: repair.After})
+	}
+	return removals
+}
+
+// indexUnused returns the first offset of substr in text at or after from
+// that is not in used, or -1.
+func indexUnused(text, substr string, from int, used map[int]bool) int {
+	if substr == "" {
+		return -1
+	}
+	for from <= len(text) {
+		i := strings.Index(text[from:], substr)
+		if i < 0 {
+			return -1
+		}
+		if !used[from+i] {
+			return from + i
+		}
+		from += i + 1
+	}
+	return -1
+}
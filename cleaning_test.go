@@ -0,0 +1,87 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestCleaningReport checks that the cleaned text and every removal, with its
+// position in the input, are reported.
+func TestCleaningReport(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Thought"}, {Name: "Action Input", IsJSON: true}}, WithCleaningReport())
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	text := "Thought: call `search` now\nAction Input:\n```json\n{\"q\": \"go\"}\n```\nThought: then `search` again\n```\nplain\n```"
+	details := parser.ParseDetailed(text)
+	if details.Cleaning == nil {
+		t.Fatal("expected a cleaning report")
+	}
+	expected := "Thought: call search now\nAction Input:\n{\"q\": \"go\"}\nThought: then search again\nplain"
+	if details.Cleaning.Text != expected {
+		t.Errorf("unexpected cleaned text: %q", details.Cleaning.Text)
+	}
+	removals := details.Cleaning.Removals
+	if len(removals) != 4 {
+		t.Fatalf("expected 4 removals, got %#v", removals)
+	}
+	// Every removal is found where it was in the input
+	for _, removal := range removals {
+		if removal.Offset < 0 || text[removal.Offset:removal.End()] != removal.Removed {
+			t.Errorf("removal %#v is not at its offset", removal)
+		}
+	}
+	kinds := []RepairKind{removals[0].Kind, removals[1].Kind, removals[2].Kind, removals[3].Kind}
+	if !reflect.DeepEqual(kinds, []RepairKind{RepairCodeFence, RepairCodeFence, RepairInlineCode, RepairInlineCode}) {
+		t.Errorf("unexpected removal kinds: %v", kinds)
+	}
+	// The same inline code twice is located at both places
+	if removals[2].Offset == removals[3].Offset || removals[2].Kept != "search" {
+		t.Errorf("inline code removals not told apart: %#v, %#v", removals[2], removals[3])
+	}
+
+	// Without the option no report is built
+	plain, _ := NewParser([]Label{{Name: "Thought"}})
+	if plain.ParseDetailed(text).Cleaning != nil {
+		t.Error("expected no cleaning report without WithCleaningReport")
+	}
+}
+
+// TestCleanPlaceholders checks that fences are kept verbatim and never
+// scanned for labels in the placeholder cleaning mode.
+func TestCleanPlaceholders(t *testing.T) {
+	labels := []Label{{Name: "Thought"}, {Name: "Answer"}}
+	text := "Answer: Use this config:\n```yaml\nThought: not a label\nkey: value\n```\nas shown. Note `inline`.\nThought: done"
+	parser, err := NewParser(labels, WithCleaningMode(CleanPlaceholders))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, errList := parser.Parse(text)
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	expected := "Use this config:\n```yaml\nThought: not a label\nkey: value\n```\nas shown. Note inline."
+	if result["answer"] != expected {
+		t.Errorf("unexpected answer: %q", result["answer"])
+	}
+	if result["thought"] != "done" {
+		t.Errorf("fence content was matched as a label: %#v", result["thought"])
+	}
+
+	// Stripping, the default, scans the fence content for labels
+	strip, _ := NewParser(labels)
+	result, _ = strip.Parse(text)
+	if !reflect.DeepEqual(result["thought"], []interface{}{"not a label\nkey: value\nas shown. Note inline.", "done"}) {
+		t.Errorf("unexpected thoughts when stripping: %#v", result["thought"])
+	}
+
+	// An unclosed fence is still stripped
+	result, _ = parser.Parse("Answer: start\n```\nThought: open")
+	if result["thought"] != "open" {
+		t.Errorf("unexpected thought with an unclosed fence: %#v", result["thought"])
+	}
+
+	if _, err := NewParser(labels, WithCleaningMode("keep")); err == nil {
+		t.Error("expected an error for an unknown cleaning mode")
+	}
+}
@@ -0,0 +1,208 @@
+// Command aiparse-debug shows how a schema parses LLM outputs: the decision
+// made for every line, the matched label spans, and the validation results.
+//
+// Usage:
+//
+//	aiparse-debug -schema labels.json [output.txt]
+//
+// The schema is a JSON array of labels, e.g.
+// [{"name": "Action", "required_with": ["Action Input"]}, {"name": "Action Input", "is_json": true}].
+// Given an output file, the tool explains it and exits. Otherwise it reads
+// outputs interactively: paste an output and end it with a line holding only
+// ".". Lines starting with ":" are commands (:schema <file>, :help, :quit).
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+)
+
+// ANSI color codes used in the report
+const (
+	colorReset  = "\033[0m"
+	colorBold   = "\033[1m"
+	colorDim    = "\033[2m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+)
+
+// debugger holds the loaded schema and output settings.
+type debugger struct {
+	parser *arkaineparser.Parser
+	out    io.Writer
+	color  bool
+}
+
+func main() {
+	schemaPath := flag.String("schema", "", "path to a JSON file holding the label schema")
+	noColor := flag.Bool("no-color", false, "disable colored output")
+	flag.Parse()
+	if *schemaPath == "" {
+		fmt.Fprintln(os.Stderr, "aiparse-debug: -schema is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	d := &debugger{out: os.Stdout, color: !*noColor && os.Getenv("NO_COLOR") == ""}
+	if err := d.loadSchema(*schemaPath); err != nil {
+		fmt.Fprintln(os.Stderr, "aiparse-debug:", err)
+		os.Exit(1)
+	}
+
+	// Explain a single file and exit
+	if flag.NArg() > 0 {
+		output, err := os.ReadFile(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "aiparse-debug:", err)
+			os.Exit(1)
+		}
+		d.explain(string(output))
+		return
+	}
+	d.repl(os.Stdin)
+}
+
+// loadSchema reads a JSON label schema and builds its parser.
+func (d *debugger) loadSchema(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var labels []arkaineparser.Label
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+	parser, err := arkaineparser.NewParser(labels)
+	if err != nil {
+		return fmt.Errorf("invalid schema %s: %w", path, err)
+	}
+	d.parser = parser
+	return nil
+}
+
+// repl reads outputs and commands from in until EOF or :quit.
+func (d *debugger) repl(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	fmt.Fprintln(d.out, "Paste an LLM output and end it with a line holding only '.'; :help lists commands.")
+	var pending []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Commands are only recognized between outputs
+		if len(pending) == 0 && strings.HasPrefix(line, ":") {
+			if !d.command(strings.Fields(line[1:])) {
+				return
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == "." {
+			d.explain(strings.Join(pending, "\n"))
+			pending = pending[:0]
+			continue
+		}
+		pending = append(pending, line)
+	}
+	// Explain an output cut short by EOF
+	if len(pending) > 0 {
+		d.explain(strings.Join(pending, "\n"))
+	}
+}
+
+// command runs a REPL command. Returns false when the REPL should exit.
+func (d *debugger) command(args []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	switch args[0] {
+	case "quit", "q", "exit":
+		return false
+	case "schema":
+		if len(args) != 2 {
+			fmt.Fprintln(d.out, "usage: :schema <file>")
+		} else if err := d.loadSchema(args[1]); err != nil {
+			fmt.Fprintln(d.out, d.paint(colorRed, err.Error()))
+		} else {
+			fmt.Fprintln(d.out, "schema loaded from", args[1])
+		}
+	default:
+		fmt.Fprintln(d.out, ":schema <file>  load another schema")
+		fmt.Fprintln(d.out, ":quit           exit")
+	}
+	return true
+}
+
+// explain prints the line decisions and validation results for an output.
+func (d *debugger) explain(output string) {
+	explanation := d.parser.Explain(output)
+
+	fmt.Fprintln(d.out, d.paint(colorBold, "Lines:"))
+	for _, line := range explanation.Lines {
+		text := line.Text
+		if line.Kind == arkaineparser.LineLabel {
+			// Highlight the matched label and separator
+			text = d.paint(colorCyan+colorBold, text[line.Span.Start:line.Span.End]) + text[line.Span.End:]
+		}
+		marker := ""
+		if line.EndsValue {
+			marker = d.paint(colorDim, "  (ends value)")
+		}
+		fmt.Fprintf(d.out, "%4d  %s %s%s\n", line.Number, d.describe(line), text, marker)
+	}
+
+	details := explanation.Details
+	result, err := json.MarshalIndent(details.Result, "", "  ")
+	if err != nil {
+		result = []byte(err.Error())
+	}
+	fmt.Fprintln(d.out, d.paint(colorBold, "Result:"))
+	fmt.Fprintln(d.out, string(result))
+
+	if len(details.Errors) == 0 {
+		fmt.Fprintln(d.out, d.paint(colorGreen, "No errors"))
+	}
+	for _, e := range details.Errors {
+		fmt.Fprintln(d.out, d.paint(colorRed, fmt.Sprintf("error [%s]: %s", e.Kind, e.Message)))
+	}
+	for _, w := range details.Warnings {
+		fmt.Fprintln(d.out, d.paint(colorYellow, fmt.Sprintf("warning [%s]: %s", w.Kind, w.Message)))
+	}
+	for _, r := range details.Repairs {
+		fmt.Fprintln(d.out, d.paint(colorDim, fmt.Sprintf("repair [%s] %s: %q -> %q", r.Kind, r.Label, r.Before, r.After)))
+	}
+}
+
+// describe renders the kind of a line and the label it belongs to, padded
+// before coloring so columns stay aligned.
+func (d *debugger) describe(line arkaineparser.LineDecision) string {
+	text := string(line.Kind)
+	if line.Label != "" {
+		text += " " + line.Label
+	}
+	text = fmt.Sprintf("%-22s", text)
+	switch line.Kind {
+	case arkaineparser.LineLabel:
+		return d.paint(colorCyan, text)
+	case arkaineparser.LineLiteral:
+		return d.paint(colorYellow, text)
+	case arkaineparser.LineIgnored:
+		return d.paint(colorDim, text)
+	}
+	return text
+}
+
+// paint wraps text in an ANSI color when colors are enabled.
+func (d *debugger) paint(color, text string) string {
+	if !d.color {
+		return text
+	}
+	return color + text + colorReset
+}
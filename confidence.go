@@ -0,0 +1,53 @@
+package arkaineparser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ConfidenceLabel returns the conventional confidence label: a "confidence"
+// value between 0 and 1, written by the model as e.g. "0.8" or "80%".
+func ConfidenceLabel(name string) Label {
+	return Label{Name: name, DataType: "confidence"}
+}
+
+// parseConfidenceValue parses a confidence as a float64, converting
+// percentages ("80%") to fractions.
+func parseConfidenceValue(p *Parser, label Label, value string) (interface{}, error) {
+	cleaned := p.locale.cleanNumber(value)
+	if percent, ok := strings.CutSuffix(cleaned, "%"); ok {
+		number, err := strconv.ParseFloat(percent, 64)
+		if err != nil {
+			return nil, errors.New("'" + cleaned + "' is not a percentage")
+		}
+		return number / 100, nil
+	}
+	return parseNumberValue(p, label, value)
+}
+
+// Confidence returns the confidence stored under label in a parse result.
+// Returns false if the label is missing, empty, or not a single number.
+func Confidence(result map[string]interface{}, label string) (float64, bool) {
+	value, _ := Result(result).Get(label)
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// FilterByConfidence returns the results whose confidence under label is at
+// least threshold, in their original order. Results without a confidence are
+// dropped.
+func FilterByConfidence(results []map[string]interface{}, label string, threshold float64) []map[string]interface{} {
+	var kept []map[string]interface{}
+	for _, result := range results {
+		if confidence, ok := Confidence(result, label); ok && confidence >= threshold {
+			kept = append(kept, result)
+		}
+	}
+	return kept
+}
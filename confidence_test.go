@@ -0,0 +1,37 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestConfidence checks coercion, the default range, and filtering blocks by confidence.
+func TestConfidence(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Claim", IsBlockStart: true},
+		ConfidenceLabel("Confidence"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	blocks, errList := parser.ParseBlocks("Claim: a\nConfidence: 0.9\nClaim: b\nConfidence: 40%\nClaim: c\nConfidence: 0.75")
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	kept := FilterByConfidence(blocks, "Confidence", 0.75)
+	var claims []interface{}
+	for _, block := range kept {
+		claims = append(claims, block["claim"])
+	}
+	if !reflect.DeepEqual(claims, []interface{}{"a", "c"}) {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+	if confidence, ok := Confidence(blocks[1], "confidence"); !ok || confidence != 0.4 {
+		t.Errorf("unexpected confidence: %v %v", confidence, ok)
+	}
+
+	_, errList = parser.Parse("Claim: d\nConfidence: 1.3")
+	if !reflect.DeepEqual(errList, []string{"'confidence' must be at most 1, got 1.3"}) {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+}
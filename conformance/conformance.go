@@ -0,0 +1,159 @@
+// Package conformance ships the parser's versioned conformance corpus and runs
+// it against any parser, so forks and ports of the parser can verify they
+// behave like this one. The corpus uses the asset convention of package
+// parsertest: for each case <name>, <name>_input.txt holds the LLM output,
+// <name>_output.json the expected result (a JSON array for block parsing) and
+// an optional <name>_errors.json the expected error strings. A conformance.json
+// manifest names the corpus version and the labels of each case, written as
+// Label JSON, so ports in other languages can build the same schemas.
+package conformance
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"slices"
+	"testing"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+	"github.com/hlfshell/go-arkaine-parser/parsertest"
+)
+
+// ManifestFile is the name of the corpus manifest.
+const ManifestFile = "conformance.json"
+
+//go:embed corpus
+var corpusFS embed.FS
+
+// Corpus is a loaded set of conformance cases.
+type Corpus struct {
+	Version string // Version of the corpus, from its manifest
+	Cases   []Case // Cases in manifest order
+}
+
+// Case is a single conformance case.
+type Case struct {
+	Name     string                // Case name, the shared file name prefix
+	Labels   []arkaineparser.Label // Schema the input is parsed with
+	Input    string                // The LLM output to parse
+	Expected interface{}           // Expected result, decoded from JSON
+	Errors   []string              // Expected error strings, in any order
+}
+
+// Blocks reports whether the case is parsed with ParseBlocks.
+func (c Case) Blocks() bool {
+	_, ok := c.Expected.([]interface{})
+	return ok
+}
+
+// manifest is the decoded conformance.json.
+type manifest struct {
+	Version string `json:"version"`
+	Cases   []struct {
+		Name   string                `json:"name"`
+		Labels []arkaineparser.Label `json:"labels"`
+	} `json:"cases"`
+}
+
+// Default returns the corpus shipped with this package.
+func Default() (*Corpus, error) {
+	sub, err := fs.Sub(corpusFS, "corpus")
+	if err != nil {
+		return nil, err
+	}
+	return Load(sub)
+}
+
+// Load reads a corpus from the root of fsys, e.g. os.DirFS of a corpus
+// directory. Returns an error if the manifest is missing or malformed, or a
+// case it names lacks its input or output file.
+func Load(fsys fs.FS) (*Corpus, error) {
+	data, err := fs.ReadFile(fsys, ManifestFile)
+	if err != nil {
+		return nil, err
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.New("Invalid conformance manifest: " + err.Error())
+	}
+	if m.Version == "" {
+		return nil, errors.New("Conformance manifest has no version")
+	}
+	corpus := &Corpus{Version: m.Version, Cases: make([]Case, 0, len(m.Cases))}
+	for _, entry := range m.Cases {
+		c := Case{Name: entry.Name, Labels: entry.Labels, Errors: []string{}}
+		input, err := fs.ReadFile(fsys, entry.Name+"_input.txt")
+		if err != nil {
+			return nil, errors.New("Conformance case '" + entry.Name + "' has no input: " + err.Error())
+		}
+		c.Input = string(input)
+		output, err := fs.ReadFile(fsys, entry.Name+"_output.json")
+		if err != nil {
+			return nil, errors.New("Conformance case '" + entry.Name + "' has no output: " + err.Error())
+		}
+		if err := json.Unmarshal(output, &c.Expected); err != nil {
+			return nil, errors.New("Conformance case '" + entry.Name + "' has invalid output: " + err.Error())
+		}
+		// The errors file is optional; without it no errors are expected
+		if data, err := fs.ReadFile(fsys, entry.Name+"_errors.json"); err == nil {
+			if err := json.Unmarshal(data, &c.Errors); err != nil {
+				return nil, errors.New("Conformance case '" + entry.Name + "' has invalid errors: " + err.Error())
+			}
+		}
+		corpus.Cases = append(corpus.Cases, c)
+	}
+	return corpus, nil
+}
+
+// ParserFactory builds the parser under test for the labels of a case.
+type ParserFactory func(labels []arkaineparser.Label) (*arkaineparser.Parser, error)
+
+// RunConformance runs the shipped corpus against the parsers factory builds,
+// as one subtest per case.
+func RunConformance(t *testing.T, factory ParserFactory) {
+	t.Helper()
+	corpus, err := Default()
+	if err != nil {
+		t.Fatalf("failed to load conformance corpus: %v", err)
+	}
+	corpus.Run(t, factory)
+}
+
+// Run runs every case of the corpus against the parsers factory builds, as
+// one subtest per case. A case passes when the result matches its expected
+// output and the error strings match its expected errors in any order.
+func (c *Corpus) Run(t *testing.T, factory ParserFactory) {
+	t.Helper()
+	for _, tc := range c.Cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			parser, err := factory(tc.Labels)
+			if err != nil {
+				t.Fatalf("failed to create parser: %v", err)
+			}
+			var (
+				got  interface{}
+				errs []string
+			)
+			if tc.Blocks() {
+				got, errs = parser.ParseBlocks(tc.Input)
+			} else {
+				got, errs = parser.Parse(tc.Input)
+			}
+			if diff := parsertest.Diff(got, tc.Expected); diff != "" {
+				t.Errorf("corpus %s, case %s: result mismatch (-expected +got):\n%s", c.Version, tc.Name, diff)
+			}
+			if !sameStrings(errs, tc.Errors) {
+				t.Errorf("corpus %s, case %s: error mismatch.\nGot: %#v\nExpected: %#v", c.Version, tc.Name, errs, tc.Errors)
+			}
+		})
+	}
+}
+
+// sameStrings reports whether a and b hold the same strings in any order.
+func sameStrings(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
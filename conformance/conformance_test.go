@@ -0,0 +1,69 @@
+package conformance
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+)
+
+// TestRunConformance runs the shipped corpus against this repository's parser.
+func TestRunConformance(t *testing.T) {
+	RunConformance(t, func(labels []arkaineparser.Label) (*arkaineparser.Parser, error) {
+		return arkaineparser.NewParser(labels)
+	})
+}
+
+// TestDefault checks the shipped manifest.
+func TestDefault(t *testing.T) {
+	corpus, err := Default()
+	if err != nil {
+		t.Fatalf("failed to load corpus: %v", err)
+	}
+	if corpus.Version != "1" || len(corpus.Cases) != 5 {
+		t.Errorf("unexpected corpus: version %q with %d cases", corpus.Version, len(corpus.Cases))
+	}
+	for _, c := range corpus.Cases {
+		if len(c.Labels) == 0 || c.Input == "" {
+			t.Errorf("case %s is incomplete", c.Name)
+		}
+		if c.Name == "block_parsing" && !c.Blocks() {
+			t.Error("expected block_parsing to parse blocks")
+		}
+	}
+}
+
+// TestLoadErrors checks that incomplete corpora are rejected.
+func TestLoadErrors(t *testing.T) {
+	manifest := `{"version": "2", "cases": [{"name": "a", "labels": [{"name": "X"}]}]}`
+	tests := []struct {
+		files    fstest.MapFS
+		expected string
+	}{
+		{fstest.MapFS{}, "open conformance.json"},
+		{fstest.MapFS{ManifestFile: {Data: []byte(`{"cases": []}`)}}, "Conformance manifest has no version"},
+		{fstest.MapFS{ManifestFile: {Data: []byte(manifest)}}, "Conformance case 'a' has no input"},
+		{fstest.MapFS{
+			ManifestFile:    {Data: []byte(manifest)},
+			"a_input.txt":   {Data: []byte("X: 1")},
+			"a_output.json": {Data: []byte(`{"x": `)},
+		}, "Conformance case 'a' has invalid output"},
+	}
+	for _, test := range tests {
+		_, err := Load(test.files)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected error containing %q, got %v", test.expected, err)
+		}
+	}
+
+	// A complete custom corpus loads
+	corpus, err := Load(fstest.MapFS{
+		ManifestFile:    {Data: []byte(manifest)},
+		"a_input.txt":   {Data: []byte("X: 1")},
+		"a_output.json": {Data: []byte(`{"x": "1"}`)},
+	})
+	if err != nil || corpus.Version != "2" || len(corpus.Cases[0].Errors) != 0 {
+		t.Errorf("unexpected corpus %#v, %v", corpus, err)
+	}
+}
@@ -0,0 +1,420 @@
+package arkaineparser
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dataTypeFunc converts the text value of a label into its typed form. The
+// label is passed for settings such as its Choices.
+// Returns an error describing why the value is malformed.
+type dataTypeFunc func(p *Parser, label Label, value string) (interface{}, error)
+
+// dataTypes maps each supported DataType (other than "text" and "json") to its converter.
+var dataTypes = map[string]dataTypeFunc{
+	"url":         parseURLValue,
+	"path":        parsePathValue,
+	"datetime":    parseDatetimeValue,
+	"duration":    parseDurationValue,
+	"number":      parseNumberValue,
+	"integer":     parseIntegerValue,
+	"confidence":  parseConfidenceValue,
+	"base64":      parseBase64Value,
+	"csv":         parseTableValue(',', false),
+	"tsv":         parseTableValue('\t', false),
+	"csv-header":  parseTableValue(',', true),
+	"tsv-header":  parseTableValue('\t', true),
+	"sql":         parseSQLValue,
+	"diff":        parseDiffValue,
+	"score":       parseScoreValue,
+	"list":        parseListValue,
+	"nested-list": parseNestedListValue,
+	"choice":      parseChoiceValue,
+	"status":      parseStatusValue,
+	"checkbox":    parseCheckboxValue,
+	"quantity":    parseQuantityValue,
+	"percent":     parsePercentValue,
+	"currency":    parseCurrencyValue,
+}
+
+// knownDataType reports whether dataType can be used in a Label.
+func knownDataType(dataType string) bool {
+	switch dataType {
+	case "", "text", "json":
+		return true
+	}
+	_, ok := dataTypes[dataType]
+	return ok
+}
+
+// parseTypedEntry converts a single non-empty entry of a typed label, recording
+// any error in details. Malformed values are returned as the raw string.
+func (p *Parser) parseTypedEntry(labelDef Label, entry string, details *Details) interface{} {
+	convert, ok := dataTypes[labelDef.DataType]
+	if !ok || entry == "" {
+		return entry
+	}
+	value, err := convert(p, labelDef, entry)
+	if err != nil {
+		details.Errors = append(details.Errors, newTypeError(labelDef.Name, labelDef.DataType, err))
+		return entry
+	}
+	return value
+}
+
+// unwrapValue strips the quotes, angle brackets and trailing punctuation LLMs
+// tend to put around single values like URLs and paths.
+func unwrapValue(value string) string {
+	value = strings.TrimSpace(value)
+	for _, pair := range []string{`""`, "''", "<>", "``"} {
+		if len(value) >= 2 && value[0] == pair[0] && value[len(value)-1] == pair[1] {
+			value = strings.TrimSpace(value[1 : len(value)-1])
+		}
+	}
+	return value
+}
+
+// parseURLValue validates a URL and normalizes its scheme and host to lowercase.
+func parseURLValue(p *Parser, label Label, value string) (interface{}, error) {
+	// Trailing punctuation may sit inside or outside the brackets
+	value = strings.TrimRight(unwrapValue(strings.TrimRight(strings.TrimSpace(value), ".,;")), ".,;")
+	if strings.ContainsAny(value, " \t\n") {
+		return nil, errors.New("URL must not contain whitespace")
+	}
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	schemes := p.urlSchemes
+	if schemes == nil {
+		schemes = []string{"http", "https"}
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme == "" {
+		return nil, errors.New("URL has no scheme")
+	}
+	if !slices.Contains(schemes, parsed.Scheme) {
+		return nil, errors.New("scheme '" + parsed.Scheme + "' is not allowed")
+	}
+	if (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host == "" {
+		return nil, errors.New("URL has no host")
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+	return parsed.String(), nil
+}
+
+// parsePathValue cleans a file path, optionally checking that it exists.
+func parsePathValue(p *Parser, label Label, value string) (interface{}, error) {
+	value = unwrapValue(value)
+	if value == "" {
+		return nil, errors.New("path is empty")
+	}
+	if strings.ContainsRune(value, 0) {
+		return nil, errors.New("path contains a NUL byte")
+	}
+	cleaned := filepath.Clean(value)
+	if p.checkPaths {
+		if _, err := os.Stat(cleaned); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, errors.New("path '" + cleaned + "' does not exist")
+			}
+			return nil, err
+		}
+	}
+	return cleaned, nil
+}
+
+// datetimeLayouts are the layouts tried, in order, for "datetime" labels.
+// Layouts without a zone are interpreted as UTC.
+var datetimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 3:04 PM",
+	"2006-01-02 3:04PM",
+	"2006-01-02",
+	"2006/01/02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.RFC822Z,
+	time.RFC822,
+	time.ANSIC,
+	"Monday, January 2, 2006 3:04 PM",
+	"Monday, January 2, 2006",
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006 15:04",
+	"January 2, 2006",
+	"Jan 2, 2006 3:04 PM",
+	"Jan 2, 2006",
+	"2 January 2006 15:04",
+	"2 January 2006",
+	"2 Jan 2006",
+}
+
+// NaturalDateFunc interprets a natural language date such as "tomorrow at 5pm".
+// Returns an error if it does not understand the value.
+type NaturalDateFunc func(value string) (time.Time, error)
+
+// parseDatetimeValue parses a date and time in any of datetimeLayouts, a
+// numeric date in the order of the parser's Locale, or a date with the
+// Locale's month names, falling back to the parser's natural date hook when
+// one is configured.
+func parseDatetimeValue(p *Parser, label Label, value string) (interface{}, error) {
+	value = strings.TrimRight(unwrapValue(value), ".")
+	for _, layout := range datetimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	// Numeric dates depend on the locale's date order
+	if t, ok, err := p.locale.parseNumericDate(value); ok {
+		return t, err
+	}
+	// Month names in the locale's language are rewritten in English
+	if localized := p.locale.localizeDate(value); localized != value {
+		for _, layout := range datetimeLayouts {
+			if t, err := time.Parse(layout, localized); err == nil {
+				return t, nil
+			}
+		}
+	}
+	if p.naturalDates != nil {
+		if t, err := p.naturalDates(value); err == nil {
+			return t, nil
+		}
+	}
+	return nil, errors.New("unrecognized date or time '" + value + "'")
+}
+
+// Patterns used to parse durations written in words
+var (
+	durationPartPattern  = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?|\ban?\b)\s*([a-zµ]+)`)
+	durationClockPattern = regexp.MustCompile(`^(\d+):(\d{2})(?::(\d{2}))?$`)
+	durationGluePattern  = regexp.MustCompile(`(?i)^(?:\s|,|and)*$`)
+)
+
+// durationUnits maps unit spellings to their length.
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond, "nanosecond": time.Nanosecond, "nanoseconds": time.Nanosecond,
+	"us": time.Microsecond, "µs": time.Microsecond, "microsecond": time.Microsecond, "microseconds": time.Microsecond,
+	"ms": time.Millisecond, "millisecond": time.Millisecond, "milliseconds": time.Millisecond,
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "wk": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// parseDurationValue parses Go durations ("2h30m"), clock durations ("1:30:00")
+// and durations in words ("90 seconds", "1 hour and 30 minutes", "2 days").
+func parseDurationValue(p *Parser, label Label, value string) (interface{}, error) {
+	value = strings.TrimRight(unwrapValue(value), ".")
+	if d, err := time.ParseDuration(strings.ReplaceAll(value, " ", "")); err == nil {
+		return d, nil
+	}
+	if m := durationClockPattern.FindStringSubmatch(value); m != nil {
+		hours, _ := strconv.Atoi(m[1])
+		minutes, _ := strconv.Atoi(m[2])
+		seconds := 0
+		if m[3] != "" {
+			seconds, _ = strconv.Atoi(m[3])
+		}
+		return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, nil
+	}
+	// Sum every "<amount> <unit>" part; only separators may remain between them
+	var total time.Duration
+	matches := durationPartPattern.FindAllStringSubmatchIndex(value, -1)
+	last := 0
+	for _, m := range matches {
+		if !durationGluePattern.MatchString(value[last:m[0]]) {
+			return nil, errors.New("unrecognized duration '" + value + "'")
+		}
+		last = m[1]
+		unit, ok := durationUnits[strings.ToLower(value[m[4]:m[5]])]
+		if !ok {
+			return nil, errors.New("unknown duration unit '" + value[m[4]:m[5]] + "'")
+		}
+		amount := 1.0
+		if number := value[m[2]:m[3]]; number[0] >= '0' && number[0] <= '9' {
+			amount, _ = strconv.ParseFloat(number, 64)
+		}
+		total += time.Duration(amount * float64(unit))
+	}
+	if len(matches) == 0 || !durationGluePattern.MatchString(value[last:]) {
+		return nil, errors.New("unrecognized duration '" + value + "'")
+	}
+	return total, nil
+}
+
+// parseNumberValue parses a decimal number into a float64, ignoring thousands
+// separators ("1,234.5", or "1.234,5" with WithLocale(LocaleGerman)) and
+// underscores.
+func parseNumberValue(p *Parser, label Label, value string) (interface{}, error) {
+	cleaned := p.locale.cleanNumber(value)
+	number, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return nil, errors.New("'" + cleaned + "' is not a number")
+	}
+	return number, nil
+}
+
+// parseIntegerValue parses a whole number into an int. Numbers with a zero
+// fraction ("3.0") are accepted.
+func parseIntegerValue(p *Parser, label Label, value string) (interface{}, error) {
+	cleaned := p.locale.cleanNumber(value)
+	if integer, err := strconv.Atoi(cleaned); err == nil {
+		return integer, nil
+	}
+	number, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil || number != math.Trunc(number) || math.Abs(number) > math.MaxInt32 {
+		return nil, errors.New("'" + cleaned + "' is not an integer")
+	}
+	return int(number), nil
+}
+
+// cleanNumber strips wrapping, a trailing period, and digit separators from a number.
+func cleanNumber(value string) string {
+	value = strings.TrimRight(unwrapValue(value), ".")
+	return strings.NewReplacer(",", "", "_", "", " ", "").Replace(value)
+}
+
+// numericValue returns a parsed value as a float64, if it is a number.
+func numericValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case Quantity:
+		return v.Value, true
+	case string:
+		number, err := strconv.ParseFloat(cleanNumber(v), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// checkRange validates a non-empty parsed value against the label's Min and Max,
+// recording an error in details when it is out of range or not a number.
+func (p *Parser) checkRange(labelDef Label, value interface{}, occurrence int, details *Details) {
+	if labelDef.Min == nil && labelDef.Max == nil {
+		return
+	}
+	number, ok := numericValue(value)
+	switch {
+	case !ok:
+		details.Errors = append(details.Errors, newRangeError(labelDef.Name, "number", "", "", occurrence))
+	case labelDef.Min != nil && number < *labelDef.Min:
+		details.Errors = append(details.Errors, newRangeError(labelDef.Name, "min", formatNumber(*labelDef.Min), formatNumber(number), occurrence))
+	case labelDef.Max != nil && number > *labelDef.Max:
+		details.Errors = append(details.Errors, newRangeError(labelDef.Name, "max", formatNumber(*labelDef.Max), formatNumber(number), occurrence))
+	}
+}
+
+// formatNumber formats a number in its shortest form.
+func formatNumber(number float64) string {
+	return strconv.FormatFloat(number, 'g', -1, 64)
+}
+
+// DefaultMaxBinarySize is the largest decoded "base64" value accepted unless
+// WithMaxBinarySize says otherwise.
+const DefaultMaxBinarySize = 1 << 20
+
+// dataURIPattern matches the prefix of a data URI such as "data:image/png;base64,".
+var dataURIPattern = regexp.MustCompile(`^(?i)data:[^,;]*(;[^,;]*)*;base64,`)
+
+// parseBase64Value decodes a base64 payload into a []byte. The payload may be
+// wrapped over several lines or given as a data URI, and may use either the
+// standard or the URL-safe alphabet, with or without padding.
+func parseBase64Value(p *Parser, label Label, value string) (interface{}, error) {
+	value = dataURIPattern.ReplaceAllString(unwrapValue(value), "")
+	value = strings.Join(strings.Fields(value), "")
+	maxSize := p.maxBinarySize
+	if maxSize == 0 {
+		maxSize = DefaultMaxBinarySize
+	}
+	// Check the size before decoding, so oversized payloads are never held twice
+	if size := base64.RawStdEncoding.DecodedLen(len(strings.TrimRight(value, "="))); maxSize > 0 && size > maxSize {
+		return nil, errors.New("payload of " + strconv.Itoa(size) + " bytes exceeds the limit of " + strconv.Itoa(maxSize) + " bytes")
+	}
+	encoding := base64.RawStdEncoding
+	if strings.ContainsAny(value, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+	decoded, err := encoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, errors.New("malformed base64: " + err.Error())
+	}
+	return decoded, nil
+}
+
+// parseTableValue returns a converter reading a multiline value as CSV (or TSV)
+// into a [][]string. With header, the first row names the columns and each
+// following row becomes a map[string]string keyed by them, producing a
+// []map[string]string. Every row must have as many fields as the first one.
+func parseTableValue(comma rune, header bool) dataTypeFunc {
+	return func(p *Parser, label Label, value string) (interface{}, error) {
+		reader := csv.NewReader(strings.NewReader(value))
+		reader.Comma = comma
+		reader.LazyQuotes = true
+		reader.TrimLeadingSpace = comma != '\t'
+		rows, err := reader.ReadAll()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, errors.New("row " + strconv.Itoa(parseErr.Line) + ": " + parseErr.Err.Error())
+			}
+			return nil, err
+		}
+		if !header {
+			return rows, nil
+		}
+		records := make([]map[string]string, 0, len(rows)-1)
+		for _, row := range rows[1:] {
+			record := make(map[string]string, len(row))
+			for i, field := range row {
+				record[rows[0][i]] = field
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	}
+}
+
+// listMarkerPattern matches a bullet or number starting a list item.
+var listMarkerPattern = regexp.MustCompile(`^\s*(?:[-*+\x{2022}]|\d+[.)])\s+`)
+
+// parseListValue splits a value into a []string of items: one per line for a
+// multiline value, dropping bullets and numbers, or separated by commas or
+// semicolons on a single line.
+func parseListValue(p *Parser, label Label, value string) (interface{}, error) {
+	var parts []string
+	if strings.Contains(value, "\n") {
+		parts = strings.Split(value, "\n")
+	} else {
+		parts = strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' })
+	}
+	items := []string{}
+	for _, part := range parts {
+		item := strings.TrimSpace(listMarkerPattern.ReplaceAllString(part, ""))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
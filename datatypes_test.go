@@ -0,0 +1,311 @@
+package arkaineparser
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestURLAndPathTypes checks validation and normalization of url and path labels.
+func TestURLAndPathTypes(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Link", DataType: "url"},
+		{Name: "File", DataType: "path"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, errList := parser.Parse("Link: <HTTPS://Example.COM/Docs?q=1>.\nFile: \"./data//../data/report.csv\"")
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	if result["link"] != "https://example.com/Docs?q=1" {
+		t.Errorf("unexpected url: %v", result["link"])
+	}
+	if result["file"] != filepath.Clean("data/report.csv") {
+		t.Errorf("unexpected path: %v", result["file"])
+	}
+
+	details := parser.ParseDetailed("Link: javascript:alert(1)\nFile: report.csv")
+	if len(details.Errors) != 1 || details.Errors[0].Kind != KindType ||
+		details.Errors[0].Message != "Invalid url in 'link': scheme 'javascript' is not allowed" {
+		t.Errorf("unexpected errors: %#v", details.Errors)
+	}
+	// Malformed values are kept as raw text
+	if details.Result["link"] != "javascript:alert(1)" {
+		t.Errorf("unexpected raw value: %v", details.Result["link"])
+	}
+
+	// The existence check is opt-in
+	checked, err := NewParser([]Label{{Name: "File", DataType: "path"}}, WithPathExistenceCheck())
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	_, errList = checked.Parse("File: " + filepath.Join(t.TempDir(), "missing.txt"))
+	if len(errList) != 1 {
+		t.Errorf("expected a missing path error, got %v", errList)
+	}
+
+	// Unknown data types are rejected, and "json" is the same as IsJSON
+	if _, err := NewParser([]Label{{Name: "Link", DataType: "uri"}}); err == nil {
+		t.Errorf("expected unknown data type error")
+	}
+	jsonParser, err := NewParser([]Label{{Name: "Arguments", DataType: "JSON"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	if result, _ := jsonParser.Parse("Arguments: {\"a\": 1}"); result["arguments"].(map[string]interface{})["a"] != 1.0 {
+		t.Errorf("expected JSON value, got %#v", result["arguments"])
+	}
+}
+
+// TestDatetimeAndDurationTypes checks the formats accepted by datetime and duration labels.
+func TestDatetimeAndDurationTypes(t *testing.T) {
+	tomorrow := time.Date(2024, 5, 2, 17, 0, 0, 0, time.UTC)
+	parser, err := NewParser([]Label{
+		{Name: "When", DataType: "datetime"},
+		{Name: "Every", DataType: "duration"},
+	}, WithNaturalDates(func(value string) (time.Time, error) {
+		if value == "tomorrow at 5pm" {
+			return tomorrow, nil
+		}
+		return time.Time{}, errors.New("unknown")
+	}))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	dates := map[string]time.Time{
+		"2024-05-01T10:00:00Z": time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		"2024-05-01 10:00":     time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		"May 1, 2024":          time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"tomorrow at 5pm":      tomorrow,
+	}
+	for input, expected := range dates {
+		result, errList := parser.Parse("When: " + input)
+		if len(errList) > 0 || !result["when"].(time.Time).Equal(expected) {
+			t.Errorf("%q: got %v, errors %v", input, result["when"], errList)
+		}
+	}
+	durations := map[string]time.Duration{
+		"2h30m":                 150 * time.Minute,
+		"90 seconds":            90 * time.Second,
+		"1 hour and 30 minutes": 90 * time.Minute,
+		"1.5 hours":             90 * time.Minute,
+		"an hour":               time.Hour,
+		"2 days":                48 * time.Hour,
+		"1:30:00":               90 * time.Minute,
+	}
+	for input, expected := range durations {
+		result, errList := parser.Parse("Every: " + input)
+		if len(errList) > 0 || result["every"] != expected {
+			t.Errorf("%q: got %v, errors %v", input, result["every"], errList)
+		}
+	}
+	_, errList := parser.Parse("When: someday\nEvery: 3 fortnights")
+	expected := []string{
+		"Invalid datetime in 'when': unrecognized date or time 'someday'",
+		"Invalid duration in 'every': unknown duration unit 'fortnights'",
+	}
+	if !reflect.DeepEqual(errList, expected) {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+}
+
+// TestNumericRange checks number coercion and Min/Max validation.
+func TestNumericRange(t *testing.T) {
+	labels, err := NewSchema().
+		Label("Confidence").DataType("number").Range(0, 1).
+		Label("Budget").DataType("integer").Min(1).
+		Label("Score").Max(10).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected schema error: %v", err)
+	}
+	parser, err := NewParser(labels)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, errList := parser.Parse("Confidence: 0.85\nBudget: 1,200\nScore: 7")
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	if result["confidence"] != 0.85 || result["budget"] != 1200 || result["score"] != "7" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	details := parser.ParseDetailed("Confidence: 1.4\nBudget: 2.5\nScore: high\nScore: 12")
+	expected := []string{
+		"'confidence' must be at most 1, got 1.4",
+		"Invalid integer in 'budget': '2.5' is not an integer",
+		"'score' occurrence 1 must be a number",
+		"'score' occurrence 2 must be at most 10, got 12",
+	}
+	if got := errorStrings(details.Errors); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected errors.\nGot: %v\nExpected: %v", got, expected)
+	}
+	if details.Errors[0].Kind != KindRange || details.Errors[3].Occurrence != 2 {
+		t.Errorf("unexpected structured error: %#v", details.Errors)
+	}
+}
+
+// TestMatchPattern checks that values must match their label's pattern in full.
+func TestMatchPattern(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Ticket", MatchPattern: `[A-Z]+-\d+`},
+		{Name: "Version", MatchPattern: `v?\d+\.\d+\.\d+`},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	if _, errList := parser.Parse("Ticket: OPS-42\nVersion: v1.2.3"); len(errList) > 0 {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+	details := parser.ParseDetailed("Ticket: see OPS-42\nVersion: 1.2")
+	expected := []string{
+		`'ticket' must match pattern '[A-Z]+-\d+', got 'see OPS-42'`,
+		`'version' must match pattern 'v?\d+\.\d+\.\d+', got '1.2'`,
+	}
+	if got := errorStrings(details.Errors); !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected errors.\nGot: %v\nExpected: %v", got, expected)
+	}
+	if details.Errors[0].Kind != KindPattern {
+		t.Errorf("unexpected kind: %v", details.Errors[0].Kind)
+	}
+	if _, err := NewParser([]Label{{Name: "Ticket", MatchPattern: `[A-Z`}}); err == nil {
+		t.Errorf("expected invalid pattern error")
+	}
+}
+
+// TestBase64Type checks decoding and size limits of base64 labels.
+func TestBase64Type(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Image", DataType: "base64"}, {Name: "Note"}}, WithMaxBinarySize(16))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	// Wrapped payloads and data URIs are accepted, with or without padding
+	for _, input := range []string{"Image: aGVsbG8g\nd29ybGQ=", "Image: data:text/plain;base64,aGVsbG8gd29ybGQ", "Image: `aGVsbG8gd29ybGQ=`"} {
+		result, errList := parser.Parse(input)
+		if len(errList) > 0 || !reflect.DeepEqual(result["image"], []byte("hello world")) {
+			t.Errorf("unexpected result for %q: %#v %v", input, result, errList)
+		}
+	}
+
+	details := parser.ParseDetailed("Image: not base64!\nNote: x")
+	if len(details.Errors) != 1 || details.Errors[0].Kind != KindType || details.Errors[0].Label != "image" {
+		t.Errorf("unexpected errors: %#v", details.Errors)
+	}
+	_, errList := parser.Parse("Image: " + strings.Repeat("QUFB", 6))
+	if len(errList) != 1 || errList[0] != "Invalid base64 in 'image': payload of 18 bytes exceeds the limit of 16 bytes" {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+}
+
+// TestTableTypes checks csv and tsv labels, with and without headers.
+func TestTableTypes(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Rows", DataType: "csv"},
+		{Name: "People", DataType: "csv-header"},
+		{Name: "Scores", DataType: "tsv"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Rows:\na, b\n\"c, d\",e\nPeople:\nname,age\nAda,36\nAlan,41\nScores: x\t1\ny\t2"
+	result, errList := parser.Parse(input)
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	expected := map[string]interface{}{
+		"rows":   [][]string{{"a", "b"}, {"c, d", "e"}},
+		"people": []map[string]string{{"name": "Ada", "age": "36"}, {"name": "Alan", "age": "41"}},
+		"scores": [][]string{{"x", "1"}, {"y", "2"}},
+	}
+	if !reflect.DeepEqual(map[string]interface{}(result), expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", result, expected)
+	}
+
+	// Ragged rows are reported with their row number
+	_, errList = parser.Parse("Rows:\na,b\nc,d\ne")
+	if len(errList) != 1 || errList[0] != "Invalid csv in 'rows': row 3: wrong number of fields" {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+
+	// Format writes tables back as CSV
+	text, err := parser.Format(map[string]interface{}{"rows": [][]string{{"a", "b c"}, {"d,e", "f"}}})
+	if err != nil || text != "Rows: a,b c\n\"d,e\",f" {
+		t.Errorf("unexpected format: %q %v", text, err)
+	}
+}
+
+// TestChoiceType checks normalization of multiple-choice answers.
+func TestChoiceType(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Answer", DataType: "choice"}, {Name: "Grade", DataType: "choice", Choices: []string{"1", "2", "3"}}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"The answer is (B)", "B"},
+		{"b) Paris", "B"},
+		{"Option C: London", "C"},
+		{"[d]", "D"},
+		{"I'd go with A", "A"},
+		{"**Answer: c**", "C"},
+	}
+	for _, tt := range tests {
+		result, errList := parser.Parse("Answer: " + tt.input)
+		if len(errList) > 0 || result["answer"] != tt.expected {
+			t.Errorf("unexpected result for %q: %#v %v", tt.input, result["answer"], errList)
+		}
+	}
+	result, errList := parser.Parse("Answer: either A or B\nGrade: choice 2, definitely")
+	if result["grade"] != "2" || !reflect.DeepEqual(errList, []string{"Invalid choice in 'answer': no choice of 'A', 'B', 'C', 'D' found in 'either A or B'"}) {
+		t.Errorf("unexpected result: %#v %v", result, errList)
+	}
+	if choice, ok := NormalizeChoice("Option 2 - maybe", []string{"1", "2"}); !ok || choice != "2" {
+		t.Errorf("unexpected choice: %q %v", choice, ok)
+	}
+}
+
+// TestStatusTypes checks status emoji and checkboxes for "status" and "checkbox" labels.
+func TestStatusTypes(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Status", DataType: "status"}, {Name: "Completed", DataType: "checkbox"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	tests := []struct {
+		input    string
+		status   string
+		complete bool
+	}{
+		{"✅ all tests pass", StatusDone, true},
+		{"✔️", StatusDone, true},
+		{"[x] merged", StatusDone, true},
+		{"[ ] write docs", StatusPending, false},
+		{"❌ build broke", StatusFailed, false},
+		{"🚧 working on it", StatusInProgress, false},
+		{"Yes, finished.", StatusDone, true},
+		{"not done yet", StatusPending, false},
+		{"Blocked on review", StatusBlocked, false},
+		{"N/A", StatusSkipped, false},
+	}
+	for _, tt := range tests {
+		result, errList := parser.Parse("Status: " + tt.input + "\nCompleted: " + tt.input)
+		if len(errList) > 0 || result["status"] != tt.status || result["completed"] != tt.complete {
+			t.Errorf("unexpected result for %q: %#v %v", tt.input, result, errList)
+		}
+	}
+	_, errList := parser.Parse("Status: ✅ ❌\nCompleted: maybe")
+	expected := []string{
+		"Invalid status in 'status': conflicting status markers in '✅ ❌'",
+		"Invalid checkbox in 'completed': no status in 'maybe'",
+	}
+	if !reflect.DeepEqual(errList, expected) {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+}
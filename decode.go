@@ -0,0 +1,386 @@
+package arkaineparser
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DecodeHookFunc converts a parsed value before it is assigned to a target of
+// type to, similar to mapstructure's DecodeHookFunc. Hooks run in order, each
+// receiving the previous hook's output; returning data unchanged passes it on.
+type DecodeHookFunc func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error)
+
+// DecodeOption configures Decode and ParseInto.
+type DecodeOption func(*decoder)
+
+// WithDecodeHook adds a hook that runs before every value is assigned.
+func WithDecodeHook(hook DecodeHookFunc) DecodeOption {
+	return func(d *decoder) {
+		d.hooks = append(d.hooks, hook)
+	}
+}
+
+// WithWeaklyTypedInput enables lenient conversions between scalar kinds:
+// strings such as "42", "3.5" or "yes" decode into numeric and bool fields,
+// and numbers and bools decode into string fields.
+func WithWeaklyTypedInput() DecodeOption {
+	return func(d *decoder) {
+		d.weak = true
+	}
+}
+
+// decoder holds the configuration of a single Decode call.
+type decoder struct {
+	hooks []DecodeHookFunc
+	weak  bool
+}
+
+// ParseInto parses text and decodes the result into the struct pointed to by
+// out (see Decode). Returns the parse errors followed by any decode errors.
+func (p *Parser) ParseInto(text string, out interface{}, opts ...DecodeOption) []string {
+	details := p.ParseDetailed(text)
+	errList := errorStrings(details.Errors)
+	for _, err := range Decode(details.Result, out, opts...) {
+		errList = append(errList, err.Error())
+	}
+	return errList
+}
+
+// Decode assigns the values of result to the struct pointed to by out.
+// Each exported field is matched, case-insensitively, against the result key
+// named by its `parser` tag, else its `json` tag, else the field name; a tag
+// of "-" skips the field. Values that already fit are assigned as they are;
+// otherwise nested structs, maps, slices of any element type (such as the
+// []string of "list" values or the records of groups) and pointers are
+// decoded recursively, and a single value decodes into a one-element slice
+// since Parse flattens labels that appear once. Targets implementing
+// encoding.TextUnmarshaler are decoded from strings.
+// Returns one error per value that could not be assigned; other fields are
+// still decoded.
+func Decode(result Result, out interface{}, opts ...DecodeOption) []error {
+	d := &decoder{}
+	for _, opt := range opts {
+		opt(d)
+	}
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return []error{fmt.Errorf("decode target must be a non-nil pointer, got %T", out)}
+	}
+	var errList []error
+	d.decode("", map[string]interface{}(result), target.Elem(), &errList)
+	return errList
+}
+
+// decode assigns data to target, recording failures under path in errList.
+func (d *decoder) decode(path string, data interface{}, target reflect.Value, errList *[]error) {
+	// Run hooks first so they can replace the value entirely
+	for _, hook := range d.hooks {
+		converted, err := hook(reflect.TypeOf(data), target.Type(), data)
+		if err != nil {
+			*errList = append(*errList, decodeError(path, err.Error()))
+			return
+		}
+		data = converted
+	}
+	if data == nil {
+		return
+	}
+	value := reflect.ValueOf(data)
+
+	// Values that already fit are assigned directly
+	if value.Type().AssignableTo(target.Type()) {
+		target.Set(value)
+		return
+	}
+	// Strings decode into TextUnmarshaler targets such as enums
+	if str, ok := data.(string); ok && target.CanAddr() {
+		if unmarshaler, ok := target.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if err := unmarshaler.UnmarshalText([]byte(str)); err != nil {
+				*errList = append(*errList, decodeError(path, err.Error()))
+			}
+			return
+		}
+	}
+
+	switch target.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(target.Type().Elem())
+		d.decode(path, data, elem.Elem(), errList)
+		target.Set(elem)
+	case reflect.Interface:
+		if !value.Type().AssignableTo(target.Type()) {
+			*errList = append(*errList, mismatchError(path, data, target.Type()))
+			return
+		}
+		target.Set(value)
+	case reflect.Struct:
+		d.decodeStruct(path, data, target, errList)
+	case reflect.Map:
+		d.decodeMap(path, data, target, errList)
+	case reflect.Slice:
+		d.decodeSlice(path, data, target, errList)
+	case reflect.String:
+		d.decodeString(path, data, target, errList)
+	case reflect.Bool:
+		d.decodeBool(path, data, target, errList)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		d.decodeNumber(path, data, target, errList)
+	default:
+		*errList = append(*errList, mismatchError(path, data, target.Type()))
+	}
+}
+
+// decodeStruct assigns the entries of a map to the matching struct fields.
+func (d *decoder) decodeStruct(path string, data interface{}, target reflect.Value, errList *[]error) {
+	source, ok := asMap(data)
+	if !ok {
+		*errList = append(*errList, mismatchError(path, data, target.Type()))
+		return
+	}
+	// Index the source keys case-insensitively
+	lowered := make(map[string]interface{}, len(source))
+	for key, value := range source {
+		lowered[strings.ToLower(key)] = value
+	}
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := fieldKey(field)
+		if key == "-" {
+			continue
+		}
+		value, present := lowered[key]
+		if !present {
+			continue
+		}
+		d.decode(joinDecodePath(path, key), value, target.Field(i), errList)
+	}
+}
+
+// decodeMap assigns the entries of a map to a map target with string keys.
+func (d *decoder) decodeMap(path string, data interface{}, target reflect.Value, errList *[]error) {
+	source, ok := asMap(data)
+	if !ok || target.Type().Key().Kind() != reflect.String {
+		*errList = append(*errList, mismatchError(path, data, target.Type()))
+		return
+	}
+	result := reflect.MakeMapWithSize(target.Type(), len(source))
+	for key, value := range source {
+		elem := reflect.New(target.Type().Elem()).Elem()
+		d.decode(joinDecodePath(path, key), value, elem, errList)
+		result.SetMapIndex(reflect.ValueOf(key).Convert(target.Type().Key()), elem)
+	}
+	target.Set(result)
+}
+
+// decodeSlice assigns a list, or a single value as a one-element list, to a slice target.
+func (d *decoder) decodeSlice(path string, data interface{}, target reflect.Value, errList *[]error) {
+	// Strings decode into byte slices as their raw bytes
+	if str, ok := data.(string); ok && target.Type().Elem().Kind() == reflect.Uint8 {
+		target.SetBytes([]byte(str))
+		return
+	}
+	// Lists of any element type, such as the []string of "list" values, the
+	// [][]string of "csv" values and the records of groups, decode item by item
+	items := reflect.ValueOf(data)
+	if kind := items.Kind(); kind != reflect.Slice && kind != reflect.Array {
+		items = reflect.ValueOf([]interface{}{data})
+	}
+	result := reflect.MakeSlice(target.Type(), items.Len(), items.Len())
+	for i := 0; i < items.Len(); i++ {
+		d.decode(fmt.Sprintf("%s[%d]", path, i), items.Index(i).Interface(), result.Index(i), errList)
+	}
+	target.Set(result)
+}
+
+// decodeString assigns a string, or a scalar when weakly typed, to a string target.
+func (d *decoder) decodeString(path string, data interface{}, target reflect.Value, errList *[]error) {
+	switch v := data.(type) {
+	case string:
+		target.SetString(v)
+		return
+	case float64, int, int64, bool:
+		if d.weak {
+			target.SetString(fmt.Sprint(v))
+			return
+		}
+	}
+	*errList = append(*errList, mismatchError(path, data, target.Type()))
+}
+
+// decodeBool assigns a bool, or a boolean-like string when weakly typed, to a bool target.
+func (d *decoder) decodeBool(path string, data interface{}, target reflect.Value, errList *[]error) {
+	switch v := data.(type) {
+	case bool:
+		target.SetBool(v)
+		return
+	case string:
+		if d.weak {
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "true", "yes", "y", "1":
+				target.SetBool(true)
+				return
+			case "false", "no", "n", "0", "":
+				target.SetBool(false)
+				return
+			}
+		}
+	}
+	*errList = append(*errList, mismatchError(path, data, target.Type()))
+}
+
+// decodeNumber assigns a number, or a numeric string when weakly typed, to a numeric target.
+func (d *decoder) decodeNumber(path string, data interface{}, target reflect.Value, errList *[]error) {
+	var number float64
+	switch v := data.(type) {
+	case float64:
+		number = v
+	case int:
+		number = float64(v)
+	case int64:
+		number = float64(v)
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if !d.weak || err != nil {
+			*errList = append(*errList, mismatchError(path, data, target.Type()))
+			return
+		}
+		number = parsed
+	default:
+		*errList = append(*errList, mismatchError(path, data, target.Type()))
+		return
+	}
+	switch target.Kind() {
+	case reflect.Float32, reflect.Float64:
+		target.SetFloat(number)
+		return
+	}
+	// Integer targets require a whole number that fits
+	if number != math.Trunc(number) {
+		*errList = append(*errList, decodeError(path, fmt.Sprintf("%v is not a whole number", number)))
+		return
+	}
+	switch target.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if number < 0 || target.OverflowUint(uint64(number)) {
+			*errList = append(*errList, decodeError(path, fmt.Sprintf("%v overflows %s", number, target.Type())))
+			return
+		}
+		target.SetUint(uint64(number))
+	default:
+		if target.OverflowInt(int64(number)) {
+			*errList = append(*errList, decodeError(path, fmt.Sprintf("%v overflows %s", number, target.Type())))
+			return
+		}
+		target.SetInt(int64(number))
+	}
+}
+
+// StringToTimeHook returns a hook decoding strings into time.Time fields using
+// the given layouts, tried in order.
+func StringToTimeHook(layouts ...string) DecodeHookFunc {
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		str, ok := data.(string)
+		if !ok || to != reflect.TypeOf(time.Time{}) {
+			return data, nil
+		}
+		for _, layout := range layouts {
+			if parsed, err := time.Parse(layout, strings.TrimSpace(str)); err == nil {
+				return parsed, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot parse %q as a time", str)
+	}
+}
+
+// StringToDurationHook returns a hook decoding strings such as "1h30m" into
+// time.Duration fields.
+func StringToDurationHook() DecodeHookFunc {
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		str, ok := data.(string)
+		if !ok || to != reflect.TypeOf(time.Duration(0)) {
+			return data, nil
+		}
+		return time.ParseDuration(strings.TrimSpace(str))
+	}
+}
+
+// EnumHook returns a hook decoding strings into fields of type T by looking up
+// their case-insensitive name in values. Unknown names are errors.
+func EnumHook[T any](values map[string]T) DecodeHookFunc {
+	lowered := make(map[string]T, len(values))
+	for name, value := range values {
+		lowered[strings.ToLower(name)] = value
+	}
+	enumType := reflect.TypeOf((*T)(nil)).Elem()
+	return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+		str, ok := data.(string)
+		if !ok || to != enumType {
+			return data, nil
+		}
+		value, known := lowered[strings.ToLower(strings.TrimSpace(str))]
+		if !known {
+			return nil, fmt.Errorf("unknown %s value %q", enumType, str)
+		}
+		return value, nil
+	}
+}
+
+// fieldKey returns the lowercase result key a struct field decodes from.
+func fieldKey(field reflect.StructField) string {
+	for _, tag := range []string{"parser", "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" {
+			return strings.ToLower(name)
+		}
+	}
+	return strings.ToLower(field.Name)
+}
+
+// asMap returns data as a string-keyed map, accepting Result values too.
+func asMap(data interface{}) (map[string]interface{}, bool) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		return v, true
+	case Result:
+		return v, true
+	}
+	// Other maps with string keys, such as the rows of "csv-header" values
+	value := reflect.ValueOf(data)
+	if value.Kind() != reflect.Map || value.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	converted := make(map[string]interface{}, value.Len())
+	iter := value.MapRange()
+	for iter.Next() {
+		converted[iter.Key().String()] = iter.Value().Interface()
+	}
+	return converted, true
+}
+
+// joinDecodePath appends a key to a decode error path.
+func joinDecodePath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// decodeError builds a decode error for the value at path.
+func decodeError(path, message string) error {
+	return fmt.Errorf("decode error in '%s': %s", path, message)
+}
+
+// mismatchError reports a value that cannot be assigned to the target type.
+func mismatchError(path string, data interface{}, to reflect.Type) error {
+	return decodeError(path, fmt.Sprintf("cannot decode %T into %s", data, to))
+}
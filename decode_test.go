@@ -0,0 +1,102 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type priority int
+
+const (
+	priorityLow priority = iota
+	priorityHigh
+)
+
+type scheduledTask struct {
+	Name     string        `parser:"task"`
+	Due      time.Time     `parser:"due"`
+	Every    time.Duration `parser:"every"`
+	Priority priority      `parser:"priority"`
+	Retries  int           `parser:"retries"`
+	Tags     []string      `parser:"tag"`
+	Args     struct {
+		Target string  `json:"target"`
+		Limit  float64 `json:"limit"`
+	} `parser:"arguments"`
+}
+
+// TestParseInto checks struct decoding with hooks and weakly typed input.
+func TestParseInto(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Task"}, {Name: "Due"}, {Name: "Every"}, {Name: "Priority"}, {Name: "Retries"}, {Name: "Tag"},
+		{Name: "Arguments", IsJSON: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Task: backup\nDue: 2024-05-01T10:00:00Z\nEvery: 1h30m\nPriority: HIGH\nRetries: 3\nTag: nightly\nArguments: {\"target\": \"s3\", \"limit\": 5}"
+	var task scheduledTask
+	errList := parser.ParseInto(input, &task,
+		WithDecodeHook(StringToTimeHook(time.RFC3339)),
+		WithDecodeHook(StringToDurationHook()),
+		WithDecodeHook(EnumHook(map[string]priority{"low": priorityLow, "high": priorityHigh})),
+		WithWeaklyTypedInput(),
+	)
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	if task.Name != "backup" || task.Every != 90*time.Minute || task.Priority != priorityHigh || task.Retries != 3 {
+		t.Errorf("unexpected scalar fields: %#v", task)
+	}
+	if !task.Due.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected due time: %v", task.Due)
+	}
+	if !reflect.DeepEqual(task.Tags, []string{"nightly"}) || task.Args.Target != "s3" || task.Args.Limit != 5 {
+		t.Errorf("unexpected nested fields: %#v", task)
+	}
+
+	// Without weak typing, a string cannot decode into an int
+	var strict struct {
+		Retries int `parser:"retries"`
+	}
+	errList = parser.ParseInto("Retries: three", &strict)
+	if len(errList) != 1 || errList[0] != "decode error in 'retries': cannot decode string into int" {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+}
+
+// TestParseIntoLists checks decoding the list values the parser produces:
+// []string, [][]string and group records.
+func TestParseIntoLists(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Tags", DataType: "list"}, {Name: "Step"}, {Name: "Rows", DataType: "csv"},
+		{Name: "Item", Group: "Items"}, {Name: "Qty", DataType: "integer", Group: "Items"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	type item struct {
+		Name string `parser:"item"`
+		Qty  int    `parser:"qty"`
+	}
+	var out struct {
+		Tags  []string   `parser:"tags"`
+		Steps []string   `parser:"step"`
+		Rows  [][]string `parser:"rows"`
+		Items []item     `parser:"items"`
+	}
+	input := "Tags: a, b\nStep: one\nStep: two\nRows:\nx,1\ny,2\nItem: apple\nQty: 3\nItem: pear\nQty: 5"
+	if errList := parser.ParseInto(input, &out); len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	if !reflect.DeepEqual(out.Tags, []string{"a", "b"}) || !reflect.DeepEqual(out.Steps, []string{"one", "two"}) {
+		t.Errorf("unexpected lists: %#v", out)
+	}
+	if !reflect.DeepEqual(out.Rows, [][]string{{"x", "1"}, {"y", "2"}}) {
+		t.Errorf("unexpected rows: %#v", out.Rows)
+	}
+	if !reflect.DeepEqual(out.Items, []item{{"apple", 3}, {"pear", 5}}) {
+		t.Errorf("unexpected items: %#v", out.Items)
+	}
+}
@@ -0,0 +1,57 @@
+package arkaineparser
+
+import "strings"
+
+// dedentValue strips the blockquote markers and common indentation of a
+// value's lines. inline says the first line was written on the label's line,
+// where its indentation was already trimmed, so it does not count towards the
+// common indentation.
+func dedentValue(entry string, inline bool) string {
+	lines := strings.Split(entry, "\n")
+
+	// Step 1: Strip one level of blockquote when every non-blank line is quoted
+	quoted := false
+	for _, line := range lines {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, ">") {
+			quoted = false
+			break
+		}
+		quoted = true
+	}
+	if quoted {
+		for i, line := range lines {
+			line = strings.TrimPrefix(strings.TrimLeft(line, " \t"), ">")
+			lines[i] = strings.TrimPrefix(line, " ")
+		}
+	}
+
+	// Step 2: Strip the indentation shared by the continuation lines
+	first := 0
+	if inline {
+		first = 1
+	}
+	prefix, found := "", false
+	for _, line := range lines[first:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			prefix, found = indent, true
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix != "" {
+		for i := first; i < len(lines); i++ {
+			lines[i] = strings.TrimPrefix(lines[i], prefix)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
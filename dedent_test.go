@@ -0,0 +1,59 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestDedentedValues checks that blockquote markers and common indentation
+// are stripped from values with WithDedentedValues.
+func TestDedentedValues(t *testing.T) {
+	labels := []Label{{Name: "Observation"}, {Name: "Thought"}, {Name: "Code"}}
+	parser, err := NewParser(labels, WithDedentedValues())
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	tests := []struct {
+		text     string
+		expected map[string]interface{}
+	}{
+		{
+			"Observation:\n> 3 results found\n>\n> - first\n>   - nested\nThought: done",
+			map[string]interface{}{"observation": "3 results found\n\n- first\n  - nested", "thought": "done", "code": ""},
+		},
+		{
+			"Observation: > quoted on the label line\n> and below\nThought: done",
+			map[string]interface{}{"observation": "quoted on the label line\nand below", "thought": "done", "code": ""},
+		},
+		{
+			"Thought: the plan\n    step one\n      detail\n    step two",
+			map[string]interface{}{"thought": "the plan\nstep one\n  detail\nstep two", "observation": "", "code": ""},
+		},
+		{
+			// Only partly quoted values keep their markers
+			"Thought: I read\n> a quote\nand replied",
+			map[string]interface{}{"thought": "I read\n> a quote\nand replied", "observation": "", "code": ""},
+		},
+		{
+			// Fenced values are verbatim
+			"Code:\n```\n    call()\n  done()\n```",
+			map[string]interface{}{"code": "call()\n  done()", "observation": "", "thought": ""},
+		},
+	}
+	for _, test := range tests {
+		result, errList := parser.Parse(test.text)
+		if len(errList) > 0 {
+			t.Errorf("unexpected errors for %q: %v", test.text, errList)
+		}
+		if !reflect.DeepEqual(map[string]interface{}(result), test.expected) {
+			t.Errorf("for %q got %#v, expected %#v", test.text, result, test.expected)
+		}
+	}
+
+	// Without the option the markers are kept
+	plain, _ := NewParser(labels)
+	result, _ := plain.Parse("Observation:\n> found\nThought: done")
+	if result["observation"] != "> found" {
+		t.Errorf("unexpected observation without the option: %q", result["observation"])
+	}
+}
@@ -0,0 +1,67 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// dedupBlocks collapses runs of consecutive blocks whose results are at least
+// p.dedupSimilarity similar into their first block, which gets a
+// KindDuplicateBlock warning counting the repeats.
+func (p *Parser) dedupBlocks(blocks []Details, blockLabel string) []Details {
+	kept := make([]Details, 0, len(blocks))
+	var (
+		last    string // Fingerprint of the last kept block
+		index   int    // 1-based position of the last kept block among all blocks
+		repeats int    // Blocks collapsed into the last kept block
+	)
+	warn := func() {
+		if repeats > 0 {
+			details := &kept[len(kept)-1]
+			details.Warnings = append(details.Warnings, newDuplicateBlockWarning(blockLabel, index, repeats))
+		}
+	}
+	for i, details := range blocks {
+		fingerprint := blockFingerprint(details)
+		if len(kept) > 0 && similarity(last, fingerprint) >= p.dedupSimilarity {
+			repeats++
+			continue
+		}
+		warn()
+		kept = append(kept, details)
+		last, index, repeats = fingerprint, i+1, 0
+	}
+	warn()
+	return kept
+}
+
+// blockFingerprint renders the result of a block as canonical JSON, with map
+// keys sorted, so equal results have equal fingerprints.
+func blockFingerprint(details Details) string {
+	encoded, err := json.Marshal(details.Result)
+	if err != nil {
+		return fmt.Sprint(details.Result)
+	}
+	return string(encoded)
+}
+
+// similarity returns how alike a and b are, from 0 to 1, as one minus their
+// edit distance relative to the longer of them.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	longest := max(len([]rune(a)), len([]rune(b)))
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+// newDuplicateBlockWarning reports the repeats of a block that were collapsed.
+func newDuplicateBlockWarning(label string, block, repeats int) ParseError {
+	return ParseError{
+		Kind:    KindDuplicateBlock,
+		Label:   label,
+		Message: fmt.Sprintf("Block %d was repeated %d time(s); the repeats were collapsed", block, repeats),
+		Params:  map[string]string{"block": strconv.Itoa(block), "repeats": strconv.Itoa(repeats)},
+	}
+}
@@ -0,0 +1,60 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestBlockDedup checks that consecutive repeated blocks are collapsed.
+func TestBlockDedup(t *testing.T) {
+	labels := []Label{{Name: "Task", IsBlockStart: true}, {Name: "Input", IsJSON: true}}
+	text := "Task: fetch\nInput: {\"url\": \"a\"}\n" +
+		"Task: fetch\nInput: {\"url\": \"a\"}\n" +
+		"Task: fetch\nInput: {\"url\": \"a\"}\n" +
+		"Task: fetch\nInput: {\"url\": \"b\"}\n" +
+		"Task: store\nInput: {}\n" +
+		"Task: fetch\nInput: {\"url\": \"a\"}"
+
+	exact, err := NewParser(labels, WithBlockDedup(1))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	blocks, err := exact.ParseBlocksDetailed(text)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(blocks) != 4 {
+		t.Fatalf("expected 4 blocks, got %d", len(blocks))
+	}
+	expected := []ParseError{{
+		Kind:    KindDuplicateBlock,
+		Label:   "task",
+		Message: "Block 1 was repeated 2 time(s); the repeats were collapsed",
+		Params:  map[string]string{"block": "1", "repeats": "2"},
+	}}
+	if !reflect.DeepEqual(blocks[0].Warnings, expected) {
+		t.Errorf("unexpected warnings: %#v", blocks[0].Warnings)
+	}
+	// Blocks repeated only later on are kept
+	if blocks[3].Result["task"] != "fetch" || len(blocks[3].Warnings) != 0 {
+		t.Errorf("unexpected last block: %#v", blocks[3])
+	}
+
+	// Near-identical blocks collapse with a lower similarity
+	fuzzy, _ := NewParser(labels, WithBlockDedup(0.9))
+	results, errList := fuzzy.ParseBlocks(text)
+	if len(errList) > 0 || len(results) != 3 {
+		t.Errorf("expected 3 blocks, got %d: %v", len(results), errList)
+	}
+
+	// Without the option every block is kept
+	plain, _ := NewParser(labels)
+	results, _ = plain.ParseBlocks(text)
+	if len(results) != 6 {
+		t.Errorf("expected 6 blocks, got %d", len(results))
+	}
+
+	if _, err := NewParser(labels, WithBlockDedup(1.5)); err == nil {
+		t.Error("expected an error for a similarity above 1")
+	}
+}
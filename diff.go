@@ -0,0 +1,273 @@
+package arkaineparser
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Patch is the parsed value of a "diff" label: a unified diff, split by file.
+type Patch struct {
+	Files []FilePatch `json:"files"`
+}
+
+// FilePatch is the part of a unified diff that changes a single file.
+type FilePatch struct {
+	// OldPath and NewPath are the paths of the "---" and "+++" lines without
+	// their "a/" and "b/" prefixes. OldPath is "/dev/null" for a new file and
+	// NewPath is "/dev/null" for a deleted file. Both are empty when the diff
+	// has no file headers.
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+	Hunks   []Hunk `json:"hunks"`
+}
+
+// Hunk is a single "@@" section of a FilePatch.
+type Hunk struct {
+	OldStart int `json:"old_start"`
+	OldLines int `json:"old_lines"`
+	NewStart int `json:"new_start"`
+	NewLines int `json:"new_lines"`
+	// Lines of the hunk, each starting with ' ' (context), '-' (removed) or '+' (added)
+	Lines []string `json:"lines"`
+}
+
+// DiffSourceFunc returns the current content of the file at path, so "diff"
+// labels can check that their patches apply. Returning an error reports it
+// against the label; returning ok false skips the check for that file.
+type DiffSourceFunc func(path string) (content string, ok bool, err error)
+
+// hunkHeaderPattern matches a hunk header such as "@@ -1,3 +1,4 @@ func main()".
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parseDiffValue parses a unified diff into a Patch, checking hunk headers
+// against the lines that follow them and, with WithDiffSource, that every
+// file patch applies to the current file content.
+func parseDiffValue(p *Parser, label Label, value string) (interface{}, error) {
+	patch, err := parsePatch(value)
+	if err != nil {
+		return nil, err
+	}
+	if p.diffSource != nil {
+		for _, file := range patch.Files {
+			path := file.OldPath
+			if path == "/dev/null" || path == "" {
+				continue
+			}
+			content, ok, err := p.diffSource(path)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if _, err := file.Apply(content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return patch, nil
+}
+
+// parsePatch splits a unified diff into file patches and hunks.
+func parsePatch(value string) (Patch, error) {
+	var patch Patch
+	var file *FilePatch
+	var hunk *Hunk
+	oldSeen, newSeen := 0, 0
+	// closeHunk checks that the hunk has as many lines as its header says
+	closeHunk := func() error {
+		if hunk != nil && (oldSeen != hunk.OldLines || newSeen != hunk.NewLines) {
+			return errors.New("hunk '@@ -" + strconv.Itoa(hunk.OldStart) + "," + strconv.Itoa(hunk.OldLines) +
+				" +" + strconv.Itoa(hunk.NewStart) + "," + strconv.Itoa(hunk.NewLines) + " @@' has " +
+				strconv.Itoa(oldSeen) + " old and " + strconv.Itoa(newSeen) + " new lines")
+		}
+		hunk = nil
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(value, "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\r")
+		// Inside a hunk, lines belong to it until it has all its lines
+		if hunk != nil && (oldSeen < hunk.OldLines || newSeen < hunk.NewLines) {
+			switch {
+			case line == "" || line[0] == ' ':
+				// Trailing whitespace may have been trimmed from blank context lines
+				hunk.Lines = append(hunk.Lines, " "+strings.TrimPrefix(line, " "))
+				oldSeen++
+				newSeen++
+				continue
+			case line[0] == '-':
+				hunk.Lines = append(hunk.Lines, line)
+				oldSeen++
+				continue
+			case line[0] == '+':
+				hunk.Lines = append(hunk.Lines, line)
+				newSeen++
+				continue
+			case line[0] == '\\':
+				continue
+			}
+		}
+		if strings.HasPrefix(line, `\`) {
+			// "\ No newline at end of file"
+			continue
+		}
+		if err := closeHunk(); err != nil {
+			return Patch{}, err
+		}
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			patch.Files = append(patch.Files, FilePatch{
+				OldPath: diffPath(line[4:], "a/"),
+				NewPath: diffPath(strings.TrimRight(lines[i+1], "\r")[4:], "b/"),
+			})
+			file = &patch.Files[len(patch.Files)-1]
+			i++
+		case strings.HasPrefix(line, "@@"):
+			m := hunkHeaderPattern.FindStringSubmatch(line)
+			if m == nil {
+				return Patch{}, errors.New("malformed hunk header '" + line + "'")
+			}
+			if file == nil {
+				// A diff without file headers patches a single unnamed file
+				patch.Files = append(patch.Files, FilePatch{})
+				file = &patch.Files[len(patch.Files)-1]
+			}
+			file.Hunks = append(file.Hunks, Hunk{
+				OldStart: atoiOr(m[1], 0), OldLines: atoiOr(m[2], 1),
+				NewStart: atoiOr(m[3], 0), NewLines: atoiOr(m[4], 1),
+			})
+			hunk = &file.Hunks[len(file.Hunks)-1]
+			oldSeen, newSeen = 0, 0
+		case strings.HasPrefix(line, "diff "), strings.HasPrefix(line, "index "),
+			strings.HasPrefix(line, "new file mode"), strings.HasPrefix(line, "deleted file mode"),
+			strings.TrimSpace(line) == "":
+			// Git extended headers and blank lines between files
+		default:
+			return Patch{}, errors.New("unexpected line '" + line + "' outside of a hunk")
+		}
+	}
+	if err := closeHunk(); err != nil {
+		return Patch{}, err
+	}
+	for _, file := range patch.Files {
+		if len(file.Hunks) == 0 {
+			return Patch{}, errors.New("file '" + file.NewPath + "' has no hunks")
+		}
+	}
+	if len(patch.Files) == 0 {
+		return Patch{}, errors.New("no hunks found")
+	}
+	return patch, nil
+}
+
+// String renders the patch back as a unified diff.
+func (p Patch) String() string {
+	var b strings.Builder
+	for _, file := range p.Files {
+		if file.OldPath != "" || file.NewPath != "" {
+			b.WriteString("--- " + diffHeaderPath(file.OldPath, "a/") + "\n")
+			b.WriteString("+++ " + diffHeaderPath(file.NewPath, "b/") + "\n")
+		}
+		for _, hunk := range file.Hunks {
+			b.WriteString("@@ -" + strconv.Itoa(hunk.OldStart) + "," + strconv.Itoa(hunk.OldLines) +
+				" +" + strconv.Itoa(hunk.NewStart) + "," + strconv.Itoa(hunk.NewLines) + " @@\n")
+			for _, line := range hunk.Lines {
+				b.WriteString(line + "\n")
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// diffHeaderPath adds the git prefix back to a path for a file header.
+func diffHeaderPath(path, prefix string) string {
+	if path == "/dev/null" {
+		return path
+	}
+	return prefix + path
+}
+
+// diffPath strips the prefix git adds to paths in file headers, and any
+// timestamp after a tab.
+func diffPath(path, prefix string) string {
+	path, _, _ = strings.Cut(path, "\t")
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return path
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// atoiOr parses a number, returning fallback for an empty string.
+func atoiOr(value string, fallback int) int {
+	if value == "" {
+		return fallback
+	}
+	number, _ := strconv.Atoi(value)
+	return number
+}
+
+// Apply applies the hunks of the file patch to content and returns the result.
+// A hunk whose lines are not found at the line its header names is looked for
+// anywhere after the previous hunk, as patch does. Lines are compared ignoring
+// trailing whitespace. Returns an error naming the first hunk that does not apply.
+func (f FilePatch) Apply(content string) (string, error) {
+	var lines []string
+	if content != "" {
+		lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	}
+	var out []string
+	next := 0 // First line of content not yet copied to out
+	for n, hunk := range f.Hunks {
+		var old, added []string
+		for _, line := range hunk.Lines {
+			if line[0] != '+' {
+				old = append(old, line[1:])
+			}
+			if line[0] != '-' {
+				added = append(added, line[1:])
+			}
+		}
+		first := hunk.OldStart - 1
+		if hunk.OldLines == 0 {
+			// Pure additions insert after line OldStart
+			first++
+		}
+		at := -1
+		for _, start := range []int{max(first, next), next} {
+			for ; start >= next && start+len(old) <= len(lines); start++ {
+				if linesEqual(lines[start:start+len(old)], old) {
+					at = start
+					break
+				}
+			}
+			if at >= 0 {
+				break
+			}
+		}
+		if at < 0 {
+			return "", errors.New("hunk " + strconv.Itoa(n+1) + " of '" + f.NewPath + "' does not apply at line " + strconv.Itoa(hunk.OldStart))
+		}
+		out = append(out, lines[next:at]...)
+		out = append(out, added...)
+		next = at + len(old)
+	}
+	out = append(out, lines[next:]...)
+	if len(out) == 0 {
+		return "", nil
+	}
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+// linesEqual compares lines ignoring trailing whitespace.
+func linesEqual(a, b []string) bool {
+	for i := range a {
+		if strings.TrimRight(a[i], " \t\r") != strings.TrimRight(b[i], " \t\r") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package arkaineparser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestDiffType checks parsing, validation and application of diff labels.
+func TestDiffType(t *testing.T) {
+	files := map[string]string{"main.go": "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"}
+	source := func(path string) (string, bool, error) {
+		if path == "broken.go" {
+			return "", false, errors.New("cannot read broken.go")
+		}
+		content, ok := files[path]
+		return content, ok, nil
+	}
+	parser, err := NewParser([]Label{{Name: "Patch", DataType: "diff"}, {Name: "Thought"}}, WithDiffSource(source))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	diff := "--- a/main.go\n+++ b/main.go\n@@ -2,3 +2,3 @@\n\n func main() {\n-\tprintln(\"hi\")\n+\tprintln(\"hello\")"
+	result, errList := parser.Parse("Thought: greet properly\nPatch:\n```diff\n" + diff + "\n```")
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	patch, ok := result["patch"].(Patch)
+	if !ok || len(patch.Files) != 1 || patch.Files[0].NewPath != "main.go" {
+		t.Fatalf("unexpected patch: %#v", result["patch"])
+	}
+	expected := Hunk{OldStart: 2, OldLines: 3, NewStart: 2, NewLines: 3,
+		Lines: []string{" ", " func main() {", "-\tprintln(\"hi\")", "+\tprintln(\"hello\")"}}
+	if !reflect.DeepEqual(patch.Files[0].Hunks, []Hunk{expected}) {
+		t.Errorf("hunk mismatch.\nGot: %#v\nExpected: %#v", patch.Files[0].Hunks, expected)
+	}
+	applied, err := patch.Files[0].Apply(files["main.go"])
+	if err != nil || applied != "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n" {
+		t.Errorf("unexpected application: %q %v", applied, err)
+	}
+	// The trimmed blank context line is written back with its space
+	if patch.String() != "--- a/main.go\n+++ b/main.go\n@@ -2,3 +2,3 @@\n \n func main() {\n-\tprintln(\"hi\")\n+\tprintln(\"hello\")" {
+		t.Errorf("unexpected rendering: %q", patch.String())
+	}
+
+	tests := []struct {
+		input   string
+		problem string
+	}{
+		{"Patch:\n--- a/main.go\n+++ b/main.go\n@@ -3,2 +3,2 @@\n func main() {\n-\tprintln(\"hey\")\n+\tprintln(\"hello\")",
+			"Invalid diff in 'patch': hunk 1 of 'main.go' does not apply at line 3"},
+		{"Patch:\n@@ -1,2 +1,2 @@\n-a\n+b",
+			"Invalid diff in 'patch': hunk '@@ -1,2 +1,2 @@' has 1 old and 1 new lines"},
+		{"Patch:\n@@ lines 1-2 @@\n-a\n+b", "Invalid diff in 'patch': malformed hunk header '@@ lines 1-2 @@'"},
+		{"Patch:\n--- a/broken.go\n+++ b/broken.go\n@@ -1 +1 @@\n-a\n+b", "Invalid diff in 'patch': cannot read broken.go"},
+	}
+	for _, tt := range tests {
+		_, errList := parser.Parse(tt.input)
+		if len(errList) != 1 || errList[0] != tt.problem {
+			t.Errorf("unexpected errors for %q: %v", tt.input, errList)
+		}
+	}
+}
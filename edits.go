@@ -0,0 +1,100 @@
+package arkaineparser
+
+import (
+	"errors"
+	"strings"
+)
+
+// FileEdit is a single search/replace edit of a file, as written by code
+// editing agents:
+//
+//	File: main.go
+//	Search:
+//	```
+//	println("hi")
+//	```
+//	Replace:
+//	```
+//	println("hello")
+//	```
+type FileEdit struct {
+	Path    string `json:"path"`
+	Search  string `json:"search"`
+	Replace string `json:"replace"`
+}
+
+// FileEditLabels returns the labels of the file-edit block protocol: a "File"
+// block start label holding a path, and the "Search" snippet to find in it and
+// the "Replace" snippet to put in its place. "Path", "Original"/"Find" and
+// "Updated"/"Replace With" are accepted as aliases.
+func FileEditLabels() []Label {
+	return []Label{
+		{Name: "File", IsBlockStart: true, DataType: "path", Aliases: []string{"Path"}},
+		{Name: "Search", Required: true, Aliases: []string{"Original", "Find"}},
+		{Name: "Replace", Aliases: []string{"Updated", "Replace With"}},
+	}
+}
+
+// FileEditParser parses output made of file-edit blocks into FileEdits.
+type FileEditParser struct {
+	parser *Parser
+}
+
+// NewFileEditParser creates a FileEditParser using FileEditLabels.
+func NewFileEditParser(opts ...Option) (*FileEditParser, error) {
+	parser, err := NewParser(FileEditLabels(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &FileEditParser{parser: parser}, nil
+}
+
+// Parse returns the edits of every block in text, in order. Besides the errors
+// of ParseBlocks (such as a missing Search), an edit whose Search equals its
+// Replace is reported, since it would change nothing. Blocks with errors are
+// left out of the edits.
+func (f *FileEditParser) Parse(text string) ([]FileEdit, []string) {
+	blocks, err := f.parser.ParseBlocksDetailed(text)
+	var errList []string
+	if err != nil {
+		errList = append(errList, err.Error())
+	}
+	var edits []FileEdit
+	for _, details := range blocks {
+		if len(details.Errors) > 0 {
+			errList = append(errList, errorStrings(details.Errors)...)
+			continue
+		}
+		edit := FileEdit{
+			Path:    stringValue(details.Result["file"]),
+			Search:  stringValue(details.Result["search"]),
+			Replace: stringValue(details.Result["replace"]),
+		}
+		if edit.Search == edit.Replace {
+			errList = append(errList, "Search and Replace of '"+edit.Path+"' are identical")
+			continue
+		}
+		edits = append(edits, edit)
+	}
+	return edits, errList
+}
+
+// stringValue returns a parsed value as a string, or "" if it is not one.
+func stringValue(value interface{}) string {
+	str, _ := value.(string)
+	return str
+}
+
+// Apply replaces the Search snippet in content with the Replace snippet.
+// Returns an error if Search is not found or is found more than once, since
+// the edit would then be ambiguous.
+func (e FileEdit) Apply(content string) (string, error) {
+	switch strings.Count(content, e.Search) {
+	case 0:
+		return "", errors.New("Search snippet not found in '" + e.Path + "'")
+	case 1:
+		return strings.Replace(content, e.Search, e.Replace, 1), nil
+	default:
+		return "", errors.New("Search snippet found more than once in '" + e.Path + "'")
+	}
+}
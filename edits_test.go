@@ -0,0 +1,38 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestFileEdits checks parsing, validation and application of file-edit blocks.
+func TestFileEdits(t *testing.T) {
+	parser, err := NewFileEditParser()
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "I'll make two edits.\nFile: `main.go`\nSearch:\n```go\n\tprintln(\"hi\")\n\treturn\n```\nReplace:\n```go\n\tprintln(\"hello\")\n\treturn\n```\n" +
+		"File: ./README.md\nOriginal: # Demo\nUpdated: # Greeter\n" +
+		"File: util.go\nReplace: func x() {}\n" +
+		"File: same.go\nSearch: x\nReplace: x"
+	edits, errList := parser.Parse(input)
+	expected := []FileEdit{
+		{Path: "main.go", Search: "println(\"hi\")\n\treturn", Replace: "println(\"hello\")\n\treturn"},
+		{Path: "README.md", Search: "# Demo", Replace: "# Greeter"},
+	}
+	if !reflect.DeepEqual(edits, expected) {
+		t.Errorf("edits mismatch.\nGot: %#v\nExpected: %#v", edits, expected)
+	}
+	expectedErrors := []string{"'search' is required", "Search and Replace of 'same.go' are identical"}
+	if !reflect.DeepEqual(errList, expectedErrors) {
+		t.Errorf("errors mismatch.\nGot: %#v\nExpected: %#v", errList, expectedErrors)
+	}
+
+	applied, err := edits[0].Apply("func main() {\n\tprintln(\"hi\")\n\treturn\n}\n")
+	if err != nil || applied != "func main() {\n\tprintln(\"hello\")\n\treturn\n}\n" {
+		t.Errorf("unexpected application: %q %v", applied, err)
+	}
+	if _, err := edits[1].Apply("# Demo\n# Demo\n"); err == nil || err.Error() != "Search snippet found more than once in 'README.md'" {
+		t.Errorf("expected ambiguity error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package arkaineparser
+
+import "strconv"
+
+// Entity is a single extracted entity with the quote supporting it.
+type Entity struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Quote string `json:"quote"`
+	// Grounded reports whether Quote was found in the source document, and
+	// Score how closely (1 for a verbatim quote)
+	Grounded bool    `json:"grounded"`
+	Score    float64 `json:"score"`
+}
+
+// EntityLabels returns the labels of an entity-extraction output made of
+// "Entity" blocks, each with a "Type" (restricted to types unless it is empty)
+// and a "Quote" from the source document as evidence:
+//
+//	Entity: Ada Lovelace
+//	Type: Person
+//	Quote: "Ada Lovelace wrote the first program"
+func EntityLabels(types []string) []Label {
+	return []Label{
+		{Name: "Entity", IsBlockStart: true},
+		{Name: "Type", Choices: types, Aliases: []string{"Entity Type", "Kind"}},
+		{Name: "Quote", Required: true, Aliases: []string{"Evidence"}},
+	}
+}
+
+// EntityExtractor parses entity-extraction outputs and checks their quotes
+// against the source document.
+type EntityExtractor struct {
+	parser *Parser
+	// Fuzziness is how loosely quotes must match the source, from 0 (verbatim,
+	// ignoring case and punctuation) to 1 (anything goes). Defaults to 0.1,
+	// tolerating small transcription differences.
+	Fuzziness float64
+}
+
+// NewEntityExtractor creates an EntityExtractor using EntityLabels(types).
+func NewEntityExtractor(types []string, opts ...Option) (*EntityExtractor, error) {
+	parser, err := NewParser(EntityLabels(types), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &EntityExtractor{parser: parser, Fuzziness: 0.1}, nil
+}
+
+// Parse returns the entities found in text. Each quote is checked against
+// source; entities whose quote is not found are still returned, with Grounded
+// false, and reported as possibly hallucinated. Blocks with parse errors, such
+// as a missing quote or an unknown type, are reported and left out.
+func (e *EntityExtractor) Parse(text, source string) ([]Entity, []string) {
+	blocks, err := e.parser.ParseBlocksDetailed(text)
+	var errList []string
+	if err != nil {
+		errList = append(errList, err.Error())
+	}
+	var entities []Entity
+	for _, details := range blocks {
+		if len(details.Errors) > 0 {
+			errList = append(errList, errorStrings(details.Errors)...)
+			continue
+		}
+		entity := Entity{
+			Name:  stringValue(details.Result["entity"]),
+			Type:  stringValue(details.Result["type"]),
+			Quote: trimQuotes(stringValue(details.Result["quote"])),
+		}
+		entity.Score, entity.Grounded = grounded(source, entity.Quote, e.Fuzziness)
+		if !entity.Grounded {
+			errList = append(errList, "Quote of entity '"+entity.Name+"' not found in source (similarity "+
+				strconv.FormatFloat(entity.Score, 'f', 2, 64)+")")
+		}
+		entities = append(entities, entity)
+	}
+	return entities, errList
+}
+
+// trimQuotes removes the quotation marks models put around quotes.
+func trimQuotes(value string) string {
+	for _, pair := range [][2]string{{`"`, `"`}, {"'", "'"}, {"“", "”"}} {
+		if len(value) >= len(pair[0])+len(pair[1]) && value[:len(pair[0])] == pair[0] && value[len(value)-len(pair[1]):] == pair[1] {
+			return value[len(pair[0]) : len(value)-len(pair[1])]
+		}
+	}
+	return value
+}
@@ -0,0 +1,73 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestEntityExtractor checks entity parsing and quote grounding.
+func TestEntityExtractor(t *testing.T) {
+	source := "In 1843, Ada Lovelace published the first algorithm intended for a machine.\nShe worked with Charles Babbage in London."
+	extractor, err := NewEntityExtractor([]string{"Person", "Place", "Date"})
+	if err != nil {
+		t.Fatalf("failed to create extractor: %v", err)
+	}
+	input := "Entity: Ada Lovelace\nType: person\nQuote: \"Ada Lovelace published the first algorithm\"\n" +
+		"Entity: London\nType: Place\nQuote: worked with Charles Babage in London\n" +
+		"Entity: Paris\nType: Place\nQuote: She moved to Paris in 1850.\n" +
+		"Entity: Babbage\nType: Person"
+	entities, errList := extractor.Parse(input, source)
+	if len(entities) != 3 {
+		t.Fatalf("unexpected entities: %#v", entities)
+	}
+	// A verbatim quote and a quote with a typo are grounded; an invented one is not
+	if !entities[0].Grounded || entities[0].Score != 1 || entities[0].Type != "Person" || entities[0].Quote != "Ada Lovelace published the first algorithm" {
+		t.Errorf("unexpected entity: %#v", entities[0])
+	}
+	if !entities[1].Grounded || entities[1].Score >= 1 {
+		t.Errorf("unexpected entity: %#v", entities[1])
+	}
+	if entities[2].Grounded {
+		t.Errorf("expected an ungrounded entity: %#v", entities[2])
+	}
+	expectedErrors := []string{
+		"Quote of entity 'Paris' not found in source (similarity 0.45)",
+		"'quote' is required",
+	}
+	if !reflect.DeepEqual(errList, expectedErrors) {
+		t.Errorf("errors mismatch.\nGot: %#v\nExpected: %#v", errList, expectedErrors)
+	}
+
+	// Without fuzziness, the quote with a typo is rejected
+	extractor.Fuzziness = 0
+	entities, _ = extractor.Parse(input, source)
+	if entities[1].Grounded {
+		t.Errorf("expected an ungrounded entity: %#v", entities[1])
+	}
+}
+
+// TestGrounding checks that unsupported sentences of grounded labels are reported.
+func TestGrounding(t *testing.T) {
+	source := "In 1843, Ada Lovelace published the first algorithm intended for a machine.\nShe worked with Charles Babbage in London."
+	parser, err := NewParser([]Label{{Name: "Answer"}, {Name: "Thought"}}, WithGrounding(source, []string{"Answer"}, 0.1))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	details := parser.ParseDetailed("Thought: I made this up.\nAnswer: Yes. Ada Lovelace published the first algorithm! She was born in Paris.")
+	expected := []ParseError{{
+		Kind:    KindUngrounded,
+		Label:   "answer",
+		Message: "'answer' is not supported by the source: 'She was born in Paris.' (similarity 0.39)",
+		Params:  map[string]string{"sentence": "She was born in Paris.", "score": "0.39"},
+	}}
+	if !reflect.DeepEqual(details.Warnings, expected) {
+		t.Errorf("warnings mismatch.\nGot: %#v\nExpected: %#v", details.Warnings, expected)
+	}
+	if len(details.Errors) > 0 {
+		t.Errorf("unexpected errors: %v", details.Errors)
+	}
+
+	if _, err := NewParser([]Label{{Name: "Answer"}}, WithGrounding(source, []string{"Response"}, 0)); err == nil {
+		t.Errorf("expected an undefined grounding label error")
+	}
+}
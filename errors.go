@@ -0,0 +1,327 @@
+package arkaineparser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors returned by ParseBlocksDetailed, together with the result of
+// parsing the whole text as a single block.
+var (
+	// ErrNoBlockStartLabel means no label is marked IsBlockStart.
+	ErrNoBlockStartLabel = errors.New("No block start label defined - must have at least one")
+	// ErrNoBlocks means the block start label never appears in the text.
+	ErrNoBlocks = errors.New("No blocks found - the block start label never appears")
+)
+
+// Sentinel errors wrapped by ParseErrors and by the errors of NewParser, so
+// callers can branch with errors.Is instead of comparing messages. The wrapping
+// errors keep their own, more specific messages.
+var (
+	// ErrMissingRequired is wrapped by KindRequired parse errors.
+	ErrMissingRequired = errors.New("Required label is missing")
+	// ErrInvalidJSON is wrapped by KindJSON parse errors.
+	ErrInvalidJSON = errors.New("Invalid JSON value")
+	// ErrDependency is wrapped by KindDependency parse errors, and by schema
+	// errors for RequiredWith naming an undefined label.
+	ErrDependency = errors.New("Label is missing a label it requires")
+	// ErrUndefinedLabel is wrapped by option errors naming an undefined label.
+	ErrUndefinedLabel = errors.New("Label is not defined")
+	// ErrTooManyBlockStarts is returned when more than one label is marked IsBlockStart.
+	ErrTooManyBlockStarts = errors.New("Only one block start label is allowed")
+	// ErrSchemaNotFound is wrapped by Registry errors naming an unregistered schema.
+	ErrSchemaNotFound = errors.New("Schema is not registered")
+	// ErrToolNotFound is wrapped by ToolRegistry errors naming an unregistered tool.
+	ErrToolNotFound = errors.New("Tool is not registered")
+)
+
+// wrappedError is an error with its own message that wraps a sentinel error.
+type wrappedError struct {
+	message  string
+	sentinel error
+}
+
+// wrapError returns an error with message that wraps sentinel.
+func wrapError(sentinel error, message string) error {
+	return &wrappedError{message: message, sentinel: sentinel}
+}
+
+func (e *wrappedError) Error() string { return e.message }
+
+func (e *wrappedError) Unwrap() error { return e.sentinel }
+
+// ErrorKind classifies a ParseError.
+type ErrorKind string
+
+const (
+	KindRequired    ErrorKind = "required"     // A required label is missing
+	KindDependency  ErrorKind = "dependency"   // A label is present without a label it requires
+	KindJSON        ErrorKind = "json"         // A JSON label's value failed to parse
+	KindType        ErrorKind = "type"         // A typed label's value is malformed for its DataType
+	KindRange       ErrorKind = "range"        // A numeric value is outside the label's Min/Max range
+	KindPattern     ErrorKind = "pattern"      // A value does not match the label's MatchPattern
+	KindChoice      ErrorKind = "choice"       // A value names none of the label's Choices
+	KindFrontMatter ErrorKind = "front-matter" // A line of the front matter is malformed
+	KindMiddleware  ErrorKind = "middleware"   // A middleware of WithMiddleware rejected a value
+	// Warning kinds, reported in Details.Warnings
+	KindSystemLabel ErrorKind = "system-label" // A system-only label appeared in model output and was stripped
+	KindUngrounded  ErrorKind = "ungrounded"   // A sentence of a grounded label is not supported by the source
+	// Consecutive identical or near-identical blocks were collapsed (see WithBlockDedup)
+	KindDuplicateBlock ErrorKind = "duplicate-block"
+	// A line matched several labels; the longest match was taken
+	KindAmbiguousLabel ErrorKind = "ambiguous-label"
+)
+
+// ParseError is a structured parse diagnostic. Its Message is the same string
+// that Parse reports in its error slice.
+type ParseError struct {
+	Kind    ErrorKind `json:"kind"`            // What kind of problem was found
+	Label   string    `json:"label,omitempty"` // The (lowercase) label the problem concerns
+	Message string    `json:"message"`         // Human readable description
+	// Occurrence is the 1-based occurrence of Label the problem concerns when the
+	// label appears several times, or 0 when it concerns the label as a whole.
+	Occurrence int `json:"occurrence,omitempty"`
+	// Params holds the values the message was built from, beyond Label and
+	// Occurrence, such as the required label of a KindDependency error. They
+	// fill the placeholders of MessageTemplates.
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Error implements the error interface.
+func (e ParseError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the sentinel error of the error's Kind (ErrMissingRequired,
+// ErrDependency or ErrInvalidJSON), or nil for other kinds.
+func (e ParseError) Unwrap() error {
+	switch e.Kind {
+	case KindRequired:
+		return ErrMissingRequired
+	case KindDependency:
+		return ErrDependency
+	case KindJSON:
+		return ErrInvalidJSON
+	}
+	return nil
+}
+
+// Details is the extended output of ParseDetailed.
+type Details struct {
+	Result  Result       // Parsed values, identical to the map returned by Parse
+	Errors  []ParseError // Structured errors, in the same order Parse reports them
+	Repairs []Repair     // Lenient interpretations applied while parsing, in the order they happened
+	// Warnings holds problems that did not fail the parse, such as stripped
+	// system-only labels. Parse does not report them.
+	Warnings []ParseError
+	// Occurrences holds every occurrence of each label in order of appearance,
+	// without flattening: one parsed value per occurrence, including empty ones.
+	Occurrences map[string][]interface{}
+	// Tokens holds approximate token counts when the parser has a Tokenizer
+	// (see WithTokenizer), or nil
+	Tokens *TokenCounts
+	// Provenance holds how each occurrence of each label was found, aligned
+	// with Occurrences, e.g. to count the lenient pathways a model exercises
+	Provenance map[string][]Provenance
+	// Cleaning holds the cleaned text and what cleaning removed when the
+	// parser was created with WithCleaningReport, or nil
+	Cleaning *Cleaning
+}
+
+// Err returns the parse errors joined into a single error, or nil if there
+// are none, e.g. for errors.Is(details.Err(), ErrMissingRequired).
+func (d Details) Err() error {
+	errList := make([]error, len(d.Errors))
+	for i, err := range d.Errors {
+		errList[i] = err
+	}
+	return errors.Join(errList...)
+}
+
+// TokenCounts are the approximate token counts of a parsed output.
+type TokenCounts struct {
+	Fields map[string]int `json:"fields"` // Tokens of the values of each label present, summed over occurrences
+	Total  int            `json:"total"`  // Tokens of the whole parsed text, labels included
+}
+
+// newRequiredError reports a missing required label.
+func newRequiredError(label string) ParseError {
+	return ParseError{Kind: KindRequired, Label: label, Message: "'" + label + "' is required"}
+}
+
+// newDependencyError reports a label present without one of its RequiredWith labels.
+func newDependencyError(label, dep string) ParseError {
+	return ParseError{
+		Kind:    KindDependency,
+		Label:   label,
+		Message: "'" + label + "' requires '" + dep + "'",
+		Params:  map[string]string{"dependency": dep},
+	}
+}
+
+// newOccurrenceDependencyError reports a single occurrence of a repeated label
+// that lacks the matching occurrence of one of its RequiredWith labels.
+func newOccurrenceDependencyError(label, dep string, occurrence int) ParseError {
+	return ParseError{
+		Kind:       KindDependency,
+		Label:      label,
+		Message:    fmt.Sprintf("'%s' occurrence %d requires '%s'", label, occurrence, dep),
+		Occurrence: occurrence,
+		Params:     map[string]string{"dependency": dep},
+	}
+}
+
+// newJSONError reports a JSON label whose value failed to parse.
+func newJSONError(label string, err error) ParseError {
+	return ParseError{
+		Kind:    KindJSON,
+		Label:   label,
+		Message: "JSON error in '" + label + "': " + err.Error(),
+		Params:  map[string]string{"error": err.Error()},
+	}
+}
+
+// newEmptyJSONError reports an empty JSON value under the EmptyJSONError policy.
+func newEmptyJSONError(label string) ParseError {
+	return ParseError{
+		Kind:    KindJSON,
+		Label:   label,
+		Message: "JSON error in '" + label + "': value is empty",
+		Params:  map[string]string{"error": "value is empty", "reason": "empty"},
+	}
+}
+
+// newTypeError reports a value that is malformed for its label's DataType.
+func newTypeError(label, dataType string, err error) ParseError {
+	return ParseError{
+		Kind:    KindType,
+		Label:   label,
+		Message: "Invalid " + dataType + " in '" + label + "': " + err.Error(),
+		Params:  map[string]string{"type": dataType, "error": err.Error()},
+	}
+}
+
+// newRangeError reports a value outside its label's Min/Max range: reason is
+// "number" for a value that is not a number, else "min" or "max" for the bound
+// it crosses. occurrence is the 1-based occurrence of a repeated label, or 0
+// for a single value.
+func newRangeError(label, reason, bound, value string, occurrence int) ParseError {
+	problem := "must be a number"
+	params := map[string]string{"reason": reason}
+	switch reason {
+	case "min":
+		problem = "must be at least " + bound + ", got " + value
+		params["bound"], params["value"] = bound, value
+	case "max":
+		problem = "must be at most " + bound + ", got " + value
+		params["bound"], params["value"] = bound, value
+	}
+	params["problem"] = problem
+	message := "'" + label + "' " + problem
+	if occurrence > 0 {
+		message = fmt.Sprintf("'%s' occurrence %d %s", label, occurrence, problem)
+	}
+	return ParseError{Kind: KindRange, Label: label, Message: message, Occurrence: occurrence, Params: params}
+}
+
+// newPatternError reports a value that does not match its label's MatchPattern.
+// occurrence is the 1-based occurrence of a repeated label, or 0 for a single value.
+func newPatternError(label, pattern, value string, occurrence int) ParseError {
+	subject := "'" + label + "'"
+	if occurrence > 0 {
+		subject = fmt.Sprintf("'%s' occurrence %d", label, occurrence)
+	}
+	return ParseError{
+		Kind:       KindPattern,
+		Label:      label,
+		Message:    fmt.Sprintf("%s must match pattern '%s', got '%s'", subject, pattern, value),
+		Occurrence: occurrence,
+		Params:     map[string]string{"pattern": pattern, "value": value},
+	}
+}
+
+// newChoiceError reports a value that names none of its label's Choices.
+// occurrence is the 1-based occurrence of a repeated label, or 0 for a single value.
+func newChoiceError(label string, choices []string, value string, occurrence int) ParseError {
+	subject := "'" + label + "'"
+	if occurrence > 0 {
+		subject = fmt.Sprintf("'%s' occurrence %d", label, occurrence)
+	}
+	return ParseError{
+		Kind:       KindChoice,
+		Label:      label,
+		Message:    fmt.Sprintf("%s must be one of '%s', got '%s'", subject, strings.Join(choices, "', '"), value),
+		Occurrence: occurrence,
+		Params:     map[string]string{"choices": strings.Join(choices, "', '"), "value": value},
+	}
+}
+
+// newFrontMatterError reports a malformed front matter line.
+func newFrontMatterError(line int, problem string) ParseError {
+	return ParseError{
+		Kind:    KindFrontMatter,
+		Message: fmt.Sprintf("Invalid front matter on line %d: %s", line, problem),
+		Params:  map[string]string{"line": strconv.Itoa(line), "problem": problem},
+	}
+}
+
+// newMiddlewareError reports a value rejected by a middleware. occurrence is
+// the 1-based occurrence of a repeated label, or 0 for a single value.
+func newMiddlewareError(label string, err error, occurrence int) ParseError {
+	subject := "'" + label + "'"
+	if occurrence > 0 {
+		subject = fmt.Sprintf("'%s' occurrence %d", label, occurrence)
+	}
+	return ParseError{
+		Kind:       KindMiddleware,
+		Label:      label,
+		Message:    "Middleware error in " + subject + ": " + err.Error(),
+		Occurrence: occurrence,
+		Params:     map[string]string{"error": err.Error()},
+	}
+}
+
+// newSystemLabelWarning reports a system-only label found in model output.
+func newSystemLabelWarning(label string, count int) ParseError {
+	return ParseError{
+		Kind:    KindSystemLabel,
+		Label:   label,
+		Message: fmt.Sprintf("'%s' is a system-only label; %d model-produced value(s) stripped", label, count),
+		Params:  map[string]string{"count": strconv.Itoa(count)},
+	}
+}
+
+// newUngroundedWarning reports a sentence of a grounded label that the
+// reference document does not support.
+func newUngroundedWarning(label, sentence string, score float64) ParseError {
+	return ParseError{
+		Kind:    KindUngrounded,
+		Label:   label,
+		Message: fmt.Sprintf("'%s' is not supported by the source: '%s' (similarity %.2f)", label, sentence, score),
+		Params:  map[string]string{"sentence": sentence, "score": fmt.Sprintf("%.2f", score)},
+	}
+}
+
+// newAmbiguousLabelWarning reports a line that matched label and, with a
+// shorter match, each of others.
+func newAmbiguousLabelWarning(label string, others []string, line string) ParseError {
+	quoted := "'" + strings.Join(others, "', '") + "'"
+	return ParseError{
+		Kind:    KindAmbiguousLabel,
+		Label:   label,
+		Message: fmt.Sprintf("Line '%s' also matches %s; '%s' was taken as the longest match", strings.TrimSpace(line), quoted, label),
+		Params:  map[string]string{"text": strings.TrimSpace(line), "others": quoted},
+	}
+}
+
+// errorStrings converts structured errors to the message slice returned by Parse.
+// The returned slice is never nil.
+func errorStrings(errList []ParseError) []string {
+	messages := make([]string, len(errList))
+	for i, err := range errList {
+		messages[i] = err.Message
+	}
+	return messages
+}
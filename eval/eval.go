@@ -0,0 +1,194 @@
+// Package eval runs a parser over a batch of LLM outputs and aggregates how
+// well they parse, for comparing prompts, models, and schema revisions.
+package eval
+
+import (
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+)
+
+// Case is a single LLM output to evaluate.
+type Case struct {
+	Name  string // Identifier used in diagnostics, e.g. the source file name
+	Input string // The raw LLM output
+}
+
+// CaseResult holds the per-case diagnostics of an evaluation run.
+type CaseResult struct {
+	Name     string
+	Result   arkaineparser.Result
+	Errors   []arkaineparser.ParseError
+	Repairs  []arkaineparser.Repair
+	Warnings []arkaineparser.ParseError
+	Success  bool // True when the case parsed without errors
+}
+
+// Report aggregates the outcome of an evaluation run.
+type Report struct {
+	Total         int                              // Number of cases evaluated
+	Succeeded     int                              // Number of cases without errors
+	ErrorsByKind  map[arkaineparser.ErrorKind]int  // Error counts per error kind
+	ErrorsByLabel map[string]int                   // Error counts per label
+	RepairsByKind map[arkaineparser.RepairKind]int // Repair counts per repair kind
+	// Warning counts per warning kind; warnings do not make a case fail
+	WarningsByKind map[arkaineparser.ErrorKind]int
+	// Label occurrences per matcher, showing which lenient pathways the outputs exercise
+	MatchersByKind map[arkaineparser.MatcherKind]int
+	Repaired       int          // Number of cases needing at least one repair
+	Cases          []CaseResult // Per-case diagnostics, in input order
+}
+
+// SuccessRate returns the fraction of cases that parsed without errors,
+// or 0 when no cases were evaluated.
+func (r Report) SuccessRate() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.Succeeded) / float64(r.Total)
+}
+
+// RepairRate returns the fraction of cases that needed at least one repair,
+// or 0 when no cases were evaluated.
+func (r Report) RepairRate() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.Repaired) / float64(r.Total)
+}
+
+// Failures returns the diagnostics of every case that produced errors.
+func (r Report) Failures() []CaseResult {
+	var failures []CaseResult
+	for _, c := range r.Cases {
+		if !c.Success {
+			failures = append(failures, c)
+		}
+	}
+	return failures
+}
+
+// String renders a short human readable summary of the report.
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "cases: %d, succeeded: %d (%.1f%%)\n", r.Total, r.Succeeded, 100*r.SuccessRate())
+	writeCounts(&b, "errors by kind", r.ErrorsByKind)
+	writeCounts(&b, "errors by label", r.ErrorsByLabel)
+	fmt.Fprintf(&b, "repaired: %d (%.1f%%)\n", r.Repaired, 100*r.RepairRate())
+	writeCounts(&b, "repairs by kind", r.RepairsByKind)
+	writeCounts(&b, "warnings by kind", r.WarningsByKind)
+	writeCounts(&b, "labels by matcher", r.MatchersByKind)
+	return b.String()
+}
+
+// writeCounts writes a titled list of counts in descending order, ties by key.
+func writeCounts[K ~string](b *strings.Builder, title string, counts map[K]int) {
+	if len(counts) == 0 {
+		return
+	}
+	keys := make([]K, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	fmt.Fprintf(b, "%s:\n", title)
+	for _, key := range keys {
+		fmt.Fprintf(b, "  %s: %d\n", key, counts[key])
+	}
+}
+
+// RunSeq parses every case yielded by cases and aggregates the results.
+func RunSeq(p *arkaineparser.Parser, cases iter.Seq[Case]) Report {
+	report := Report{
+		ErrorsByKind:   make(map[arkaineparser.ErrorKind]int),
+		ErrorsByLabel:  make(map[string]int),
+		RepairsByKind:  make(map[arkaineparser.RepairKind]int),
+		WarningsByKind: make(map[arkaineparser.ErrorKind]int),
+		MatchersByKind: make(map[arkaineparser.MatcherKind]int),
+	}
+	for c := range cases {
+		details := p.ParseDetailed(c.Input)
+		caseResult := CaseResult{
+			Name:     c.Name,
+			Result:   details.Result,
+			Errors:   details.Errors,
+			Repairs:  details.Repairs,
+			Warnings: details.Warnings,
+			Success:  len(details.Errors) == 0,
+		}
+		// Aggregate statistics
+		report.Total++
+		if caseResult.Success {
+			report.Succeeded++
+		}
+		for _, err := range details.Errors {
+			report.ErrorsByKind[err.Kind]++
+			report.ErrorsByLabel[err.Label]++
+		}
+		if len(details.Repairs) > 0 {
+			report.Repaired++
+		}
+		for _, repair := range details.Repairs {
+			report.RepairsByKind[repair.Kind]++
+		}
+		for _, warning := range details.Warnings {
+			report.WarningsByKind[warning.Kind]++
+		}
+		for matcher, count := range details.MatcherCounts() {
+			report.MatchersByKind[matcher] += count
+		}
+		report.Cases = append(report.Cases, caseResult)
+	}
+	return report
+}
+
+// Run parses every case and aggregates the results.
+func Run(p *arkaineparser.Parser, cases []Case) Report {
+	return RunSeq(p, slices.Values(cases))
+}
+
+// LoadDir reads every file in dir matching the glob pattern (e.g. "*.txt")
+// as a Case named after the file, sorted by name.
+func LoadDir(dir, pattern string) ([]Case, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var cases []Case
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		input, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, Case{Name: filepath.Base(path), Input: string(input)})
+	}
+	return cases, nil
+}
+
+// RunDir loads the cases in dir matching pattern and evaluates them.
+func RunDir(p *arkaineparser.Parser, dir, pattern string) (Report, error) {
+	cases, err := LoadDir(dir, pattern)
+	if err != nil {
+		return Report{}, err
+	}
+	return Run(p, cases), nil
+}
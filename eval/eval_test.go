@@ -0,0 +1,65 @@
+package eval
+
+import (
+	"testing"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+)
+
+// TestRun checks the aggregate statistics of an evaluation run.
+func TestRun(t *testing.T) {
+	parser, err := arkaineparser.NewParser([]arkaineparser.Label{
+		{Name: "Result", Required: true},
+		{Name: "Data", IsJSON: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	cases := []Case{
+		{Name: "good", Input: "Result: done\nData: `{\"a\": 1}`"},
+		{Name: "missing", Input: "Data: {\"a\": 1}"},
+		{Name: "broken", Input: "Result: done\nData: {broken"},
+		{Name: "both", Input: "Data: {broken"},
+	}
+	report := Run(parser, cases)
+	if report.Total != 4 || report.Succeeded != 1 {
+		t.Errorf("unexpected totals: %d cases, %d succeeded", report.Total, report.Succeeded)
+	}
+	if report.SuccessRate() != 0.25 {
+		t.Errorf("unexpected success rate: %v", report.SuccessRate())
+	}
+	if report.ErrorsByKind[arkaineparser.KindRequired] != 2 || report.ErrorsByKind[arkaineparser.KindJSON] != 2 {
+		t.Errorf("unexpected errors by kind: %v", report.ErrorsByKind)
+	}
+	if report.ErrorsByLabel["result"] != 2 || report.ErrorsByLabel["data"] != 2 {
+		t.Errorf("unexpected errors by label: %v", report.ErrorsByLabel)
+	}
+	// The inline code is stripped and both "{broken" values never close
+	if report.Repaired != 3 || report.RepairsByKind[arkaineparser.RepairInlineCode] != 1 || report.RepairsByKind[arkaineparser.RepairUnclosedStructure] != 2 {
+		t.Errorf("unexpected repairs: %d cases, %v", report.Repaired, report.RepairsByKind)
+	}
+	// Every label was written by name: 2 Results and 4 Data
+	if len(report.MatchersByKind) != 1 || report.MatchersByKind[arkaineparser.MatchLabel] != 6 {
+		t.Errorf("unexpected matchers: %v", report.MatchersByKind)
+	}
+	failures := report.Failures()
+	if len(failures) != 3 || failures[0].Name != "missing" {
+		t.Errorf("unexpected failures: %#v", failures)
+	}
+}
+
+// TestRunDir checks loading cases from the conformance corpus directory.
+func TestRunDir(t *testing.T) {
+	parser, _ := arkaineparser.NewParser([]arkaineparser.Label{{Name: "Task", Required: true}})
+	report, err := RunDir(parser, "../conformance/corpus", "*_input.txt")
+	if err != nil {
+		t.Fatalf("failed to run directory: %v", err)
+	}
+	if report.Total != 5 {
+		t.Fatalf("expected 5 cases, got %d", report.Total)
+	}
+	// Only the block parsing input contains a Task label
+	if report.Succeeded != 1 || report.Cases[0].Name != "basic_functionality_input.txt" {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+}
@@ -0,0 +1,61 @@
+package arkaineparser
+
+// LineKind classifies how a line took part in parsing.
+type LineKind string
+
+const (
+	LineLabel        LineKind = "label"        // The line starts a label
+	LineContinuation LineKind = "continuation" // The line continues the current value
+	LineLiteral      LineKind = "literal"      // The line is inside an end-marked value or an open structure, so labels are not detected
+	LineIgnored      LineKind = "ignored"      // The line belongs to no value (before the first label or after an end marker)
+)
+
+// LineDecision explains how a single cleaned line was interpreted.
+type LineDecision struct {
+	Number int      `json:"number"`          // 1-based line number in the cleaned text
+	Text   string   `json:"text"`            // The cleaned line
+	Kind   LineKind `json:"kind"`            // How the line was interpreted
+	Label  string   `json:"label,omitempty"` // The label the line belongs to, if any
+	// Span is the byte range of the label and its separator within Text, for label lines
+	Span Span `json:"span"`
+	// EndsValue is true when the line holds the end marker of its label's value
+	EndsValue bool `json:"ends_value,omitempty"`
+}
+
+// Explanation describes every decision Parse makes for a text, for debugging
+// outputs that do not parse as expected.
+type Explanation struct {
+	Cleaned string         // The text after cleaning
+	Lines   []LineDecision // One decision per cleaned line
+	Details Details        // The parse result, errors, repairs and warnings
+}
+
+// Explain parses text like ParseDetailed and also reports how each line was
+// interpreted.
+func (p *Parser) Explain(text string) Explanation {
+	prepared := p.prepare(text)
+	matches, _ := p.matchLines(prepared.lines, prepared.fenced, nil)
+	explanation := Explanation{Cleaned: prepared.cleaned, Details: p.ParseDetailed(text)}
+	currentLabel := ""
+	for i, match := range matches {
+		decision := LineDecision{Number: i + 1, Text: match.line, EndsValue: match.ends}
+		switch {
+		case match.label != "":
+			currentLabel = match.label
+			decision.Kind = LineLabel
+			decision.Span = match.span
+		case currentLabel == "":
+			decision.Kind = LineIgnored
+		case match.literal:
+			decision.Kind = LineLiteral
+		default:
+			decision.Kind = LineContinuation
+		}
+		decision.Label = currentLabel
+		if match.ends {
+			currentLabel = ""
+		}
+		explanation.Lines = append(explanation.Lines, decision)
+	}
+	return explanation
+}
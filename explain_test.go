@@ -0,0 +1,36 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestExplain checks the per-line decisions for a schema loaded from JSON.
+func TestExplain(t *testing.T) {
+	var labels []Label
+	schema := `[{"name": "Thought"}, {"name": "Answer", "end_marker": "END"}]`
+	if err := json.Unmarshal([]byte(schema), &labels); err != nil {
+		t.Fatalf("failed to load schema: %v", err)
+	}
+	parser, err := NewParser(labels)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	explanation := parser.Explain("intro\n  Thought: hmm\nmore\nAnswer: a\nThought: quoted\nEND\ntrailing")
+	expected := []LineDecision{
+		{Number: 1, Text: "intro", Kind: LineIgnored},
+		{Number: 2, Text: "  Thought: hmm", Kind: LineLabel, Label: "thought", Span: Span{0, 11}},
+		{Number: 3, Text: "more", Kind: LineContinuation, Label: "thought"},
+		{Number: 4, Text: "Answer: a", Kind: LineLabel, Label: "answer", Span: Span{0, 8}},
+		{Number: 5, Text: "Thought: quoted", Kind: LineLiteral, Label: "answer"},
+		{Number: 6, Text: "END", Kind: LineLiteral, Label: "answer", EndsValue: true},
+		{Number: 7, Text: "trailing", Kind: LineIgnored},
+	}
+	if !reflect.DeepEqual(explanation.Lines, expected) {
+		t.Errorf("decisions mismatch.\nGot: %#v\nExpected: %#v", explanation.Lines, expected)
+	}
+	if explanation.Details.Result["answer"] != "a\nThought: quoted" {
+		t.Errorf("unexpected result: %#v", explanation.Details.Result)
+	}
+}
@@ -0,0 +1,294 @@
+package arkaineparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The exporters below translate the schema into constrained-decoding
+// artifacts, so local models can only generate output the parser accepts.
+// Each field is a single "Label: value" line ending in a newline, in
+// declaration order; optional labels may be left out, and when a label is
+// marked IsBlockStart the fields repeat as blocks starting with it.
+// System-sourced labels are never generated.
+
+// valueKind is the shape a label's value is constrained to when exported.
+type valueKind string
+
+const (
+	kindText     valueKind = "text"
+	kindJSON     valueKind = "json"
+	kindInteger  valueKind = "integer"
+	kindNumber   valueKind = "number"
+	kindURL      valueKind = "url"
+	kindDatetime valueKind = "datetime"
+	kindCheckbox valueKind = "checkbox"
+	kindChoice   valueKind = "choice"
+)
+
+// exportKind returns the valueKind a label is exported as. Data types that
+// cannot be constrained more precisely are text.
+func exportKind(label Label) valueKind {
+	switch {
+	case len(label.Choices) > 0:
+		return kindChoice
+	case label.IsJSON:
+		return kindJSON
+	}
+	switch label.DataType {
+	case "integer":
+		return kindInteger
+	case "number", "confidence", "score":
+		return kindNumber
+	case "url":
+		return kindURL
+	case "datetime":
+		return kindDatetime
+	case "checkbox":
+		return kindCheckbox
+	}
+	return kindText
+}
+
+// gbnfJSON are the GBNF rules of a JSON value.
+const gbnfJSON = `json-value ::= object | array
+value ::= object | array | string | number | ("true" | "false" | "null") ws
+object ::= "{" ws ( string ":" ws value ("," ws string ":" ws value)* )? "}" ws
+array ::= "[" ws ( value ("," ws value)* )? "]" ws
+string ::= "\"" ( [^"\\\x7F\x00-\x1F] | "\\" (["\\/bfnrt] | "u" [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F] [0-9a-fA-F]) )* "\"" ws
+number ::= "-"? [0-9]+ ("." [0-9]+)? ([eE] [-+]? [0-9]+)? ws
+ws ::= [ \t\n]*`
+
+// GBNF returns a GBNF grammar, as used by llama.cpp, that generates only
+// outputs the parser accepts. JSON labels get a full JSON grammar; other
+// values are constrained by their Choices and DataType.
+func (p *Parser) GBNF() string {
+	var rules []string
+	used := make(map[valueKind]bool)
+	var fields []string
+	blockStart := ""
+	for _, label := range p.modelLabels() {
+		rule := gbnfRuleName(label.Name) + "-field"
+		kind := exportKind(label)
+		value := string(kind) + "-value"
+		if kind == kindChoice {
+			quoted := make([]string, len(label.Choices))
+			for i, choice := range label.Choices {
+				quoted[i] = gbnfLiteral(choice)
+			}
+			value = "( " + strings.Join(quoted, " | ") + " )"
+		} else {
+			used[kind] = true
+		}
+		rules = append(rules, rule+" ::= "+gbnfLiteral(p.display[label.Name]+": ")+" "+value+` "\n"`)
+		switch {
+		case label.IsBlockStart:
+			blockStart = rule
+		case label.Required:
+			fields = append(fields, rule)
+		default:
+			fields = append(fields, rule+"?")
+		}
+	}
+
+	var b strings.Builder
+	if blockStart != "" {
+		b.WriteString("root ::= block+\nblock ::= " + strings.TrimSpace(blockStart+" "+strings.Join(fields, " ")) + "\n")
+	} else {
+		b.WriteString("root ::= " + strings.Join(fields, " ") + "\n")
+	}
+	for _, rule := range rules {
+		b.WriteString(rule + "\n")
+	}
+	// Value rules, in a fixed order; the JSON rules come with their own helpers
+	decimal := gbnfLiteral(string(p.locale.decimal()))
+	valueRules := []struct {
+		kind valueKind
+		rule string
+	}{
+		{kindText, `text-value ::= [^\n]+`},
+		{kindJSON, gbnfJSON},
+		{kindInteger, `integer-value ::= "-"? [0-9]+`},
+		{kindNumber, `number-value ::= "-"? [0-9]+ (` + decimal + ` [0-9]+)?`},
+		{kindURL, `url-value ::= "http" "s"? "://" [^ \t\n]+`},
+		{kindDatetime, `datetime-value ::= [0-9] [0-9] [0-9] [0-9] "-" [0-9] [0-9] "-" [0-9] [0-9] "T" [0-9] [0-9] ":" [0-9] [0-9] ":" [0-9] [0-9] ("Z" | [-+] [0-9] [0-9] ":" [0-9] [0-9])`},
+		{kindCheckbox, `checkbox-value ::= "[x]" | "[ ]"`},
+	}
+	for _, value := range valueRules {
+		if used[value.kind] {
+			b.WriteString(value.rule + "\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// gbnfRuleName turns a label name into a GBNF rule name: lowercase letters,
+// digits and hyphens.
+func gbnfRuleName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "-") {
+			b.WriteRune('-')
+		}
+	}
+	rule := strings.TrimSuffix(b.String(), "-")
+	if rule == "" {
+		rule = "label"
+	}
+	return rule
+}
+
+// gbnfLiteral quotes text as a GBNF string literal.
+func gbnfLiteral(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(text) + `"`
+}
+
+// DecodingRegex returns a regular expression, for regex-guided decoding,
+// that matches only outputs the parser accepts. Values are constrained by
+// their Choices, MatchPattern and DataType; JSON values must be written on a
+// single line. The expression uses syntax shared by Go and Python.
+func (p *Parser) DecodingRegex() string {
+	var fields []string
+	start := ""
+	for _, label := range p.modelLabels() {
+		field := regexp.QuoteMeta(p.display[label.Name]+": ") + p.regexValue(label) + `\n`
+		switch {
+		case label.IsBlockStart:
+			start = field
+		case label.Required:
+			fields = append(fields, field)
+		default:
+			fields = append(fields, "(?:"+field+")?")
+		}
+	}
+	if start != "" {
+		return "(?:" + start + strings.Join(fields, "") + ")+"
+	}
+	return strings.Join(fields, "")
+}
+
+// regexValue returns the expression a label's value must match.
+func (p *Parser) regexValue(label Label) string {
+	if label.MatchPattern != "" && exportKind(label) != kindChoice {
+		return "(?:" + strings.TrimSuffix(strings.TrimPrefix(label.MatchPattern, "^"), "$") + ")"
+	}
+	switch exportKind(label) {
+	case kindChoice:
+		quoted := make([]string, len(label.Choices))
+		for i, choice := range label.Choices {
+			quoted[i] = regexp.QuoteMeta(choice)
+		}
+		return "(?:" + strings.Join(quoted, "|") + ")"
+	case kindJSON:
+		return `[\[{][^\n]*`
+	case kindInteger:
+		return `-?[0-9]+`
+	case kindNumber:
+		return `-?[0-9]+(?:` + regexp.QuoteMeta(string(p.locale.decimal())) + `[0-9]+)?`
+	case kindURL:
+		return `https?://[^\s]+`
+	case kindDatetime:
+		return `[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(?:Z|[-+][0-9]{2}:[0-9]{2})`
+	case kindCheckbox:
+		return `\[[ x]\]`
+	}
+	return `[^\n]+`
+}
+
+// JSONSchema returns a JSON Schema of the parse result, e.g. for
+// Outlines-style JSON-guided decoding or to validate stored results: an
+// object with a property per label (or, with a block start label, an array of
+// such objects), typed by its Choices, DataType and Min/Max range.
+func (p *Parser) JSONSchema() map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+	hasBlocks := false
+	for _, label := range p.modelLabels() {
+		properties[label.Name] = jsonSchemaValue(label)
+		if label.Required || label.IsBlockStart {
+			required = append(required, label.Name)
+		}
+		hasBlocks = hasBlocks || label.IsBlockStart
+	}
+	object := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+	if hasBlocks {
+		return map[string]interface{}{"$schema": jsonSchemaDialect, "type": "array", "items": object}
+	}
+	object["$schema"] = jsonSchemaDialect
+	return object
+}
+
+// jsonSchemaDialect is the JSON Schema version JSONSchema produces.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchemaValue returns the schema of a label's parsed value.
+func jsonSchemaValue(label Label) map[string]interface{} {
+	schema := make(map[string]interface{})
+	switch exportKind(label) {
+	case kindChoice:
+		schema["type"] = "string"
+		schema["enum"] = append([]string(nil), label.Choices...)
+		return schema
+	case kindJSON:
+		return schema
+	case kindInteger:
+		schema["type"] = "integer"
+	case kindNumber:
+		schema["type"] = "number"
+	case kindURL:
+		schema["type"] = "string"
+		schema["format"] = "uri"
+	case kindDatetime:
+		schema["type"] = "string"
+		schema["format"] = "date-time"
+	case kindCheckbox:
+		schema["type"] = "boolean"
+	default:
+		switch label.DataType {
+		case "list":
+			schema["type"] = "array"
+			schema["items"] = map[string]interface{}{"type": "string"}
+		case "nested-list":
+			schema["$defs"] = map[string]interface{}{"item": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"text":     map[string]interface{}{"type": "string"},
+					"children": map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/$defs/item"}},
+				},
+				"required": []string{"text"},
+			}}
+			schema["type"] = "array"
+			schema["items"] = map[string]interface{}{"$ref": "#/$defs/item"}
+		case "status":
+			schema["type"] = "string"
+			schema["enum"] = []string{StatusDone, StatusFailed, StatusPending, StatusInProgress, StatusBlocked, StatusSkipped}
+		case "quantity", "percent", "currency":
+			schema["type"] = "object"
+			schema["properties"] = map[string]interface{}{
+				"value":       map[string]interface{}{"type": "number"},
+				"unit":        map[string]interface{}{"type": "string"},
+				"currency":    map[string]interface{}{"type": "string"},
+				"approximate": map[string]interface{}{"type": "boolean"},
+			}
+			schema["required"] = []string{"value"}
+		default:
+			schema["type"] = "string"
+			if label.MatchPattern != "" {
+				schema["pattern"] = label.MatchPattern
+			}
+		}
+	}
+	if label.Min != nil {
+		schema["minimum"] = *label.Min
+	}
+	if label.Max != nil {
+		schema["maximum"] = *label.Max
+	}
+	return schema
+}
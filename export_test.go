@@ -0,0 +1,71 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+// TestExporters checks the constrained-decoding exports of a schema.
+func TestExporters(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Task", IsBlockStart: true},
+		{Name: "Sentiment", Choices: []string{"positive", "negative"}, Required: true},
+		{Name: "Confidence", DataType: "confidence", Min: float64Ptr(0), Max: float64Ptr(1)},
+		{Name: "Ticket", MatchPattern: `^[A-Z]+-[0-9]+$`},
+		{Name: "Details", IsJSON: true},
+		{Name: "Observation", Source: SourceSystem},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+
+	expectedGrammar := `root ::= block+
+block ::= task-field sentiment-field confidence-field? ticket-field? details-field?
+task-field ::= "Task: " text-value "\n"
+sentiment-field ::= "Sentiment: " ( "positive" | "negative" ) "\n"
+confidence-field ::= "Confidence: " number-value "\n"
+ticket-field ::= "Ticket: " text-value "\n"
+details-field ::= "Details: " json-value "\n"
+text-value ::= [^\n]+
+` + gbnfJSON + `
+number-value ::= "-"? [0-9]+ ("." [0-9]+)?`
+	if grammar := parser.GBNF(); grammar != expectedGrammar {
+		t.Errorf("unexpected grammar:\n%s", grammar)
+	}
+
+	// The regex accepts what the parser accepts, and nothing outside the schema
+	pattern := regexp.MustCompile("^" + parser.DecodingRegex() + "$")
+	valid := "Task: Review\nSentiment: negative\nTicket: OPS-12\nTask: Second\nSentiment: positive\nConfidence: 0.5\nDetails: {\"ok\": true}\n"
+	if !pattern.MatchString(valid) {
+		t.Errorf("regex %s rejects valid output", pattern)
+	}
+	if blocks, errList := parser.ParseBlocks(valid); len(errList) > 0 || len(blocks) != 2 {
+		t.Errorf("valid output does not parse: %v %v", blocks, errList)
+	}
+	for _, invalid := range []string{
+		"Task: Review\nSentiment: neutral\n",
+		"Task: Review\nConfidence: 0.5\n",
+		"Task: Review\nSentiment: negative\nTicket: ops\n",
+		"Task: Review\nSentiment: negative\nObservation: done\n",
+	} {
+		if pattern.MatchString(invalid) {
+			t.Errorf("regex accepts %q", invalid)
+		}
+	}
+
+	schema, err := json.Marshal(parser.JSONSchema())
+	if err != nil {
+		t.Fatalf("failed to encode schema: %v", err)
+	}
+	expectedSchema := `{"$schema":"https://json-schema.org/draft/2020-12/schema","items":{"properties":{"confidence":{"maximum":1,"minimum":0,"type":"number"},"details":{},"sentiment":{"enum":["positive","negative"],"type":"string"},"task":{"type":"string"},"ticket":{"pattern":"^[A-Z]+-[0-9]+$","type":"string"}},"required":["task","sentiment"],"type":"object"},"type":"array"}`
+	if string(schema) != expectedSchema {
+		t.Errorf("unexpected schema: %s", schema)
+	}
+
+	// Numbers follow the parser's locale
+	german, _ := NewParser([]Label{{Name: "Amount", DataType: "number"}}, WithLocale(LocaleGerman))
+	if got := german.DecodingRegex(); got != `(?:Amount: -?[0-9]+(?:,[0-9]+)?\n)?` {
+		t.Errorf("unexpected German regex: %s", got)
+	}
+}
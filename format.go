@@ -0,0 +1,118 @@
+package arkaineparser
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Format renders values as labeled text that Parse reads back, e.g. to build a
+// scratchpad of previous steps for the next prompt. Labels are written in
+// declaration order using their declared names; a slice value of a text label
+// writes the label once per element. Keys may be label names or aliases in any case.
+// Returns an error if a key is not a defined label or a value cannot be rendered.
+func (p *Parser) Format(values map[string]interface{}) (string, error) {
+	return p.format(values, "")
+}
+
+// FormatFrom renders values like Format, but refuses labels that source does
+// not write: the system may not render model labels as if it produced them, and
+// the model's output may not be rendered with system labels such as a tool
+// Observation it invented.
+func (p *Parser) FormatFrom(source LabelSource, values map[string]interface{}) (string, error) {
+	if source == "" {
+		source = SourceModel
+	}
+	return p.format(values, source)
+}
+
+// format renders values, checking each label's source unless source is "".
+func (p *Parser) format(values map[string]interface{}, source LabelSource) (string, error) {
+	// Step 1: Resolve keys to canonical label names, flattening nested groups
+	byLabel := make(map[string]interface{}, len(values))
+	var errList []error
+	for key, value := range p.flattenValues(values) {
+		canonical, ok := p.names[strings.ToLower(strings.TrimSpace(key))]
+		if !ok {
+			errList = append(errList, errors.New("Label '"+key+"' is not defined"))
+			continue
+		}
+		byLabel[canonical] = value
+	}
+
+	// Step 2: Render labels in declaration order
+	var b strings.Builder
+	for _, label := range p.labels {
+		value, ok := byLabel[label.Name]
+		if !ok {
+			continue
+		}
+		labelSource := label.Source
+		if labelSource == "" {
+			labelSource = SourceModel
+		}
+		if source != "" && labelSource != source {
+			errList = append(errList, fmt.Errorf("Label '%s' is %s-sourced and cannot be rendered as %s output", label.Name, labelSource, source))
+			continue
+		}
+		// Text labels repeat once per slice element; JSON values are written whole
+		items, isList := value.([]interface{})
+		if !isList || label.IsJSON {
+			items = []interface{}{value}
+		}
+		for _, item := range items {
+			text, err := formatValue(label, item)
+			if err != nil {
+				errList = append(errList, err)
+				continue
+			}
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			b.WriteString(p.display[label.Name] + ": " + text)
+		}
+	}
+	if len(errList) > 0 {
+		return "", errors.Join(errList...)
+	}
+	return b.String(), nil
+}
+
+// formatValue renders a single value of label as text. Strings are written
+// as-is; objects, arrays and any value of a JSON label are written as compact JSON.
+func formatValue(label Label, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case []string:
+		return strings.Join(v, ", "), nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case [][]string:
+		var b strings.Builder
+		writer := csv.NewWriter(&b)
+		if label.DataType == "tsv" {
+			writer.Comma = '\t'
+		}
+		writer.WriteAll(v)
+		return strings.TrimSuffix(b.String(), "\n"), writer.Error()
+	case fmt.Stringer:
+		return v.String(), nil
+	case map[string]interface{}, []interface{}:
+	default:
+		if !label.IsJSON {
+			return fmt.Sprint(value), nil
+		}
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", errors.New("Label '" + label.Name + "' value cannot be rendered: " + err.Error())
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,55 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestFormat checks that Format output parses back and that sources are enforced.
+func TestFormat(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought"},
+		{Name: "Tool"},
+		{Name: "Arguments", IsJSON: true},
+		{Name: "Observation", Source: SourceSystem},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	values := map[string]interface{}{
+		"thought":   []interface{}{"look it up", "then answer"},
+		"TOOL":      "search",
+		"arguments": map[string]interface{}{"q": "go"},
+	}
+	text, err := parser.FormatFrom(SourceModel, values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "Thought: look it up\nThought: then answer\nTool: search\nArguments: {\"q\":\"go\"}"
+	if text != expected {
+		t.Errorf("unexpected text.\nGot: %q\nExpected: %q", text, expected)
+	}
+	result, errList := parser.Parse(text)
+	values["tool"] = values["TOOL"]
+	delete(values, "TOOL")
+	values["observation"] = ""
+	if len(errList) > 0 || !reflect.DeepEqual(result, values) {
+		t.Errorf("round trip mismatch: %#v %v", result, errList)
+	}
+
+	// The system may render observations, but not model labels
+	if _, err := parser.FormatFrom(SourceSystem, map[string]interface{}{"Observation": "3 results"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = parser.FormatFrom(SourceSystem, map[string]interface{}{"Thought": "forged"})
+	if err == nil || !strings.Contains(err.Error(), "Label 'thought' is model-sourced and cannot be rendered as system output") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A system-sourced label in model output is stripped like WithSystemLabels
+	details := parser.ParseDetailed("Tool: search\nObservation: invented")
+	if details.Result["observation"] != "" || len(details.Warnings) != 1 {
+		t.Errorf("expected observation to be stripped: %#v", details)
+	}
+}
@@ -0,0 +1,146 @@
+package arkaineparser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// frontMatter is the metadata preamble found at the top of an output.
+type frontMatter struct {
+	values map[string]interface{}
+	errors []ParseError
+}
+
+// splitFrontMatter removes YAML (between "---" lines) or TOML (between "+++"
+// lines) front matter from the top of text, ignoring leading blank lines.
+// Returns the rest of the text and the parsed front matter, or the text
+// unchanged and nil when it has none or the preamble is never closed.
+func splitFrontMatter(text string) (string, *frontMatter) {
+	lines := strings.Split(strings.TrimLeft(text, " \t\r\n"), "\n")
+	delimiter := strings.TrimSpace(lines[0])
+	if delimiter != "---" && delimiter != "+++" {
+		return text, nil
+	}
+	end := 1
+	for end < len(lines) && strings.TrimSpace(lines[end]) != delimiter {
+		end++
+	}
+	if end == len(lines) {
+		return text, nil
+	}
+	front := &frontMatter{values: make(map[string]interface{})}
+	if delimiter == "---" {
+		front.parseYAML(lines[1:end])
+	} else {
+		front.parseTOML(lines[1:end])
+	}
+	return strings.Join(lines[end+1:], "\n"), front
+}
+
+// parseYAML reads flat "key: value" pairs. A key with no value takes the
+// "- item" lines below it as a list.
+func (f *frontMatter) parseYAML(lines []string) {
+	listKey := "" // Key collecting "- item" lines, or ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok && listKey != "" {
+			list, _ := f.values[listKey].([]interface{})
+			f.values[listKey] = append(list, frontMatterScalar(item))
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || line[0] == ' ' || line[0] == '\t' {
+			f.fail(i+2, "expected 'key: value', got '"+trimmed+"'")
+			listKey = ""
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			// The value is a list on the following lines, or empty
+			listKey = key
+			f.values[key] = []interface{}{}
+			continue
+		}
+		listKey = ""
+		f.values[key] = frontMatterScalar(value)
+	}
+}
+
+// parseTOML reads "key = value" pairs. "[table]" headers put the pairs that
+// follow into a nested map.
+func (f *frontMatter) parseTOML(lines []string) {
+	table := f.values
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") && !strings.Contains(trimmed, "=") {
+			name := strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if name == "" {
+				f.fail(i+2, "empty table name")
+				continue
+			}
+			nested, ok := f.values[name].(map[string]interface{})
+			if !ok {
+				nested = make(map[string]interface{})
+				f.values[name] = nested
+			}
+			table = nested
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, "=")
+		key = strings.Trim(strings.TrimSpace(key), `"'`)
+		if !ok || key == "" {
+			f.fail(i+2, "expected 'key = value', got '"+trimmed+"'")
+			continue
+		}
+		table[key] = frontMatterScalar(strings.TrimSpace(value))
+	}
+}
+
+// fail records a malformed front matter line. line is 1-based within the
+// text, counting the opening delimiter.
+func (f *frontMatter) fail(line int, problem string) {
+	f.errors = append(f.errors, newFrontMatterError(line, problem))
+}
+
+// frontMatterScalar converts a front matter value: quoted strings are
+// unquoted, "[a, b]" becomes a list, and booleans and numbers are converted
+// like JSON values (bool and float64). Anything else stays a string.
+func frontMatterScalar(value string) interface{} {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		if unquoted, err := strconv.Unquote(`"` + value[1:len(value)-1] + `"`); err == nil && value[0] == '"' {
+			return unquoted
+		}
+		return value[1 : len(value)-1]
+	}
+	if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+		list := []interface{}{}
+		for _, item := range strings.Split(value[1:len(value)-1], ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, frontMatterScalar(item))
+			}
+		}
+		return list
+	}
+	// Drop a trailing comment
+	if idx := strings.Index(value, " #"); idx >= 0 {
+		value = strings.TrimSpace(value[:idx])
+	}
+	switch strings.ToLower(value) {
+	case "true", "yes":
+		return true
+	case "false", "no":
+		return false
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(number, 0) && !math.IsNaN(number) {
+		return number
+	}
+	return value
+}
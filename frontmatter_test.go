@@ -0,0 +1,64 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestFrontMatter checks YAML and TOML front matter parsing.
+func TestFrontMatter(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Summary"}, {Name: "Answer"}}, WithFrontMatter("meta"))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	text := "---\ntitle: \"Quarterly: report\"\nconfidence: 0.8\ndraft: false\ntags:\n  - finance\n  - q3\nauthors: [ana, bo]\n---\nSummary: Revenue grew.\nAnswer: yes"
+	details := parser.ParseDetailed(text)
+	if len(details.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", details.Errors)
+	}
+	expected := map[string]interface{}{
+		"title":      "Quarterly: report",
+		"confidence": 0.8,
+		"draft":      false,
+		"tags":       []interface{}{"finance", "q3"},
+		"authors":    []interface{}{"ana", "bo"},
+	}
+	if !reflect.DeepEqual(details.Result["meta"], expected) {
+		t.Errorf("front matter mismatch.\nGot: %#v\nExpected: %#v", details.Result["meta"], expected)
+	}
+	if details.Result["summary"] != "Revenue grew." || details.Result["answer"] != "yes" {
+		t.Errorf("unexpected result: %#v", details.Result)
+	}
+
+	result, errs := parser.Parse("+++\nversion = 2\n[model]\nname = 'small'\n+++\nAnswer: no")
+	expected = map[string]interface{}{"version": 2.0, "model": map[string]interface{}{"name": "small"}}
+	if len(errs) > 0 || !reflect.DeepEqual(result["meta"], expected) || result["answer"] != "no" {
+		t.Errorf("unexpected TOML result: %#v %v", result, errs)
+	}
+
+	// Without front matter the key holds an empty map
+	result, _ = parser.Parse("Answer: no")
+	if !reflect.DeepEqual(result["meta"], map[string]interface{}{}) {
+		t.Errorf("expected empty front matter, got %#v", result["meta"])
+	}
+
+	_, errs = parser.Parse("---\ntitle: ok\nnot a pair\n---\nAnswer: no")
+	if len(errs) != 1 || errs[0] != "Invalid front matter on line 3: expected 'key: value', got 'not a pair'" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	// Every block gets the front matter; its errors are reported once
+	blockParser, err := NewParser([]Label{{Name: "Task", IsBlockStart: true}}, WithFrontMatter("meta"))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	blocks, errs := blockParser.ParseBlocks("---\nrun: 7\nbad\n---\nTask: a\nTask: b")
+	if len(blocks) != 2 || len(errs) != 1 || !reflect.DeepEqual(blocks[1]["meta"], map[string]interface{}{"run": 7.0}) {
+		t.Errorf("unexpected blocks: %#v %v", blocks, errs)
+	}
+
+	_, err = NewParser([]Label{{Name: "Meta"}}, WithFrontMatter("meta"))
+	if err == nil {
+		t.Errorf("expected front matter key collision error")
+	}
+}
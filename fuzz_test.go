@@ -0,0 +1,100 @@
+package arkaineparser
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+// fuzzLabels exercise every matching feature: JSON, end markers, typed values,
+// aliases, overlapping names and blocks.
+var fuzzLabels = []Label{
+	{Name: "Task", IsBlockStart: true},
+	{Name: "Action", RequiredWith: []string{"Action Input"}},
+	{Name: "Action Input", IsJSON: true},
+	{Name: "Answer", EndMarker: "END", Aliases: []string{"Final Answer"}},
+	{Name: "Count", DataType: "integer", Min: float64Ptr(0)},
+	{Name: "Rows", DataType: "csv"},
+	{Name: "Patch", DataType: "diff"},
+	{Name: "Query", DataType: "sql"},
+	{Name: "Choice", DataType: "choice"},
+}
+
+// fuzzSeeds are inputs the fuzz targets start from.
+var fuzzSeeds = []string{
+	"Action: search\nAction Input: {\"q\": \"go\"}",
+	"Task: one\nAnswer: a\nb END\nTask: two\nCount: 3",
+	"Action Input:\n```json\n{\"a\": [1,\n```\nScript: <<EOF\nx\nEOF",
+	"Rows:\na,b\n\"c\nPatch:\n@@ -1 +1 @@\n-a\n+b\nQuery: SELECT 'x",
+	"Final Answer: \x00fence:7 `x` ```",
+	"Choice: (B)\nCount: ¹²³\nAnswer: \xff\xfe",
+}
+
+// FuzzParse checks that no input makes Parse, ParseBlocks or Explain panic,
+// and that every result key is a defined label.
+func FuzzParse(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	parser, err := NewParser(fuzzLabels, WithIndentedValues())
+	if err != nil {
+		f.Fatalf("failed to create parser: %v", err)
+	}
+	htmlParser, err := NewParser(fuzzLabels, WithHTMLCapture("answer", "Answer"), WithFrontMatter("meta"))
+	if err != nil {
+		f.Fatalf("failed to create parser: %v", err)
+	}
+	f.Add([]byte("---\nk: [1, \"a\"]\n---\n<p><b>Task:</b> x<br><answer>1 <i>2</i></answer></p>"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		result, _ := parser.ParseBytes(data)
+		for key := range result {
+			if _, ok := parser.labelMap[key]; !ok {
+				t.Errorf("undefined label %q in result", key)
+			}
+		}
+		parser.ParseBlocks(string(data))
+		parser.Explain(string(data))
+		htmlParser.ParseBlocks(string(data))
+		if utf8.Valid(data) {
+			parser.Parse(string(data))
+		}
+	})
+}
+
+// FuzzHelpers checks that the standalone extraction helpers never panic.
+func FuzzHelpers(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Add("~ -€1 234,5k per cent ✅ [ ] not done")
+	f.Add("Step 1 (depends on: 2): a\nStep 2 (depends on: 1): b \\boxed{\\frac{1}{0}} [1]: http://x")
+	f.Fuzz(func(t *testing.T, text string) {
+		ParsePlan(text)
+		ExtractNumericAnswer(text)
+		ExtractCitations(text)
+		NormalizeChoice(text, []string{"A", "B"})
+		parseStatus(text)
+		parseQuantity(text, LocaleEnglish)
+		parseQuantity(text, LocaleFrench)
+		LocaleFrench.localizeDate(text)
+		LocaleEnglish.parseNumericDate(text)
+	})
+}
+
+// TestParseBytes checks invalid UTF-8 and inputs that found hangs and panics.
+func TestParseBytes(t *testing.T) {
+	parser, err := NewParser(fuzzLabels, WithIndentedValues())
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, errs := parser.ParseBytes([]byte("Answer: caf\xe9 END"))
+	if result["answer"] != "caf�" || len(errs) != 0 {
+		t.Errorf("unexpected result: %v %v", result, errs)
+	}
+	// An unclosed value restarting structure tracking used to loop forever
+	parser.ParseBytes([]byte("Action Input:\n```json{\"a\": [1,\n```\nScript: <<EOF\nx\nEOF"))
+	// Input spelling out a placeholder must not reach the protected lines
+	result, _ = parser.Parse("Answer:\n```\nx\n```\n\x00fence:-1\n\x00fence:0 END")
+	if result["answer"] != "x\nfence:-1\nfence:0" {
+		t.Errorf("unexpected placeholder result: %q", result["answer"])
+	}
+}
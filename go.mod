@@ -1,3 +1,5 @@
 module github.com/hlfshell/go-arkaine-parser
 
 go 1.24.2
+
+require golang.org/x/text v0.34.0
@@ -0,0 +1,70 @@
+package arkaineparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// groundingScore returns how well quote is supported by source, from 0 to 1.
+// Both are compared ignoring case, punctuation and spacing; a quote found
+// verbatim scores 1, otherwise the score is the similarity (1 minus the
+// relative edit distance) of the closest run of source words of the same length.
+func groundingScore(source, quote string) float64 {
+	quoteKey := choiceKey(quote)
+	if quoteKey == "" {
+		return 0
+	}
+	sourceKey := choiceKey(source)
+	if strings.Contains(" "+sourceKey+" ", " "+quoteKey+" ") {
+		return 1
+	}
+	words := strings.Fields(sourceKey)
+	n := len(strings.Fields(quoteKey))
+	best := 0.0
+	for start := 0; start < len(words); start++ {
+		// Allow the run to be a word shorter or longer than the quote
+		for size := max(n-1, 1); size <= n+1 && start+size <= len(words); size++ {
+			window := strings.Join(words[start:start+size], " ")
+			length := max(len([]rune(window)), len([]rune(quoteKey)))
+			score := 1 - float64(levenshtein(window, quoteKey))/float64(length)
+			if score > best {
+				best = score
+			}
+		}
+	}
+	return best
+}
+
+// grounded reports whether quote is supported by source given a fuzziness
+// between 0 (the quote must appear verbatim, ignoring case and punctuation)
+// and 1 (anything goes).
+func grounded(source, quote string, fuzziness float64) (float64, bool) {
+	score := groundingScore(source, quote)
+	return score, score >= 1-fuzziness
+}
+
+// sentencePattern matches a sentence: text up to and including its final
+// punctuation, or up to the end of a line.
+var sentencePattern = regexp.MustCompile(`[^.!?\n]+[.!?]*`)
+
+// minGroundedWords is the shortest sentence checked for grounding; shorter
+// fragments such as "Yes." carry no claim to look up.
+const minGroundedWords = 3
+
+// checkGrounding adds a warning to details for every sentence of a grounded
+// label's values that the grounding source does not support.
+func (p *Parser) checkGrounding(data map[string][]string, details *Details) {
+	for _, label := range p.groundedLabels {
+		for _, entry := range data[label] {
+			for _, sentence := range sentencePattern.FindAllString(entry, -1) {
+				sentence = strings.TrimSpace(sentence)
+				if len(strings.Fields(choiceKey(sentence))) < minGroundedWords {
+					continue
+				}
+				if score, ok := grounded(p.groundingSource, sentence, p.groundingFuzziness); !ok {
+					details.Warnings = append(details.Warnings, newUngroundedWarning(label, sentence, score))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package arkaineparser
+
+// labelGroup is a set of labels assembled into repeated records.
+type labelGroup struct {
+	name   string   // Result key of the records
+	labels []string // Member labels in declaration order; the first starts each record
+}
+
+// buildGroups collects the groups of labels in order of their first member.
+func buildGroups(labels []Label) []labelGroup {
+	var groups []labelGroup
+	index := make(map[string]int)
+	for _, label := range labels {
+		if label.Group == "" {
+			continue
+		}
+		i, ok := index[label.Group]
+		if !ok {
+			i = len(groups)
+			index[label.Group] = i
+			groups = append(groups, labelGroup{name: label.Group})
+		}
+		groups[i].labels = append(groups[i].labels, label.Name)
+	}
+	return groups
+}
+
+// assembleGroups replaces the values of grouped labels in details.Result with
+// one []map[string]interface{} per group. Labels are walked in order of
+// appearance; every appearance of the group's first label starts a new record,
+// and members appearing before it start the first one. Each record holds the
+// parsed value of every member that appeared in it. Occurrences are left as
+// they are.
+func (p *Parser) assembleGroups(matches []lineMatch, details *Details) {
+	for _, group := range p.groups {
+		members := make(map[string]bool, len(group.labels))
+		for _, label := range group.labels {
+			members[label] = true
+			delete(details.Result, label)
+		}
+		records := []map[string]interface{}{}
+		seen := make(map[string]int) // Occurrences of each member consumed so far
+		for _, match := range matches {
+			if !members[match.label] {
+				continue
+			}
+			occurrence := seen[match.label]
+			seen[match.label]++
+			// Stripped system labels have no occurrences left
+			occurrences := details.Occurrences[match.label]
+			if occurrence >= len(occurrences) {
+				continue
+			}
+			if match.label == group.labels[0] || len(records) == 0 {
+				records = append(records, make(map[string]interface{}))
+			}
+			records[len(records)-1][match.label] = occurrences[occurrence]
+		}
+		details.Result[group.name] = records
+	}
+}
@@ -0,0 +1,57 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLabelGroups checks that grouped labels are assembled into records.
+func TestLabelGroups(t *testing.T) {
+	parser, err := NewSchema().
+		Label("Summary").
+		Label("Name").Group("Items").
+		Label("Price").DataType("number").Group("Items").
+		Label("Note").Group("Items").
+		Parser()
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	text := "Summary: two products\nName: Lamp\nPrice: 20\nNote: warm light\nName: Desk\nPrice: 150"
+	result, errList := parser.Parse(text)
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	expected := []map[string]interface{}{
+		{"name": "Lamp", "price": 20.0, "note": "warm light"},
+		{"name": "Desk", "price": 150.0},
+	}
+	if !reflect.DeepEqual(result["items"], expected) {
+		t.Errorf("unexpected records: %#v", result["items"])
+	}
+	if result["summary"] != "two products" {
+		t.Errorf("ungrouped label changed: %#v", result["summary"])
+	}
+	for _, name := range []string{"name", "price", "note"} {
+		if _, ok := result[name]; ok {
+			t.Errorf("grouped label %q should not remain in the result", name)
+		}
+	}
+
+	// Members before the first label start a record; a missing group is empty
+	result, _ = parser.Parse("Price: 5\nName: Pen")
+	expected = []map[string]interface{}{{"price": 5.0}, {"name": "Pen"}}
+	if !reflect.DeepEqual(result["items"], expected) {
+		t.Errorf("unexpected records: %#v", result["items"])
+	}
+	result, _ = parser.Parse("Summary: nothing")
+	if records, ok := result["items"].([]map[string]interface{}); !ok || len(records) != 0 {
+		t.Errorf("expected no records, got %#v", result["items"])
+	}
+
+	// A group may not be named like a label
+	_, err = NewParser([]Label{{Name: "Items"}, {Name: "Name", Group: "items"}})
+	if err == nil || !strings.Contains(err.Error(), "Label group 'items' of 'name' collides with label 'items'") {
+		t.Errorf("expected a group collision error, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package arkaineparser
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Patterns for the simple HTML converted by WithHTML
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlItemPattern  = regexp.MustCompile(`(?i)<li(\s[^>]*)?>`)
+	htmlBlockPattern = regexp.MustCompile(`(?i)</?(p|div|ul|ol|li|h[1-6]|tr|table|section|article|blockquote|pre|hr)(\s[^>]*)?/?>`)
+	htmlTagPattern   = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(\s[^>]*)?/?>`)
+	blankRunPattern  = regexp.MustCompile(`\n[ \t]*\n([ \t]*\n)+`)
+)
+
+// htmlCapture routes the content of an HTML element to a label.
+type htmlCapture struct {
+	tag     string
+	label   string
+	pattern *regexp.Regexp // Matches the whole element, capturing its content
+}
+
+// capturePlaceholder marks the line where a captured element was, followed by
+// its index in the captures returned by convertHTML.
+const capturePlaceholder = "\x00capture:"
+
+// convertHTML turns simple HTML into plain text outside code fences: line
+// breaks and block elements become new lines (list items start with "- "),
+// other tags are dropped and entities are decoded. Captured elements are
+// replaced by placeholder lines, and their converted content is returned in
+// order. Returns the converted text, the captures and whether anything changed.
+func (p *Parser) convertHTML(text string) (string, []routedFence, bool) {
+	lines := strings.Split(text, "\n")
+	var (
+		out      []string // Converted text, by segment
+		segment  []string // Lines outside fences not yet converted
+		inFence  bool
+		captures []routedFence
+	)
+	for _, line := range lines {
+		switch {
+		case inFence:
+			out = append(out, line)
+			inFence = !fenceClosePattern.MatchString(line)
+		case fenceOpenPattern.MatchString(line):
+			if len(segment) > 0 {
+				out = append(out, p.convertHTMLSegment(strings.Join(segment, "\n"), &captures))
+				segment = nil
+			}
+			out = append(out, line)
+			inFence = true
+		default:
+			segment = append(segment, line)
+		}
+	}
+	if len(segment) > 0 {
+		out = append(out, p.convertHTMLSegment(strings.Join(segment, "\n"), &captures))
+	}
+	converted := strings.Join(out, "\n")
+	return converted, captures, converted != text
+}
+
+// convertHTMLSegment converts a piece of text holding no code fences,
+// appending its captured elements to captures.
+func (p *Parser) convertHTMLSegment(text string, captures *[]routedFence) string {
+	for _, capture := range p.htmlCaptures {
+		text = capture.pattern.ReplaceAllStringFunc(text, func(element string) string {
+			content := stripHTML(capture.pattern.FindStringSubmatch(element)[2])
+			*captures = append(*captures, routedFence{label: capture.label, lines: strings.Split(strings.TrimSpace(content), "\n"), matcher: MatchHTMLCapture})
+			return "\n" + capturePlaceholder + strconv.Itoa(len(*captures)-1) + "\n"
+		})
+	}
+	return stripHTML(text)
+}
+
+// stripHTML converts line breaks and block elements to new lines, drops other
+// tags and decodes entities.
+func stripHTML(text string) string {
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlItemPattern.ReplaceAllString(text, "\n- ")
+	text = htmlBlockPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	// Tags around existing line breaks leave runs of blank lines behind
+	return blankRunPattern.ReplaceAllString(text, "\n\n")
+}
+
+// compileHTMLCapture builds the pattern matching a whole element with tag.
+func compileHTMLCapture(tag string) *regexp.Regexp {
+	return regexp.MustCompile(`(?is)<` + regexp.QuoteMeta(tag) + `(\s[^>]*)?>(.*?)</` + regexp.QuoteMeta(tag) + `\s*>`)
+}
@@ -0,0 +1,43 @@
+package arkaineparser
+
+import "testing"
+
+// TestHTML checks HTML conversion and element capture.
+func TestHTML(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Thought"}, {Name: "Action"}, {Name: "Steps", DataType: "list"}, {Name: "Code"}}, WithHTML())
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	text := "<p><b>Thought:</b> look it up &amp; compare</p><p><b>Action:</b> search<br>web</p>\n" +
+		"<strong>Steps:</strong><ul><li>one</li><li>two</li></ul>\nCode:\n```html\n<p>kept</p>\n```"
+	details := parser.ParseDetailed(text)
+	if len(details.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", details.Errors)
+	}
+	expected := map[string]interface{}{
+		"thought": "look it up & compare",
+		"action":  "search\nweb",
+		"steps":   []string{"one", "two"},
+		"code":    "<p>kept</p>",
+	}
+	if !deepEqual(map[string]interface{}(details.Result), expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", details.Result, expected)
+	}
+	if len(details.Repairs) == 0 || details.Repairs[0].Kind != RepairHTML {
+		t.Errorf("expected an html repair, got %#v", details.Repairs)
+	}
+
+	capture, err := NewParser([]Label{{Name: "Reasoning"}, {Name: "Answer"}}, WithHTMLCapture("answer", "Answer"), WithHTMLCapture("think", "reasoning"))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, errs := capture.Parse("<think>\n2 + 2 is 4 </think>\nSo: <Answer class=\"final\">4</Answer>")
+	if len(errs) > 0 || result["reasoning"] != "2 + 2 is 4" || result["answer"] != "4" {
+		t.Errorf("unexpected capture result: %#v %v", result, errs)
+	}
+
+	_, err = NewParser([]Label{{Name: "Answer"}}, WithHTMLCapture("final", "Result"))
+	if err == nil || err.Error() != "HTML capture label 'result' is not defined" {
+		t.Errorf("expected undefined capture label error, got %v", err)
+	}
+}
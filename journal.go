@@ -0,0 +1,229 @@
+package arkaineparser
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JournalEntry is a single recorded parse, one line of a journal.
+type JournalEntry struct {
+	Time      time.Time `json:"time"`
+	InputHash string    `json:"input_hash"` // Hex SHA-256 of Input
+	Input     string    `json:"input"`
+	Schema    string    `json:"schema,omitempty"` // Schema version the parser was built for
+	// Blocks is true for ParseBlocks calls, whose Results hold one result per
+	// block; a plain parse has a single result
+	Blocks   bool         `json:"blocks,omitempty"`
+	Results  []Result     `json:"results"`
+	Errors   []ParseError `json:"errors"`
+	Warnings []ParseError `json:"warnings,omitempty"`
+	Repairs  []Repair     `json:"repairs,omitempty"`
+}
+
+// Journal appends recorded parses to a writer as JSON lines. A Journal is
+// safe for concurrent use by any number of parsers.
+type Journal struct {
+	schema string
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer // The file opened by OpenJournal, or nil
+	err    error     // The first write error
+}
+
+// NewJournal creates a Journal writing to w, tagging entries with schema, the
+// version of the schema in use (which may be empty).
+func NewJournal(w io.Writer, schema string) *Journal {
+	return &Journal{schema: schema, w: w}
+}
+
+// OpenJournal creates a Journal appending to the file at path, creating it if needed.
+func OpenJournal(path, schema string) (*Journal, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{schema: schema, w: file, closer: file}, nil
+}
+
+// Err returns the first error met while writing entries. Parsing never fails
+// because of the journal, so check Err to know whether entries were lost.
+func (j *Journal) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// Close closes the file opened by OpenJournal and returns the first write
+// error, if any.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closer != nil {
+		if err := j.closer.Close(); err != nil && j.err == nil {
+			j.err = err
+		}
+		j.closer = nil
+	}
+	return j.err
+}
+
+// record appends the parse of text to the journal.
+func (j *Journal) record(text string, blocks []Details, isBlocks bool) {
+	hash := sha256.Sum256([]byte(text))
+	entry := JournalEntry{
+		Time:      time.Now().UTC(),
+		InputHash: hex.EncodeToString(hash[:]),
+		Input:     text,
+		Schema:    j.schema,
+		Blocks:    isBlocks,
+		Errors:    []ParseError{},
+	}
+	for _, details := range blocks {
+		entry.Results = append(entry.Results, details.Result)
+		entry.Errors = append(entry.Errors, details.Errors...)
+		entry.Warnings = append(entry.Warnings, details.Warnings...)
+		entry.Repairs = append(entry.Repairs, details.Repairs...)
+	}
+	line, err := marshalStable(entry)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err == nil {
+		_, err = j.w.Write(append(line, '\n'))
+	}
+	if err != nil && j.err == nil {
+		j.err = err
+	}
+}
+
+// ReplayDiff describes how re-parsing a journal entry changed its outcome.
+type ReplayDiff struct {
+	Line      int      `json:"line"` // 1-based line of the entry in the journal
+	InputHash string   `json:"input_hash"`
+	Schema    string   `json:"schema,omitempty"` // Schema version of the recorded parse
+	Changes   []string `json:"changes"`          // Human readable differences
+}
+
+// ReplayReport summarizes a replay.
+type ReplayReport struct {
+	Entries int          `json:"entries"` // Entries replayed
+	Changed int          `json:"changed"` // Entries whose results or errors changed
+	Diffs   []ReplayDiff `json:"diffs"`
+}
+
+// Replay re-parses every entry of a journal with p, e.g. a parser for a new
+// schema version, and reports the entries whose results or error messages
+// changed. Block entries are re-parsed with ParseBlocks. Returns an error if
+// the journal cannot be read or decoded.
+func Replay(r io.Reader, p *Parser) (ReplayReport, error) {
+	var report ReplayReport
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return report, errors.New("Journal line " + strconv.Itoa(line) + ": " + err.Error())
+		}
+		report.Entries++
+		var blocks []Details
+		if entry.Blocks {
+			blocks, _ = p.parseBlocksDetailed(entry.Input)
+		} else {
+			blocks = []Details{p.parseDetailed(entry.Input, nil)}
+		}
+		changes, err := diffReplay(entry, blocks)
+		if err != nil {
+			return report, errors.New("Journal line " + strconv.Itoa(line) + ": " + err.Error())
+		}
+		if len(changes) > 0 {
+			report.Changed++
+			report.Diffs = append(report.Diffs, ReplayDiff{Line: line, InputHash: entry.InputHash, Schema: entry.Schema, Changes: changes})
+		}
+	}
+	return report, scanner.Err()
+}
+
+// diffReplay lists the differences between a recorded parse and a new one.
+// Results are compared in their JSON form, as recorded.
+func diffReplay(entry JournalEntry, blocks []Details) ([]string, error) {
+	var changes []string
+	if len(blocks) != len(entry.Results) {
+		changes = append(changes, "block count: "+strconv.Itoa(len(entry.Results))+" -> "+strconv.Itoa(len(blocks)))
+	}
+	for i := 0; i < min(len(blocks), len(entry.Results)); i++ {
+		prefix := ""
+		if entry.Blocks {
+			prefix = "block " + strconv.Itoa(i+1) + ": "
+		}
+		old, current := entry.Results[i], blocks[i].Result
+		keys := make(map[string]bool)
+		for key := range old {
+			keys[key] = true
+		}
+		for key := range current {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			before, err := replayValue(old, key)
+			if err != nil {
+				return nil, err
+			}
+			after, err := replayValue(current, key)
+			if err != nil {
+				return nil, err
+			}
+			if before != after {
+				changes = append(changes, prefix+"'"+key+"': "+before+" -> "+after)
+			}
+		}
+	}
+	var newErrors []ParseError
+	for _, details := range blocks {
+		newErrors = append(newErrors, details.Errors...)
+	}
+	before, after := errorStrings(entry.Errors), errorStrings(newErrors)
+	for _, message := range before {
+		if !slices.Contains(after, message) {
+			changes = append(changes, "error fixed: "+message)
+		}
+	}
+	for _, message := range after {
+		if !slices.Contains(before, message) {
+			changes = append(changes, "new error: "+message)
+		}
+	}
+	return changes, nil
+}
+
+// replayValue renders the value of key in result as compact JSON, or
+// "(missing)" when the key is absent.
+func replayValue(result Result, key string) (string, error) {
+	value, ok := result[key]
+	if !ok {
+		return "(missing)", nil
+	}
+	encoded, err := marshalStable(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
@@ -0,0 +1,83 @@
+package arkaineparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestJournalReplay checks that parses are journaled and replayed against a new schema.
+func TestJournalReplay(t *testing.T) {
+	var buf bytes.Buffer
+	journal := NewJournal(&buf, "v1")
+	v1, err := NewParser([]Label{{Name: "Tool"}, {Name: "Args", IsJSON: true}, {Name: "Task", IsBlockStart: true}}, WithJournal(journal))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	v1.Parse("Tool: search\nArgs: {\"q\": \"go\"}")
+	v1.Parse("Tool: calc\nArgs: {oops}")
+	v1.ParseBlocks("Task: a\nTool: x\nTask: b\nTool: y")
+	if journal.Err() != nil {
+		t.Fatalf("unexpected journal error: %v", journal.Err())
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 journal entries, got %d", len(lines))
+	}
+	var entry JournalEntry
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatalf("failed to decode entry: %v", err)
+	}
+	if entry.Schema != "v1" || entry.Input != "Tool: calc\nArgs: {oops}" || len(entry.InputHash) != 64 || len(entry.Errors) != 1 || entry.Errors[0].Kind != KindJSON {
+		t.Errorf("unexpected entry: %#v", entry)
+	}
+
+	// The new schema renames Tool and makes Args plain text
+	v2, err := NewParser([]Label{{Name: "Action", Aliases: []string{"Tool"}}, {Name: "Args"}, {Name: "Task", IsBlockStart: true}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	report, err := Replay(&buf, v2)
+	if err != nil {
+		t.Fatalf("unexpected replay error: %v", err)
+	}
+	if report.Entries != 3 || report.Changed != 3 {
+		t.Fatalf("unexpected report: %#v", report)
+	}
+	expected := []string{
+		`'action': (missing) -> "calc"`,
+		`'tool': "calc" -> (missing)`,
+		"error fixed: JSON error in 'args': invalid character 'o' looking for beginning of object key string",
+	}
+	if report.Diffs[1].Line != 2 || report.Diffs[1].Schema != "v1" || !reflect.DeepEqual(report.Diffs[1].Changes, expected) {
+		t.Errorf("unexpected diff: %#v", report.Diffs[1])
+	}
+	if !strings.HasPrefix(report.Diffs[2].Changes[0], "block 1: 'action'") {
+		t.Errorf("unexpected block diff: %#v", report.Diffs[2])
+	}
+
+	// Replaying against the same schema changes nothing
+	file := filepath.Join(t.TempDir(), "journal.jsonl")
+	fileJournal, err := OpenJournal(file, "v1")
+	if err != nil {
+		t.Fatalf("failed to open journal: %v", err)
+	}
+	journaled, _ := NewParser([]Label{{Name: "Tool"}}, WithJournal(fileJournal))
+	journaled.Parse("Tool: search")
+	if err := fileJournal.Close(); err != nil {
+		t.Fatalf("unexpected close error: %v", err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+	plain, _ := NewParser([]Label{{Name: "Tool"}})
+	report, err = Replay(bytes.NewReader(data), plain)
+	if err != nil || report.Entries != 1 || report.Changed != 0 {
+		t.Errorf("unexpected report: %#v %v", report, err)
+	}
+}
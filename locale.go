@@ -0,0 +1,204 @@
+package arkaineparser
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Locale describes how numbers and dates are written in the outputs a Parser
+// reads. The zero Locale is LocaleEnglish.
+type Locale struct {
+	Decimal   rune // Decimal separator; '.' when zero
+	Thousands rune // Thousands separator; ',' when zero. A space also accepts no-break spaces
+	// Currency is the ISO 4217 code the "$" sign and "dollars" stand for, such
+	// as "CAD" in Canada; "USD" when empty
+	Currency string
+	// DateOrder orders the parts of numeric dates such as 03/04/2024. When
+	// empty, only unambiguous numeric dates (13/04/2024) are accepted.
+	DateOrder DateOrder
+	// Months are the month names of the locale's language, January first.
+	// Abbreviations of at least three letters ("févr.") are accepted too.
+	Months []string
+	// Weekdays are the weekday names of the locale's language, Monday first.
+	// They are dropped from dates, along with a following comma.
+	Weekdays []string
+}
+
+// DateOrder is the order of day, month and year in numeric dates.
+type DateOrder string
+
+const (
+	DateOrderMDY DateOrder = "mdy" // 04/13/2024, as in the United States
+	DateOrderDMY DateOrder = "dmy" // 13/04/2024, as in most of Europe
+	DateOrderYMD DateOrder = "ymd" // 2024/04/13, as in East Asia
+)
+
+// Predefined locales.
+var (
+	LocaleEnglish = Locale{Decimal: '.', Thousands: ','}
+	LocaleUS      = Locale{Decimal: '.', Thousands: ',', DateOrder: DateOrderMDY}
+	LocaleUK      = Locale{Decimal: '.', Thousands: ',', DateOrder: DateOrderDMY}
+	LocaleGerman  = Locale{Decimal: ',', Thousands: '.', DateOrder: DateOrderDMY,
+		Months:   []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		Weekdays: []string{"Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag", "Sonntag"}}
+	LocaleFrench = Locale{Decimal: ',', Thousands: ' ', DateOrder: DateOrderDMY,
+		Months:   []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		Weekdays: []string{"lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi", "dimanche"}}
+	LocaleSpanish = Locale{Decimal: ',', Thousands: '.', DateOrder: DateOrderDMY,
+		Months:   []string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		Weekdays: []string{"lunes", "martes", "miércoles", "jueves", "viernes", "sábado", "domingo"}}
+	LocaleSwiss = Locale{Decimal: '.', Thousands: '\'', DateOrder: DateOrderDMY, Months: LocaleGerman.Months, Weekdays: LocaleGerman.Weekdays}
+)
+
+// decimal returns the decimal separator, defaulting to '.'.
+func (l Locale) decimal() rune {
+	if l.Decimal == 0 {
+		return '.'
+	}
+	return l.Decimal
+}
+
+// thousands returns the thousands separator, defaulting to ','.
+func (l Locale) thousands() rune {
+	if l.Thousands == 0 {
+		return ','
+	}
+	return l.Thousands
+}
+
+// isThousands reports whether r separates thousands in the locale.
+func (l Locale) isThousands(r rune) bool {
+	if l.thousands() == ' ' {
+		return r == ' ' || r == '\u00a0' || r == '\u202f'
+	}
+	return r == l.thousands()
+}
+
+// normalizeNumber rewrites a number written in the locale ("1.234,5" in
+// German) in Go syntax ("1234.5"), dropping thousands separators and
+// underscores.
+func (l Locale) normalizeNumber(number string) string {
+	var b strings.Builder
+	for _, r := range number {
+		switch {
+		case l.isThousands(r) || r == '_':
+		case r == l.decimal():
+			b.WriteRune('.')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// cleanNumber strips wrapping, a trailing period, spaces and digit separators
+// from a number written in the locale, returning it in Go syntax.
+func (l Locale) cleanNumber(value string) string {
+	value = strings.TrimRight(unwrapValue(value), ".")
+	return strings.ReplaceAll(l.normalizeNumber(value), " ", "")
+}
+
+// dateFillers are words between the parts of dates in some languages
+// ("1 de enero de 2024", "le 1er mars").
+var dateFillers = map[string]bool{"de": true, "del": true, "le": true, "der": true, "den": true, "am": true}
+
+// dateWordPattern finds the words of a date, with any abbreviation dot and
+// comma after them, and ordinal days ("1er", "1.").
+var dateWordPattern = regexp.MustCompile(`\pL+\.?,?|\d+(?:er|\.)(?:\s|$)`)
+
+// localizeDate rewrites the month names of the locale in English and drops
+// weekdays, filler words and ordinal marks, so the English layouts can read
+// the date. Returns the value unchanged if the locale has no month names.
+func (l Locale) localizeDate(value string) string {
+	if len(l.Months) != 12 {
+		return value
+	}
+	localized := dateWordPattern.ReplaceAllStringFunc(value, func(word string) string {
+		if unicode.IsDigit(rune(word[0])) {
+			// "1er" and "1." are the first day
+			return strings.TrimRight(strings.TrimSpace(word), "er.") + " "
+		}
+		comma := ""
+		if strings.HasSuffix(word, ",") {
+			comma = ","
+		}
+		lower := strings.ToLower(strings.TrimRight(word, ".,"))
+		if dateFillers[lower] || matchesName(lower, l.Weekdays) >= 0 {
+			return ""
+		}
+		if month := matchesName(lower, l.Months); month >= 0 {
+			return time.Month(month+1).String() + comma
+		}
+		return word
+	})
+	return strings.Join(strings.Fields(localized), " ")
+}
+
+// matchesName returns the index of the name that word (lowercase) is, or
+// abbreviates with at least three letters, or -1.
+func matchesName(word string, names []string) int {
+	for i, name := range names {
+		name = strings.ToLower(name)
+		if word == name || (len([]rune(word)) >= 3 && strings.HasPrefix(name, word)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// numericDatePattern matches a numeric date, optionally followed by a time.
+var numericDatePattern = regexp.MustCompile(`^(\d{1,4})[./-](\d{1,2})[./-](\d{1,4})(?:[ T,]+(.+))?$`)
+
+// numericTimeLayouts are the layouts tried for the time after a numeric date.
+var numericTimeLayouts = []string{"15:04:05", "15:04", "3:04:05 PM", "3:04 PM", "3:04PM", "3PM", "3 PM"}
+
+// parseNumericDate parses a numeric date such as 13.04.2024 or 04/13/24 3:30
+// PM in the locale's DateOrder. Returns false if value is not a numeric date,
+// and an error if it is ambiguous or invalid.
+func (l Locale) parseNumericDate(value string) (time.Time, bool, error) {
+	m := numericDatePattern.FindStringSubmatch(value)
+	if m == nil {
+		return time.Time{}, false, nil
+	}
+	parts := [3]int{}
+	for i := range parts {
+		parts[i], _ = strconv.Atoi(m[i+1])
+	}
+	var year, month, day int
+	switch {
+	case len(m[1]) > 2 || l.DateOrder == DateOrderYMD:
+		year, month, day = parts[0], parts[1], parts[2]
+	case l.DateOrder == DateOrderMDY:
+		month, day, year = parts[0], parts[1], parts[2]
+	case l.DateOrder == DateOrderDMY:
+		day, month, year = parts[0], parts[1], parts[2]
+	case parts[0] > 12 && parts[1] <= 12:
+		day, month, year = parts[0], parts[1], parts[2]
+	case parts[1] > 12 && parts[0] <= 12:
+		month, day, year = parts[0], parts[1], parts[2]
+	case parts[0] == parts[1]:
+		day, month, year = parts[0], parts[1], parts[2]
+	default:
+		return time.Time{}, true, errors.New("ambiguous date '" + value + "'; set a Locale DateOrder")
+	}
+	if year < 100 {
+		year += 2000
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if month < 1 || month > 12 || date.Day() != day {
+		return time.Time{}, true, errors.New("invalid date '" + value + "'")
+	}
+	if m[4] == "" {
+		return date, true, nil
+	}
+	for _, layout := range numericTimeLayouts {
+		if t, err := time.Parse(layout, strings.ToUpper(m[4])); err == nil {
+			return date.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second), true, nil
+		}
+	}
+	return time.Time{}, true, errors.New("unrecognized time in '" + value + "'")
+}
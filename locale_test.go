@@ -0,0 +1,59 @@
+package arkaineparser
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLocale checks locale-aware numbers and dates.
+func TestLocale(t *testing.T) {
+	labels := []Label{
+		{Name: "Amount", DataType: "number"},
+		{Name: "Count", DataType: "integer"},
+		{Name: "Confidence", DataType: "confidence"},
+		{Name: "Date", DataType: "datetime"},
+	}
+	german, err := NewParser(labels, WithLocale(LocaleGerman))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, errList := german.Parse("Amount: 1.234,56\nCount: 12.000\nConfidence: 0,8\nDate: Montag, 1. März 2024")
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	if result["amount"] != 1234.56 || result["count"] != 12000 || result["confidence"] != 0.8 {
+		t.Errorf("unexpected numbers: %#v", result)
+	}
+	if date, _ := result["date"].(time.Time); !date.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date: %#v", result["date"])
+	}
+
+	dates := []struct {
+		locale   Locale
+		input    string
+		expected time.Time
+	}{
+		{LocaleGerman, "03.04.2024", time.Date(2024, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{LocaleUS, "03/04/2024 3:30 PM", time.Date(2024, 3, 4, 15, 30, 0, 0, time.UTC)},
+		{LocaleEnglish, "13/04/24", time.Date(2024, 4, 13, 0, 0, 0, 0, time.UTC)},
+		{LocaleFrench, "1er févr. 2024", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{LocaleSpanish, "5 de mayo de 2024 18:00", time.Date(2024, 5, 5, 18, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range dates {
+		parser, err := NewParser(labels, WithLocale(tt.locale))
+		if err != nil {
+			t.Fatalf("failed to create parser: %v", err)
+		}
+		result, errList := parser.Parse("Date: " + tt.input)
+		if date, _ := result["date"].(time.Time); len(errList) > 0 || !date.Equal(tt.expected) {
+			t.Errorf("unexpected date for %q: %#v %v", tt.input, result["date"], errList)
+		}
+	}
+
+	// Without a date order, ambiguous numeric dates are reported, not guessed
+	english, _ := NewParser(labels)
+	_, errList = english.Parse("Date: 03/04/2024")
+	if len(errList) != 1 || errList[0] != "Invalid datetime in 'date': ambiguous date '03/04/2024'; set a Locale DateOrder" {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+}
@@ -0,0 +1,132 @@
+package arkaineparser
+
+import (
+	"strings"
+	"sync"
+)
+
+// LoopDetector watches the sequential steps of an agent and signals when a
+// step repeats earlier ones, such as the same tool called with the same
+// arguments again, so the orchestration layer can intervene. Steps are
+// compared on a few labels only ("action" and "action input" by default),
+// against a window of the most recent steps. A LoopDetector is safe for
+// concurrent use.
+type LoopDetector struct {
+	labels     []string // Lowercase labels steps are compared on
+	window     int      // Number of previous steps compared against
+	similarity float64  // Similarity at which two steps match; 1 for exact matches only
+	repeats    int      // Matching previous steps needed to signal a loop
+
+	mu      sync.Mutex
+	history []string // Fingerprints of the most recent steps, oldest first
+}
+
+// LoopOption configures a LoopDetector.
+type LoopOption func(*LoopDetector)
+
+// WithLoopLabels sets the labels steps are compared on.
+func WithLoopLabels(labels ...string) LoopOption {
+	return func(d *LoopDetector) {
+		d.labels = make([]string, len(labels))
+		for i, label := range labels {
+			d.labels[i] = strings.ToLower(strings.TrimSpace(label))
+		}
+	}
+}
+
+// WithLoopWindow sets how many previous steps each step is compared against
+// (default 5). Values below 1 are ignored.
+func WithLoopWindow(n int) LoopOption {
+	return func(d *LoopDetector) {
+		if n >= 1 {
+			d.window = n
+		}
+	}
+}
+
+// WithLoopSimilarity makes steps match when their compared values are at
+// least similarity alike, from 0 to 1, instead of only when they are equal
+// (see WithBlockDedup for the measure). Values outside (0, 1] are ignored.
+func WithLoopSimilarity(similarity float64) LoopOption {
+	return func(d *LoopDetector) {
+		if similarity > 0 && similarity <= 1 {
+			d.similarity = similarity
+		}
+	}
+}
+
+// WithLoopRepeats sets how many previous steps in the window a step must
+// match to signal a loop (default 1). Values below 1 are ignored.
+func WithLoopRepeats(n int) LoopOption {
+	return func(d *LoopDetector) {
+		if n >= 1 {
+			d.repeats = n
+		}
+	}
+}
+
+// NewLoopDetector creates a LoopDetector with no steps observed yet.
+func NewLoopDetector(opts ...LoopOption) *LoopDetector {
+	d := &LoopDetector{labels: []string{"action", "action input"}, window: 5, similarity: 1, repeats: 1}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// LoopSignal is what a LoopDetector found about one step.
+type LoopSignal struct {
+	Looping bool `json:"looping"` // Whether the step matched enough previous steps
+	// StepsAgo lists how many steps back each matching step is, most recent first
+	StepsAgo []int `json:"steps_ago,omitempty"`
+}
+
+// Observe records the next step, as parsed, and reports whether it repeats the
+// steps before it. Steps lacking all compared labels, such as a final answer,
+// take part in the window but never match.
+func (d *LoopDetector) Observe(result Result) LoopSignal {
+	fingerprint := d.fingerprint(result)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var signal LoopSignal
+	if fingerprint != "" {
+		for i := len(d.history) - 1; i >= 0; i-- {
+			if d.history[i] != "" && similarity(d.history[i], fingerprint) >= d.similarity {
+				signal.StepsAgo = append(signal.StepsAgo, len(d.history)-i)
+			}
+		}
+		signal.Looping = len(signal.StepsAgo) >= d.repeats
+	}
+	d.history = append(d.history, fingerprint)
+	if len(d.history) > d.window {
+		d.history = d.history[len(d.history)-d.window:]
+	}
+	return signal
+}
+
+// Reset forgets every observed step, e.g. when a new task starts.
+func (d *LoopDetector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.history = nil
+}
+
+// fingerprint renders the compared values of a step as stable JSON, or ""
+// when the step has none of them.
+func (d *LoopDetector) fingerprint(result Result) string {
+	values := make(map[string]interface{}, len(d.labels))
+	for _, label := range d.labels {
+		if value, ok := result.Get(label); ok && value != "" && value != nil {
+			values[label] = value
+		}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	encoded, err := marshalStable(values)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
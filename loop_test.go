@@ -0,0 +1,51 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestLoopDetector checks that repeated steps are signalled within the window.
+func TestLoopDetector(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Action"}, {Name: "Action Input", IsJSON: true}, {Name: "Final Answer"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	step := func(text string) Result {
+		return parser.ParseDetailed(text).Result
+	}
+
+	detector := NewLoopDetector(WithLoopWindow(3))
+	steps := []struct {
+		text     string
+		expected LoopSignal
+	}{
+		{"Action: search\nAction Input: {\"q\": \"go\", \"n\": 1}", LoopSignal{}},
+		{"Action: search\nAction Input: {\"q\": \"rust\", \"n\": 1}", LoopSignal{}},
+		// Key order does not matter
+		{"Action: search\nAction Input: {\"n\": 1, \"q\": \"go\"}", LoopSignal{Looping: true, StepsAgo: []int{2}}},
+		{"Final Answer: done", LoopSignal{}},
+		{"Action: search\nAction Input: {\"q\": \"go\", \"n\": 1}", LoopSignal{Looping: true, StepsAgo: []int{2}}},
+		// The first step has left the window
+		{"Action: search\nAction Input: {\"q\": \"rust\", \"n\": 1}", LoopSignal{}},
+	}
+	for i, s := range steps {
+		if got := detector.Observe(step(s.text)); !reflect.DeepEqual(got, s.expected) {
+			t.Errorf("step %d: got %#v, expected %#v", i+1, got, s.expected)
+		}
+	}
+
+	// Fuzzy matching with several repeats required
+	fuzzy := NewLoopDetector(WithLoopSimilarity(0.8), WithLoopRepeats(2))
+	fuzzy.Observe(step("Action: search\nAction Input: {\"q\": \"golang\"}"))
+	if signal := fuzzy.Observe(step("Action: search\nAction Input: {\"q\": \"golang!\"}")); signal.Looping || len(signal.StepsAgo) != 1 {
+		t.Errorf("expected one match without a loop, got %#v", signal)
+	}
+	if signal := fuzzy.Observe(step("Action: search\nAction Input: {\"q\": \"golang?\"}")); !signal.Looping {
+		t.Errorf("expected a loop, got %#v", signal)
+	}
+	fuzzy.Reset()
+	if signal := fuzzy.Observe(step("Action: search\nAction Input: {\"q\": \"golang\"}")); len(signal.StepsAgo) != 0 {
+		t.Errorf("expected no matches after Reset, got %#v", signal)
+	}
+}
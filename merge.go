@@ -0,0 +1,87 @@
+package arkaineparser
+
+// MergePolicy chooses how MergeResults combines a patch into a base result.
+type MergePolicy string
+
+const (
+	// MergeFillMissing only fills labels that are missing or empty in base,
+	// recursing into JSON objects to fill their missing keys (the default).
+	MergeFillMissing MergePolicy = "fill-missing"
+	// MergePreferPatch replaces base values with every non-empty patch value.
+	MergePreferPatch MergePolicy = "prefer-patch"
+	// MergeAppend keeps base values and appends non-empty patch values, turning
+	// labels present in both into slices of occurrences.
+	MergeAppend MergePolicy = "append"
+)
+
+// MergeResults merges a follow-up parse (e.g. the answer to "please provide the
+// missing fields") into an earlier partial parse according to policy. Empty
+// patch values ("", nil, or an empty object) never overwrite anything. Neither
+// input is modified; the returned Result shares no maps or slices with them.
+func MergeResults(base, patch Result, policy MergePolicy) Result {
+	merged := make(Result, len(base))
+	for key, value := range base {
+		merged[key] = copyValue(value)
+	}
+	for key, value := range patch {
+		if isEmptyValue(value) {
+			if _, ok := merged[key]; !ok {
+				merged[key] = copyValue(value)
+			}
+			continue
+		}
+		existing, ok := merged[key]
+		switch {
+		case !ok || isEmptyValue(existing):
+			merged[key] = copyValue(value)
+		case policy == MergePreferPatch:
+			merged[key] = copyValue(value)
+		case policy == MergeAppend:
+			merged[key] = append(occurrencesOf(existing), occurrencesOf(copyValue(value))...)
+		default:
+			merged[key] = fillMissing(existing, value)
+		}
+	}
+	return merged
+}
+
+// fillMissing fills the missing or empty keys of a base JSON object from
+// patch, recursively. Values that are not both objects keep the base value.
+func fillMissing(base, patch interface{}) interface{} {
+	baseMap, ok := base.(map[string]interface{})
+	patchMap, ok2 := patch.(map[string]interface{})
+	if !ok || !ok2 {
+		return base
+	}
+	for key, value := range patchMap {
+		if existing, ok := baseMap[key]; ok && !isEmptyValue(existing) {
+			baseMap[key] = fillMissing(existing, value)
+		} else {
+			baseMap[key] = copyValue(value)
+		}
+	}
+	return baseMap
+}
+
+// occurrencesOf returns a result value as a list of occurrences.
+func occurrencesOf(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{value}
+}
+
+// isEmptyValue reports whether a result value carries no information.
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case map[string]interface{}:
+		return len(v) == 0
+	case []byte:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMergeResults checks each merge policy and that inputs are left untouched.
+func TestMergeResults(t *testing.T) {
+	base := Result{
+		"answer":    "42",
+		"reason":    "",
+		"arguments": map[string]interface{}{"q": "go", "page": ""},
+	}
+	patch := Result{
+		"answer":    "43",
+		"reason":    "computed it",
+		"arguments": map[string]interface{}{"page": 2.0, "limit": 10.0},
+		"sources":   "",
+	}
+
+	tests := []struct {
+		policy   MergePolicy
+		expected Result
+	}{
+		{MergeFillMissing, Result{
+			"answer":    "42",
+			"reason":    "computed it",
+			"arguments": map[string]interface{}{"q": "go", "page": 2.0, "limit": 10.0},
+			"sources":   "",
+		}},
+		{MergePreferPatch, Result{
+			"answer":    "43",
+			"reason":    "computed it",
+			"arguments": map[string]interface{}{"page": 2.0, "limit": 10.0},
+			"sources":   "",
+		}},
+		{MergeAppend, Result{
+			"answer": []interface{}{"42", "43"},
+			"reason": "computed it",
+			"arguments": []interface{}{
+				map[string]interface{}{"q": "go", "page": ""},
+				map[string]interface{}{"page": 2.0, "limit": 10.0},
+			},
+			"sources": "",
+		}},
+	}
+	for _, tt := range tests {
+		merged := MergeResults(base, patch, tt.policy)
+		if !reflect.DeepEqual(merged, tt.expected) {
+			t.Errorf("%s: mismatch.\nGot: %#v\nExpected: %#v", tt.policy, merged, tt.expected)
+		}
+	}
+	if base["arguments"].(map[string]interface{})["page"] != "" {
+		t.Errorf("base was modified: %#v", base)
+	}
+}
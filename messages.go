@@ -0,0 +1,103 @@
+package arkaineparser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MessageTemplates rephrase the messages of ParseErrors, keyed by ErrorKind,
+// e.g. to word correction prompts the way a model responds best to. A
+// template's placeholders are replaced with the error's values:
+//   - {label}: the label the error concerns
+//   - {occurrence}: the 1-based occurrence of a repeated label, or ""
+//   - {subject}: "'label'", or "'label' occurrence N" for a repeated label
+//   - {message}: the default message
+//   - each of the error's Params, by key
+//
+// The Params of each kind are:
+//   - KindDependency: {dependency}
+//   - KindJSON: {error}, and {reason} "empty" for an empty value
+//   - KindType: {type}, {error}
+//   - KindRange: {reason} ("number", "min" or "max"), {problem}, and for
+//     "min" and "max" {bound} and {value}
+//   - KindPattern: {pattern}, {value}
+//   - KindChoice: {choices}, {value}
+//   - KindFrontMatter: {line}, {problem}
+//   - KindMiddleware: {error}
+//   - KindSystemLabel: {count}
+//   - KindUngrounded: {sentence}, {score}
+//   - KindDuplicateBlock: {block}, {repeats}
+//   - KindAmbiguousLabel: {text} (the line), {others}
+//
+// A template keyed by "kind:reason", such as "range:min" or "json:empty",
+// takes precedence over the kind's template for errors with that reason, so
+// each variant can be phrased in full. The SubjectTemplate and
+// OccurrenceSubjectTemplate keys rephrase {subject} itself. Unknown
+// placeholders are left as they are. Kinds without a template keep their
+// default message.
+type MessageTemplates map[ErrorKind]string
+
+// Template keys of MessageTemplates that are not error kinds.
+const (
+	SubjectTemplate           ErrorKind = "subject"            // Renders {subject} for a single value
+	OccurrenceSubjectTemplate ErrorKind = "subject:occurrence" // Renders {subject} for an occurrence of a repeated label
+)
+
+// renderMessages rewrites the messages of errList with the parser's templates.
+func (p *Parser) renderMessages(errList []ParseError) {
+	if len(p.messageTemplates) == 0 {
+		return
+	}
+	for i, err := range errList {
+		if template, ok := p.messageTemplates.lookup(err); ok {
+			errList[i].Message = p.messageTemplates.render(template, err)
+		}
+	}
+}
+
+// renderDetails rewrites the messages of the errors and warnings of details.
+func (p *Parser) renderDetails(details *Details) {
+	p.renderMessages(details.Errors)
+	p.renderMessages(details.Warnings)
+}
+
+// lookup returns the template for err: that of its kind and reason, else that
+// of its kind.
+func (t MessageTemplates) lookup(err ParseError) (string, bool) {
+	if reason := err.Params["reason"]; reason != "" {
+		if template, ok := t[ErrorKind(string(err.Kind)+":"+reason)]; ok {
+			return template, true
+		}
+	}
+	template, ok := t[err.Kind]
+	return template, ok
+}
+
+// render fills the placeholders of template with the values of err.
+func (t MessageTemplates) render(template string, err ParseError) string {
+	subject := "'" + err.Label + "'"
+	occurrence := ""
+	if err.Occurrence > 0 {
+		occurrence = strconv.Itoa(err.Occurrence)
+		subject = fmt.Sprintf("'%s' occurrence %d", err.Label, err.Occurrence)
+	}
+	// The subject has placeholders of its own
+	key := SubjectTemplate
+	if err.Occurrence > 0 {
+		key = OccurrenceSubjectTemplate
+	}
+	if subjectTemplate, ok := t[key]; ok {
+		subject = strings.NewReplacer("{label}", err.Label, "{occurrence}", occurrence).Replace(subjectTemplate)
+	}
+	pairs := []string{
+		"{label}", err.Label,
+		"{occurrence}", occurrence,
+		"{subject}", subject,
+		"{message}", err.Message,
+	}
+	for key, value := range err.Params {
+		pairs = append(pairs, "{"+key+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
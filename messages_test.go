@@ -0,0 +1,68 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMessageTemplates checks that templates rephrase errors and warnings of
+// their kind and leave other kinds alone.
+func TestMessageTemplates(t *testing.T) {
+	minimum := 1.0
+	labels := []Label{
+		{Name: "Action", Required: true},
+		{Name: "Action Input", RequiredWith: []string{"Action"}},
+		{Name: "Score", DataType: "integer", Min: &minimum},
+		{Name: "Observation", Source: SourceSystem},
+		{Name: "Mode", Choices: []string{"fast", "slow"}},
+	}
+	parser, err := NewParser(labels,
+		WithMessageTemplates(MessageTemplates{
+			KindRequired:   "Please add the {label} field.",
+			KindDependency: "{subject} only makes sense together with '{dependency}'.",
+		}),
+		WithMessageTemplates(MessageTemplates{
+			KindRange:       "{label}: {value} is below {bound} ({reason}); {unknown}",
+			KindSystemLabel: "Do not write {label} ({count}x).",
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	details := parser.ParseDetailed("Action Input: {}\nAction Input: []\nScore: 0\nObservation: made up\nMode: medium")
+	// Required and dependency errors are reported last
+	expected := []string{
+		"score: 0 is below 1 (min); {unknown}",
+		"'mode' must be one of 'fast', 'slow', got 'medium'",
+		"Please add the action field.",
+		"'action input' occurrence 1 only makes sense together with 'Action'.",
+		"'action input' occurrence 2 only makes sense together with 'Action'.",
+	}
+	var messages []string
+	for _, parseErr := range details.Errors {
+		messages = append(messages, parseErr.Message)
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("unexpected messages: %#v", messages)
+	}
+	if len(details.Warnings) != 1 || details.Warnings[0].Message != "Do not write observation (1x)." {
+		t.Errorf("unexpected warnings: %#v", details.Warnings)
+	}
+
+	// Validate reports the same messages
+	validation := parser.Validate("Action Input: x")
+	if len(validation) != 2 || validation[0].Message != "Please add the action field." {
+		t.Errorf("unexpected validation errors: %#v", validation)
+	}
+}
+
+// TestMessageTemplatesBlocks checks that block parses are rephrased too.
+func TestMessageTemplatesBlocks(t *testing.T) {
+	labels := []Label{{Name: "Task", IsBlockStart: true}, {Name: "Owner", Required: true}}
+	parser, _ := NewParser(labels, WithMessageTemplates(MessageTemplates{KindRequired: "{label} is missing ({message})"}))
+	_, errList := parser.ParseBlocks("Task: a\nTask: b\nOwner: me")
+	expected := []string{"owner is missing ('owner' is required)"}
+	if !reflect.DeepEqual(errList, expected) {
+		t.Errorf("unexpected errors: %#v", errList)
+	}
+}
@@ -0,0 +1,38 @@
+package arkaineparser
+
+// Next hands a value on to the rest of a middleware chain and returns what the
+// chain made of it.
+type Next func(value interface{}) (interface{}, error)
+
+// Middleware transforms the parsed value of label, set with WithMiddleware.
+// It calls next to run the middleware after it, and may change the value
+// before and after doing so, or return without calling next to cut the chain
+// short.
+type Middleware func(label string, value interface{}, next Next) (interface{}, error)
+
+// applyMiddleware runs the parser's middleware chain on a value of label,
+// recording an error in details and returning the value unchanged if the
+// chain fails.
+func (p *Parser) applyMiddleware(label string, value interface{}, occurrence int, details *Details) interface{} {
+	if len(p.middleware) == 0 {
+		return value
+	}
+	result, err := p.chain(label, 0)(value)
+	if err != nil {
+		details.Errors = append(details.Errors, newMiddlewareError(label, err, occurrence))
+		return value
+	}
+	return result
+}
+
+// chain returns the Next that runs the middleware from index on.
+func (p *Parser) chain(label string, index int) Next {
+	if index == len(p.middleware) {
+		return func(value interface{}) (interface{}, error) {
+			return value, nil
+		}
+	}
+	return func(value interface{}) (interface{}, error) {
+		return p.middleware[index](label, value, p.chain(label, index+1))
+	}
+}
@@ -0,0 +1,75 @@
+package arkaineparser
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMiddleware checks that middleware run in order on every parsed value.
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(label string, value interface{}, next Next) (interface{}, error) {
+			calls = append(calls, name+":"+label)
+			return next(value)
+		}
+	}
+	upper := func(label string, value interface{}, next Next) (interface{}, error) {
+		if text, ok := value.(string); ok {
+			value = strings.ToUpper(text)
+		}
+		return next(value)
+	}
+	exclaim := func(label string, value interface{}, next Next) (interface{}, error) {
+		value, err := next(value)
+		if text, ok := value.(string); ok {
+			value = text + "!"
+		}
+		return value, err
+	}
+	labels := []Label{{Name: "Thought"}, {Name: "Count", DataType: "integer"}, {Name: "Note"}}
+	parser, err := NewParser(labels, WithMiddleware(trace("first"), upper), WithMiddleware(exclaim, trace("last")))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, errList := parser.Parse("Thought: go on\nCount: 3\nNote:")
+	if len(errList) != 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	if result["thought"] != "GO ON!" || result["count"] != 3 || result["note"] != "" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	// Empty values skip the chain
+	expected := []string{"first:thought", "last:thought", "first:count", "last:count"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("unexpected calls: %v", calls)
+	}
+}
+
+// TestMiddlewareError checks that a failing chain reports an error and keeps
+// the value it was given.
+func TestMiddlewareError(t *testing.T) {
+	reject := func(label string, value interface{}, next Next) (interface{}, error) {
+		if value == "secret" {
+			return nil, errors.New("value is not allowed")
+		}
+		return next(value)
+	}
+	parser, _ := NewParser([]Label{{Name: "Action"}}, WithMiddleware(reject))
+	details := parser.ParseDetailed("Action: search\nAction: secret")
+	expected := []ParseError{{
+		Kind:       KindMiddleware,
+		Label:      "action",
+		Message:    "Middleware error in 'action' occurrence 2: value is not allowed",
+		Occurrence: 2,
+		Params:     map[string]string{"error": "value is not allowed"},
+	}}
+	if !reflect.DeepEqual(details.Errors, expected) {
+		t.Errorf("unexpected errors: %#v", details.Errors)
+	}
+	if !reflect.DeepEqual(details.Result["action"], []interface{}{"search", "secret"}) {
+		t.Errorf("unexpected result: %#v", details.Result["action"])
+	}
+}
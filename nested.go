@@ -0,0 +1,108 @@
+package arkaineparser
+
+import (
+	"errors"
+	"strings"
+)
+
+// isLabelGroup reports whether name is the dotted group of at least one label,
+// as "action" is for "Action.Name".
+func isLabelGroup(name string, labels []Label) bool {
+	for _, label := range labels {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(label.Name)), name+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNestedKeys rejects dotted label names that cannot nest: with an empty
+// segment, or nesting under another label's value.
+func checkNestedKeys(labels []Label) error {
+	var errList []error
+	defined := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		defined[label.Name] = true
+	}
+	for _, label := range labels {
+		segments := strings.Split(label.Name, ".")
+		for i, segment := range segments {
+			if strings.TrimSpace(segment) == "" {
+				errList = append(errList, errors.New("Label '"+label.Name+"' has an empty key segment"))
+				break
+			}
+			if prefix := strings.Join(segments[:i], "."); i > 0 && defined[prefix] {
+				errList = append(errList, errors.New("Label '"+label.Name+"' nests under label '"+prefix+"'"))
+				break
+			}
+		}
+	}
+	return errors.Join(errList...)
+}
+
+// nestResult moves the values of dotted label names into nested maps.
+func nestResult(result Result) Result {
+	nested := make(Result, len(result))
+	for key, value := range result {
+		segments := strings.Split(key, ".")
+		target := map[string]interface{}(nested)
+		for _, segment := range segments[:len(segments)-1] {
+			child, ok := target[segment].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				target[segment] = child
+			}
+			target = child
+		}
+		target[segments[len(segments)-1]] = value
+	}
+	return nested
+}
+
+// flattenValues turns the nested maps of dotted label groups back into
+// dotted keys, so values shaped like a nested result can be formatted. Keys
+// naming a label are kept as they are.
+func (p *Parser) flattenValues(values map[string]interface{}) map[string]interface{} {
+	if !p.nestedKeys {
+		return values
+	}
+	flat := make(map[string]interface{}, len(values))
+	var walk func(prefix string, values map[string]interface{})
+	walk = func(prefix string, values map[string]interface{}) {
+		for key, value := range values {
+			key = prefix + key
+			group, isMap := value.(map[string]interface{})
+			if _, isLabel := p.names[strings.ToLower(strings.TrimSpace(key))]; !isLabel && isMap {
+				walk(key+".", group)
+				continue
+			}
+			flat[key] = value
+		}
+	}
+	walk("", values)
+	return flat
+}
+
+// dependencyEntries returns the entries a RequiredWith dependency is checked
+// against: the label's own entries, or for a dotted group the first non-empty
+// entry of its nested labels at each occurrence.
+func (p *Parser) dependencyEntries(dep string, data map[string][]string) []string {
+	if canonical, ok := p.names[dep]; ok {
+		return data[canonical]
+	}
+	var entries []string
+	for _, label := range p.labels {
+		if !strings.HasPrefix(label.Name, dep+".") {
+			continue
+		}
+		for i, entry := range data[label.Name] {
+			if i == len(entries) {
+				entries = append(entries, "")
+			}
+			if entries[i] == "" {
+				entries[i] = entry
+			}
+		}
+	}
+	return entries
+}
@@ -0,0 +1,87 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestNestedKeys checks that dotted label names nest in the result and in
+// dependency checks, and that nested values format back to text.
+func TestNestedKeys(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought"},
+		{Name: "Action.Name", RequiredWith: []string{"Action.Args"}},
+		{Name: "Action.Args", IsJSON: true},
+		{Name: "Final Answer", RequiredWith: []string{"Action"}},
+	}, WithNestedKeys())
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	text := "Thought: search\nAction.Name: web_search\nAction.Args: {\"q\": \"go\"}\nFinal Answer: done"
+	result, errList := parser.Parse(text)
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	action, ok := result["action"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a nested action map, got %#v", result["action"])
+	}
+	if !reflect.DeepEqual(action["name"], "web_search") {
+		t.Errorf("unexpected action name: %#v", action["name"])
+	}
+	if !reflect.DeepEqual(action["args"], map[string]interface{}{"q": "go"}) {
+		t.Errorf("unexpected action args: %#v", action["args"])
+	}
+	if _, flat := result["action.name"]; flat {
+		t.Error("dotted key should not remain in the result")
+	}
+
+	// Dependencies resolve against nested paths and whole groups
+	_, errList = parser.Parse("Action.Name: web_search")
+	if len(errList) != 1 || !strings.Contains(errList[0], "requires 'Action.Args'") {
+		t.Errorf("expected a missing action.args dependency, got %v", errList)
+	}
+	_, errList = parser.Parse("Final Answer: done")
+	if len(errList) != 1 || !strings.Contains(errList[0], "requires 'Action'") {
+		t.Errorf("expected a missing action dependency, got %v", errList)
+	}
+
+	// Nested values format back into dotted labels
+	formatted, err := parser.Format(result)
+	if err != nil {
+		t.Fatalf("failed to format: %v", err)
+	}
+	again, errList := parser.Parse(formatted)
+	if len(errList) > 0 || !reflect.DeepEqual(again, result) {
+		t.Errorf("round trip changed the result: %#v, %v", again, errList)
+	}
+
+	// Without the option dotted names stay flat
+	flat, err := NewParser([]Label{{Name: "Action.Name"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, _ = flat.Parse("Action.Name: x")
+	if result["action.name"] != "x" {
+		t.Errorf("expected a flat dotted key, got %#v", result)
+	}
+}
+
+// TestNestedKeysInvalid checks the label sets WithNestedKeys rejects.
+func TestNestedKeysInvalid(t *testing.T) {
+	tests := []struct {
+		labels   []Label
+		expected string
+	}{
+		{[]Label{{Name: "Action"}, {Name: "Action.Name"}}, "Label 'action.name' nests under label 'action'"},
+		{[]Label{{Name: "Action..Name"}}, "Label 'action..name' has an empty key segment"},
+		{[]Label{{Name: "Action.Name", RequiredWith: []string{"Tool"}}}, "requires undefined label 'Tool'"},
+	}
+	for _, test := range tests {
+		_, err := NewParser(test.labels, WithNestedKeys())
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected error containing %q, got %v", test.expected, err)
+		}
+	}
+}
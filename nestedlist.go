@@ -0,0 +1,96 @@
+package arkaineparser
+
+import "strings"
+
+// ListItem is an item of a "nested-list" value, with the items indented
+// under it as its children.
+type ListItem struct {
+	Text     string     `json:"text"`
+	Children []ListItem `json:"children,omitempty"`
+}
+
+// listNode is a ListItem under construction.
+type listNode struct {
+	indent   int // Column the item starts at, or -1 for the root
+	text     string
+	children []*listNode
+}
+
+// items converts the children of n into ListItems.
+func (n *listNode) items() []ListItem {
+	items := make([]ListItem, len(n.children))
+	for i, child := range n.children {
+		items[i] = ListItem{Text: child.text}
+		if len(child.children) > 0 {
+			items[i].Children = child.items()
+		}
+	}
+	return items
+}
+
+// parseNestedListValue reads an outline into a tree of ListItems: each line is
+// an item, nested under the closest less indented item before it, with
+// bullets and numbers dropped. When the value uses list markers, lines without
+// one continue the text of the item before them. A single line is split at
+// commas or semicolons like a "list" value.
+func parseNestedListValue(p *Parser, label Label, value string) (interface{}, error) {
+	if !strings.Contains(value, "\n") {
+		flat, _ := parseListValue(p, label, value)
+		items := []ListItem{}
+		for _, text := range flat.([]string) {
+			items = append(items, ListItem{Text: text})
+		}
+		return items, nil
+	}
+	lines := strings.Split(value, "\n")
+	marked := false
+	for _, line := range lines {
+		if listMarkerPattern.MatchString(line) {
+			marked = true
+			break
+		}
+	}
+
+	root := &listNode{indent: -1}
+	stack := []*listNode{root}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		top := stack[len(stack)-1]
+		if marked && !listMarkerPattern.MatchString(line) && top != root {
+			// A wrapped line of the previous item
+			top.text += " " + strings.TrimSpace(line)
+			continue
+		}
+		node := &listNode{
+			indent: indentWidth(line),
+			text:   strings.TrimSpace(listMarkerPattern.ReplaceAllString(line, "")),
+		}
+		// Close the items indented as deep or deeper than this one
+		for len(stack) > 1 && stack[len(stack)-1].indent >= node.indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+		stack = append(stack, node)
+	}
+	return root.items(), nil
+}
+
+// indentWidth returns the column the text of line starts at, counting a tab
+// as four columns.
+func indentWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
@@ -0,0 +1,58 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestNestedListValues checks that "nested-list" values keep their hierarchy.
+func TestNestedListValues(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Plan", DataType: "nested-list"}, {Name: "Thought"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	tests := []struct {
+		text     string
+		expected []ListItem
+	}{
+		{
+			"Plan:\n1. Research\n   - read the docs\n   - find examples\n     * in tests\n2. Build\n- Ship\nThought: ok",
+			[]ListItem{
+				{Text: "Research", Children: []ListItem{
+					{Text: "read the docs"},
+					{Text: "find examples", Children: []ListItem{{Text: "in tests"}}},
+				}},
+				{Text: "Build"},
+				{Text: "Ship"},
+			},
+		},
+		{
+			// Unmarked lines wrap the item before them
+			"Plan:\n- Write the parser,\n  carefully\n\t- then test it",
+			[]ListItem{{Text: "Write the parser, carefully", Children: []ListItem{{Text: "then test it"}}}},
+		},
+		{
+			// Outlines without markers nest by indentation alone
+			"Plan:\nSetup\n  install\n  configure\nRun",
+			[]ListItem{{Text: "Setup", Children: []ListItem{{Text: "install"}, {Text: "configure"}}}, {Text: "Run"}},
+		},
+		{"Plan: a, b", []ListItem{{Text: "a"}, {Text: "b"}}},
+	}
+	for _, test := range tests {
+		result, errList := parser.Parse(test.text)
+		if len(errList) > 0 {
+			t.Errorf("unexpected errors for %q: %v", test.text, errList)
+		}
+		if !reflect.DeepEqual(result["plan"], test.expected) {
+			t.Errorf("for %q got %#v", test.text, result["plan"])
+		}
+	}
+
+	// The example value of the prompt template parses back into a tree
+	example := parser.exampleValue(parser.labelMap["plan"])
+	result, _ := parser.Parse("Plan: " + example)
+	expected := []ListItem{{Text: "first", Children: []ListItem{{Text: "detail"}}}, {Text: "second"}}
+	if !reflect.DeepEqual(result["plan"], expected) {
+		t.Errorf("example value %q parsed as %#v", example, result["plan"])
+	}
+}
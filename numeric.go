@@ -0,0 +1,93 @@
+package arkaineparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AnswerStrategy is a way of locating the final answer in chain-of-thought text.
+type AnswerStrategy string
+
+const (
+	// StrategyBoxed takes the last \boxed{...} answer, as in MATH-style outputs
+	StrategyBoxed AnswerStrategy = "boxed"
+	// StrategyAnswerLine takes the number after the last "Answer:", "The answer
+	// is" or GSM8K-style "####"
+	StrategyAnswerLine AnswerStrategy = "answer-line"
+	// StrategyLastNumber takes the last number in the text
+	StrategyLastNumber AnswerStrategy = "last-number"
+)
+
+// DefaultAnswerStrategies are the strategies ExtractNumericAnswer tries when
+// none are given, from the most to the least explicit.
+var DefaultAnswerStrategies = []AnswerStrategy{StrategyBoxed, StrategyAnswerLine, StrategyLastNumber}
+
+// Patterns used to extract numeric answers
+var (
+	boxedPattern      = regexp.MustCompile(`\\boxed\s*\{((?:[^{}]|\{[^{}]*\})*)\}`)
+	answerLinePattern = regexp.MustCompile(`(?i)(?:\banswer\b\s*(?:is|=|:)?|####)\s*:?\s*([^\n]*)`)
+	fracPattern       = regexp.MustCompile(`\\d?frac\s*\{\s*(-?[\d.]+)\s*\}\s*\{\s*(-?[\d.]+)\s*\}`)
+	// A number with optional sign, currency, thousands separators, decimals,
+	// exponent, or fraction
+	answerNumberPattern = regexp.MustCompile(`-?\$?\d[\d,]*(?:\.\d+)?(?:[eE][-+]?\d+)?(?:\s*/\s*\d+(?:\.\d+)?)?|-?\$?\.\d+`)
+)
+
+// ExtractNumericAnswer extracts the final numeric answer from chain-of-thought
+// text, trying each strategy in order (DefaultAnswerStrategies if none are
+// given) and returning the first number found. Thousands separators, dollar
+// signs and LaTeX fractions are handled: "$1,200" is 1200 and
+// "\frac{3}{4}" or "3/4" is 0.75. Returns false if no strategy finds a number.
+func ExtractNumericAnswer(text string, strategies ...AnswerStrategy) (float64, bool) {
+	if len(strategies) == 0 {
+		strategies = DefaultAnswerStrategies
+	}
+	for _, strategy := range strategies {
+		var candidate string
+		switch strategy {
+		case StrategyBoxed:
+			if matches := boxedPattern.FindAllStringSubmatch(text, -1); len(matches) > 0 {
+				candidate = matches[len(matches)-1][1]
+			}
+		case StrategyAnswerLine:
+			if matches := answerLinePattern.FindAllStringSubmatch(text, -1); len(matches) > 0 {
+				candidate = matches[len(matches)-1][1]
+			}
+		case StrategyLastNumber:
+			candidate = text
+		}
+		if candidate == "" {
+			continue
+		}
+		candidate = fracPattern.ReplaceAllString(candidate, "$1/$2")
+		numbers := answerNumberPattern.FindAllString(candidate, -1)
+		if len(numbers) == 0 {
+			continue
+		}
+		// The answer line and boxed strategies take their first number, since
+		// units or explanations may follow it
+		number := numbers[0]
+		if strategy == StrategyLastNumber {
+			number = numbers[len(numbers)-1]
+		}
+		if value, ok := parseAnswerNumber(number); ok {
+			return value, true
+		}
+	}
+	return 0, false
+}
+
+// parseAnswerNumber parses a number found by answerNumberPattern.
+func parseAnswerNumber(number string) (float64, bool) {
+	number = strings.NewReplacer("$", "", ",", "", " ", "").Replace(number)
+	if numerator, denominator, ok := strings.Cut(number, "/"); ok {
+		n, err1 := strconv.ParseFloat(numerator, 64)
+		d, err2 := strconv.ParseFloat(denominator, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		return n / d, true
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	return value, err == nil
+}
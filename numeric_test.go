@@ -0,0 +1,32 @@
+package arkaineparser
+
+import "testing"
+
+// TestExtractNumericAnswer checks each answer extraction strategy.
+func TestExtractNumericAnswer(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		strategies []AnswerStrategy
+		expected   float64
+		ok         bool
+	}{
+		{"boxed", "So 3 + 4 = 7, then doubled is \\boxed{14}. Check: 14 - 7 = 7.", nil, 14, true},
+		{"boxed fraction", "The probability is $\\boxed{\\frac{3}{4}}$", nil, 0.75, true},
+		{"answer line", "She has 12 apples and buys 30 more.\nAnswer: $1,200 in total, not 12", nil, 1200, true},
+		{"gsm8k", "48/2 = 24 clips in May.\n48+24 = 72\n#### 72", nil, 72, true},
+		{"the answer is", "Thus the answer is -3.5 degrees.", nil, -3.5, true},
+		{"last number", "First 5, then 6, finally 7.", nil, 7, true},
+		{"strategy order", "Answer: 3\n\\boxed{4}", []AnswerStrategy{StrategyAnswerLine, StrategyBoxed}, 3, true},
+		{"strategy not found", "It is 42.", []AnswerStrategy{StrategyBoxed}, 0, false},
+		{"no number", "I don't know.", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := ExtractNumericAnswer(tt.text, tt.strategies...)
+			if ok != tt.ok || value != tt.expected {
+				t.Errorf("got %v %v, expected %v %v", value, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ObservationOption configures NewObservation.
+type ObservationOption func(*observationConfig)
+
+// observationConfig holds the settings of NewObservation.
+type observationConfig struct {
+	limit int // Maximum bytes of the text; 0 for no limit
+}
+
+// WithObservationLimit truncates the text of an observation to at most n
+// bytes (default 4000), marking what was cut, so a large tool result cannot
+// flood the prompt. 0 means no limit; negative values are ignored.
+func WithObservationLimit(n int) ObservationOption {
+	return func(c *observationConfig) {
+		if n >= 0 {
+			c.limit = n
+		}
+	}
+}
+
+// NewObservation turns an arbitrary tool result into an Observation:
+//   - an error becomes an error observation, "Error: " followed by its message;
+//   - a string, []byte or fmt.Stringer is used as its text;
+//   - an Observation is kept as it is;
+//   - any other value is pretty-printed as JSON, indented by two spaces.
+//
+// The text is then truncated to the observation limit.
+func NewObservation(value interface{}, opts ...ObservationOption) Observation {
+	config := observationConfig{limit: 4000}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	var observation Observation
+	switch v := value.(type) {
+	case nil:
+	case Observation:
+		observation = v
+	case error:
+		observation = Observation{Text: "Error: " + v.Error(), IsError: true}
+	case string:
+		observation.Text = v
+	case []byte:
+		observation.Text = string(v)
+	case fmt.Stringer:
+		observation.Text = v.String()
+	default:
+		encoded, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			observation.Text = fmt.Sprintf("%v", v)
+		} else {
+			observation.Text = string(encoded)
+		}
+	}
+	observation.Text = truncateText(observation.Text, config.limit)
+	return observation
+}
+
+// truncateText cuts text to at most limit bytes, at a line break in the last
+// half of the kept text when there is one and never inside a character, and
+// notes how many bytes were cut. The note counts towards the limit.
+func truncateText(text string, limit int) string {
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+	note := func(cut int) string {
+		return "\n[truncated " + strconv.Itoa(len(text)-cut) + " bytes]"
+	}
+	cut := max(limit-len(note(0)), 0)
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if line := strings.LastIndexByte(text[:cut], '\n'); line >= cut/2 && line > 0 {
+		cut = line
+	}
+	return text[:cut] + note(cut)
+}
+
+// FormatObservation renders an observation as the value of label, so that
+// Parse reads its text back unchanged apart from trailing whitespace. Text
+// on a single line is written after the label; text spanning lines, or that
+// cleaning would alter such as inline code, is written as a heredoc value
+// (Observation: <<EOF), whose lines are never matched as labels. Returns an
+// error wrapping ErrUndefinedLabel if label is not defined.
+func (p *Parser) FormatObservation(label string, observation Observation) (string, error) {
+	canonical, ok := p.names[strings.ToLower(strings.TrimSpace(label))]
+	if !ok {
+		return "", wrapError(ErrUndefinedLabel, "Label '"+label+"' is not defined")
+	}
+	name := p.display[canonical]
+	text := strings.TrimSpace(observation.Text)
+	if text == "" {
+		return name + ":", nil
+	}
+	if !strings.ContainsAny(text, "\n\r`\x00") && !strings.HasPrefix(text, "<<") && openingQuote(text) == "" {
+		return name + ": " + text, nil
+	}
+
+	// Pick a delimiter no line of the text could be mistaken for
+	lines := strings.Split(strings.ReplaceAll(text, "\x00", ""), "\n")
+	delimiter := "EOF"
+	for n := 1; ; n++ {
+		taken := false
+		for _, line := range lines {
+			if strings.TrimSpace(line) == delimiter {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			break
+		}
+		delimiter = "EOF" + strconv.Itoa(n)
+	}
+	return name + ": <<" + delimiter + "\n" + strings.Join(lines, "\n") + "\n" + delimiter, nil
+}
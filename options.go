@@ -0,0 +1,338 @@
+package arkaineparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Option configures optional Parser behavior in NewParser.
+type Option func(*Parser)
+
+// WithFallbackLabel makes Parse return the whole cleaned text as the value of
+// the named label when no label at all is found, for models that sometimes
+// answer directly instead of following the format. The label (or one of its
+// aliases) must be defined; NewParser returns an error otherwise.
+func WithFallbackLabel(name string) Option {
+	return func(p *Parser) {
+		p.fallbackLabel = strings.ToLower(strings.TrimSpace(name))
+	}
+}
+
+// WithCleaningMode sets how code fences outside label values are cleaned:
+// CleanStrip (the default) or CleanPlaceholders. Fences holding a label's
+// value or routed by FenceLanguages are handled the same way in both modes.
+func WithCleaningMode(mode CleaningMode) Option {
+	return func(p *Parser) {
+		p.cleaningMode = mode
+	}
+}
+
+// WithCleaningReport makes ParseDetailed fill in Details.Cleaning with the
+// cleaned text labels were matched in and every code fence and inline code
+// span cleaning removed, with its position in the input, so callers can audit
+// the cleaning or reconstruct the original formatting.
+func WithCleaningReport() Option {
+	return func(p *Parser) {
+		p.cleaningReport = true
+	}
+}
+
+// WithNestedKeys nests the values of dotted label names in the result:
+// "Action.Name" and "Action.Args" become result["action"]["name"] and
+// result["action"]["args"]. Format and ReAskPrompt accept such nested values
+// too. NewParser returns an error if a dotted name nests under another label,
+// such as "Action.Name" under "Action", or has an empty segment.
+func WithNestedKeys() Option {
+	return func(p *Parser) {
+		p.nestedKeys = true
+	}
+}
+
+// WithDeclaredKeys keys the result by label and group names as declared,
+// e.g. result["Action Input"] rather than result["action input"], for JSON
+// consumers expecting the casing of the schema. Keys of other outputs, such
+// as Details.Occurrences, stay lowercase; Parser.Keys maps between the two.
+func WithDeclaredKeys() Option {
+	return func(p *Parser) {
+		p.declaredKeys = true
+	}
+}
+
+// WithSystemLabels marks labels the model must never produce, such as a ReAct
+// "Observation" that only the tool runtime may write, like setting their Source
+// to SourceSystem. Values of system labels found in model output are stripped
+// from the result and reported in Details.Warnings. The labels must be defined;
+// NewParser returns an error otherwise.
+func WithSystemLabels(names ...string) Option {
+	return func(p *Parser) {
+		for _, name := range names {
+			p.systemLabels = append(p.systemLabels, strings.ToLower(strings.TrimSpace(name)))
+		}
+	}
+}
+
+// WithDedentedValues strips the prefix a value is formatted with from each of
+// its lines: a markdown blockquote marker ("> ") when every non-blank line of
+// the value is quoted, and the indentation its continuation lines share. Lines
+// taken verbatim, such as fenced or heredoc values, are left alone.
+func WithDedentedValues() Option {
+	return func(p *Parser) {
+		p.dedentValues = true
+	}
+}
+
+// WithBoilerplateStripping removes common chat boilerplate before labels are
+// matched: preambles such as "Sure, here is the analysis:", "Certainly!" or an
+// apology at the start of the output, up to the first label, and sign-offs
+// such as "Let me know if you need anything else!" on its last lines. Each
+// pattern of extra is also tried, at the start of the output and at the start
+// of each trailing line. Every removal is reported as a RepairBoilerplate
+// repair.
+func WithBoilerplateStripping(extra ...*regexp.Regexp) Option {
+	return func(p *Parser) {
+		p.stripsBoilerplate = true
+		p.boilerplate = append(p.boilerplate, extra...)
+	}
+}
+
+// WithRoleMarkers keeps only the model's answer from output wrapped in chat
+// roles ("Assistant:", "### Response:", "<|im_start|>assistant", "[/INST]")
+// or running on into invented turns ("User: thanks"). The answer is the first
+// assistant turn holding a label, else the first text outside any turn holding
+// one, else the last assistant turn. Role words that are label names, such as
+// a "Response" label, are never taken for roles.
+func WithRoleMarkers() Option {
+	return func(p *Parser) {
+		p.roleMarkers = true
+	}
+}
+
+// WithPromptEcho strips the lines at the start of the output that repeat lines
+// of prompt, for models that echo their instructions before answering.
+func WithPromptEcho(prompt string) Option {
+	return func(p *Parser) {
+		p.prompt = prompt
+	}
+}
+
+// WithBlockDedup makes ParseBlocks collapse consecutive blocks whose results
+// are at least similarity alike (1 for identical results only) into the first
+// of them, as looping models emit the same block over and over. The kept block
+// gets a KindDuplicateBlock warning counting the repeats. Similarity is one
+// minus the edit distance between the blocks' JSON-encoded results relative
+// to the longer one. A similarity of 0 disables deduplication; NewParser
+// returns an error unless similarity is between 0 and 1.
+func WithBlockDedup(similarity float64) Option {
+	return func(p *Parser) {
+		p.dedupSimilarity = similarity
+	}
+}
+
+// WithBareLabels treats a line holding nothing but a label's name or alias,
+// without a separator, as that label with its value on the following lines.
+// The name may be written as a markdown heading or in bold ("## Thought",
+// "**Final Answer**"), since models asked for headings tend to drop the colon.
+// Without it, such lines continue the value before them.
+func WithBareLabels() Option {
+	return func(p *Parser) {
+		p.bareLabels = true
+	}
+}
+
+// WithMiddleware adds middleware applied to every parsed value, after its
+// label's type conversion and checks, for cross-cutting transforms such as
+// trimming, normalization or encryption. Middleware run in the order they were
+// added, each calling next to hand the value on; the last next returns the
+// value as it stands. Values that are empty or failed to parse are not passed
+// through. An error is reported as a KindMiddleware error, keeping the value
+// as it was before the chain.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(p *Parser) {
+		p.middleware = append(p.middleware, middleware...)
+	}
+}
+
+// WithMessageTemplates rephrases the messages of errors and warnings of the
+// given kinds (see MessageTemplates), since they are often fed back to models
+// in correction prompts. Templates of the same kind added later win.
+func WithMessageTemplates(templates MessageTemplates) Option {
+	return func(p *Parser) {
+		if p.messageTemplates == nil {
+			p.messageTemplates = make(MessageTemplates)
+		}
+		for kind, template := range templates {
+			p.messageTemplates[kind] = template
+		}
+	}
+}
+
+// WithLanguage reports errors and warnings in language, such as "de", using
+// the templates of the first of catalogs that knows it, or of DefaultCatalog
+// when none are given. Templates of WithMessageTemplates take precedence over
+// the catalog's. NewParser returns an error if no catalog knows the language.
+func WithLanguage(language string, catalogs ...MessageCatalog) Option {
+	return func(p *Parser) {
+		p.language = language
+		p.catalogs = catalogs
+	}
+}
+
+// WithIndentedValues requires values that start on the line after their label
+// (a label line with nothing after the separator) to be indented. Indented lines
+// then always belong to the value, even when they look like labels, and the
+// first unindented line that is not a label is ignored, closing the value.
+func WithIndentedValues() Option {
+	return func(p *Parser) {
+		p.indentedValues = true
+	}
+}
+
+// WithURLSchemes sets the schemes accepted by "url" labels (default http and https).
+func WithURLSchemes(schemes ...string) Option {
+	return func(p *Parser) {
+		p.urlSchemes = make([]string, len(schemes))
+		for i, scheme := range schemes {
+			p.urlSchemes[i] = strings.ToLower(scheme)
+		}
+	}
+}
+
+// WithPathExistenceCheck makes "path" labels report an error when the path
+// does not exist on the local filesystem. Disabled by default, since paths are
+// often meant for another machine or are about to be created.
+func WithPathExistenceCheck() Option {
+	return func(p *Parser) {
+		p.checkPaths = true
+	}
+}
+
+// WithNaturalDates sets a hook that interprets natural language dates such as
+// "tomorrow at 5pm" for "datetime" labels. It is called only for values that
+// match none of the built-in layouts.
+func WithNaturalDates(fn NaturalDateFunc) Option {
+	return func(p *Parser) {
+		p.naturalDates = fn
+	}
+}
+
+// WithMaxBinarySize sets the largest decoded size in bytes accepted by "base64"
+// labels (default DefaultMaxBinarySize). Larger payloads are reported as
+// KindType errors without being decoded. A negative size removes the limit.
+func WithMaxBinarySize(size int) Option {
+	return func(p *Parser) {
+		p.maxBinarySize = size
+	}
+}
+
+// WithSQLValidator adds a validator run on every statement of "sql" labels,
+// after the statement is extracted and normalized. Validators run in the order
+// they were added; ReadOnlySQL rejects statements that may modify data.
+func WithSQLValidator(validator SQLValidator) Option {
+	return func(p *Parser) {
+		p.sqlValidators = append(p.sqlValidators, validator)
+	}
+}
+
+// WithDiffSource sets the hook "diff" labels use to read the files their
+// patches change, reporting a KindType error when a hunk does not apply.
+// Without it, diffs are only checked to be well formed.
+func WithDiffSource(source DiffSourceFunc) Option {
+	return func(p *Parser) {
+		p.diffSource = source
+	}
+}
+
+// WithGrounding checks the values of the named labels against a reference
+// document, such as the retrieved passages of a RAG pipeline. Every sentence of
+// their values must be found in source, ignoring case, punctuation and spacing,
+// within fuzziness (from 0, verbatim, to 1, anything goes). Unsupported
+// sentences are reported in Details.Warnings as KindUngrounded. The labels must
+// be defined; NewParser returns an error otherwise.
+func WithGrounding(source string, labels []string, fuzziness float64) Option {
+	return func(p *Parser) {
+		p.groundingSource = source
+		p.groundingFuzziness = fuzziness
+		p.groundedLabels = nil
+		for _, name := range labels {
+			p.groundedLabels = append(p.groundedLabels, strings.ToLower(strings.TrimSpace(name)))
+		}
+	}
+}
+
+// WithTokenizer makes parses report approximate token counts in
+// Details.Tokens, per label and for the whole text, e.g. for cost accounting
+// or to decide when to compress agent memory. ApproxTokenizer works when no
+// model-specific tokenizer is at hand.
+func WithTokenizer(tokenizer Tokenizer) Option {
+	return func(p *Parser) {
+		p.tokenizer = tokenizer
+	}
+}
+
+// WithJournal records every ParseDetailed and ParseBlocksDetailed call (and so
+// every Parse and ParseBlocks) in journal, for later replay with Replay. A
+// Stream records only its final parse, when closed.
+func WithJournal(journal *Journal) Option {
+	return func(p *Parser) {
+		p.journal = journal
+	}
+}
+
+// WithFrontMatter parses YAML ("---") or TOML ("+++") front matter at the top
+// of the text into a map stored under key in the result, and parses the rest
+// of the text by labels as usual. Only flat keys with strings, numbers,
+// booleans and lists (and TOML tables) are understood; malformed lines are
+// reported as KindFrontMatter errors. The key must not be a label name;
+// NewParser returns an error otherwise.
+func WithFrontMatter(key string) Option {
+	return func(p *Parser) {
+		p.frontMatterKey = strings.ToLower(strings.TrimSpace(key))
+	}
+}
+
+// WithHTML converts simple HTML in the output to text before labels are
+// matched, for models that format their answers as HTML (<b>Action:</b>,
+// <br>, <p>): line breaks and block elements become new lines, list items
+// start with "- ", other tags are dropped and entities are decoded. Code
+// fences are left untouched.
+func WithHTML() Option {
+	return func(p *Parser) {
+		p.html = true
+	}
+}
+
+// WithHTMLCapture makes the content of every <tag> element the value of the
+// named label, as if the model had written the label, e.g. <answer>42</answer>
+// for an "Answer" label. It implies WithHTML. The label (or one of its
+// aliases) must be defined; NewParser returns an error otherwise.
+func WithHTMLCapture(tag, label string) Option {
+	return func(p *Parser) {
+		p.html = true
+		p.htmlCaptures = append(p.htmlCaptures, htmlCapture{
+			tag:   strings.ToLower(strings.TrimSpace(tag)),
+			label: strings.ToLower(strings.TrimSpace(label)),
+		})
+	}
+}
+
+// WithLocale sets how numbers and dates are written in the outputs, e.g.
+// LocaleGerman for "1.234,56 €" and "1. März 2024". It applies to the
+// "number", "integer", "confidence", "quantity", "percent", "currency" and
+// "datetime" data types.
+func WithLocale(locale Locale) Option {
+	return func(p *Parser) {
+		p.locale = locale
+	}
+}
+
+// WithExactUnicode matches labels byte for byte. By default, label names,
+// aliases and the label of each output line are normalized for matching (see
+// parsec.Normalize), so full-width labels such as "Ａｃｔｉｏｎ：", names with
+// decomposed accents and non-breaking spaces match their plain forms, and
+// each label matched that way gets a RepairUnicode repair. Values are never
+// normalized.
+func WithExactUnicode() Option {
+	return func(p *Parser) {
+		p.exactUnicode = true
+	}
+}
@@ -0,0 +1,46 @@
+package arkaineparser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pair couples the nth occurrences of two interleaved labels, such as each
+// Action with its Action Input.
+type Pair struct {
+	A     interface{} // The nth value of the first label, or nil if it has no nth occurrence
+	B     interface{} // The nth value of the second label, or nil if it has no nth occurrence
+	Index int         // The 0-based occurrence index
+}
+
+// Pairs matches the occurrences of labels a and b by position and returns one
+// Pair per occurrence, in order. If the labels occur a different number of
+// times, every index is still returned (with nil for the missing side) along
+// with an error describing the mismatch.
+func (d Details) Pairs(a, b string) ([]Pair, error) {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	aValues, aKnown := d.Occurrences[a]
+	bValues, bKnown := d.Occurrences[b]
+	if !aKnown || !bKnown {
+		missing := a
+		if aKnown {
+			missing = b
+		}
+		return []Pair{}, fmt.Errorf("unknown label '%s'", missing)
+	}
+	count := max(len(aValues), len(bValues))
+	pairs := make([]Pair, count)
+	for i := range pairs {
+		pairs[i].Index = i
+		if i < len(aValues) {
+			pairs[i].A = aValues[i]
+		}
+		if i < len(bValues) {
+			pairs[i].B = bValues[i]
+		}
+	}
+	if len(aValues) != len(bValues) {
+		return pairs, fmt.Errorf("'%s' occurs %d times but '%s' occurs %d times", a, len(aValues), b, len(bValues))
+	}
+	return pairs, nil
+}
@@ -0,0 +1,35 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestPairs checks pairing of interleaved label occurrences.
+func TestPairs(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Tool"}, {Name: "Arguments", IsJSON: true}, {Name: "Thought"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Thought: look it up\nTool: search\nArguments: {\"q\": \"go\"}\nTool: finish\nArguments:"
+	pairs, err := parser.ParseDetailed(input).Pairs("Tool", "Arguments")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Pair{
+		{A: "search", B: map[string]interface{}{"q": "go"}, Index: 0},
+		{A: "finish", B: map[string]interface{}{}, Index: 1},
+	}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("pairs mismatch.\nGot: %#v\nExpected: %#v", pairs, expected)
+	}
+
+	// Mismatched counts still return every index, plus an error
+	pairs, err = parser.ParseDetailed("Tool: a\nTool: b\nArguments: {}").Pairs("tool", "arguments")
+	if err == nil || len(pairs) != 2 || pairs[1].A != "b" || pairs[1].B != nil {
+		t.Errorf("unexpected mismatch handling: %#v %v", pairs, err)
+	}
+	if _, err := parser.ParseDetailed(input).Pairs("tool", "observation"); err == nil {
+		t.Errorf("expected error for unknown label")
+	}
+}
@@ -0,0 +1,66 @@
+package parsec
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize applies Unicode NFKC normalization (see
+// golang.org/x/text/unicode/norm), so visually identical text matches alike:
+// full-width ASCII (as typed with CJK input methods) becomes ASCII,
+// non-breaking and other special spaces become a space, ligatures such as 'ﬁ'
+// become their letters, and a letter followed by a combining accent becomes
+// the precomposed letter. Pure ASCII text is returned as it is.
+func Normalize(text string) string {
+	normalized, _ := NormalizeOffsets(text)
+	return normalized
+}
+
+// NormalizeOffsets normalizes text like Normalize, also returning for each
+// byte offset of the normalized text, and for its end, the offset in text it
+// comes from, so positions found in the normalized text can be mapped back.
+// Offsets within the normalized form of a changed character sequence map to
+// the end of that sequence.
+func NormalizeOffsets(text string) (string, []int) {
+	if isASCII(text) {
+		offsets := make([]int, len(text)+1)
+		for i := range offsets {
+			offsets[i] = i
+		}
+		return text, offsets
+	}
+	out := make([]byte, 0, len(text))
+	offsets := make([]int, 0, len(text)+1)
+	var iter norm.Iter
+	iter.InitString(norm.NFKC, text)
+	for !iter.Done() {
+		start := iter.Pos()
+		segment := iter.Next()
+		end := iter.Pos()
+		unchanged := string(segment) == text[start:end]
+		for i := range segment {
+			switch {
+			case unchanged:
+				// Unchanged text maps byte for byte
+				offsets = append(offsets, start+i)
+			case i == 0:
+				offsets = append(offsets, start)
+			default:
+				offsets = append(offsets, end)
+			}
+		}
+		out = append(out, segment...)
+	}
+	return string(out), append(offsets, len(text))
+}
+
+// isASCII reports whether text holds only ASCII bytes.
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
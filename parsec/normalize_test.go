@@ -0,0 +1,44 @@
+package parsec
+
+import "testing"
+
+// TestNormalize checks the NFKC forms Normalize produces.
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Action: plain ASCII", "Action: plain ASCII"},
+		{"Ａｃｔｉｏｎ\u3000Ｉｎｐｕｔ：\u3000｛｝", "Action Input: {}"},
+		{"Cafe\u0301 cre\u0300me", "Café crème"},
+		{"Ü\u0308", "Ü\u0308"}, // Composed letters take no further mark
+		{"\u0301 leading mark", "\u0301 leading mark"},
+		{"ﬁnal anﬆ", "final anst"},
+		{"漢字 stays", "漢字 stays"},
+		{"a\u00a0b\u2009c\u202fd", "a b c d"},
+	}
+	for _, test := range tests {
+		if got := Normalize(test.text); got != test.expected {
+			t.Errorf("%q: got %q, expected %q", test.text, got, test.expected)
+		}
+	}
+}
+
+// TestNormalizeOffsets checks that offsets in the normalized text map back to
+// the text.
+func TestNormalizeOffsets(t *testing.T) {
+	text := "Ａｃｔｉｏｎ：　café ﬁn"
+	normalized, offsets := NormalizeOffsets(text)
+	if normalized != "Action: café fin" || len(offsets) != len(normalized)+1 {
+		t.Fatalf("unexpected normalization: %q, %d offsets", normalized, len(offsets))
+	}
+	if colon := len("Action:"); text[offsets[colon]:] != "　café ﬁn" {
+		t.Errorf("unexpected offset %d", offsets[colon])
+	}
+	if accent := len("Action: caf"); offsets[accent+1] != len(text)-len(" ﬁn") {
+		t.Errorf("expected the middle of 'é' to map to its end, got %d", offsets[accent+1])
+	}
+	if offsets[len(normalized)] != len(text) {
+		t.Errorf("expected the end to map to the end")
+	}
+}
@@ -0,0 +1,362 @@
+// Package parsec exposes the building blocks the parser matches labels with,
+// so custom parsers (mixed XML and label formats, say) can be composed from
+// them instead of forking the parser:
+//   - a Matcher finds the label at the start of a line,
+//   - a Classifier decides which lines start labels and which continue a
+//     value, never taking lines inside an unclosed JSON structure for labels,
+//   - Assemble joins classified lines into label entries.
+package parsec
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// patternCache holds compiled label regexes keyed by label name. Label
+// patterns depend only on the name, so every matcher sharing a label shares
+// its compiled pattern too.
+var patternCache sync.Map
+
+// LabelPattern returns the regex matching label name at the start of a line:
+// case insensitive, with any whitespace between its words, followed by a
+// separator of ':', '~' or '-' characters. Patterns are compiled on first use
+// and cached.
+func LabelPattern(name string) *regexp.Regexp {
+	if cached, ok := patternCache.Load(name); ok {
+		return cached.(*regexp.Regexp)
+	}
+	// Create a regex pattern for the label
+	labelRegex := strings.Join(strings.Fields(regexp.QuoteMeta(name)), `\s+`)
+	pattern := regexp.MustCompile(`(?i)^\s*` + labelRegex + `\s*[:~\-]+\s*`)
+	cached, _ := patternCache.LoadOrStore(name, pattern)
+	return cached.(*regexp.Regexp)
+}
+
+// SplitLines splits text into lines and trims right whitespace.
+func SplitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return lines
+}
+
+// Match is a label found at the start of a line.
+type Match struct {
+	Label string // The label as it was added to the Matcher
+	Value string // The trimmed text after the label and its separator
+	Start int    // Byte offset of the label within the line
+	End   int    // Byte offset just past the separator
+	// Other labels the line matches too, sorted, which lost to Label; nil
+	// when the match was unambiguous
+	Others []string
+}
+
+// pattern matches one name of a label.
+type pattern struct {
+	label  string
+	name   string // The lowercase name matched
+	regex  *regexp.Regexp
+	prefix string // Lowercase first word of the name, to skip the regex on lines that cannot match
+}
+
+// Matcher finds labels at the start of lines. The zero Matcher matches
+// nothing; labels are added with Add. Patterns are kept sorted longest name
+// first, then by label and name, so matching never depends on the order
+// labels were added in. A Matcher must not be modified while
+// it is in use, but is otherwise safe for concurrent use.
+type Matcher struct {
+	patterns []pattern
+}
+
+// NewMatcher creates an empty Matcher.
+func NewMatcher() *Matcher {
+	return &Matcher{}
+}
+
+// Add registers label, matched by its own name and by each of aliases, all
+// reported as label.
+func (m *Matcher) Add(label string, aliases ...string) {
+	for _, name := range append([]string{label}, aliases...) {
+		name = strings.ToLower(name)
+		m.patterns = append(m.patterns, pattern{label: label, name: name, regex: LabelPattern(name), prefix: firstWord(name)})
+	}
+	slices.SortStableFunc(m.patterns, func(a, b pattern) int {
+		if len(a.name) != len(b.name) {
+			return len(b.name) - len(a.name)
+		}
+		return cmp.Or(strings.Compare(a.label, b.label), strings.Compare(a.name, b.name))
+	})
+}
+
+// Match returns the label matching at the start of line. When several labels
+// match, as "Step" and "Step-1" both do on "Step-1: go", the longest match
+// wins, so the result never depends on the order labels were added: the match
+// reaching furthest into the line, then the longest name, then the label
+// first in alphabetical order. The others are reported in Match.Others.
+//
+// The line is lowercased once and only patterns whose first word prefixes it
+// are run; the value is always sliced from the original line, since
+// lowercasing may change byte offsets.
+func (m *Matcher) Match(line string) (Match, bool) {
+	head := strings.ToLower(strings.TrimLeft(line, " \t\f\v\r"))
+	var (
+		best  Match
+		found bool
+	)
+	for _, pat := range m.patterns {
+		if !strings.HasPrefix(head, pat.prefix) {
+			continue
+		}
+		loc := pat.regex.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		if !found {
+			best, found = Match{Label: pat.label, Start: loc[0], End: loc[1]}, true
+			continue
+		}
+		// Patterns come in tie-breaking order, so only a further match wins
+		if loc[1] > best.End {
+			best.Others = addOther(best.Others, best.Label, pat.label)
+			best.Label, best.Start, best.End = pat.label, loc[0], loc[1]
+		} else {
+			best.Others = addOther(best.Others, pat.label, best.Label)
+		}
+	}
+	if !found {
+		return Match{}, false
+	}
+	best.Value = strings.TrimSpace(line[best.End:])
+	return best, true
+}
+
+// addOther adds label to the sorted others, unless it is already there or is
+// the winning label, which may match under several of its names.
+func addOther(others []string, label, winner string) []string {
+	others = slices.DeleteFunc(others, func(other string) bool { return other == winner })
+	if label == winner {
+		return others
+	}
+	i, exists := slices.BinarySearch(others, label)
+	if exists {
+		return others
+	}
+	return slices.Insert(others, i, label)
+}
+
+// firstWord returns the first whitespace separated word of name.
+func firstWord(name string) string {
+	if fields := strings.Fields(name); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// Structure tracks unclosed brackets and double-quoted strings across the
+// lines of a value, so label-like text inside structured content is not
+// mistaken for a new label. The zero Structure starts tracking when the
+// value's first non-blank character is '{', '[' or '"'.
+type Structure struct {
+	tracking bool // Whether structure is being tracked for this value
+	decided  bool // Whether the first non-blank character has been seen
+	depth    int  // Unclosed '{' and '[' count
+	inString bool // Inside a double-quoted string
+	escaped  bool // Previous character was a backslash inside a string
+}
+
+// Track makes the structure tracked whatever the value starts with, as for
+// the values of JSON labels.
+func (s *Structure) Track() {
+	s.tracking = true
+}
+
+// Ignore makes the structure never tracked, as for values known not to be
+// structured or known never to close.
+func (s *Structure) Ignore() {
+	s.tracking, s.decided = false, true
+}
+
+// Feed advances the state over one line of the value.
+func (s *Structure) Feed(line string) {
+	for _, r := range line {
+		if !s.decided {
+			if r == ' ' || r == '\t' {
+				continue
+			}
+			s.decided = true
+			if r == '{' || r == '[' || r == '"' {
+				s.tracking = true
+			}
+		}
+		if !s.tracking {
+			return
+		}
+		switch {
+		case s.inString && s.escaped:
+			s.escaped = false
+		case s.inString && r == '\\':
+			s.escaped = true
+		case r == '"':
+			s.inString = !s.inString
+		case s.inString:
+		case r == '{' || r == '[':
+			s.depth++
+		case (r == '}' || r == ']') && s.depth > 0:
+			s.depth--
+		}
+	}
+	// A line break ends any escape sequence
+	s.escaped = false
+}
+
+// Open reports whether the value has an unclosed bracket or string.
+func (s *Structure) Open() bool {
+	return s.tracking && (s.depth > 0 || s.inString)
+}
+
+// LineKind classifies a line.
+type LineKind string
+
+const (
+	LineLabel        LineKind = "label"        // The line starts a label
+	LineContinuation LineKind = "continuation" // The line continues the value before it
+	// The line lies inside an unclosed structure and continues the value
+	// before it, even if it looks like a label
+	LineLiteral LineKind = "literal"
+)
+
+// Line is a classified line.
+type Line struct {
+	Text  string   // The original line
+	Kind  LineKind // How the line takes part in the value
+	Match Match    // The label started on the line, when Kind is LineLabel
+}
+
+// Classifier classifies lines with a Matcher.
+type Classifier struct {
+	Matcher *Matcher
+	// Structured reports whether the values of label are always tracked as
+	// structures (see Structure.Track), e.g. for JSON labels. When nil, only
+	// values starting with '{', '[' or '"' are tracked.
+	Structured func(label string) bool
+}
+
+// Classify classifies each of lines. While a value has an unclosed bracket or
+// string, its lines are literal. If such a structure is still open at the end
+// of the lines it is taken as malformed, and the lines after it are classified
+// as if it were plain text. After MaxUnclosedStructures such structures, the
+// structure of later values is no longer tracked, so malformed input cannot
+// make classifying quadratic.
+func (c Classifier) Classify(lines []string) []Line {
+	classified := make([]Line, len(lines))
+	unclosed := make(map[int]bool)
+	from, untrackedFrom := 0, len(lines)
+	for {
+		openAt := c.classify(lines, classified, from, unclosed, untrackedFrom)
+		if openAt < 0 {
+			return classified
+		}
+		unclosed[openAt] = true
+		if len(unclosed) >= MaxUnclosedStructures {
+			untrackedFrom = openAt
+		}
+		// The lines before the structure classify the same way
+		from = openAt
+	}
+}
+
+// MaxUnclosedStructures is the number of structures left open at the end of
+// the lines after which Classify stops tracking structures.
+const MaxUnclosedStructures = 16
+
+// classify performs a single pass over lines from line from on, which must
+// start a label or be 0, storing the lines in classified. It ignores the
+// structure of values starting on the lines in unclosed or on line
+// untrackedFrom and after. Returns the index of the line starting a structure
+// left open at the end, or -1.
+func (c Classifier) classify(lines []string, classified []Line, from int, unclosed map[int]bool, untrackedFrom int) int {
+	var (
+		structure   Structure
+		structureAt = -1
+	)
+	for i := from; i < len(lines); i++ {
+		text := lines[i]
+		line := Line{Text: text, Kind: LineContinuation}
+		if structure.Open() {
+			line.Kind = LineLiteral
+			structure.Feed(text)
+			classified[i] = line
+			continue
+		}
+		match, ok := Match{}, false
+		if c.Matcher != nil {
+			match, ok = c.Matcher.Match(text)
+		}
+		if !ok {
+			structure.Feed(text)
+			classified[i] = line
+			continue
+		}
+		line.Kind, line.Match = LineLabel, match
+		// A new value starts; track its structure unless it never closes
+		structure, structureAt = Structure{}, i
+		if unclosed[i] || i >= untrackedFrom {
+			structure.Ignore()
+		} else {
+			if c.Structured != nil && c.Structured(match.Label) {
+				structure.Track()
+			}
+			structure.Feed(match.Value)
+		}
+		classified[i] = line
+	}
+	if structure.Open() {
+		return structureAt
+	}
+	return -1
+}
+
+// Entry is the value of one occurrence of a label.
+type Entry struct {
+	Label string // The label, as reported by the Matcher
+	Value string // The value, its lines joined and surrounding whitespace trimmed
+	Line  int    // Index of the line the label was found on
+}
+
+// Assemble joins each label line with the lines continuing it into an entry,
+// in order of appearance. Lines before the first label are dropped.
+func Assemble(lines []Line) []Entry {
+	var (
+		entries []Entry
+		value   strings.Builder
+	)
+	finalize := func() {
+		entries[len(entries)-1].Value = strings.TrimSpace(value.String())
+		value.Reset()
+	}
+	for i, line := range lines {
+		if line.Kind == LineLabel {
+			if len(entries) > 0 {
+				finalize()
+			}
+			entries = append(entries, Entry{Label: line.Match.Label, Line: i})
+			value.WriteString(line.Match.Value)
+			continue
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		if value.Len() > 0 {
+			value.WriteString("\n")
+		}
+		value.WriteString(line.Text)
+	}
+	if len(entries) > 0 {
+		finalize()
+	}
+	return entries
+}
@@ -0,0 +1,168 @@
+package parsec
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestMatcher checks that names and aliases are matched at the start of lines.
+func TestMatcher(t *testing.T) {
+	matcher := NewMatcher()
+	matcher.Add("final answer", "answer")
+	matcher.Add("thought")
+
+	match, ok := matcher.Match("  Final   Answer :- 42")
+	expected := Match{Label: "final answer", Value: "42", Start: 0, End: 20}
+	if !ok || !reflect.DeepEqual(match, expected) {
+		t.Errorf("unexpected match: %#v, %v", match, ok)
+	}
+	if match, ok := matcher.Match("ANSWER: yes"); !ok || match.Label != "final answer" || match.Value != "yes" {
+		t.Errorf("unexpected alias match: %#v, %v", match, ok)
+	}
+	if _, ok := matcher.Match("I thought: maybe"); ok {
+		t.Error("expected no match in the middle of a line")
+	}
+	if _, ok := (&Matcher{}).Match("thought: x"); ok {
+		t.Error("expected the zero Matcher to match nothing")
+	}
+}
+
+// TestMatcherOverlap checks that the longest match wins whatever the order
+// labels were added in, and that the labels it beat are reported.
+func TestMatcherOverlap(t *testing.T) {
+	for _, order := range [][]string{{"step", "step-1", "step-1 b"}, {"step-1 b", "step-1", "step"}} {
+		matcher := NewMatcher()
+		for _, label := range order {
+			matcher.Add(label)
+		}
+		tests := []struct {
+			line     string
+			expected Match
+		}{
+			{"Step-1: go", Match{Label: "step-1", Value: "go", End: 8, Others: []string{"step"}}},
+			{"Step-1 B: go", Match{Label: "step-1 b", Value: "go", End: 10, Others: []string{"step"}}},
+			{"Step: go", Match{Label: "step", Value: "go", End: 6}},
+		}
+		for _, test := range tests {
+			if match, ok := matcher.Match(test.line); !ok || !reflect.DeepEqual(match, test.expected) {
+				t.Errorf("%v, %q: unexpected match: %#v", order, test.line, match)
+			}
+		}
+	}
+
+	// Ties go to the longer name, and a label matching under several of its
+	// names is reported once
+	matcher := NewMatcher()
+	matcher.Add("a")
+	matcher.Add("b", "a-")
+	matcher.Add("c", "a", "a -")
+	match, _ := matcher.Match("A-: x")
+	if !reflect.DeepEqual(match, Match{Label: "b", Value: "x", End: 4, Others: []string{"a", "c"}}) {
+		t.Errorf("unexpected match: %#v", match)
+	}
+}
+
+// TestStructure checks bracket and string tracking across lines.
+func TestStructure(t *testing.T) {
+	var structure Structure
+	for _, line := range []string{`{"a": "x}`, `y\"", "b": [1,`} {
+		structure.Feed(line)
+	}
+	if !structure.Open() {
+		t.Error("expected the structure to be open")
+	}
+	structure.Feed(`2]}`)
+	if structure.Open() {
+		t.Error("expected the structure to be closed")
+	}
+
+	// Plain text is not tracked unless asked to
+	plain, tracked := Structure{}, Structure{}
+	tracked.Track()
+	plain.Feed("value {")
+	tracked.Feed("value {")
+	if plain.Open() || !tracked.Open() {
+		t.Errorf("unexpected tracking: plain %v, tracked %v", plain.Open(), tracked.Open())
+	}
+	ignored := Structure{}
+	ignored.Ignore()
+	ignored.Feed("{")
+	if ignored.Open() {
+		t.Error("expected an ignored structure never to open")
+	}
+}
+
+// TestClassifyAndAssemble checks a parse composed from the primitives.
+func TestClassifyAndAssemble(t *testing.T) {
+	matcher := NewMatcher()
+	matcher.Add("action")
+	matcher.Add("input")
+	classifier := Classifier{Matcher: matcher, Structured: func(label string) bool { return label == "input" }}
+	text := "Preamble\nAction: search\nInput: {\n  \"query\": \"x\",\n  \"action: y\": 1\n}\nAction: stop\nInput: {\ndone"
+
+	lines := classifier.Classify(SplitLines(text))
+	kinds := make([]LineKind, len(lines))
+	for i, line := range lines {
+		kinds[i] = line.Kind
+	}
+	expectedKinds := []LineKind{
+		LineContinuation, LineLabel, LineLabel, LineLiteral, LineLiteral, LineLiteral,
+		LineLabel, LineLabel, LineContinuation,
+	}
+	if !reflect.DeepEqual(kinds, expectedKinds) {
+		t.Errorf("unexpected kinds: %v", kinds)
+	}
+
+	expected := []Entry{
+		{Label: "action", Value: "search", Line: 1},
+		{Label: "input", Value: "{\n  \"query\": \"x\",\n  \"action: y\": 1\n}", Line: 2},
+		{Label: "action", Value: "stop", Line: 6},
+		// The unclosed structure is taken as plain text
+		{Label: "input", Value: "{\ndone", Line: 7},
+	}
+	if entries := Assemble(lines); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("unexpected entries: %#v", entries)
+	}
+}
+
+// TestClassifyUnclosed checks that values left open one after another are
+// all classified as labels, also past MaxUnclosedStructures.
+func TestClassifyUnclosed(t *testing.T) {
+	matcher := NewMatcher()
+	matcher.Add("input")
+	lines := Classifier{Matcher: matcher}.Classify(strings.Split(strings.Repeat("Input: [\n", 4*MaxUnclosedStructures-1)+"Input: [", "\n"))
+	for i, line := range lines {
+		if line.Kind != LineLabel {
+			t.Fatalf("line %d: unexpected kind %s", i, line.Kind)
+		}
+	}
+}
+
+// TestMixedFormat builds a parser for labels mixed with XML tags, treating
+// each tag line as a label of its own.
+func TestMixedFormat(t *testing.T) {
+	tag := regexp.MustCompile(`^<(\w+)>(.*)$`)
+	matcher := NewMatcher()
+	matcher.Add("thought")
+	text := "Thought: look it up\n<tool>search\nweather in Paris\n</tool>\nThought: done"
+
+	var lines []Line
+	for _, line := range (Classifier{Matcher: matcher}).Classify(SplitLines(text)) {
+		if sub := tag.FindStringSubmatch(line.Text); sub != nil {
+			line.Kind, line.Match = LineLabel, Match{Label: sub[1], Value: sub[2]}
+		} else if strings.HasPrefix(line.Text, "</") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	expected := []Entry{
+		{Label: "thought", Value: "look it up", Line: 0},
+		{Label: "tool", Value: "search\nweather in Paris", Line: 1},
+		{Label: "thought", Value: "done", Line: 3},
+	}
+	if entries := Assemble(lines); !reflect.DeepEqual(entries, expected) {
+		t.Errorf("unexpected entries: %#v", entries)
+	}
+}
@@ -7,72 +7,348 @@ import (
 	"encoding/json" // For JSON field parsing
 	"errors"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+
+	"github.com/hlfshell/go-arkaine-parser/parsec"
 )
 
 // Label defines a label for parsing with options for required, data type, dependencies, JSON, and block start.
+// The json tags let schemas be stored as JSON files.
 type Label struct {
-	Name         string   // Name of the label (case-insensitive)
-	Required     bool     // Whether this label is required
-	DataType     string   // Data type (e.g. "text", "json")
-	RequiredWith []string // List of other label names required with this one
-	IsJSON       bool     // Whether this label should be parsed as JSON
-	IsBlockStart bool     // Whether this label starts a new block
+	Name         string   `json:"name"`                     // Name of the label (case-insensitive)
+	Required     bool     `json:"required,omitempty"`       // Whether this label is required
+	RequiredWith []string `json:"required_with,omitempty"`  // List of other label names required with this one
+	IsJSON       bool     `json:"is_json,omitempty"`        // Whether this label should be parsed as JSON
+	IsBlockStart bool     `json:"is_block_start,omitempty"` // Whether this label starts a new block
+	EndMarker    string   `json:"end_marker,omitempty"`     // Optional marker (e.g. "END", "</value>") that ends this label's value
+	// DataType is "text" (default), "json" (same as IsJSON), or one of the
+	// types in datatypes.go such as "url", "number" or "list"
+	DataType string `json:"data_type,omitempty"`
+	// EmptyJSON chooses what an empty value of a JSON label becomes (default EmptyJSONObject)
+	EmptyJSON EmptyJSONPolicy `json:"empty_json,omitempty"`
+	Aliases   []string        `json:"aliases,omitempty"` // Alternative names matched as this label (case-insensitive)
+	Min       *float64        `json:"min,omitempty"`     // Optional inclusive lower bound for numeric values
+	Max       *float64        `json:"max,omitempty"`     // Optional inclusive upper bound for numeric values
+	// MatchPattern is an optional regular expression the whole value must match
+	MatchPattern string `json:"match_pattern,omitempty"`
+	// Source says who writes this label: the model (default) or the system
+	Source LabelSource `json:"source,omitempty"`
+	// Choices optionally restricts the value to a fixed set, such as class names.
+	// Values are normalized to the matching choice.
+	Choices []string `json:"choices,omitempty"`
+	// FenceLanguages are code fence language tags (e.g. "python") whose fenced
+	// blocks become this label's value wherever they appear, even without the
+	// label. A JSON label claims "json" fences unless it sets its own, as long
+	// as it is the only JSON label.
+	FenceLanguages []string `json:"fence_languages,omitempty"`
+	// Unordered says the order of the label's values carries no meaning, as
+	// for a set of tags, so Canonicalize sorts them
+	Unordered bool `json:"unordered,omitempty"`
+	// Group assembles this label with the others of the same group into
+	// repeated records, one per appearance of the group's first label
+	Group string `json:"group,omitempty"`
 }
 
+// LabelSource says who is allowed to produce a label.
+type LabelSource string
+
+const (
+	SourceModel  LabelSource = "model"  // Written by the model (the default)
+	SourceSystem LabelSource = "system" // Written only by the system, e.g. a tool Observation
+)
+
+// EmptyJSONPolicy chooses what a JSON label that appears with an empty value becomes.
+type EmptyJSONPolicy string
+
+const (
+	EmptyJSONObject EmptyJSONPolicy = "object" // An empty object, map[string]interface{}{} (the default)
+	EmptyJSONNil    EmptyJSONPolicy = "nil"    // A nil value
+	EmptyJSONString EmptyJSONPolicy = "string" // An empty string, as for a text label
+	EmptyJSONError  EmptyJSONPolicy = "error"  // An empty string plus a JSON error
+)
+
 // Parser parses labeled sections from text input.
+//
+// A Parser is immutable once NewParser returns: it keeps its own copy of the
+// label definitions and never modifies them while parsing, so a single Parser
+// may be shared by any number of goroutines calling Parse and ParseBlocks
+// concurrently.
 type Parser struct {
 	labels   []Label
-	patterns []labelPattern
+	matcher  *parsec.Matcher // Finds labels and aliases at the start of lines
 	labelMap map[string]Label
-}
+	names    map[string]string         // Lowercase label names and aliases to canonical label name
+	matchers map[string]*regexp.Regexp // Compiled MatchPattern of each label that has one
+	display  map[string]string         // Lowercase label names to their names as declared
+	keys     map[string]string         // Lowercase result keys (label and group names) to their keys as declared
+	// Lowercase fence language tags to the label their fenced blocks are routed to
+	fenceRoutes map[string]string
 
-type labelPattern struct {
-	// Name of the label
-	Name string
-	// Regex pattern for the label
-	Pattern *regexp.Regexp
+	// Settings applied by Options
+	fallbackLabel string   // Label receiving the whole text when no label is found, or ""
+	systemLabels  []string // Labels the model must never produce; stripped from output
+	// Whether values starting on the line after their label must be indented
+	indentedValues bool
+	urlSchemes     []string // Schemes accepted by "url" labels, or nil for http and https
+	checkPaths     bool     // Whether "path" labels must exist on the local filesystem
+	// Interprets natural language dates for "datetime" labels, or nil
+	naturalDates NaturalDateFunc
+	// Largest decoded "base64" value in bytes; 0 means DefaultMaxBinarySize
+	maxBinarySize int
+	sqlValidators []SQLValidator // Run in order on the statements of "sql" labels
+	diffSource    DiffSourceFunc // Provides file content "diff" labels must apply to, or nil
+	// Reference document the values of groundedLabels must be supported by
+	groundingSource    string
+	groundedLabels     []string
+	groundingFuzziness float64
+	tokenizer          Tokenizer // Counts tokens for Details.Tokens, or nil
+	journal            *Journal  // Records every parse, or nil
+	frontMatterKey     string    // Result key of the parsed front matter, or "" to leave it as text
+	html               bool      // Whether simple HTML is converted to text before matching
+	htmlCaptures       []htmlCapture
+	locale             Locale           // How numbers are written; the zero Locale is LocaleEnglish
+	nestedKeys         bool             // Whether dotted label names nest in the result
+	declaredKeys       bool             // Whether result keys keep the casing labels were declared with
+	groups             []labelGroup     // Label groups assembled into records, in declaration order
+	cleaningReport     bool             // Whether Details.Cleaning is filled in
+	cleaningMode       CleaningMode     // How code fences outside label values are cleaned
+	dedentValues       bool             // Whether blockquote markers and common indentation are stripped from values
+	bareLabels         bool             // Whether a line holding only a label's name opens that label
+	stripsBoilerplate  bool             // Whether chat preambles and sign-offs are stripped before matching
+	boilerplate        []*regexp.Regexp // Extra boilerplate patterns for WithBoilerplateStripping
+	roleMarkers        bool             // Whether chat role markers and other turns are stripped
+	prompt             string           // Prompt whose echoed lines are stripped, or ""
+	dedupSimilarity    float64          // Similarity at which consecutive blocks are collapsed, or 0
+	middleware         []Middleware     // Applied in order to every parsed value
+	messageTemplates   MessageTemplates // Rephrased messages by error kind
+	language           string           // Language of the messages, or "" for the defaults
+	catalogs           []MessageCatalog // Catalogs the language is looked up in
+	exactUnicode       bool             // Match labels byte for byte, without normalizing them
 }
 
-// NewParser creates a new Parser with the given labels.
-// Returns error if more than one block start label is defined.
-func NewParser(labels []Label) (*Parser, error) {
+// NewParser creates a new Parser with the given labels and options.
+// The labels slice is copied, so the caller may reuse or modify it afterwards.
+// Returns error if the label set is inconsistent: empty or duplicate names,
+// aliases colliding with other names or aliases, RequiredWith referencing
+// undefined labels, unknown data types or EmptyJSON policies, or more than one
+// block start label. The error wraps ErrDependency, ErrTooManyBlockStarts or
+// ErrUndefinedLabel where those apply, so callers can test it with errors.Is.
+func NewParser(labels []Label, opts ...Option) (*Parser, error) {
+	// Copy the labels so normalizing names never mutates the caller's slice
+	labels = copyLabels(labels)
+	// Apply the options first, as they decide how names are normalized
+	parser := &Parser{}
+	for _, opt := range opts {
+		opt(parser)
+	}
+	if !parser.exactUnicode {
+		// Names match the normalized input only when normalized alike
+		for i := range labels {
+			labels[i].Name = parsec.Normalize(labels[i].Name)
+			for j, alias := range labels[i].Aliases {
+				labels[i].Aliases[j] = parsec.Normalize(alias)
+			}
+			for j, dep := range labels[i].RequiredWith {
+				labels[i].RequiredWith[j] = parsec.Normalize(dep)
+			}
+		}
+		parser.fallbackLabel = parsec.Normalize(parser.fallbackLabel)
+		for i, name := range parser.systemLabels {
+			parser.systemLabels[i] = parsec.Normalize(name)
+		}
+		for i, name := range parser.groundedLabels {
+			parser.groundedLabels[i] = parsec.Normalize(name)
+		}
+		for i := range parser.htmlCaptures {
+			parser.htmlCaptures[i].label = parsec.Normalize(parser.htmlCaptures[i].label)
+		}
+	}
+	// Reject inconsistent schemas, once names that normalize alike are alike
+	if err := validateLabels(labels); err != nil {
+		return nil, err
+	}
 	// Create a map of label names to label definitions
 	labelMap := make(map[string]Label)
-	// Count the number of block start labels
-	blockStartCount := 0
+	// Map every name and alias to its canonical label name
+	names := make(map[string]string)
+	display := make(map[string]string)
+	keys := make(map[string]string)
+	var systemLabels []string
 	for i := range labels {
-		// Convert label name to lowercase
+		display[strings.ToLower(labels[i].Name)] = strings.TrimSpace(labels[i].Name)
+		if group := strings.TrimSpace(labels[i].Group); group != "" {
+			if _, ok := keys[strings.ToLower(group)]; !ok {
+				keys[strings.ToLower(group)] = group
+			}
+		}
+		// Convert label name and data type to lowercase
 		labels[i].Name = strings.ToLower(labels[i].Name)
+		labels[i].DataType = strings.ToLower(strings.TrimSpace(labels[i].DataType))
+		labels[i].Group = strings.ToLower(strings.TrimSpace(labels[i].Group))
+		// DataType "json" is another way of setting IsJSON
+		if labels[i].DataType == "json" {
+			labels[i].IsJSON = true
+		}
+		// Confidences range from 0 to 1 unless bounds are given
+		if labels[i].DataType == "confidence" && labels[i].Min == nil && labels[i].Max == nil {
+			zero, one := 0.0, 1.0
+			labels[i].Min, labels[i].Max = &zero, &one
+		}
 		// Add label to map
 		labelMap[labels[i].Name] = labels[i]
-		// Increment block start count if label is a block start
-		if labels[i].IsBlockStart {
-			blockStartCount++
+		if labels[i].Source == SourceSystem {
+			systemLabels = append(systemLabels, labels[i].Name)
+		}
+		names[labels[i].Name] = labels[i].Name
+		for _, alias := range labels[i].Aliases {
+			names[strings.ToLower(alias)] = labels[i].Name
+		}
+	}
+	// Compile value patterns; validateLabels already rejected invalid ones
+	matchers := make(map[string]*regexp.Regexp)
+	for _, label := range labels {
+		if label.MatchPattern != "" {
+			matchers[label.Name] = regexp.MustCompile(anchorPattern(label.MatchPattern))
+		}
+	}
+	// Fill in the Parser; system labels set by options are resolved below
+	parser.labels = labels
+	parser.matcher = buildMatcher(labels)
+	parser.labelMap = labelMap
+	parser.names = names
+	parser.matchers = matchers
+	parser.display = display
+	for name, declared := range display {
+		keys[name] = declared
+	}
+	parser.keys = keys
+	parser.fenceRoutes = buildFenceRoutes(labels)
+	parser.systemLabels = append(systemLabels, parser.systemLabels...)
+	parser.groups = buildGroups(labels)
+	if parser.fallbackLabel != "" {
+		canonical, ok := names[parser.fallbackLabel]
+		if !ok {
+			return nil, wrapError(ErrUndefinedLabel, "Fallback label '"+parser.fallbackLabel+"' is not defined")
+		}
+		parser.fallbackLabel = canonical
+	}
+	// Resolve system labels set by options, marking their labels as system-sourced
+	systemLabels = nil
+	for _, name := range parser.systemLabels {
+		canonical, ok := names[name]
+		if !ok {
+			return nil, wrapError(ErrUndefinedLabel, "System label '"+name+"' is not defined")
+		}
+		if slices.Contains(systemLabels, canonical) {
+			continue
+		}
+		systemLabels = append(systemLabels, canonical)
+		for i := range labels {
+			if labels[i].Name == canonical {
+				labels[i].Source = SourceSystem
+				labelMap[canonical] = labels[i]
+			}
+		}
+	}
+	parser.systemLabels = systemLabels
+	for i, capture := range parser.htmlCaptures {
+		canonical, ok := names[capture.label]
+		if !ok {
+			return nil, wrapError(ErrUndefinedLabel, "HTML capture label '"+capture.label+"' is not defined")
+		}
+		parser.htmlCaptures[i].label = canonical
+		parser.htmlCaptures[i].pattern = compileHTMLCapture(capture.tag)
+	}
+	if parser.nestedKeys {
+		if err := checkNestedKeys(labels); err != nil {
+			return nil, err
 		}
 	}
-	// Check if more than one block start label is defined
-	if blockStartCount > 1 {
-		return nil, errors.New("Only one block start label is allowed")
+	if parser.dedupSimilarity < 0 || parser.dedupSimilarity > 1 {
+		return nil, errors.New("Block dedup similarity must be between 0 and 1")
 	}
-	// Build regex patterns for each label
-	patterns := buildPatterns(labels)
-	// Create a new Parser
-	return &Parser{labels: labels, patterns: patterns, labelMap: labelMap}, nil
+	switch parser.cleaningMode {
+	case "", CleanStrip, CleanPlaceholders:
+	default:
+		return nil, errors.New("Unknown cleaning mode '" + string(parser.cleaningMode) + "'")
+	}
+	if parser.language != "" {
+		if err := parser.resolveLanguage(); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := names[parser.frontMatterKey]; ok {
+		return nil, errors.New("Front matter key '" + parser.frontMatterKey + "' collides with a label")
+	}
+	for i, name := range parser.groundedLabels {
+		canonical, ok := names[name]
+		if !ok {
+			return nil, wrapError(ErrUndefinedLabel, "Grounding label '"+name+"' is not defined")
+		}
+		parser.groundedLabels[i] = canonical
+	}
+	return parser, nil
+}
+
+// copyLabels returns a deep copy of labels, including their slices and bounds.
+func copyLabels(labels []Label) []Label {
+	copied := make([]Label, len(labels))
+	for i, label := range labels {
+		copied[i] = label
+		if label.RequiredWith != nil {
+			copied[i].RequiredWith = append([]string(nil), label.RequiredWith...)
+		}
+		if label.Aliases != nil {
+			copied[i].Aliases = append([]string(nil), label.Aliases...)
+		}
+		if label.Choices != nil {
+			copied[i].Choices = append([]string(nil), label.Choices...)
+		}
+		if label.FenceLanguages != nil {
+			copied[i].FenceLanguages = append([]string(nil), label.FenceLanguages...)
+		}
+		if label.Min != nil {
+			min := *label.Min
+			copied[i].Min = &min
+		}
+		if label.Max != nil {
+			max := *label.Max
+			copied[i].Max = &max
+		}
+	}
+	return copied
+}
+
+// buildFenceRoutes maps fence language tags to the labels claiming them. The
+// only JSON label without FenceLanguages claims "json"; with several, "json"
+// fences are not routed at all.
+func buildFenceRoutes(labels []Label) map[string]string {
+	routes := make(map[string]string)
+	var jsonLabels []Label
+	for _, label := range labels {
+		for _, language := range label.FenceLanguages {
+			routes[strings.ToLower(strings.TrimSpace(language))] = label.Name
+		}
+		if label.IsJSON {
+			jsonLabels = append(jsonLabels, label)
+		}
+	}
+	if _, claimed := routes["json"]; !claimed && len(jsonLabels) == 1 && len(jsonLabels[0].FenceLanguages) == 0 {
+		routes["json"] = jsonLabels[0].Name
+	}
+	return routes
 }
 
-// buildPatterns constructs regex patterns for each label.
-func buildPatterns(labels []Label) []labelPattern {
-	// Create a list of regex patterns
-	var patterns []labelPattern
+// buildMatcher registers each label with its aliases in a matcher.
+func buildMatcher(labels []Label) *parsec.Matcher {
+	matcher := parsec.NewMatcher()
 	for _, label := range labels {
-		// Create a regex pattern for the label
-		labelRegex := strings.Join(strings.Fields(label.Name), `\\s+`)
-		pattern := regexp.MustCompile(`(?i)^\\s*` + labelRegex + `\\s*[:~\-]+\\s*`)
-		// Add pattern to list
-		patterns = append(patterns, labelPattern{Name: label.Name, Pattern: pattern})
+		// Aliases match like the name but report the canonical label
+		matcher.Add(label.Name, label.Aliases...)
 	}
-	return patterns
+	return matcher
 }
 
 // Parse parses the text into a map of label names (all lowercase) to their values. Each label can have a single value or a slice of values.
@@ -81,11 +357,201 @@ func buildPatterns(labels []Label) []labelPattern {
 //   - Parses JSON fields if specified
 //   - Validates required fields and dependencies
 //   - Returns a map of results and a slice of error strings
+//
+// The result map and error slice are never nil, even for empty text, so
+// callers can range over them without checks. Use ParseDetailed for
+// structured errors.
 func (p *Parser) Parse(text string) (map[string]interface{}, []string) {
-	// Step 1: Clean the input text (remove markdown/code blocks, inline code)
-	cleaned := cleanText(text)
-	lines := splitAndTrimLines(cleaned)
+	details := p.ParseDetailed(text)
+	return map[string]interface{}(details.Result), errorStrings(details.Errors)
+}
+
+// ParseE parses the text like ParseDetailed, for idiomatic Go error flow: it
+// returns the result with the parse errors joined into a single error (see
+// Details.Err), or nil when there are none. Warnings never fail it. Each
+// joined error is a ParseError, so errors.Is matches the sentinels of their
+// kinds and errors.As retrieves the first of them.
+func (p *Parser) ParseE(text string) (Result, error) {
+	details := p.ParseDetailed(text)
+	return details.Result, details.Err()
+}
+
+// ParseBytes parses raw bytes like Parse, for untrusted input that may not be
+// valid UTF-8: invalid sequences are replaced with U+FFFD before parsing. No
+// input makes Parse, ParseBytes or ParseBlocks panic; fuzz_test.go holds the
+// fuzz targets enforcing this.
+func (p *Parser) ParseBytes(data []byte) (map[string]interface{}, []string) {
+	return p.Parse(strings.ToValidUTF8(string(data), "\uFFFD"))
+}
+
+// ParseDetailed parses the text like Parse, but returns the extended Details
+// output, including structured ParseErrors instead of error strings.
+func (p *Parser) ParseDetailed(text string) Details {
+	details := p.parseDetailed(text, nil)
+	if p.journal != nil {
+		p.journal.record(text, []Details{details}, false)
+	}
+	return details
+}
+
+// parseDetailed implements ParseDetailed, reusing the buffers of s unless it
+// is nil.
+func (p *Parser) parseDetailed(text string, s *scratch) Details {
+	matches, repairs, front, cleaned := p.matchText(text, s)
+	return p.detailsOf(text, matches, repairs, front, cleaned)
+}
+
+// detailsOf runs the parsing phase of ParseDetailed over the results of
+// matchText for text.
+func (p *Parser) detailsOf(text string, matches []lineMatch, repairs []Repair, front *frontMatter, cleaned string) Details {
+	details := p.parseMatches(matches, repairs)
+	p.addFrontMatter(&details, front)
+	if p.cleaningReport {
+		details.Cleaning = &Cleaning{Text: cleaned, Removals: locateRemovals(text, repairs)}
+	}
+	p.renderDetails(&details)
+	return details
+}
+
+// matchText runs the structural phase of a parse: it splits off any front
+// matter, cleans the text and matches its lines to labels. Returns the matches,
+// which live in the buffers of s unless it is nil, the repairs, the front
+// matter and the cleaned text.
+func (p *Parser) matchText(text string, s *scratch) ([]lineMatch, []Repair, *frontMatter, string) {
+	// Step 1: Split off any front matter, then clean the input text (remove
+	// markdown/code blocks, inline code)
+	text, front := p.splitFrontMatter(text)
+	prepared := p.prepare(text)
+	cleaned, repairs := prepared.cleaned, prepared.repairs
+	matches, matchRepairs := p.matchLines(prepared.lines, prepared.fenced, s)
+	prepared.annotate(matches)
+	repairs = append(repairs, matchRepairs...)
+	// A direct answer without any label goes to the fallback label
+	if p.fallbackLabel != "" && cleaned != "" && lastLabel(matches) == "" {
+		matches = []lineMatch{{line: cleaned, label: p.fallbackLabel, value: cleaned, matcher: MatchFallback}}
+		repairs = append(repairs, Repair{Kind: RepairFallbackLabel, Label: p.fallbackLabel, Before: cleaned, After: cleaned})
+	}
+	return matches, repairs, front, cleaned
+}
+
+// splitFrontMatter removes the front matter from text when WithFrontMatter is set.
+func (p *Parser) splitFrontMatter(text string) (string, *frontMatter) {
+	if p.frontMatterKey == "" {
+		return text, nil
+	}
+	return splitFrontMatter(text)
+}
+
+// addFrontMatter stores the front matter in the result under its key (an
+// empty map when there is none) and reports its errors first.
+func (p *Parser) addFrontMatter(details *Details, front *frontMatter) {
+	if p.frontMatterKey == "" {
+		return
+	}
+	if front == nil {
+		details.Result[p.frontMatterKey] = map[string]interface{}{}
+		return
+	}
+	details.Result[p.frontMatterKey] = copyValue(front.values)
+	details.Errors = append(append([]ParseError{}, front.errors...), details.Errors...)
+}
+
+// parseMatches assembles already matched lines into label values, then parses
+// and validates them. repairs holds the repairs already applied to the text.
+func (p *Parser) parseMatches(matches []lineMatch, repairs []Repair) Details {
+	// Step 2 and 3: Collect the raw values of each label
+	data := p.collectEntries(matches)
+
+	// Step 4: Strip values of system-only labels the model should never have produced
+	details := Details{Errors: []ParseError{}, Repairs: repairs, Provenance: p.provenance(matches)}
+	for _, label := range p.systemLabels {
+		if count := len(data[label]); count > 0 {
+			details.Warnings = append(details.Warnings, newSystemLabelWarning(label, count))
+			data[label] = []string{}
+			details.Provenance[label] = []Provenance{}
+		}
+	}
+	// Warn about lines several labels matched, as the choice may be unintended
+	for _, match := range matches {
+		if match.label != "" && len(match.others) > 0 {
+			details.Warnings = append(details.Warnings, newAmbiguousLabelWarning(match.label, match.others, match.line))
+		}
+	}
+
+	// Step 5: Process results: parse JSON fields, flatten single-value lists, collect errors
+	p.processResults(data, &details)
+
+	// Step 6: Warn about grounded labels asserting what the source does not say
+	p.checkGrounding(data, &details)
+
+	// Step 7: Count tokens for cost accounting
+	if p.tokenizer != nil {
+		details.Tokens = p.countTokens(matches, data)
+	}
 
+	// Step 8: Assemble grouped labels into records
+	if len(p.groups) > 0 {
+		p.assembleGroups(matches, &details)
+	}
+
+	// Step 9: Key the result by the names as declared
+	if p.declaredKeys {
+		details.Result = p.declareKeys(details.Result)
+	}
+
+	// Step 10: Nest the values of dotted label names
+	if p.nestedKeys {
+		details.Result = nestResult(details.Result)
+	}
+	return details
+}
+
+// Keys returns the result keys of the parser, label and group names in
+// lowercase, mapped to their names as declared, e.g. "action input" to
+// "Action Input". It tells consumers which casing the schema declared
+// without WithDeclaredKeys.
+func (p *Parser) Keys() map[string]string {
+	keys := make(map[string]string, len(p.keys))
+	for key, declared := range p.keys {
+		keys[key] = declared
+	}
+	return keys
+}
+
+// resultKey returns the result key of a label or group name: the name as
+// declared with WithDeclaredKeys, else the lowercase name.
+func (p *Parser) resultKey(name string) string {
+	if declared, ok := p.keys[name]; ok && p.declaredKeys {
+		return declared
+	}
+	return name
+}
+
+// declareKeys renames the label and group keys of result to their names as
+// declared, including the label keys of group records. Other keys, such as
+// the front matter key, are kept as they are.
+func (p *Parser) declareKeys(result Result) Result {
+	declared := make(Result, len(result))
+	for key, value := range result {
+		_, isLabel := p.labelMap[key]
+		if records, ok := value.([]map[string]interface{}); ok && !isLabel {
+			renamed := make([]map[string]interface{}, len(records))
+			for i, record := range records {
+				renamed[i] = make(map[string]interface{}, len(record))
+				for name, item := range record {
+					renamed[i][p.resultKey(name)] = item
+				}
+			}
+			value = renamed
+		}
+		declared[p.resultKey(key)] = value
+	}
+	return declared
+}
+
+// collectEntries assembles matched lines into the raw values of each label,
+// in order of appearance.
+func (p *Parser) collectEntries(matches []lineMatch) map[string][]string {
 	// Step 2: Initialize data structures
 	// Map of label name (lowercase) to list of captured values
 	data := make(map[string][]string)
@@ -95,150 +561,709 @@ func (p *Parser) Parse(text string) (map[string]interface{}, []string) {
 	var (
 		currentLabel string          // The label currently being populated
 		currentEntry strings.Builder // Accumulates multiline values
+		inline       bool            // Whether the current value started on its label's line
+		verbatim     bool            // Whether the current value holds verbatim (fenced) lines
 	)
+	finalize := func() {
+		entry := currentEntry.String()
+		if p.dedentValues && !verbatim {
+			entry = dedentValue(entry, inline)
+		}
+		p.finalizeEntry(data, currentLabel, entry)
+		currentEntry.Reset()
+	}
 
-	// Step 3: Iterate over each line to parse labels and values
-	for _, line := range lines {
-		labelName, value := p.parseLine(line)
-		if labelName != "" {
+	// Step 3: Iterate over each matched line to collect labels and values
+	for _, match := range matches {
+		if match.label != "" {
 			// If we were collecting a previous entry, finalize it
 			if currentLabel != "" {
-				finalizeEntry(data, currentLabel, currentEntry.String())
-				currentEntry.Reset()
+				finalize()
 			}
-			currentLabel = strings.ToLower(labelName)
-			currentEntry.WriteString(value)
+			currentLabel = match.label
+			currentEntry.WriteString(match.value)
+			inline, verbatim = match.value != "", false
 		} else if currentLabel != "" {
-			// Only treat as continuation if the line does not start with any known label
-			isLabelLine := false
-			for _, lbl := range p.labels {
-				if strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), strings.ToLower(lbl.Name)+":") {
-					isLabelLine = true
-					break
-				}
-			}
-			if !isLabelLine {
-				if currentEntry.Len() > 0 {
-					currentEntry.WriteString("\n")
-				}
-				currentEntry.WriteString(line)
+			// matchLines already ruled out a label on this line: it continues the value
+			if currentEntry.Len() > 0 {
+				currentEntry.WriteString("\n")
 			}
+			currentEntry.WriteString(match.value)
+			verbatim = verbatim || match.verbatim
+		}
+		// An end marker closes the value; text after it is ignored until the next label
+		if match.ends && currentLabel != "" {
+			finalize()
+			currentLabel = ""
 		}
 	}
 	// Finalize last entry if present
 	if currentLabel != "" {
-		finalizeEntry(data, currentLabel, currentEntry.String())
+		finalize()
 	}
-
-	// Step 4: Process results: parse JSON fields, flatten single-value lists, collect errors
-	results, errList := p.processResults(data)
-	return results, errList
+	return data
 }
 
+// Patterns used to clean text before parsing
+var (
+	codeBlockPattern  = regexp.MustCompile("(?s)```(?:\\w+)?\\s*(.*?)\\s*```")
+	inlineCodePattern = regexp.MustCompile("`([^`]+)`")
+)
+
 // cleanText removes markdown code blocks (```...```) and inline code (`...`) from the input text.
-func cleanText(text string) string {
+// Returns the cleaned text and a repair for every removed block or span.
+func cleanText(text string) (string, []Repair) {
+	var repairs []Repair
 	// Remove markdown code blocks (```...```)
-	codeBlock := regexp.MustCompile("(?s)```(?:\\w+)?\\s*(.*?)\\s*```")
-	text = codeBlock.ReplaceAllStringFunc(text, func(match string) string {
-		sub := codeBlock.FindStringSubmatch(match)
-		if len(sub) > 1 {
-			return sub[1]
+	text = codeBlockPattern.ReplaceAllStringFunc(text, func(match string) string {
+		content := ""
+		if sub := codeBlockPattern.FindStringSubmatch(match); len(sub) > 1 {
+			content = sub[1]
 		}
-		return ""
+		repairs = append(repairs, Repair{Kind: RepairCodeFence, Before: match, After: content})
+		return content
 	})
 	// Remove inline code (`...`)
-	inlineCode := regexp.MustCompile("`([^`]+)`")
-	text = inlineCode.ReplaceAllString(text, "$1")
-	return strings.TrimSpace(text)
+	text = inlineCodePattern.ReplaceAllStringFunc(text, func(match string) string {
+		content := match[1 : len(match)-1]
+		repairs = append(repairs, Repair{Kind: RepairInlineCode, Before: match, After: content})
+		return content
+	})
+	return strings.TrimSpace(text), repairs
+}
+
+// preparedText is input text cleaned and split into lines, ready for matching.
+type preparedText struct {
+	cleaned string   // The cleaned text
+	lines   []string // The cleaned text split into right-trimmed lines
+	fenced  []bool   // Whether each line came from a fenced block holding a label's value
+	// How the label on each line came about when not written by the model
+	// (a routed fence or HTML capture), or nil when no line was synthesized
+	origins []MatcherKind
+	repairs []Repair // Repairs applied while cleaning
+}
+
+// Patterns matching whole-line code fences
+var (
+	fenceOpenPattern  = regexp.MustCompile("^\\s*```[\\w+.-]*\\s*$")
+	fenceClosePattern = regexp.MustCompile("^\\s*```\\s*$")
+)
+
+// heredocPattern matches a heredoc opener such as <<EOF, <<-EOF or <<'EOF'.
+var heredocPattern = regexp.MustCompile(`^<<[-~]?\s*(['"]?)([A-Za-z_][A-Za-z0-9_]*)(['"]?)$`)
+
+// fencePlaceholder marks a protected fenced line while the rest is cleaned.
+const fencePlaceholder = "\x00fence:"
+
+// originPlaceholder marks the label line of a routed block while the rest is cleaned.
+const originPlaceholder = "\x00origin:"
+
+// prepare cleans text and splits it into lines. A code fence opening on the
+// line after a label with no value (or right after the separator) holds that
+// label's value: its lines are kept verbatim and flagged as fenced, so they are
+// never matched as labels. Elsewhere, a fence whose language tag a label claims
+// (see Label.FenceLanguages) becomes a value of that label, placed after the
+// value it interrupted. With CleanPlaceholders, every other closed fence is
+// kept verbatim, markers included. The lines of a heredoc value (Script: <<EOF), up to the
+// line holding only the delimiter, and of a triple-quoted value (Summary: """),
+// up to the closing quotes, are kept verbatim too. All other fences and inline
+// code are stripped by cleanText. With WithHTML, simple HTML is converted to
+// text first.
+func (p *Parser) prepare(text string) preparedText {
+	// NUL bytes never carry meaning in model output; dropping them keeps the
+	// input from forging placeholders
+	text = strings.ReplaceAll(text, "\x00", "")
+	var (
+		out       []string // Lines handed to cleanText, with placeholders for fenced lines
+		protected []string // Original fenced lines, indexed by placeholder
+		repairs   []Repair
+		awaiting  string // Label whose value is still empty, or ""
+		current   string // Label whose value is being read, or ""
+		// Fenced blocks routed by language, held back until the current value ends
+		routed []routedFence
+	)
+	var captures []routedFence // HTML elements captured into labels
+	if p.html {
+		converted, captured, changed := p.convertHTML(text)
+		if changed {
+			repairs = append(repairs, Repair{Kind: RepairHTML, Before: text, After: converted})
+			text, captures = converted, captured
+		}
+	}
+	if p.roleMarkers {
+		text = p.stripRoles(text, &repairs)
+	}
+	if p.prompt != "" {
+		text = p.stripPromptEcho(text, &repairs)
+	}
+	if p.stripsBoilerplate {
+		text = p.stripBoilerplate(text, &repairs)
+	}
+	raw := strings.Split(text, "\n")
+	// protect replaces lines with placeholders so cleaning leaves them untouched
+	protect := func(lines []string) {
+		for _, line := range lines {
+			out = append(out, fencePlaceholder+strconv.Itoa(len(protected)))
+			protected = append(protected, strings.TrimRight(line, " \t\r"))
+		}
+	}
+	// flush appends the routed blocks as values of their labels, behind
+	// placeholders that remember how each label line came about
+	var synthesized []routedFence
+	flush := func() {
+		for _, fence := range routed {
+			out = append(out, originPlaceholder+strconv.Itoa(len(synthesized)))
+			synthesized = append(synthesized, fence)
+			protect(fence.lines)
+		}
+		routed = nil
+	}
+	for i := 0; i < len(raw); i++ {
+		line := raw[i]
+		if index, ok := strings.CutPrefix(line, capturePlaceholder); ok {
+			// A captured HTML element becomes a value once the current one ends
+			if n, err := strconv.Atoi(index); err == nil && n >= 0 && n < len(captures) {
+				routed = append(routed, captures[n])
+			}
+			continue
+		}
+		if awaiting != "" && fenceOpenPattern.MatchString(line) {
+			// Find the closing fence; an unclosed fence is left to cleanText
+			end := i + 1
+			for end < len(raw) && !fenceClosePattern.MatchString(raw[end]) {
+				end++
+			}
+			if end < len(raw) {
+				content := raw[i+1 : end]
+				protect(content)
+				repairs = append(repairs, Repair{
+					Kind:   RepairCodeFence,
+					Label:  awaiting,
+					Before: strings.Join(raw[i:end+1], "\n"),
+					After:  strings.Join(content, "\n"),
+				})
+				awaiting = ""
+				i = end
+				continue
+			}
+		}
+		if route := p.fenceRoutes[fenceLanguage(line)]; route != "" && awaiting == "" && route != current && fenceOpenPattern.MatchString(line) {
+			// A tagged fence outside its label's value: route it to the label
+			// claiming its language, unless the fence never closes
+			end := i + 1
+			for end < len(raw) && !fenceClosePattern.MatchString(raw[end]) {
+				end++
+			}
+			if end < len(raw) {
+				content := raw[i+1 : end]
+				routed = append(routed, routedFence{label: route, lines: content, matcher: MatchFenceRouted})
+				repairs = append(repairs, Repair{
+					Kind:   RepairFenceRouted,
+					Label:  route,
+					Before: strings.Join(raw[i:end+1], "\n"),
+					After:  strings.Join(content, "\n"),
+				})
+				i = end
+				continue
+			}
+		}
+		if p.cleaningMode == CleanPlaceholders && fenceOpenPattern.MatchString(line) {
+			// Keep the whole fence, markers included, out of label scanning
+			end := i + 1
+			for end < len(raw) && !fenceClosePattern.MatchString(raw[end]) {
+				end++
+			}
+			if end < len(raw) {
+				protect(raw[i : end+1])
+				awaiting = ""
+				i = end
+				continue
+			}
+		}
+		if label, value, span := p.parseLine(line); label != "" {
+			flush()
+			awaiting = ""
+			current = label
+			if value == "" {
+				awaiting = label
+			} else if m := heredocPattern.FindStringSubmatch(value); m != nil && m[1] == m[3] {
+				// A heredoc runs to the line holding only its delimiter, or to the end
+				delimiter := m[2]
+				end := i + 1
+				for end < len(raw) && strings.TrimSpace(raw[end]) != delimiter {
+					end++
+				}
+				out = append(out, line[:span.End])
+				protect(raw[i+1 : min(end, len(raw))])
+				if end == len(raw) {
+					repairs = append(repairs, Repair{Kind: RepairMissingEndMarker, Label: label, Before: delimiter})
+				}
+				i = end
+				continue
+			} else if quote := openingQuote(value); quote != "" {
+				// A triple-quoted value runs verbatim to its closing quotes, or to the end
+				content, end, closed := quotedLines(raw, i, value, quote)
+				out = append(out, line[:span.End])
+				protect(content)
+				if closed {
+					repairs = append(repairs, Repair{Kind: RepairQuoted, Label: label, Before: strings.Join(append([]string{value}, raw[i+1:end+1]...), "\n"), After: strings.Join(content, "\n")})
+				} else {
+					repairs = append(repairs, Repair{Kind: RepairMissingEndMarker, Label: label, Before: quote})
+				}
+				i = end
+				continue
+			} else if fenceOpenPattern.MatchString(value) {
+				// The fence opens right after the separator: move it to its own line
+				awaiting = label
+				out = append(out, line[:span.End])
+				raw[i] = value
+				i--
+				continue
+			}
+		} else if strings.TrimSpace(line) != "" {
+			awaiting = ""
+		}
+		out = append(out, line)
+	}
+	flush()
+
+	cleaned, cleanRepairs := cleanText(strings.Join(out, "\n"))
+	repairs = append(repairs, cleanRepairs...)
+	lines := parsec.SplitLines(cleaned)
+	fenced := make([]bool, len(lines))
+	var origins []MatcherKind
+	if len(protected) > 0 || len(synthesized) > 0 {
+		// Restore the fenced lines and the label lines of routed blocks
+		origins = make([]MatcherKind, len(lines))
+		for i, line := range lines {
+			if index, ok := strings.CutPrefix(strings.TrimSpace(line), fencePlaceholder); ok {
+				if n, err := strconv.Atoi(index); err == nil && n >= 0 && n < len(protected) {
+					lines[i] = protected[n]
+					fenced[i] = true
+				}
+			} else if index, ok := strings.CutPrefix(strings.TrimSpace(line), originPlaceholder); ok {
+				if n, err := strconv.Atoi(index); err == nil && n >= 0 && n < len(synthesized) {
+					lines[i] = p.display[synthesized[n].label] + ":"
+					origins[i] = synthesized[n].matcher
+				}
+			}
+		}
+		cleaned = strings.Join(lines, "\n")
+	}
+	return preparedText{cleaned: cleaned, lines: lines, fenced: fenced, origins: origins, repairs: repairs}
+}
+
+// openingQuote returns the triple quote a value starts with, three double or
+// three single quotes, or "".
+// Values in plain double quotes already keep label-like lines inside them
+// through structure tracking, quotes included.
+func openingQuote(value string) string {
+	for _, quote := range []string{`"""`, "'''"} {
+		if strings.HasPrefix(value, quote) {
+			return quote
+		}
+	}
+	return ""
+}
+
+// quotedLines collects the content of a triple-quoted value opening on
+// raw[start] with value, up to its closing quotes. Text after the closing quote is
+// dropped, as are the blank lines the quotes stand on. Returns the content,
+// the index of the last line consumed, and whether the quote closed.
+func quotedLines(raw []string, start int, value, quote string) ([]string, int, bool) {
+	var content []string
+	body, end, closed := value[len(quote):], start, false
+	for {
+		if idx := strings.Index(body, quote); idx >= 0 {
+			content = append(content, body[:idx])
+			closed = true
+			break
+		}
+		content = append(content, body)
+		if end+1 == len(raw) {
+			break
+		}
+		end++
+		body = raw[end]
+	}
+	if len(content) > 1 && strings.TrimSpace(content[0]) == "" {
+		content = content[1:]
+	}
+	if len(content) > 1 && strings.TrimSpace(content[len(content)-1]) == "" {
+		content = content[:len(content)-1]
+	}
+	return content, end, closed
+}
+
+// routedFence is a fenced block routed to the label claiming its language.
+type routedFence struct {
+	label   string
+	lines   []string
+	matcher MatcherKind // How the block was found: MatchFenceRouted or MatchHTMLCapture
 }
 
-// splitAndTrimLines splits text into lines and trims right whitespace.
-func splitAndTrimLines(text string) []string {
-	lines := strings.Split(text, "\n")
+// fenceLanguage returns the lowercase language tag of a fence opening line,
+// or "" for any other line.
+func fenceLanguage(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "```") {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(trimmed, "```"))
+}
+
+// parseLine tries to match a label at the start of the line. Returns label name, value and the span of the
+// label and its separator (if matched), else empty strings.
+// When several labels match, the longest match wins (see parsec.Matcher.Match).
+func (p *Parser) parseLine(line string) (string, string, Span) {
+	label, value, span, _ := p.matchLine(line)
+	return label, value, span
+}
+
+// matchLine is parseLine, also returning the other labels the line matches.
+func (p *Parser) matchLine(line string) (string, string, Span, []string) {
+	if match, ok := p.matchLabel(line); ok {
+		return match.Label, match.Value, Span{match.Start, match.End}, match.Others
+	}
+	// A line holding nothing but a label's name opens it (see WithBareLabels)
+	if p.bareLabels {
+		if label := p.bareLabel(line); label != "" {
+			return label, "", Span{0, len(line)}, nil
+		}
+	}
+	// No match; treat as continuation
+	return "", "", Span{}, nil
+}
+
+// matchLabel matches the label starting line with the matcher. Unless the
+// parser is byte-exact, the line is matched in its normalized form (see
+// parsec.Normalize), while the span and value returned are those of the line
+// as written.
+func (p *Parser) matchLabel(line string) (parsec.Match, bool) {
+	if p.exactUnicode {
+		return p.matcher.Match(line)
+	}
+	normalized, offsets := parsec.NormalizeOffsets(line)
+	match, ok := p.matcher.Match(normalized)
+	if ok && normalized != line {
+		match.Start, match.End = offsets[match.Start], offsets[match.End]
+		match.Value = strings.TrimSpace(line[match.End:])
+	}
+	return match, ok
+}
+
+// scannedLine holds the label detected on a line by parseLine.
+type scannedLine struct {
+	label string // Label started on this line, or ""
+	value string // Value following the label
+	span  Span   // Label and separator within the line
+	// Other labels the line matches too, which lost to label
+	others []string
+}
+
+// scanLines runs parseLine once over every line, so the passes of matchLines
+// (and the block splitting of ParseBlocks) never repeat label detection.
+func (p *Parser) scanLines(lines []string, s *scratch) []scannedLine {
+	scanned := s.scannedLines(len(lines))
 	for i, line := range lines {
-		lines[i] = strings.TrimRight(line, " \t\r")
+		scanned[i].label, scanned[i].value, scanned[i].span, scanned[i].others = p.matchLine(line)
+	}
+	return scanned
+}
+
+// lineMatch describes how a single line takes part in label detection.
+type lineMatch struct {
+	line    string // The original line
+	label   string // Label started on this line, or "" for continuation lines
+	value   string // Value text this line contributes
+	literal bool   // Line is inside an end-marked value and must not be treated as a label
+	// Line is a fenced line kept verbatim (see prepare)
+	verbatim bool
+	ends     bool // Line contains the end marker of the current value
+	span     Span // Label and separator within the line, when label is set
+	// How the label was found when not by its pattern (see annotate), or ""
+	matcher MatcherKind
+	others  []string // Other labels the line matches too, which lost to label
+}
+
+// matchLines detects the label starting on each line. While a label with an
+// EndMarker is open, lines are never matched as labels; the marker and anything
+// after it on its line are dropped from the value. Likewise, while the value of
+// a JSON label (or any value starting with '{', '[' or '"') has an unclosed
+// bracket or string, its lines are never matched as labels. If such a structure
+// is still open at the end of the text it is treated as malformed, and the
+// lines after it are matched normally instead. After parsec.MaxUnclosedStructures
+// such structures, the structure of later values is no longer tracked, so
+// malformed output cannot make matching quadratic. Fenced lines (see prepare)
+// are never matched as labels either.
+// Returns the matches and a repair for each recovered structure or missing end
+// marker. The matches live in the buffers of s unless it is nil.
+func (p *Parser) matchLines(lines []string, fenced []bool, s *scratch) ([]lineMatch, []Repair) {
+	unclosed := make(map[int]bool)
+	var repairs []Repair
+	// Detect labels once; every pass below only replays the structure tracking
+	scanned := p.scanLines(lines, s)
+	matches := s.lineMatches(len(lines))
+	from, untrackedFrom := 0, len(lines)
+	for {
+		openAt, pendingMarker := p.matchLinesFrom(lines, fenced, scanned, matches, from, unclosed, untrackedFrom)
+		if openAt < 0 {
+			if pendingMarker != "" {
+				// The last end-marked value ran to the end of the text
+				label := lastLabel(matches)
+				repairs = append(repairs, Repair{Kind: RepairMissingEndMarker, Label: label, Before: pendingMarker})
+			}
+			return matches, append(repairs, p.unicodeRepairs(matches)...)
+		}
+		unclosed[openAt] = true
+		if len(unclosed) >= parsec.MaxUnclosedStructures {
+			untrackedFrom = openAt
+		}
+		// Before holds the text that opened the structure; nothing was rewritten
+		repairs = append(repairs, Repair{Kind: RepairUnclosedStructure, Label: matches[openAt].label, Before: matches[openAt].value})
+		// The lines before the structure matched the same way; only rematch the rest
+		from = openAt
+	}
+}
+
+// unicodeRepairs returns a RepairUnicode for each label that only matched
+// once normalized, holding the label as written and as matched.
+func (p *Parser) unicodeRepairs(matches []lineMatch) []Repair {
+	if p.exactUnicode {
+		return nil
 	}
-	return lines
+	var repairs []Repair
+	for _, match := range matches {
+		if match.label == "" {
+			continue
+		}
+		written := match.line[match.span.Start:match.span.End]
+		if normalized := parsec.Normalize(written); normalized != written {
+			repairs = append(repairs, Repair{Kind: RepairUnicode, Label: match.label, Before: written, After: normalized})
+		}
+	}
+	return repairs
 }
 
-// parseLine tries to match a label at the start of the line. Returns label name and value (if matched), else empty string.
-func (p *Parser) parseLine(line string) (string, string) {
-	// Try regex patterns for each label (case-insensitive)
-	for _, pat := range p.patterns {
-		if loc := pat.Pattern.FindStringIndex(line); loc != nil {
-			value := strings.TrimSpace(line[loc[1]:])
-			return pat.Name, value
+// lastLabel returns the last label started in matches, or "".
+func lastLabel(matches []lineMatch) string {
+	for i := len(matches) - 1; i >= 0; i-- {
+		if matches[i].label != "" {
+			return matches[i].label
 		}
 	}
-	// Fallback: check for label prefix with separator
-	for labelName := range p.labelMap {
-		trimmed := strings.TrimSpace(line)
-		if strings.HasPrefix(strings.ToLower(trimmed), labelName) {
-			remain := trimmed[len(labelName):]
-			if sep, _ := regexp.MatchString(`^\s*[:~\-]+`, remain); sep {
-				content := regexp.MustCompile(`^\s*[:~\-]+`).ReplaceAllString(remain, "")
-				return labelName, strings.TrimSpace(content)
+	return ""
+}
+
+// matchLinesFrom performs a single matching pass over the scanned lines from
+// line from on, storing the matches in matches. It ignores value structure for
+// values starting on the lines in unclosed or on line untrackedFrom and after.
+// Matching starts afresh at from, which must start a label or be 0. Returns
+// the index of the line starting a structure left open at the end of the text
+// (or -1), and the end marker still pending at the end of the text (or "").
+func (p *Parser) matchLinesFrom(lines []string, fenced []bool, scanned []scannedLine, matches []lineMatch, from int, unclosed map[int]bool, untrackedFrom int) (int, string) {
+	pendingMarker := ""
+	var (
+		structure   parsec.Structure // Bracket and string state of the current value
+		structureAt = -1             // Line the current value started on
+		// Whether the current value began on the following lines and, with
+		// WithIndentedValues, continues only while lines are indented
+		indented bool
+	)
+	for i := from; i < len(lines); i++ {
+		line := lines[i]
+		match := lineMatch{line: line, value: line}
+		if pendingMarker != "" {
+			// Inside an end-marked value: only look for the marker
+			match.literal = true
+			if idx := strings.Index(line, pendingMarker); idx >= 0 {
+				match.value = line[:idx]
+				match.ends = true
+				pendingMarker = ""
+			}
+			matches[i] = match
+			continue
+		}
+		if fenced[i] {
+			// A fenced value is taken verbatim and never parsed as a structure
+			match.literal, match.verbatim = true, true
+			structure = parsec.Structure{}
+			structure.Ignore()
+			matches[i] = match
+			continue
+		}
+		if structure.Open() {
+			// Inside an unclosed bracket or string: the line belongs to the value
+			match.literal = true
+			structure.Feed(line)
+			matches[i] = match
+			continue
+		}
+		if indented {
+			if strings.TrimSpace(line) == "" || line[0] == ' ' || line[0] == '\t' {
+				// Blank or indented lines continue the value, even if they look like labels
+				match.literal = true
+				structure.Feed(line)
+				matches[i] = match
+				continue
+			}
+			// The first unindented line closes the value
+			indented = false
+			if scanned[i].label == "" {
+				matches[i-1].ends = true
+			}
+		}
+		match.label, match.value, match.span = scanned[i].label, scanned[i].value, scanned[i].span
+		match.others = scanned[i].others
+		if match.label == "" {
+			match.value = line
+			structure.Feed(line)
+		} else {
+			// A new value starts; track its structure unless it never closes
+			structure = parsec.Structure{}
+			structureAt = i
+			if unclosed[i] || i >= untrackedFrom {
+				// Never track it again, not even from its continuation lines
+				structure.Ignore()
 			} else {
-				// treat as continuation
-				return "", trimmed
+				if p.labelMap[match.label].IsJSON {
+					structure.Track()
+				}
+				structure.Feed(match.value)
+			}
+			if marker := p.labelMap[match.label].EndMarker; marker != "" {
+				// The marker may close the value on the label line itself
+				if idx := strings.Index(match.value, marker); idx >= 0 {
+					match.value = match.value[:idx]
+					match.ends = true
+				} else {
+					pendingMarker = marker
+				}
+			} else if match.value == "" && p.indentedValues {
+				// The value starts on the following, indented lines
+				indented = true
 			}
 		}
+		matches[i] = match
 	}
-	// No match; treat as continuation
-	return "", ""
+	if structure.Open() {
+		return structureAt, ""
+	}
+	return -1, pendingMarker
+}
+
+// anchorPattern makes a MatchPattern match the whole value.
+func anchorPattern(pattern string) string {
+	return `^(?:` + pattern + `)$`
 }
 
-// finalizeEntry appends a non-empty entry to the data map for a label.
-func finalizeEntry(data map[string][]string, labelName, entry string) {
-	content := strings.TrimSpace(entry)
-	if content != "" {
-		data[labelName] = append(data[labelName], content)
+// checkPattern validates a non-empty text value against the label's
+// MatchPattern, recording an error in details when it does not match.
+func (p *Parser) checkPattern(labelDef Label, entry string, occurrence int, details *Details) {
+	matcher, ok := p.matchers[labelDef.Name]
+	if !ok || matcher.MatchString(entry) {
+		return
 	}
+	details.Errors = append(details.Errors, newPatternError(labelDef.Name, labelDef.MatchPattern, entry, occurrence))
 }
 
-// processResults parses JSON fields, flattens single-value lists, and collects errors.
-func (p *Parser) processResults(rawData map[string][]string) (map[string]interface{}, []string) {
-	results := make(map[string]interface{})
-	errList := []string{}
-	for labelName, entries := range rawData {
-		labelDef := p.labelMap[labelName]
+// finalizeEntry appends an entry to the data map for a label.
+// Empty entries are kept so every occurrence of a label can be validated by position.
+func (p *Parser) finalizeEntry(data map[string][]string, labelName, entry string) {
+	data[labelName] = append(data[labelName], strings.TrimSpace(entry))
+}
+
+// processResults parses JSON fields, flattens single-value lists, and collects errors
+// and the repairs applied to empty JSON values into details.
+func (p *Parser) processResults(rawData map[string][]string, details *Details) {
+	details.Result = make(Result)
+	details.Occurrences = make(map[string][]interface{})
+	// Walk labels in declaration order so errors are reported deterministically
+	for _, labelDef := range p.labels {
+		labelName := labelDef.Name
+		entries := rawData[labelName]
+		// occurrences holds one value per raw entry; parsedEntries drops empty text
+		occurrences := make([]interface{}, 0, len(entries))
 		parsedEntries := []interface{}{}
-		for _, entry := range entries {
+		for i, entry := range entries {
+			// Range errors name the occurrence when the label repeats
+			occurrence := 0
+			if len(entries) > 1 {
+				occurrence = i + 1
+			}
+			// Only values that parsed cleanly are range checked
+			errorCount := len(details.Errors)
 			if labelDef.IsJSON {
-				// If entry is empty, treat as empty object
-				if strings.TrimSpace(entry) == "" {
-					parsedEntries = append(parsedEntries, map[string]interface{}{})
-					continue
+				value := p.parseJSONEntry(labelDef, entry, details)
+				if entry != "" && len(details.Errors) == errorCount {
+					p.checkRange(labelDef, value, occurrence, details)
 				}
-				var obj interface{}
-				if err := importJSONUnmarshal([]byte(entry), &obj); err != nil {
-					parsedEntries = append(parsedEntries, entry)
-					errList = append(errList, "JSON error in '"+labelDef.Name+"': "+err.Error())
-				} else {
-					parsedEntries = append(parsedEntries, obj)
+				if entry != "" && len(details.Errors) == errorCount {
+					value = p.applyMiddleware(labelName, value, occurrence, details)
 				}
+				occurrences = append(occurrences, value)
+				parsedEntries = append(parsedEntries, value)
 			} else {
-				parsedEntries = append(parsedEntries, entry)
+				value := p.parseTypedEntry(labelDef, entry, details)
+				if entry != "" && len(details.Errors) == errorCount {
+					p.checkPattern(labelDef, entry, occurrence, details)
+					p.checkRange(labelDef, value, occurrence, details)
+					value = p.checkChoices(labelDef, value, occurrence, details)
+				}
+				if entry != "" && len(details.Errors) == errorCount {
+					value = p.applyMiddleware(labelName, value, occurrence, details)
+				}
+				occurrences = append(occurrences, value)
+				if entry != "" {
+					// Empty occurrences of text labels only count for validation
+					parsedEntries = append(parsedEntries, value)
+				}
 			}
 		}
+		details.Occurrences[labelName] = occurrences
 		// Flatten if only one entry
 		if len(parsedEntries) == 1 {
 			// If the entry is an empty string, flatten to ""
 			if str, ok := parsedEntries[0].(string); ok && str == "" {
-				results[labelName] = ""
+				details.Result[labelName] = ""
 			} else {
-				results[labelName] = parsedEntries[0]
+				details.Result[labelName] = parsedEntries[0]
 			}
 		} else if len(parsedEntries) == 0 {
 			// If no entries, flatten to ""
-			results[labelName] = ""
+			details.Result[labelName] = ""
 		} else {
-			results[labelName] = parsedEntries
+			details.Result[labelName] = parsedEntries
 		}
 	}
 	// Validate required fields and dependencies
-	errList = append(errList, p.validateDependencies(rawData)...)
-	return results, errList
+	details.Errors = append(details.Errors, p.validateDependencies(rawData)...)
+}
+
+// parseJSONEntry parses a single entry of a JSON label, recording any error or
+// repair in details. Malformed JSON is returned as the raw string.
+func (p *Parser) parseJSONEntry(labelDef Label, entry string, details *Details) interface{} {
+	// If entry is empty, apply the label's empty JSON policy
+	if strings.TrimSpace(entry) == "" {
+		switch labelDef.EmptyJSON {
+		case EmptyJSONNil:
+			return nil
+		case EmptyJSONString:
+			return ""
+		case EmptyJSONError:
+			details.Errors = append(details.Errors, newEmptyJSONError(labelDef.Name))
+			return ""
+		default:
+			details.Repairs = append(details.Repairs, Repair{Kind: RepairEmptyJSON, Label: labelDef.Name, After: "{}"})
+			return map[string]interface{}{}
+		}
+	}
+	var obj interface{}
+	if err := importJSONUnmarshal([]byte(entry), &obj); err != nil {
+		details.Errors = append(details.Errors, newJSONError(labelDef.Name, err))
+		return entry
+	}
+	return obj
 }
 
 // importJSONUnmarshal wraps json.Unmarshal for clarity and future flexibility.
@@ -247,26 +1272,28 @@ func importJSONUnmarshal(data []byte, v interface{}) error {
 }
 
 // validateDependencies checks required and required_with constraints.
-func (p *Parser) validateDependencies(data map[string][]string) []string {
-	errList := []string{}
+// Dependencies are checked per occurrence: the nth occurrence of a label needs a
+// non-empty nth occurrence of each label it requires.
+func (p *Parser) validateDependencies(data map[string][]string) []ParseError {
+	errList := []ParseError{}
 	for _, label := range p.labels {
 		key := strings.ToLower(label.Name)
-		entries, present := data[key]
-		// Treat empty string or empty slice as missing
-		missing := !present || len(entries) == 0 || (len(entries) == 1 && entries[0] == "")
-		if label.Required && missing {
-			errList = append(errList, "'"+label.Name+"' is required")
-		}
-		if len(label.RequiredWith) > 0 {
-			for _, dep := range label.RequiredWith {
-				depKey := strings.ToLower(dep)
-				depEntries, depPresent := data[depKey]
-				depMissing := !depPresent || len(depEntries) == 0 || (len(depEntries) == 1 && depEntries[0] == "")
-				// Enforce dependency if this label is present (even if empty)
-				if present {
-					if depMissing {
-						errList = append(errList, "'"+label.Name+"' requires '"+dep+"'")
-					}
+		entries := data[key]
+		// Treat empty strings or no entries as missing
+		if label.Required && !hasValue(entries) {
+			errList = append(errList, newRequiredError(label.Name))
+		}
+		for _, dep := range label.RequiredWith {
+			depEntries := p.dependencyEntries(strings.ToLower(strings.TrimSpace(dep)), data)
+			// Enforce the dependency for every occurrence of this label (even if empty)
+			for i := range entries {
+				if i < len(depEntries) && depEntries[i] != "" {
+					continue
+				}
+				if len(entries) == 1 {
+					errList = append(errList, newDependencyError(label.Name, dep))
+				} else {
+					errList = append(errList, newOccurrenceDependencyError(label.Name, dep, i+1))
 				}
 			}
 		}
@@ -274,12 +1301,55 @@ func (p *Parser) validateDependencies(data map[string][]string) []string {
 	return errList
 }
 
+// hasValue reports whether any entry is non-empty.
+func hasValue(entries []string) bool {
+	for _, entry := range entries {
+		if entry != "" {
+			return true
+		}
+	}
+	return false
+}
+
 // ParseBlocks parses the text into blocks, splitting at the block start label.
 // Each block is parsed as a separate document, and results are returned as a slice of maps.
 // Errors are collected for each block and returned as a combined error list.
 // Returns a slice of maps (one per block) and a slice of error strings.
+//
+// If there is no block start label, or it never appears, the whole text is
+// parsed as a single block and the sentinel error's message is reported first.
+// The result and error slices are never nil, and there is always at least one
+// result.
 func (p *Parser) ParseBlocks(text string) ([]map[string]interface{}, []string) {
-	// Find the block start label (must be exactly one)
+	blocks, err := p.ParseBlocksDetailed(text)
+	results := make([]map[string]interface{}, 0, len(blocks))
+	errList := []string{}
+	if err != nil {
+		errList = append(errList, err.Error())
+	}
+	for _, details := range blocks {
+		errList = append(errList, errorStrings(details.Errors)...)
+		results = append(results, map[string]interface{}(details.Result))
+	}
+	return results, errList
+}
+
+// ParseBlocksDetailed parses the text into blocks like ParseBlocks, returning
+// the extended Details output of each block, with the repairs made to its lines.
+// Returns ErrNoBlockStartLabel if no label is marked IsBlockStart and ErrNoBlocks
+// if the block start label never appears; in both cases the whole text is parsed
+// like ParseDetailed and returned as a single, best-effort block.
+func (p *Parser) ParseBlocksDetailed(text string) ([]Details, error) {
+	blocks, err := p.parseBlocksDetailed(text)
+	if p.journal != nil {
+		p.journal.record(text, blocks, true)
+	}
+	return blocks, err
+}
+
+// parseBlocksDetailed implements ParseBlocksDetailed.
+func (p *Parser) parseBlocksDetailed(text string) ([]Details, error) {
+	// Find the block start label (there is at most one)
 	blockLabel := ""
 	for _, label := range p.labels {
 		if label.IsBlockStart {
@@ -287,53 +1357,119 @@ func (p *Parser) ParseBlocks(text string) ([]map[string]interface{}, []string) {
 			break
 		}
 	}
-	if blockLabel == "" {
-		return nil, []string{"No block start label defined - must have at least one"}
+
+	// Split off any front matter, shared by every block, then clean and split
+	// input into lines and match labels once over the whole text
+	matches, repairs, front, cleaned := p.matchText(text, nil)
+
+	// Find where each block starts; lines before the first block are ignored
+	var starts []int
+	for i, match := range matches {
+		if blockLabel != "" && match.label == blockLabel {
+			starts = append(starts, i)
+		}
 	}
 
-	// Clean and split input into lines
-	cleaned := cleanText(text)
-	lines := splitAndTrimLines(cleaned)
+	// Without blocks, salvage what a single Parse finds
+	if len(starts) == 0 {
+		err := ErrNoBlocks
+		if blockLabel == "" {
+			err = ErrNoBlockStartLabel
+		}
+		return []Details{p.detailsOf(text, matches, repairs, front, cleaned)}, err
+	}
 
-	var (
-		blocks       [][]string // Each block is a slice of lines
-		currentBlock []string
-		inBlock      bool
-	)
+	// Parse each block from its slice of the matched lines, without re-cleaning,
+	// re-splitting or re-matching its text
+	blocks := make([]Details, 0, len(starts))
+	blockRepairs := placeRepairs(matches, starts, repairs)
+	for i, start := range starts {
+		end := len(matches)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		details := p.parseMatches(matches[start:end], blockRepairs[i])
+		shared := front
+		if i > 0 && front != nil {
+			// Errors in the front matter are reported once, with the first block
+			shared = &frontMatter{values: front.values}
+		}
+		p.addFrontMatter(&details, shared)
+		blocks = append(blocks, details)
+	}
+	if p.dedupSimilarity > 0 {
+		blocks = p.dedupBlocks(blocks, blockLabel)
+	}
+	for i := range blocks {
+		p.renderDetails(&blocks[i])
+	}
+	return blocks, nil
+}
 
-	// Iterate through lines, splitting at each new block start
-	for _, line := range lines {
-		labelName, _ := p.parseLine(line)
-		if strings.ToLower(labelName) == blockLabel {
-			if inBlock && len(currentBlock) > 0 {
-				blocks = append(blocks, currentBlock)
-				currentBlock = []string{}
+// placeRepairs distributes the repairs of a text over its blocks, starting at
+// the matched lines in starts, so each block reports the repairs made to its
+// lines. Each repair is found back in the lines after the one of the same kind
+// before it, like locateRemovals does in the text. A missing end marker lets
+// the value run to the end, so it goes with the last block; text-wide repairs,
+// and repairs to lines before the first block or that cannot be placed, go
+// with the first.
+func placeRepairs(matches []lineMatch, starts []int, repairs []Repair) [][]Repair {
+	blocks := make([][]Repair, len(starts))
+	cursor := make(map[RepairKind]int)
+	for _, repair := range repairs {
+		var found func(match lineMatch) bool
+		switch repair.Kind {
+		case RepairMissingEndMarker:
+			blocks[len(blocks)-1] = append(blocks[len(blocks)-1], repair)
+			continue
+		case RepairUnicode:
+			found = func(match lineMatch) bool {
+				return match.label == repair.Label && match.line[match.span.Start:match.span.End] == repair.Before
+			}
+		case RepairUnclosedStructure:
+			found = func(match lineMatch) bool {
+				return match.label == repair.Label && match.value == repair.Before
+			}
+		case RepairCodeFence, RepairInlineCode, RepairQuoted, RepairFenceRouted:
+			// The kept text starts on the line the repair was made to
+			first, _, _ := strings.Cut(strings.TrimSpace(repair.After), "\n")
+			if first = strings.TrimSpace(first); first == "" {
+				break
+			}
+			found = func(match lineMatch) bool {
+				return strings.Contains(match.line, first)
 			}
-			inBlock = true
 		}
-		if inBlock {
-			currentBlock = append(currentBlock, line)
+		line := -1
+		if found != nil {
+			line = indexMatch(matches, cursor[repair.Kind], found)
+			if line < 0 {
+				line = indexMatch(matches, 0, found)
+			}
 		}
+		block := 0
+		if line >= 0 {
+			cursor[repair.Kind] = line
+			if i, ok := slices.BinarySearch(starts, line); ok {
+				block = i
+			} else {
+				block = max(i-1, 0)
+			}
+		}
+		blocks[block] = append(blocks[block], repair)
 	}
-	// Append last block if present
-	if inBlock && len(currentBlock) > 0 {
-		blocks = append(blocks, currentBlock)
-	}
+	return blocks
+}
 
-	// Parse each block using the normal Parse logic
-	var (
-		results []map[string]interface{}
-		errList []string
-	)
-	for _, blockLines := range blocks {
-		blockText := strings.Join(blockLines, "\n")
-		result, blockErr := p.Parse(blockText)
-		if len(blockErr) > 0 {
-			errList = append(errList, blockErr...)
+// indexMatch returns the index of the first match at or after from that found
+// reports, or -1.
+func indexMatch(matches []lineMatch, from int, found func(match lineMatch) bool) int {
+	for i := from; i < len(matches); i++ {
+		if found(matches[i]) {
+			return i
 		}
-		results = append(results, result)
 	}
-	return results, errList
+	return -1
 }
 
 // Additional helpers and logic to be implemented.
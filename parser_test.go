@@ -2,9 +2,12 @@ package arkaineparser
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Test scaffolding for parser, will load test cases from assets.
@@ -12,13 +15,13 @@ import (
 // TestBasicFunctionality verifies that the parser correctly parses a typical input with all fields present.
 func TestBasicFunctionality(t *testing.T) {
 	// Load input text from asset file
-	input, err := os.ReadFile("assets/basic_functionality_input.txt")
+	input, err := os.ReadFile("conformance/corpus/basic_functionality_input.txt")
 	if err != nil {
 		t.Fatalf("failed to read input asset: %v", err)
 	}
 
 	// Load expected output from asset file
-	expectedBytes, err := os.ReadFile("assets/basic_functionality_output.json")
+	expectedBytes, err := os.ReadFile("conformance/corpus/basic_functionality_output.json")
 	if err != nil {
 		t.Fatalf("failed to read output asset: %v", err)
 	}
@@ -100,8 +103,8 @@ func deepEqual(a, b interface{}) bool {
 
 // TestMixedCaseMultiline checks handling of mixed case labels and multiline values.
 func TestMixedCaseMultiline(t *testing.T) {
-	input, _ := os.ReadFile("assets/mixed_case_multiline_input.txt")
-	expectedBytes, _ := os.ReadFile("assets/mixed_case_multiline_output.json")
+	input, _ := os.ReadFile("conformance/corpus/mixed_case_multiline_input.txt")
+	expectedBytes, _ := os.ReadFile("conformance/corpus/mixed_case_multiline_output.json")
 	var expected map[string]interface{}
 	json.Unmarshal(expectedBytes, &expected)
 	labels := []Label{
@@ -119,9 +122,9 @@ func TestMixedCaseMultiline(t *testing.T) {
 
 // TestJSONAndMalformed checks JSON and malformed JSON parsing and error reporting.
 func TestJSONAndMalformed(t *testing.T) {
-	input, _ := os.ReadFile("assets/json_and_malformed_input.txt")
-	expectedBytes, _ := os.ReadFile("assets/json_and_malformed_output.json")
-	errorsBytes, _ := os.ReadFile("assets/json_and_malformed_errors.json")
+	input, _ := os.ReadFile("conformance/corpus/json_and_malformed_input.txt")
+	expectedBytes, _ := os.ReadFile("conformance/corpus/json_and_malformed_output.json")
+	errorsBytes, _ := os.ReadFile("conformance/corpus/json_and_malformed_errors.json")
 	var expected map[string]interface{}
 	var expectedErrors []string
 	json.Unmarshal(expectedBytes, &expected)
@@ -141,9 +144,9 @@ func TestJSONAndMalformed(t *testing.T) {
 
 // TestRequiredDependency checks required and dependency validation.
 func TestRequiredDependency(t *testing.T) {
-	input, _ := os.ReadFile("assets/required_dependency_input.txt")
-	expectedBytes, _ := os.ReadFile("assets/required_dependency_output.json")
-	errorsBytes, _ := os.ReadFile("assets/required_dependency_errors.json")
+	input, _ := os.ReadFile("conformance/corpus/required_dependency_input.txt")
+	expectedBytes, _ := os.ReadFile("conformance/corpus/required_dependency_output.json")
+	errorsBytes, _ := os.ReadFile("conformance/corpus/required_dependency_errors.json")
 	var expected map[string]interface{}
 	var expectedErrors []string
 	json.Unmarshal(expectedBytes, &expected)
@@ -161,10 +164,41 @@ func TestRequiredDependency(t *testing.T) {
 	}
 }
 
+// TestSentinelErrors checks that parse and schema errors wrap sentinel errors.
+func TestSentinelErrors(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Answer", Required: true},
+		{Name: "Action", RequiredWith: []string{"Tool"}},
+		{Name: "Tool"},
+		{Name: "Input", IsJSON: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	details := parser.ParseDetailed("Action: search\nInput: {bad")
+	for _, sentinel := range []error{ErrMissingRequired, ErrDependency, ErrInvalidJSON} {
+		if !errors.Is(details.Err(), sentinel) {
+			t.Errorf("expected %v in %v", sentinel, details.Err())
+		}
+	}
+	if parser.ParseDetailed("Answer: 42").Err() != nil {
+		t.Errorf("expected no error")
+	}
+
+	_, err = NewParser([]Label{{Name: "A", IsBlockStart: true}, {Name: "B", IsBlockStart: true, RequiredWith: []string{"C"}}})
+	if !errors.Is(err, ErrTooManyBlockStarts) || !errors.Is(err, ErrDependency) {
+		t.Errorf("expected wrapped schema errors, got %v", err)
+	}
+	_, err = NewParser([]Label{{Name: "A"}}, WithFallbackLabel("B"))
+	if !errors.Is(err, ErrUndefinedLabel) || err.Error() != "Fallback label 'b' is not defined" {
+		t.Errorf("expected wrapped undefined label error, got %v", err)
+	}
+}
+
 // TestBlockParsing checks block parsing with multiple blocks.
 func TestBlockParsing(t *testing.T) {
-	input, _ := os.ReadFile("assets/block_parsing_input.txt")
-	expectedBytes, _ := os.ReadFile("assets/block_parsing_output.json")
+	input, _ := os.ReadFile("conformance/corpus/block_parsing_input.txt")
+	expectedBytes, _ := os.ReadFile("conformance/corpus/block_parsing_output.json")
 	var expected []map[string]interface{}
 	json.Unmarshal(expectedBytes, &expected)
 	labels := []Label{
@@ -181,3 +215,711 @@ func TestBlockParsing(t *testing.T) {
 }
 
 // ...additional tests matching Python test_parser.py
+
+// TestEndMarker checks that an end marker protects label-like lines inside a value.
+func TestEndMarker(t *testing.T) {
+	labels := []Label{
+		{Name: "Final Answer", EndMarker: "END"},
+		{Name: "Action"},
+		{Name: "Thought"},
+	}
+	parser, err := NewParser(labels)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Thought: done\nFinal Answer: First run the search.\nAction: this line is part of the answer\nEND\nignored trailing text\nAction: finish"
+	result, errors := parser.Parse(input)
+	if len(errors) > 0 {
+		t.Errorf("unexpected errors: %v", errors)
+	}
+	expected := map[string]interface{}{
+		"thought":      "done",
+		"final answer": "First run the search.\nAction: this line is part of the answer",
+		"action":       "finish",
+	}
+	if !deepEqual(result, expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", result, expected)
+	}
+
+	// The marker may also close the value on the label line itself
+	result, _ = parser.Parse("Final Answer: short END\nThought: next")
+	if result["final answer"] != "short" || result["thought"] != "next" {
+		t.Errorf("unexpected inline marker result: %#v", result)
+	}
+}
+
+// TestStructureAwareLabels checks that label-like text inside JSON values and quoted strings is kept in the value.
+func TestStructureAwareLabels(t *testing.T) {
+	labels := []Label{{Name: "Action Input", IsJSON: true}, {Name: "Thought"}, {Name: "Answer"}}
+	parser, err := NewParser(labels)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Action Input: {\n\"note\": \"Thought: remember this\",\n\"list\": [\n\"Answer: no\"\n]\n}\nThought: real thought\nAnswer: \"a quoted\nAnswer: still quoted\"\n"
+	result, errors := parser.Parse(input)
+	if len(errors) > 0 {
+		t.Errorf("unexpected errors: %v", errors)
+	}
+	expected := map[string]interface{}{
+		"action input": map[string]interface{}{"note": "Thought: remember this", "list": []interface{}{"Answer: no"}},
+		"thought":      "real thought",
+		"answer":       "\"a quoted\nAnswer: still quoted\"",
+	}
+	if !deepEqual(result, expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", result, expected)
+	}
+
+	// A structure that never closes does not swallow the labels after it
+	result, errors = parser.Parse("Action Input: {\"broken\": [1,\nThought: recovered")
+	if result["thought"] != "recovered" || len(errors) != 1 {
+		t.Errorf("unexpected recovery result: %#v %v", result, errors)
+	}
+}
+
+// TestEmptyJSONPolicy checks each policy for a JSON label that appears without a value.
+func TestEmptyJSONPolicy(t *testing.T) {
+	cases := []struct {
+		policy   EmptyJSONPolicy
+		expected interface{}
+		errors   int
+	}{
+		{"", map[string]interface{}{}, 0},
+		{EmptyJSONObject, map[string]interface{}{}, 0},
+		{EmptyJSONNil, nil, 0},
+		{EmptyJSONString, "", 0},
+		{EmptyJSONError, "", 1},
+	}
+	for _, c := range cases {
+		parser, err := NewParser([]Label{{Name: "Tool"}, {Name: "Arguments", IsJSON: true, EmptyJSON: c.policy}})
+		if err != nil {
+			t.Fatalf("failed to create parser: %v", err)
+		}
+		result, errors := parser.Parse("Tool: search\nArguments:")
+		if !deepEqual(result["arguments"], c.expected) {
+			t.Errorf("policy %q: got %#v, expected %#v", c.policy, result["arguments"], c.expected)
+		}
+		if len(errors) != c.errors {
+			t.Errorf("policy %q: unexpected errors: %v", c.policy, errors)
+		}
+	}
+}
+
+// TestDependencyOccurrences checks that repeated labels are validated pairwise by position.
+func TestDependencyOccurrences(t *testing.T) {
+	labels := []Label{
+		{Name: "Tool", RequiredWith: []string{"Arguments"}},
+		{Name: "Arguments", IsJSON: true},
+		{Name: "Notes", RequiredWith: []string{"Tool"}},
+	}
+	parser, err := NewParser(labels)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Tool: search\nArguments: {\"q\": \"a\"}\nTool: search\nArguments: {\"q\": \"b\"}\nTool: finish"
+	details := parser.ParseDetailed(input)
+	if len(details.Errors) != 1 {
+		t.Fatalf("expected exactly one error, got %v", details.Errors)
+	}
+	err0 := details.Errors[0]
+	if err0.Message != "'tool' occurrence 3 requires 'Arguments'" || err0.Occurrence != 3 || err0.Kind != KindDependency {
+		t.Errorf("unexpected error: %#v", err0)
+	}
+
+	// A label that never appears does not enforce its dependencies
+	_, errors := parser.Parse("Arguments: {}")
+	if len(errors) > 0 {
+		t.Errorf("unexpected errors: %v", errors)
+	}
+}
+
+// TestOverlappingLabelNames checks that labels sharing a prefix are matched
+// deterministically and that lowercasing never shifts value offsets.
+func TestOverlappingLabelNames(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Action", RequiredWith: []string{"Action Input"}},
+		{Name: "Action Input", IsJSON: true},
+		{Name: "Thought"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	// 'İ' lowercases to a longer byte sequence
+	input := "Thought: İİİ check\nAction: search\nAction   Input: {\"q\": \"İ\"}"
+	expected := map[string]interface{}{
+		"thought":      "İİİ check",
+		"action":       "search",
+		"action input": map[string]interface{}{"q": "İ"},
+	}
+	for i := 0; i < 50; i++ {
+		result, errList := parser.Parse(input)
+		if len(errList) > 0 || !reflect.DeepEqual(result, expected) {
+			t.Fatalf("run %d: got %#v, errors %v", i, result, errList)
+		}
+	}
+}
+
+// BenchmarkParse measures parsing a long agent transcript.
+func BenchmarkParse(b *testing.B) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought"},
+		{Name: "Action", RequiredWith: []string{"Action Input"}},
+		{Name: "Action Input", IsJSON: true},
+		{Name: "Observation"},
+		{Name: "Final Answer"},
+	})
+	if err != nil {
+		b.Fatalf("failed to create parser: %v", err)
+	}
+	var sb strings.Builder
+	for i := 0; i < 200; i++ {
+		sb.WriteString("Thought: I should look this up\nand think it over\n")
+		sb.WriteString("Action: search\nAction Input: {\"query\": \"step\", \"page\": 1}\n")
+		sb.WriteString("Observation: some results\nmore results here\n")
+	}
+	sb.WriteString("Final Answer: done")
+	input := sb.String()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser.Parse(input)
+	}
+}
+
+// BenchmarkParseBlocks measures splitting and parsing a 1,000-block output.
+func BenchmarkParseBlocks(b *testing.B) {
+	parser, err := NewParser([]Label{
+		{Name: "Task", IsBlockStart: true},
+		{Name: "Thought"},
+		{Name: "Result", IsJSON: true},
+	})
+	if err != nil {
+		b.Fatalf("failed to create parser: %v", err)
+	}
+	var sb strings.Builder
+	for i := 0; i < 1000; i++ {
+		sb.WriteString("Task: summarize\nThought: read it\nthen write it\nResult: {\"ok\": true}\n")
+	}
+	input := sb.String()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser.ParseBlocks(input)
+	}
+}
+
+// TestFallbackLabel checks that unlabeled answers go to the fallback label.
+func TestFallbackLabel(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought"},
+		{Name: "Final Answer", Required: true},
+	}, WithFallbackLabel("final answer"))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	details := parser.ParseDetailed("The capital of France is Paris.\nIt has been since 987.")
+	if len(details.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", details.Errors)
+	}
+	if details.Result["final answer"] != "The capital of France is Paris.\nIt has been since 987." || details.Result["thought"] != "" {
+		t.Errorf("unexpected result: %#v", details.Result)
+	}
+	if len(details.Repairs) != 1 || details.Repairs[0].Kind != RepairFallbackLabel {
+		t.Errorf("unexpected repairs: %#v", details.Repairs)
+	}
+
+	// Labeled output is parsed normally
+	result, _ := parser.Parse("Thought: easy\nFinal Answer: Paris")
+	if result["final answer"] != "Paris" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	if _, err := NewParser([]Label{{Name: "Thought"}}, WithFallbackLabel("Answer")); err == nil {
+		t.Errorf("expected undefined fallback label error")
+	}
+}
+
+// TestParseBlocksFallback checks the sentinel errors and salvaged results without blocks.
+func TestParseBlocksFallback(t *testing.T) {
+	noBlockLabel, err := NewParser([]Label{{Name: "Thought"}, {Name: "Result"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	blocks, err := noBlockLabel.ParseBlocksDetailed("Thought: hmm\nResult: 42")
+	if !errors.Is(err, ErrNoBlockStartLabel) || len(blocks) != 1 || blocks[0].Result["result"] != "42" {
+		t.Errorf("unexpected fallback: %v %#v", err, blocks)
+	}
+
+	parser, err := NewParser([]Label{{Name: "Task", IsBlockStart: true}, {Name: "Result"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	results, errList := parser.ParseBlocks("Result: 42")
+	if len(results) != 1 || results[0]["result"] != "42" {
+		t.Errorf("unexpected salvaged results: %#v", results)
+	}
+	if len(errList) != 1 || errList[0] != ErrNoBlocks.Error() {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+
+	// The salvaged result goes through ParseDetailed, fallback label included
+	fallback, err := NewParser([]Label{{Name: "Task", IsBlockStart: true}, {Name: "Result"}}, WithFallbackLabel("Result"))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	blocks, err = fallback.ParseBlocksDetailed("Just 42.")
+	expected := []Repair{{Kind: RepairFallbackLabel, Label: "result", Before: "Just 42.", After: "Just 42."}}
+	if !errors.Is(err, ErrNoBlocks) || len(blocks) != 1 || blocks[0].Result["result"] != "Just 42." || !reflect.DeepEqual(blocks[0].Repairs, expected) {
+		t.Errorf("unexpected salvaged blocks: %v %#v", err, blocks)
+	}
+}
+
+// TestParseBlocksRepairs checks that each block reports the repairs made to its lines.
+func TestParseBlocksRepairs(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Task", IsBlockStart: true}, {Name: "Note"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	blocks, err := parser.ParseBlocksDetailed("Task: one\nNote: use `grep`\nTask: two\nＮｏｔｅ： fine\nTask: three\nNote: {\"open\":")
+	if err != nil || len(blocks) != 3 {
+		t.Fatalf("unexpected blocks: %v %#v", err, blocks)
+	}
+	expected := [][]Repair{
+		{{Kind: RepairInlineCode, Before: "`grep`", After: "grep"}},
+		{{Kind: RepairUnicode, Label: "note", Before: "Ｎｏｔｅ： ", After: "Note: "}},
+		{{Kind: RepairUnclosedStructure, Label: "note", Before: `{"open":`}},
+	}
+	for i, block := range blocks {
+		if !reflect.DeepEqual(block.Repairs, expected[i]) {
+			t.Errorf("block %d: unexpected repairs: %#v", i, block.Repairs)
+		}
+	}
+}
+
+// TestNonNilReturns checks that Parse and ParseBlocks never return nil.
+func TestNonNilReturns(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Task", IsBlockStart: true}, {Name: "Result"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	for _, text := range []string{"", "Task: one\nResult: 1"} {
+		result, errList := parser.Parse(text)
+		if result == nil || errList == nil {
+			t.Errorf("Parse(%q) returned nil: %v %v", text, result, errList)
+		}
+		results, errList := parser.ParseBlocks(text)
+		if len(results) == 0 || results[0] == nil || errList == nil {
+			t.Errorf("ParseBlocks(%q) returned nil: %v %v", text, results, errList)
+		}
+	}
+}
+
+// TestSystemLabels checks that model-invented observations are stripped with a warning.
+func TestSystemLabels(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought"}, {Name: "Tool"}, {Name: "Observation"},
+	}, WithSystemLabels("Observation"))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	details := parser.ParseDetailed("Thought: search first\nTool: search\nObservation: Paris is the capital\nThought: done")
+	if len(details.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", details.Errors)
+	}
+	if details.Result["observation"] != "" || details.Result["tool"] != "search" {
+		t.Errorf("unexpected result: %#v", details.Result)
+	}
+	if len(details.Warnings) != 1 || details.Warnings[0].Kind != KindSystemLabel ||
+		details.Warnings[0].Message != "'observation' is a system-only label; 1 model-produced value(s) stripped" {
+		t.Errorf("unexpected warnings: %#v", details.Warnings)
+	}
+	// Warnings are not reported by Parse
+	if _, errList := parser.Parse("Observation: made up"); len(errList) > 0 {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+	if _, err := NewParser([]Label{{Name: "Thought"}}, WithSystemLabels("Observation")); err == nil {
+		t.Errorf("expected undefined system label error")
+	}
+}
+
+// TestValueOnFollowingLines checks values starting on the line after their label.
+func TestValueOnFollowingLines(t *testing.T) {
+	labels := []Label{{Name: "Notes"}, {Name: "Tool"}, {Name: "Arguments", IsJSON: true}}
+	parser, err := NewParser(labels)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	// A fenced block after an empty label is its value, even where it looks like labels
+	input := "Tool: run\nArguments:\n```json\n{\"script\": \"a\",\n\"argv\": [\"x\"]}\n```\nNotes:\n```\nTool: not a label\n```"
+	result, errList := parser.Parse(input)
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	expected := map[string]interface{}{
+		"tool":      "run",
+		"arguments": map[string]interface{}{"script": "a", "argv": []interface{}{"x"}},
+		"notes":     "Tool: not a label",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", result, expected)
+	}
+
+	// With WithIndentedValues, indented lines belong to the value and the
+	// first unindented non-label line closes it
+	indented, err := NewParser(labels, WithIndentedValues())
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, _ = indented.Parse("Notes:\n  Tool: quoted in notes\n\n  second paragraph\nstray text\nTool: run")
+	expected = map[string]interface{}{
+		"notes":     "Tool: quoted in notes\n\n  second paragraph",
+		"tool":      "run",
+		"arguments": "",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", result, expected)
+	}
+}
+
+// TestHeredocValues checks that heredoc values are captured verbatim.
+func TestHeredocValues(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Script"}, {Name: "Thought"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Script: <<'EOF'\n#!/bin/sh\nThought: echo `date`\n```\nEOF\nThought: done\nScript: <<END\nrm -rf build"
+	details := parser.ParseDetailed(input)
+	expected := map[string]interface{}{
+		"script":  []interface{}{"#!/bin/sh\nThought: echo `date`\n```", "rm -rf build"},
+		"thought": "done",
+	}
+	if !reflect.DeepEqual(map[string]interface{}(details.Result), expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", details.Result, expected)
+	}
+	if len(details.Repairs) != 1 || details.Repairs[0].Kind != RepairMissingEndMarker || details.Repairs[0].Before != "END" {
+		t.Errorf("unexpected repairs: %#v", details.Repairs)
+	}
+}
+
+// TestTripleQuotedValues checks that triple-quoted values are captured verbatim.
+func TestTripleQuotedValues(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Summary"}, {Name: "Thought"}, {Name: "Data", IsJSON: true}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Summary: \"\"\"First line\nThought: not a label\n  `kept`\"\"\" trailing\nThought: real\nData: '''\n{\"a\": 1}\n'''\nSummary: \"\"\"short\"\"\"\nThought: \"\"\"never closed\nSummary: inside"
+	details := parser.ParseDetailed(input)
+	expected := map[string]interface{}{
+		"summary": []interface{}{"First line\nThought: not a label\n  `kept`", "short"},
+		"thought": []interface{}{"real", "never closed\nSummary: inside"},
+		"data":    map[string]interface{}{"a": 1.0},
+	}
+	if !reflect.DeepEqual(map[string]interface{}(details.Result), expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", details.Result, expected)
+	}
+	var kinds []RepairKind
+	for _, repair := range details.Repairs {
+		kinds = append(kinds, repair.Kind)
+	}
+	if !reflect.DeepEqual(kinds, []RepairKind{RepairQuoted, RepairQuoted, RepairQuoted, RepairMissingEndMarker}) {
+		t.Errorf("unexpected repairs: %#v", details.Repairs)
+	}
+}
+
+// TestFenceRouting checks that tagged code fences go to the label claiming their language.
+func TestFenceRouting(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Explanation"},
+		{Name: "Arguments", IsJSON: true},
+		{Name: "Code", FenceLanguages: []string{"python", "py"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	text := "Explanation: First the arguments, then the script.\n```json\n{\"n\": 3}\n```\nAnd the code:\n```python\nfor i in range(3):\n    print(i)\n```"
+	details := parser.ParseDetailed(text)
+	if len(details.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", details.Errors)
+	}
+	expected := map[string]interface{}{
+		"explanation": "First the arguments, then the script.\nAnd the code:",
+		"arguments":   map[string]interface{}{"n": float64(3)},
+		"code":        "for i in range(3):\n    print(i)",
+	}
+	if !deepEqual(map[string]interface{}(details.Result), expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", details.Result, expected)
+	}
+	if len(details.Repairs) != 2 || details.Repairs[0].Kind != RepairFenceRouted || details.Repairs[1].Label != "code" {
+		t.Errorf("unexpected repairs: %#v", details.Repairs)
+	}
+
+	// A fence right after a label stays with it, whatever its language
+	result, _ := parser.Parse("Explanation:\n```python\nx = 1\n```")
+	if result["explanation"] != "x = 1" || result["code"] != "" {
+		t.Errorf("unexpected awaited fence result: %#v", result)
+	}
+	// Untagged fences are still stripped into the current value
+	result, _ = parser.Parse("Explanation: see\n```\nplain\n```")
+	if result["explanation"] != "see\nplain" {
+		t.Errorf("unexpected untagged fence result: %#v", result)
+	}
+
+	_, err = NewParser([]Label{{Name: "A", FenceLanguages: []string{"go"}}, {Name: "B", FenceLanguages: []string{"Go"}}})
+	if err == nil || err.Error() != "Fence language 'go' of 'b' is claimed by label 'a'" {
+		t.Errorf("expected fence language collision, got %v", err)
+	}
+}
+
+// TestBlankLinesInValues checks that paragraph breaks inside multiline values
+// survive parsing, whatever way the value was written.
+func TestBlankLinesInValues(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Thought"}, {Name: "Answer"}, {Name: "Notes", EndMarker: "END"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	tests := []struct {
+		text     string
+		label    string
+		expected string
+	}{
+		{"Answer: one\n\ntwo\n\n\nthree\nThought: done", "answer", "one\n\ntwo\n\n\nthree"},
+		{"Answer:\n\nfirst paragraph\n\nsecond paragraph\n\n", "answer", "first paragraph\n\nsecond paragraph"},
+		{"Answer: one\r\n  \r\ntwo\r\n", "answer", "one\n\ntwo"},
+		{"Notes: one\n\nThought: kept\nEND", "notes", "one\n\nThought: kept"},
+		{"Answer: \"\"\"\none\n\ntwo\n\"\"\"", "answer", "one\n\ntwo"},
+	}
+	for _, test := range tests {
+		result, errList := parser.Parse(test.text)
+		if len(errList) > 0 {
+			t.Errorf("unexpected errors for %q: %v", test.text, errList)
+		}
+		if result[test.label] != test.expected {
+			t.Errorf("expected %q for %q, got %q", test.expected, test.text, result[test.label])
+		}
+	}
+}
+
+// TestParseE checks that ParseE joins errors and ignores warnings.
+func TestParseE(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Action", Required: true},
+		{Name: "Input", IsJSON: true},
+		{Name: "Observation", Source: SourceSystem},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, err := parser.ParseE("Action: search\nObservation: invented")
+	if err != nil {
+		t.Errorf("expected warnings not to fail the parse, got %v", err)
+	}
+	if result["action"] != "search" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	result, err = parser.ParseE("Input: {bad")
+	if !errors.Is(err, ErrMissingRequired) || !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("expected both sentinels, got %v", err)
+	}
+	var parseErr ParseError
+	if !errors.As(err, &parseErr) || parseErr.Kind != KindJSON {
+		t.Errorf("expected the first error to be a JSON error, got %#v", parseErr)
+	}
+	_, errList := parser.Parse("Input: {bad")
+	if err.Error() != strings.Join(errList, "\n") || result["input"] != "{bad" {
+		t.Errorf("unexpected error or result: %v, %#v", err, result)
+	}
+}
+
+// TestAmbiguousLabels checks that overlapping labels resolve to the longest
+// match whatever their declaration order, with a warning.
+func TestAmbiguousLabels(t *testing.T) {
+	text := "Step: plan\nStep-2: act"
+	for _, labels := range [][]Label{
+		{{Name: "Step"}, {Name: "Step-2"}},
+		{{Name: "Step-2"}, {Name: "Step"}},
+	} {
+		parser, err := NewParser(labels)
+		if err != nil {
+			t.Fatalf("failed to create parser: %v", err)
+		}
+		details := parser.ParseDetailed(text)
+		if details.Result["step"] != "plan" || details.Result["step-2"] != "act" {
+			t.Errorf("unexpected result: %#v", details.Result)
+		}
+		expected := ParseError{
+			Kind:    KindAmbiguousLabel,
+			Label:   "step-2",
+			Message: "Line 'Step-2: act' also matches 'step'; 'step-2' was taken as the longest match",
+			Params:  map[string]string{"text": "Step-2: act", "others": "'step'"},
+		}
+		if len(details.Warnings) != 1 || !reflect.DeepEqual(details.Warnings[0], expected) {
+			t.Errorf("unexpected warnings: %#v", details.Warnings)
+		}
+	}
+}
+
+// TestOverlappingLabelsAnyOrder checks that schemas with overlapping label
+// names parse alike in every declaration order.
+func TestOverlappingLabelsAnyOrder(t *testing.T) {
+	labels := []Label{
+		{Name: "Action"},
+		{Name: "Action Input", IsJSON: true},
+		{Name: "Action Input Format", Aliases: []string{"Format"}},
+		{Name: "Plan"},
+		{Name: "Plan-B"},
+	}
+	text := "Plan: look it up\nAction: search\nAction Input: {\"q\": \"Action: no\"}\nACTION  INPUT  FORMAT ~ json\nPlan-B: ask"
+	expected := Result{
+		"action":              "search",
+		"action input":        map[string]interface{}{"q": "Action: no"},
+		"action input format": "json",
+		"plan":                "look it up",
+		"plan-b":              "ask",
+	}
+	var permute func(n int)
+	permute = func(n int) {
+		if n == 1 {
+			parser, err := NewParser(labels)
+			if err != nil {
+				t.Fatalf("failed to create parser: %v", err)
+			}
+			details := parser.ParseDetailed(text)
+			if !reflect.DeepEqual(details.Result, expected) || len(details.Errors) != 0 {
+				t.Errorf("order %v: unexpected result: %#v, %v", labels, details.Result, details.Errors)
+			}
+			if len(details.Warnings) != 1 || details.Warnings[0].Label != "plan-b" {
+				t.Errorf("order %v: unexpected warnings: %#v", labels, details.Warnings)
+			}
+			return
+		}
+		for i := 0; i < n; i++ {
+			permute(n - 1)
+			if n%2 == 0 {
+				labels[i], labels[n-1] = labels[n-1], labels[i]
+			} else {
+				labels[0], labels[n-1] = labels[n-1], labels[0]
+			}
+		}
+	}
+	permute(len(labels))
+}
+
+// TestUnicodeNormalization checks that visually identical labels match, unless
+// the parser is byte-exact.
+func TestUnicodeNormalization(t *testing.T) {
+	labels := []Label{{Name: "Action", RequiredWith: []string{"Re\u0301sume\u0301"}}, {Name: "Re\u0301sume\u0301"}}
+	text := "Ａｃｔｉｏｎ：\u3000search\nRésumé:\u00a0two\u00a0words"
+	parser, err := NewParser(labels)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	details := parser.ParseDetailed(text)
+	// Labels match normalized, while values are kept byte for byte
+	if details.Result["action"] != "search" || details.Result["résumé"] != "two\u00a0words" || len(details.Errors) != 0 {
+		t.Errorf("unexpected result: %#v, %v", details.Result, details.Errors)
+	}
+	expected := []Repair{
+		{Kind: RepairUnicode, Label: "action", Before: "Ａｃｔｉｏｎ：\u3000", After: "Action: "},
+		{Kind: RepairUnicode, Label: "résumé", Before: "Résumé:\u00a0", After: "Résumé: "},
+	}
+	if !reflect.DeepEqual(details.Repairs, expected) {
+		t.Errorf("unexpected repairs: %#v", details.Repairs)
+	}
+	if result, _ := parser.Parse("Action: 検索：「東京」\u3000ｘ"); result["action"] != "検索：「東京」\u3000ｘ" {
+		t.Errorf("expected full-width text in values to survive: %#v", result)
+	}
+	if details := parser.ParseDetailed("Action: plain"); len(details.Repairs) != 0 {
+		t.Errorf("expected no repair for plain text: %#v", details.Repairs)
+	}
+
+	exact, _ := NewParser(labels, WithExactUnicode())
+	details = exact.ParseDetailed(text)
+	if details.Result["action"] != "" || details.Result["re\u0301sume\u0301"] != "" || len(details.Repairs) != 0 {
+		t.Errorf("expected nothing to match byte for byte: %#v", details.Result)
+	}
+	if result, _ := exact.Parse("Re\u0301sume\u0301: kept\u00a0exact"); result["re\u0301sume\u0301"] != "kept\u00a0exact" {
+		t.Errorf("unexpected exact result: %#v", result)
+	}
+
+	// Label names given to options are normalized like the labels
+	if _, err := NewParser(labels, WithGrounding("source", []string{"Re\u0301sume\u0301"}, 0), WithHTMLCapture("cv", "Re\u0301sume\u0301"), WithSystemLabels("Ａｃｔｉｏｎ")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// TestDeclaredKeys checks that the result keeps the casing of the schema, with
+// group records, and that Keys maps lowercase keys to declared ones.
+func TestDeclaredKeys(t *testing.T) {
+	labels := []Label{
+		{Name: "Action"},
+		{Name: "Action Input", IsJSON: true},
+		{Name: "Task Name", Group: "Tasks"},
+		{Name: "Due", Group: "Tasks"},
+	}
+	parser, err := NewParser(labels, WithDeclaredKeys())
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, errs := parser.Parse("action: search\nACTION INPUT: {\"q\": \"go\"}\nTask Name: write\nDue: today\nTask Name: test")
+	expected := map[string]interface{}{
+		"Action":       "search",
+		"Action Input": map[string]interface{}{"q": "go"},
+		"Tasks": []map[string]interface{}{
+			{"Task Name": "write", "Due": "today"},
+			{"Task Name": "test"},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) || len(errs) != 0 {
+		t.Errorf("unexpected result: %#v, %v", result, errs)
+	}
+	if value, ok := Result(result).Get("action input"); !ok || value == nil {
+		t.Errorf("expected Get to find the declared key")
+	}
+
+	keys := parser.Keys()
+	if keys["action input"] != "Action Input" || keys["tasks"] != "Tasks" || len(keys) != 5 {
+		t.Errorf("unexpected keys: %#v", keys)
+	}
+	keys["action"] = "changed"
+	if parser.Keys()["action"] != "Action" {
+		t.Error("expected Keys to return a copy")
+	}
+}
+
+// TestUnicodeDuplicateLabels checks that names normalizing alike are rejected
+// as duplicates, unless the parser is byte-exact.
+func TestUnicodeDuplicateLabels(t *testing.T) {
+	labels := []Label{{Name: "Action"}, {Name: "Ａｃｔｉｏｎ"}}
+	if _, err := NewParser(labels); err == nil {
+		t.Error("expected full-width and plain names to be duplicates")
+	}
+	if _, err := NewParser([]Label{{Name: "Action"}, {Name: "Tool", Aliases: []string{"ａｃｔｉｏｎ"}}}); err == nil {
+		t.Error("expected a full-width alias to collide with the name")
+	}
+	if _, err := NewParser(labels, WithExactUnicode()); err != nil {
+		t.Errorf("unexpected error for the byte-exact parser: %v", err)
+	}
+	if len(labels[1].Name) == len("Action") {
+		t.Error("expected the caller's labels to be left as they are")
+	}
+}
+
+// TestManyUnclosedStructures checks that values left open one after another
+// are recovered without quadratic rematching.
+func TestManyUnclosedStructures(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Thought"}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	start := time.Now()
+	details := parser.ParseDetailed(strings.Repeat("Thought: {\n", 8000))
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("parsing took %v", elapsed)
+	}
+	if values, _ := details.Result["thought"].([]interface{}); len(values) != 8000 {
+		t.Errorf("expected 8000 values, got %T", details.Result["thought"])
+	}
+	if len(details.Repairs) != 16 {
+		t.Errorf("expected 16 repairs, got %d", len(details.Repairs))
+	}
+}
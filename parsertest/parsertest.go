@@ -0,0 +1,206 @@
+// Package parsertest provides helpers for golden tests of parser schemas, using
+// the same asset convention as this repository: for each case <name>, a
+// <name>_input.txt file holds the LLM output, <name>_output.json the expected
+// result, and an optional <name>_errors.json the expected error strings.
+package parsertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+)
+
+// Golden is a single asset-driven test case.
+type Golden struct {
+	Name   string // Case name, the shared file name prefix
+	Input  string // Path of the input text file
+	Output string // Path of the expected output JSON file
+	Errors string // Path of the expected errors JSON file, or "" if the case has none
+}
+
+// Discover finds every golden case in dir, sorted by name. A case is any
+// <name>_input.txt file with a matching <name>_output.json.
+func Discover(dir string) ([]Golden, error) {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*_input.txt"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(inputs)
+	var cases []Golden
+	for _, input := range inputs {
+		prefix := strings.TrimSuffix(input, "_input.txt")
+		golden := Golden{Name: filepath.Base(prefix), Input: input, Output: prefix + "_output.json"}
+		if _, err := os.Stat(golden.Output); err != nil {
+			continue
+		}
+		if _, err := os.Stat(prefix + "_errors.json"); err == nil {
+			golden.Errors = prefix + "_errors.json"
+		}
+		cases = append(cases, golden)
+	}
+	return cases, nil
+}
+
+// Run parses the case input with p and reports any mismatch through t.
+// An expected output holding a JSON array is compared against ParseBlocks,
+// anything else against Parse. When the case has an errors file, the error
+// strings must match it regardless of order; otherwise no errors are allowed.
+func (g Golden) Run(t testing.TB, p *arkaineparser.Parser) {
+	t.Helper()
+	input, err := os.ReadFile(g.Input)
+	if err != nil {
+		t.Fatalf("failed to read input asset: %v", err)
+	}
+	var expected interface{}
+	if err := readJSON(g.Output, &expected); err != nil {
+		t.Fatalf("failed to read output asset: %v", err)
+	}
+
+	// Parse with the mode the expected output implies
+	var (
+		got  interface{}
+		errs []string
+	)
+	if _, isBlocks := expected.([]interface{}); isBlocks {
+		got, errs = p.ParseBlocks(string(input))
+	} else {
+		got, errs = p.Parse(string(input))
+	}
+	if diff := Diff(got, expected); diff != "" {
+		t.Errorf("%s: result mismatch (-expected +got):\n%s", g.Name, diff)
+	}
+
+	// Compare errors as an unordered list
+	expectedErrors := []string{}
+	if g.Errors != "" {
+		if err := readJSON(g.Errors, &expectedErrors); err != nil {
+			t.Fatalf("failed to read errors asset: %v", err)
+		}
+	}
+	if !sameStrings(errs, expectedErrors) {
+		t.Errorf("%s: error mismatch.\nGot: %#v\nExpected: %#v", g.Name, errs, expectedErrors)
+	}
+}
+
+// Normalize converts a parse result into the plain JSON data model
+// (map[string]interface{}, []interface{}, float64, string, bool, nil), so values
+// built in Go compare equal to values decoded from JSON assets.
+// Values that cannot be encoded as JSON are returned unchanged.
+func Normalize(v interface{}) interface{} {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return v
+	}
+	return normalized
+}
+
+// Equal reports whether got and expected are equal after normalization.
+func Equal(got, expected interface{}) bool {
+	return reflect.DeepEqual(Normalize(got), Normalize(expected))
+}
+
+// Diff returns a readable, line-per-difference description of how got differs
+// from expected after normalization, or "" when they are equal. Each line names
+// the path of the difference, e.g. `- [1].input.text: "a"`.
+func Diff(got, expected interface{}) string {
+	var lines []string
+	diffValues(&lines, "", Normalize(expected), Normalize(got))
+	return strings.Join(lines, "\n")
+}
+
+// diffValues appends the differences between expected and got under path.
+func diffValues(lines *[]string, path string, expected, got interface{}) {
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		if g, ok := got.(map[string]interface{}); ok {
+			// Walk the union of keys in sorted order
+			keys := make(map[string]bool)
+			for key := range exp {
+				keys[key] = true
+			}
+			for key := range g {
+				keys[key] = true
+			}
+			sorted := make([]string, 0, len(keys))
+			for key := range keys {
+				sorted = append(sorted, key)
+			}
+			sort.Strings(sorted)
+			for _, key := range sorted {
+				expValue, inExp := exp[key]
+				gotValue, inGot := g[key]
+				switch {
+				case !inGot:
+					*lines = append(*lines, "- "+joinPath(path, key)+": "+render(expValue))
+				case !inExp:
+					*lines = append(*lines, "+ "+joinPath(path, key)+": "+render(gotValue))
+				default:
+					diffValues(lines, joinPath(path, key), expValue, gotValue)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if g, ok := got.([]interface{}); ok && len(g) == len(exp) {
+			for i := range exp {
+				diffValues(lines, fmt.Sprintf("%s[%d]", path, i), exp[i], g[i])
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(expected, got) {
+		if path == "" {
+			path = "."
+		}
+		*lines = append(*lines, "- "+path+": "+render(expected), "+ "+path+": "+render(got))
+	}
+}
+
+// joinPath appends a map key to a diff path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// render formats a normalized value for a diff line.
+func render(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%#v", v)
+	}
+	return string(encoded)
+}
+
+// readJSON decodes the JSON file at path into v.
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// sameStrings reports whether a and b hold the same strings in any order.
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string(nil), a...)
+	sortedB := append([]string(nil), b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	return reflect.DeepEqual(sortedA, sortedB)
+}
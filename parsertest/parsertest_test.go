@@ -0,0 +1,59 @@
+package parsertest
+
+import (
+	"testing"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+)
+
+// TestGoldenAssets runs the repository's own assets through Discover and Run.
+func TestGoldenAssets(t *testing.T) {
+	schemas := map[string][]arkaineparser.Label{
+		"mixed_case_multiline": {
+			{Name: "Context"}, {Name: "Intention"}, {Name: "Role"}, {Name: "Action"}, {Name: "Outcome"}, {Name: "Notes"},
+		},
+		"json_and_malformed": {
+			{Name: "Config", IsJSON: true}, {Name: "Data", IsJSON: true}, {Name: "Description"},
+		},
+		"required_dependency": {
+			{Name: "FieldA"}, {Name: "FieldB", RequiredWith: []string{"FieldA"}},
+		},
+		"block_parsing": {
+			{Name: "Task", IsBlockStart: true}, {Name: "Input", IsJSON: true}, {Name: "Result"},
+		},
+	}
+	cases, err := Discover("../conformance/corpus")
+	if err != nil {
+		t.Fatalf("failed to discover assets: %v", err)
+	}
+	ran := 0
+	for _, golden := range cases {
+		labels, ok := schemas[golden.Name]
+		if !ok {
+			continue
+		}
+		parser, err := arkaineparser.NewParser(labels)
+		if err != nil {
+			t.Fatalf("failed to create parser: %v", err)
+		}
+		t.Run(golden.Name, func(t *testing.T) { golden.Run(t, parser) })
+		ran++
+	}
+	if ran != len(schemas) {
+		t.Errorf("expected %d golden cases, ran %d", len(schemas), ran)
+	}
+}
+
+// TestDiff checks normalization and the readable diff output.
+func TestDiff(t *testing.T) {
+	got := []map[string]interface{}{{"task": "a", "input": map[string]interface{}{"n": 1}}}
+	expected := []interface{}{map[string]interface{}{"task": "a", "input": map[string]interface{}{"n": 1.0}}}
+	if !Equal(got, expected) {
+		t.Errorf("expected normalized values to be equal")
+	}
+	diff := Diff(map[string]interface{}{"a": "x", "c": true}, map[string]interface{}{"a": "y", "b": 1})
+	expectedDiff := "- a: \"y\"\n+ a: \"x\"\n- b: 1\n+ c: true"
+	if diff != expectedDiff {
+		t.Errorf("diff mismatch.\nGot:\n%s\nExpected:\n%s", diff, expectedDiff)
+	}
+}
@@ -0,0 +1,187 @@
+package arkaineparser
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hlfshell/go-arkaine-parser/parsec"
+)
+
+// Plan is a set of numbered steps and the steps each one depends on, as
+// written by planner agents:
+//
+//	Step 1: Fetch the sales data
+//	Step 2: Fetch the weather data
+//	Step 3 (depends on: 1, 2): Correlate sales with the weather
+type Plan struct {
+	Steps []PlanStep `json:"steps"`
+}
+
+// PlanStep is a single step of a Plan.
+type PlanStep struct {
+	ID          int    `json:"id"`
+	Description string `json:"description"`
+	DependsOn   []int  `json:"depends_on"`
+}
+
+// planStepPattern matches the line starting a step, capturing its number, its
+// dependency list and the start of its description. Markdown list markers and
+// bold are tolerated, and "after" or "requires" may replace "depends on".
+var planStepPattern = regexp.MustCompile(`(?i)^\s*(?:[-*]\s+)?(?:\*\*)?step\s+#?(\d+)\s*(?:\(\s*(?:depends\s+on|after|requires)\s*:?\s*([^)]*)\))?(?:\*\*)?\s*[:.\-]+(?:\*\*)?\s*(.*)$`)
+
+// stepNumberPattern matches the step numbers in a dependency list such as
+// "1, 2", "steps 1 and 2" or "#1".
+var stepNumberPattern = regexp.MustCompile(`\d+`)
+
+// ParsePlan parses the steps of a plan and checks that they form a DAG. Lines
+// after a step line that do not start another step continue its description;
+// lines before the first step are ignored. Dependencies are written in
+// parentheses after the step number, and "none" means no dependencies.
+// Returns the plan and an error joining every problem found: duplicate step
+// numbers, dependencies on undefined steps, and dependency cycles.
+func ParsePlan(text string) (Plan, error) {
+	cleaned, _ := cleanText(text)
+	var plan Plan
+	var errList []error
+	seen := make(map[int]bool)
+	for _, line := range parsec.SplitLines(cleaned) {
+		m := planStepPattern.FindStringSubmatch(line)
+		if m == nil {
+			if len(plan.Steps) > 0 && strings.TrimSpace(line) != "" {
+				step := &plan.Steps[len(plan.Steps)-1]
+				step.Description = strings.TrimSpace(step.Description + "\n" + line)
+			}
+			continue
+		}
+		id, _ := strconv.Atoi(m[1])
+		if seen[id] {
+			errList = append(errList, errors.New("Step "+m[1]+" is defined more than once"))
+		}
+		seen[id] = true
+		step := PlanStep{ID: id, Description: strings.TrimSpace(m[3])}
+		for _, dep := range stepNumberPattern.FindAllString(m[2], -1) {
+			depID, _ := strconv.Atoi(dep)
+			if !slices.Contains(step.DependsOn, depID) {
+				step.DependsOn = append(step.DependsOn, depID)
+			}
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	for _, step := range plan.Steps {
+		for _, dep := range step.DependsOn {
+			if !seen[dep] {
+				errList = append(errList, errors.New("Step "+strconv.Itoa(step.ID)+" depends on undefined step "+strconv.Itoa(dep)))
+			}
+		}
+	}
+	if cycle := plan.findCycle(); cycle != nil {
+		path := make([]string, len(cycle))
+		for i, id := range cycle {
+			path[i] = strconv.Itoa(id)
+		}
+		errList = append(errList, errors.New("Plan has a dependency cycle: "+strings.Join(path, " -> ")))
+	}
+	return plan, errors.Join(errList...)
+}
+
+// Step returns the step with the given number.
+func (p Plan) Step(id int) (PlanStep, bool) {
+	for _, step := range p.Steps {
+		if step.ID == id {
+			return step, true
+		}
+	}
+	return PlanStep{}, false
+}
+
+// Dependents returns the numbers of the steps that depend directly on step id.
+func (p Plan) Dependents(id int) []int {
+	var dependents []int
+	for _, step := range p.Steps {
+		if slices.Contains(step.DependsOn, id) {
+			dependents = append(dependents, step.ID)
+		}
+	}
+	return dependents
+}
+
+// Levels groups the step numbers into levels that can run in order, with the
+// steps of each level runnable in parallel: every step comes after all the
+// steps it depends on. Dependencies on undefined steps are ignored.
+// Returns an error if the steps contain a dependency cycle.
+func (p Plan) Levels() ([][]int, error) {
+	if p.findCycle() != nil {
+		return nil, errors.New("Plan has a dependency cycle")
+	}
+	done := make(map[int]bool)
+	var levels [][]int
+	for len(done) < len(p.Steps) {
+		var level []int
+		for _, step := range p.Steps {
+			if done[step.ID] || slices.Contains(level, step.ID) {
+				continue
+			}
+			ready := true
+			for _, dep := range step.DependsOn {
+				if _, ok := p.Step(dep); ok && !done[dep] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				level = append(level, step.ID)
+			}
+		}
+		if len(level) == 0 {
+			// Only duplicates of finished steps remain
+			break
+		}
+		for _, id := range level {
+			done[id] = true
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// findCycle returns the step numbers of a dependency cycle, starting and
+// ending with the same step, or nil if the plan is acyclic.
+func (p Plan) findCycle() []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[int]int)
+	var stack []int
+	var visit func(id int) []int
+	visit = func(id int) []int {
+		step, ok := p.Step(id)
+		if !ok || state[id] == visited {
+			return nil
+		}
+		if state[id] == visiting {
+			start := slices.Index(stack, id)
+			return append(slices.Clone(stack[start:]), id)
+		}
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range step.DependsOn {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+		return nil
+	}
+	for _, step := range p.Steps {
+		if cycle := visit(step.ID); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
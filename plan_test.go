@@ -0,0 +1,48 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParsePlan checks parsing of steps and dependencies into a DAG.
+func TestParsePlan(t *testing.T) {
+	input := "Here is my plan:\n- **Step 1:** Fetch the sales data\n- **Step 2 (depends on: none):** Fetch the weather\n  for the same period\n" +
+		"- **Step 3 (depends on: 1, 2):** Correlate them\nStep 4 (after: step 3): Write the report"
+	plan, err := ParsePlan(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []PlanStep{
+		{ID: 1, Description: "Fetch the sales data"},
+		{ID: 2, Description: "Fetch the weather\n  for the same period"},
+		{ID: 3, Description: "Correlate them", DependsOn: []int{1, 2}},
+		{ID: 4, Description: "Write the report", DependsOn: []int{3}},
+	}
+	if !reflect.DeepEqual(plan.Steps, expected) {
+		t.Errorf("steps mismatch.\nGot: %#v\nExpected: %#v", plan.Steps, expected)
+	}
+	levels, err := plan.Levels()
+	if err != nil || !reflect.DeepEqual(levels, [][]int{{1, 2}, {3}, {4}}) {
+		t.Errorf("unexpected levels: %v %v", levels, err)
+	}
+	if dependents := plan.Dependents(1); !reflect.DeepEqual(dependents, []int{3}) {
+		t.Errorf("unexpected dependents: %v", dependents)
+	}
+
+	// Every structural problem is reported
+	_, err = ParsePlan("Step 1 (depends on: 3): a\nStep 2 (depends on: 1): b\nStep 3 (depends on: 2): c\nStep 2: d\nStep 4 (depends on: 9): e")
+	if err == nil {
+		t.Fatalf("expected plan errors")
+	}
+	for _, problem := range []string{
+		"Step 2 is defined more than once",
+		"Step 4 depends on undefined step 9",
+		"Plan has a dependency cycle: 1 -> 3 -> 2 -> 1",
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected error to mention %q, got:\n%v", problem, err)
+		}
+	}
+}
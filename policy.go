@@ -0,0 +1,254 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PolicyAction is what a Policy decides for a tool call.
+type PolicyAction string
+
+const (
+	PolicyAllow     PolicyAction = "allow"     // The call may run as it is
+	PolicyDeny      PolicyAction = "deny"      // The call must not run
+	PolicyTransform PolicyAction = "transform" // The call may run as rewritten
+)
+
+// PolicyDecision is the verdict of a Policy on a tool call.
+type PolicyDecision struct {
+	Action PolicyAction `json:"action"`
+	Reason string       `json:"reason,omitempty"` // Why the call was denied or rewritten
+	Call   *ToolCall    `json:"call,omitempty"`   // The rewritten call, for PolicyTransform
+}
+
+// Policy decides whether a parsed tool call may run, e.g. restricting tools
+// or the hosts they reach. Checking calls once, where they are parsed, is
+// safer than trusting every Executor to check its own.
+type Policy interface {
+	Evaluate(call ToolCall) PolicyDecision
+}
+
+// PolicyFunc adapts a function to the Policy interface.
+type PolicyFunc func(call ToolCall) PolicyDecision
+
+// Evaluate calls f.
+func (f PolicyFunc) Evaluate(call ToolCall) PolicyDecision {
+	return f(call)
+}
+
+// Allow returns a decision allowing a call.
+func Allow() PolicyDecision {
+	return PolicyDecision{Action: PolicyAllow}
+}
+
+// Deny returns a decision denying a call for reason.
+func Deny(reason string) PolicyDecision {
+	return PolicyDecision{Action: PolicyDeny, Reason: reason}
+}
+
+// Transform returns a decision running call instead, rewritten for reason.
+func Transform(call ToolCall, reason string) PolicyDecision {
+	return PolicyDecision{Action: PolicyTransform, Reason: reason, Call: &call}
+}
+
+// ApplyPolicies evaluates policies on call in order. Each policy sees the call
+// as rewritten by the ones before it; the first denial stops the evaluation.
+// Returns the call to run, the decisions that denied or rewrote it, and
+// whether it may run.
+func ApplyPolicies(call ToolCall, policies ...Policy) (ToolCall, []PolicyDecision, bool) {
+	var decisions []PolicyDecision
+	for _, policy := range policies {
+		decision := policy.Evaluate(call)
+		switch decision.Action {
+		case PolicyDeny:
+			return call, append(decisions, decision), false
+		case PolicyTransform:
+			if decision.Call != nil {
+				call = *decision.Call
+			}
+			decisions = append(decisions, decision)
+		}
+	}
+	return call, decisions, true
+}
+
+// AllowTools allows calls to the named tools only.
+func AllowTools(names ...string) Policy {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	return PolicyFunc(func(call ToolCall) PolicyDecision {
+		if !allowed[call.Name] {
+			return Deny("Tool '" + call.Name + "' is not allowed")
+		}
+		return Allow()
+	})
+}
+
+// MaxArgumentSize denies calls whose arguments take more than n bytes, as
+// JSON when they were converted and as written otherwise.
+func MaxArgumentSize(n int) Policy {
+	return PolicyFunc(func(call ToolCall) PolicyDecision {
+		size := len(call.Input)
+		if call.Arguments != nil {
+			encoded, _ := json.Marshal(call.Arguments)
+			size = len(encoded)
+		}
+		if size > n {
+			return Deny("Tool '" + call.Name + "' arguments take " + strconv.Itoa(size) + " bytes, more than " + strconv.Itoa(n))
+		}
+		return Allow()
+	})
+}
+
+// AllowDomains restricts the URLs found in the arguments of a call, at any
+// depth, to http and https URLs whose host is one of domains or a subdomain
+// of one. Words without a scheme that start with a host name, such as
+// "evil.com", "//evil.com", "evil.com/path" or "10.0.0.1:8080", are taken as
+// URLs too, so they cannot slip past the allowlist; this errs on the side of
+// denying, e.g. for file names like "notes.txt". Arguments are inspected in
+// the shape of their JSON, whatever their Go types, including object keys;
+// arguments with no JSON form are denied.
+func AllowDomains(domains ...string) Policy {
+	allowed := make([]string, len(domains))
+	for i, domain := range domains {
+		allowed[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+	}
+	return PolicyFunc(func(call ToolCall) PolicyDecision {
+		values := []string{call.Input}
+		if call.Arguments != nil {
+			// Arguments of any type are inspected in the shape of their JSON,
+			// and denied when they have none
+			encoded, err := json.Marshal(map[string]interface{}(call.Arguments))
+			if err != nil {
+				return Deny("Tool '" + call.Name + "' arguments cannot be inspected: " + err.Error())
+			}
+			var decoded interface{}
+			if err := json.Unmarshal(encoded, &decoded); err != nil {
+				return Deny("Tool '" + call.Name + "' arguments cannot be inspected: " + err.Error())
+			}
+			values = argumentStrings(decoded, nil)
+		}
+		// URLs are taken word by word, so text around them does not matter
+		for _, value := range values {
+			for _, word := range strings.Fields(value) {
+				raw, ok := urlCandidate(word)
+				if !ok {
+					continue
+				}
+				if reason := checkURL(raw, allowed); reason != "" {
+					return Deny("Tool '" + call.Name + "' " + reason)
+				}
+			}
+		}
+		return Allow()
+	})
+}
+
+// argumentStrings appends the strings within a decoded JSON value, at any
+// depth, to list, including the keys of objects.
+func argumentStrings(value interface{}, list []string) []string {
+	switch v := value.(type) {
+	case string:
+		list = append(list, v)
+	case map[string]interface{}:
+		// Keys are visited in order so the first denial is always the same
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			list = argumentStrings(v[key], append(list, key))
+		}
+	case []interface{}:
+		for _, item := range v {
+			list = argumentStrings(item, list)
+		}
+	}
+	return list
+}
+
+// urlCandidate returns word as a URL to check, with a scheme added when it
+// has none, and whether word may be a URL at all.
+func urlCandidate(word string) (string, bool) {
+	word = strings.Trim(word, `"'()<>[]{},;!?`)
+	word = strings.TrimRight(word, ".")
+	lower := strings.ToLower(word)
+	switch {
+	case word == "":
+		return "", false
+	case strings.Contains(word, "://"), strings.HasPrefix(lower, "http:"), strings.HasPrefix(lower, "https:"):
+		return word, true
+	case strings.HasPrefix(word, "//"):
+		return "http:" + word, true
+	}
+	// Without a scheme, a URL must start with a host, optionally with a port
+	host, rest := word, ""
+	if i := strings.IndexAny(word, "/?#"); i >= 0 {
+		host, rest = word[:i], word[i:]
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		if rest == "" {
+			// An email address, not a URL
+			return "", false
+		}
+		host = host[i+1:]
+	}
+	port := ""
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host, port = host[:i], host[i+1:]
+		if _, err := strconv.Atoi(port); err != nil {
+			return "", false
+		}
+	}
+	if !isHostName(host) && (port == "" || host == "") {
+		return "", false
+	}
+	return "http://" + word, true
+}
+
+// isHostName reports whether name is a dotted host name ending in a
+// top-level domain, such as "example.com", or an IPv4 address.
+func isHostName(name string) bool {
+	parts := strings.Split(name, ".")
+	if len(parts) < 2 {
+		return false
+	}
+	digits := 0
+	for _, part := range parts {
+		if part == "" || strings.Trim(part, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-") != "" {
+			return false
+		}
+		if strings.Trim(part, "0123456789") == "" {
+			digits++
+		}
+	}
+	tld := parts[len(parts)-1]
+	if digits == len(parts) {
+		return len(parts) == 4
+	}
+	return len(tld) >= 2 && strings.Trim(tld, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+}
+
+// checkURL reports why raw is not an allowed URL, or "".
+func checkURL(raw string, domains []string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "URL '" + raw + "' is malformed"
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "URL '" + raw + "' must use http or https"
+	}
+	host := strings.ToLower(parsed.Hostname())
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return ""
+		}
+	}
+	return "URL host '" + host + "' is not allowed"
+}
@@ -0,0 +1,83 @@
+package arkaineparser
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestPolicies checks the built-in policies and how decisions chain.
+func TestPolicies(t *testing.T) {
+	fetch := ToolCall{Name: "fetch", Arguments: Result{"url": "https://docs.example.com/a", "note": "see http://api.example.com"}}
+	tests := []struct {
+		policy Policy
+		call   ToolCall
+		reason string // "" when allowed
+	}{
+		{AllowTools("fetch", "search"), fetch, ""},
+		{AllowTools("search"), fetch, "Tool 'fetch' is not allowed"},
+		{AllowDomains("example.com"), fetch, ""},
+		{AllowDomains("Example.COM"), ToolCall{Name: "fetch", Input: "get https://example.com.evil.io/x"}, "Tool 'fetch' URL host 'example.com.evil.io' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Arguments: Result{"urls": []interface{}{"file:///etc/passwd"}}}, "Tool 'fetch' URL 'file:///etc/passwd' must use http or https"},
+		// URLs without a scheme cannot bypass the allowlist
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Arguments: Result{"url": "evil.com"}}, "Tool 'fetch' URL host 'evil.com' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Input: "//evil.com/x"}, "Tool 'fetch' URL host 'evil.com' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Input: "get EVIL.com/path?q=1"}, "Tool 'fetch' URL host 'evil.com' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Input: "(10.0.0.1:8080/admin)"}, "Tool 'fetch' URL host '10.0.0.1' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Input: "localhost:6379"}, "Tool 'fetch' URL host 'localhost' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Input: "https:evil.com"}, "Tool 'fetch' URL host '' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Input: "docs.example.com/a, version 1.2 by me@evil.com."}, ""},
+		// Arguments of the typed shapes parsed values take are inspected too
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Arguments: Result{"rows": [][]string{{"http://evil.com/x"}}}}, "Tool 'fetch' URL host 'evil.com' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Arguments: Result{"recs": []map[string]string{{"u": "evil.com"}}}}, "Tool 'fetch' URL host 'evil.com' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Arguments: Result{"items": []ListItem{{Text: "docs", Children: []ListItem{{Text: "//evil.com"}}}}}}, "Tool 'fetch' URL host 'evil.com' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Arguments: Result{"evil.com/x": true}}, "Tool 'fetch' URL host 'evil.com' is not allowed"},
+		{AllowDomains("example.com"), ToolCall{Name: "fetch", Arguments: Result{"hook": func() {}}}, "Tool 'fetch' arguments cannot be inspected: json: unsupported type: func()"},
+		{MaxArgumentSize(100), fetch, ""},
+		{MaxArgumentSize(10), ToolCall{Name: "search", Input: "a long query"}, "Tool 'search' arguments take 12 bytes, more than 10"},
+	}
+	for _, test := range tests {
+		decision := test.policy.Evaluate(test.call)
+		if test.reason == "" && decision.Action != PolicyAllow || test.reason != "" && decision != Deny(test.reason) {
+			t.Errorf("%#v: unexpected decision: %#v", test.call, decision)
+		}
+	}
+
+	// Transforms apply in order and the first denial stops the rest
+	lowercase := PolicyFunc(func(call ToolCall) PolicyDecision {
+		call.Name = strings.ToLower(call.Name)
+		return Transform(call, "lowercase the tool name")
+	})
+	call, decisions, allowed := ApplyPolicies(ToolCall{Name: "Search"}, lowercase, AllowTools("search"))
+	if !allowed || call.Name != "search" || len(decisions) != 1 || decisions[0].Reason != "lowercase the tool name" {
+		t.Errorf("unexpected result: %#v, %#v, %v", call, decisions, allowed)
+	}
+	var evaluated bool
+	last := PolicyFunc(func(ToolCall) PolicyDecision { evaluated = true; return Allow() })
+	if _, decisions, allowed := ApplyPolicies(fetch, AllowTools("search"), last); allowed || evaluated || len(decisions) != 1 {
+		t.Errorf("expected the denial to stop evaluation: %#v", decisions)
+	}
+}
+
+// TestRunStepPolicies checks that denied calls do not run.
+func TestRunStepPolicies(t *testing.T) {
+	parser := reactParser(t)
+	var ran []ToolCall
+	executor := ExecutorFunc(func(ctx context.Context, call ToolCall) (Observation, error) {
+		ran = append(ran, call)
+		return Observation{Text: "ok"}, nil
+	})
+	policies := WithStepPolicies(AllowTools("fetch"), AllowDomains("example.com"))
+	step, err := RunStep(context.Background(), parser, "Action: fetch\nAction Input: https://evil.io", executor, policies)
+	if err != nil || len(ran) != 0 || step.Observation != (Observation{Text: "Error: Tool call denied: Tool 'fetch' URL host 'evil.io' is not allowed", IsError: true}) {
+		t.Errorf("unexpected step: %#v, %v", step, err)
+	}
+	expected := []PolicyDecision{Deny("Tool 'fetch' URL host 'evil.io' is not allowed")}
+	if !reflect.DeepEqual(step.Decisions, expected) {
+		t.Errorf("unexpected decisions: %#v", step.Decisions)
+	}
+	if step, _ := RunStep(context.Background(), parser, "Action: fetch\nAction Input: https://example.com", executor, policies); len(ran) != 1 || step.Observation.Text != "ok" {
+		t.Errorf("expected the allowed call to run: %#v", step)
+	}
+}
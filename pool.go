@@ -0,0 +1,65 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// ParserPool caches constructed Parsers keyed by their label schema, so services
+// that build a parser per request only pay the construction cost once for each
+// distinct set of labels. A ParserPool is safe for concurrent use, and since
+// Parsers are immutable the same *Parser may be handed to many goroutines.
+type ParserPool struct {
+	mu      sync.RWMutex
+	parsers map[string]*Parser
+	opts    []Option // Options applied to every Parser the pool builds
+}
+
+// NewParserPool creates an empty ParserPool whose parsers are all built with opts.
+func NewParserPool(opts ...Option) *ParserPool {
+	return &ParserPool{parsers: make(map[string]*Parser), opts: opts}
+}
+
+// Get returns the Parser for the given labels, constructing and caching it on
+// first use. Label sets that differ only in the casing of names or aliases get
+// separate Parsers, since the casing shows in their results (see
+// WithDeclaredKeys), formatted text and prompts.
+// Returns the NewParser error if the labels are invalid; invalid sets are not cached.
+func (pp *ParserPool) Get(labels []Label) (*Parser, error) {
+	key := schemaKey(labels)
+	// Fast path: the schema has been compiled before
+	pp.mu.RLock()
+	parser, ok := pp.parsers[key]
+	pp.mu.RUnlock()
+	if ok {
+		return parser, nil
+	}
+	// Slow path: build the parser outside the lock, then publish it
+	parser, err := NewParser(labels, pp.opts...)
+	if err != nil {
+		return nil, err
+	}
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	// Another goroutine may have won the race; keep the first parser stored
+	if existing, ok := pp.parsers[key]; ok {
+		return existing, nil
+	}
+	pp.parsers[key] = parser
+	return parser, nil
+}
+
+// Len returns the number of distinct schemas cached in the pool.
+func (pp *ParserPool) Len() int {
+	pp.mu.RLock()
+	defer pp.mu.RUnlock()
+	return len(pp.parsers)
+}
+
+// schemaKey builds a canonical cache key for a label set, keeping the casing
+// of names and aliases as declared.
+func schemaKey(labels []Label) string {
+	// Label holds only plain data, so its JSON encoding is a stable key
+	key, _ := json.Marshal(labels)
+	return string(key)
+}
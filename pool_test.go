@@ -0,0 +1,78 @@
+package arkaineparser
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestNewParserDoesNotMutateLabels checks that NewParser works on its own copy of the labels.
+func TestNewParserDoesNotMutateLabels(t *testing.T) {
+	labels := []Label{{Name: "Action Input", RequiredWith: []string{"Action"}}, {Name: "Action"}}
+	if _, err := NewParser(labels); err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	if labels[0].Name != "Action Input" || labels[1].Name != "Action" {
+		t.Errorf("caller's labels were modified: %#v", labels)
+	}
+}
+
+// TestParserPool checks that the pool shares parsers per schema and is safe for concurrent use.
+func TestParserPool(t *testing.T) {
+	pool := NewParserPool()
+	var wg sync.WaitGroup
+	parsers := make([]*Parser, 16)
+	for i := range parsers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			parser, err := pool.Get([]Label{{Name: "Result", Required: true}})
+			if err != nil {
+				t.Errorf("failed to get parser: %v", err)
+				return
+			}
+			parsers[i] = parser
+			result, errs := parser.Parse("Result: done")
+			if len(errs) > 0 || result["result"] != "done" {
+				t.Errorf("unexpected parse output: %v %v", result, errs)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, parser := range parsers {
+		if parser != parsers[0] {
+			t.Fatalf("pool returned distinct parsers for the same schema")
+		}
+	}
+	if _, err := pool.Get([]Label{{Name: "Result"}, {Name: "Thought"}}); err != nil {
+		t.Fatalf("failed to get parser: %v", err)
+	}
+	if pool.Len() != 2 {
+		t.Errorf("expected 2 cached schemas, got %d", pool.Len())
+	}
+	if _, err := pool.Get([]Label{{Name: "A", IsBlockStart: true}, {Name: "B", IsBlockStart: true}}); err == nil {
+		t.Errorf("expected error for invalid schema")
+	}
+}
+
+// TestParserPoolCasing checks that schemas differing only in casing get
+// separate parsers, so each keeps its declared casing.
+func TestParserPoolCasing(t *testing.T) {
+	pool := NewParserPool(WithDeclaredKeys())
+	upper, err := pool.Get([]Label{{Name: "Action Input", Aliases: []string{"Input"}}})
+	if err != nil {
+		t.Fatalf("failed to get parser: %v", err)
+	}
+	lower, err := pool.Get([]Label{{Name: "action input", Aliases: []string{"input"}}})
+	if err != nil {
+		t.Fatalf("failed to get parser: %v", err)
+	}
+	if upper == lower || pool.Len() != 2 {
+		t.Fatalf("expected separate parsers, got %d cached", pool.Len())
+	}
+	if result, _ := lower.Parse("Action Input: go"); result["action input"] != "go" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	if result, _ := upper.Parse("action input: go"); result["Action Input"] != "go" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+}
@@ -0,0 +1,161 @@
+package arkaineparser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kinds of Warning reported by CheckPrompt.
+const (
+	KindPromptMissing  ErrorKind = "prompt-missing"  // A label of the schema is never instructed by the prompt
+	KindPromptSpelling ErrorKind = "prompt-spelling" // The prompt spells a label differently from the schema
+	KindPromptOrder    ErrorKind = "prompt-order"    // The prompt instructs labels in a different order than the schema
+)
+
+// Warning is a mismatch between a prompt template and the parser's schema,
+// found by CheckPrompt.
+type Warning struct {
+	Kind    ErrorKind `json:"kind"`            // What kind of mismatch was found
+	Label   string    `json:"label,omitempty"` // The (lowercase) label the mismatch concerns
+	Line    int       `json:"line,omitempty"`  // 1-based line of the prompt, or 0 for a label it lacks
+	Message string    `json:"message"`         // Human readable description
+}
+
+// promptLabelPattern finds a label being instructed at the start of a prompt
+// line, after any list marker or markup: "- **Final Answer**: <answer>".
+var promptLabelPattern = regexp.MustCompile("^\\s*(?:[-*+>#]+\\s+|\\d+[.)]\\s+)?[*_`]*(\\pL[\\pL\\pN_-]*(?:[ \\t]+[\\pL\\pN_-]+){0,3})[*_`]*[ \\t]*:")
+
+// promptLabel is a label instructed by a prompt.
+type promptLabel struct {
+	name string // The label as the prompt writes it
+	line int    // 1-based line of its first mention
+}
+
+// CheckPrompt scans a prompt template for the labels it instructs ("Thought:"
+// at the start of a line) and compares them with the schema, warning about
+// labels the prompt never mentions, labels it spells differently ("Final_Answer"
+// or "Finel Answer" for "Final Answer"), and labels it instructs out of
+// declaration order. System-sourced labels need not be mentioned. Lines that
+// look like no label at all ("Note: ...") are ignored. The returned slice is
+// never nil.
+func (p *Parser) CheckPrompt(promptTemplate string) []Warning {
+	warnings := []Warning{}
+
+	// Step 1: Find the first mention of each label, and near misses of labels
+	mentioned := make(map[string]promptLabel)
+	var order []string
+	misspelled := make(map[string]bool)
+	for i, line := range strings.Split(promptTemplate, "\n") {
+		m := promptLabelPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		name := strings.Join(strings.Fields(m[1]), " ")
+		if canonical, ok := p.names[strings.ToLower(name)]; ok {
+			if _, seen := mentioned[canonical]; !seen {
+				mentioned[canonical] = promptLabel{name: name, line: i + 1}
+				order = append(order, canonical)
+			}
+			continue
+		}
+		if canonical := p.nearLabel(name); canonical != "" && !misspelled[canonical] {
+			misspelled[canonical] = true
+			warnings = append(warnings, Warning{
+				Kind:    KindPromptSpelling,
+				Label:   canonical,
+				Line:    i + 1,
+				Message: fmt.Sprintf("Prompt line %d spells '%s' as '%s'", i+1, p.display[canonical], name),
+			})
+		}
+	}
+
+	// Step 2: Labels the prompt never instructs, unless only misspelled
+	for _, label := range p.labels {
+		if _, ok := mentioned[label.Name]; ok || misspelled[label.Name] || label.Source == SourceSystem {
+			continue
+		}
+		warnings = append(warnings, Warning{
+			Kind:    KindPromptMissing,
+			Label:   label.Name,
+			Message: "Prompt never instructs '" + p.display[label.Name] + "'",
+		})
+	}
+
+	// Step 3: Labels instructed before a label the schema declares ahead of them
+	position := make(map[string]int, len(p.labels))
+	for i, label := range p.labels {
+		position[label.Name] = i
+	}
+	latest := ""
+	for _, name := range order {
+		if latest != "" && position[name] < position[latest] {
+			warnings = append(warnings, Warning{
+				Kind:    KindPromptOrder,
+				Label:   name,
+				Line:    mentioned[name].line,
+				Message: fmt.Sprintf("Prompt line %d instructs '%s' after '%s', but the schema declares it before", mentioned[name].line, p.display[name], p.display[latest]),
+			})
+			continue
+		}
+		latest = name
+	}
+	return warnings
+}
+
+// nearLabel returns the canonical label (or alias) that name misspells: the
+// same words with other separators, or within a small edit distance. Returns
+// "" if name is not close to any label.
+func (p *Parser) nearLabel(name string) string {
+	normalized := normalizeLabelName(name)
+	best, bestDistance := "", -1
+	for _, label := range p.labels {
+		for _, key := range append([]string{label.Name}, label.Aliases...) {
+			candidate := normalizeLabelName(key)
+			if candidate == normalized {
+				return label.Name
+			}
+			// Allow one edit in short names and two in longer ones
+			allowed := 1
+			if len(candidate) >= 8 {
+				allowed = 2
+			}
+			if len(candidate) < 4 {
+				continue
+			}
+			if d := editDistance(normalized, candidate); d <= allowed && (bestDistance < 0 || d < bestDistance) {
+				best, bestDistance = label.Name, d
+			}
+		}
+	}
+	return best
+}
+
+// normalizeLabelName lowercases name and separates its words with single
+// spaces, treating underscores and hyphens as spaces.
+func normalizeLabelName(name string) string {
+	name = strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToLower(name))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// editDistance returns the Levenshtein distance between a and b in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
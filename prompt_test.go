@@ -0,0 +1,51 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestCheckPrompt checks the warnings about prompt and schema drift.
+func TestCheckPrompt(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought", Required: true},
+		{Name: "Action"},
+		{Name: "Action Input", IsJSON: true},
+		{Name: "Observation", Source: SourceSystem},
+		{Name: "Final Answer", Aliases: []string{"Answer"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+
+	matching := "Respond in this format:\n\n**Thought:** <your reasoning>\n- Action: <tool name>\n- Action Input: <JSON arguments>\nObservation: <tool result>\n\nNote: repeat as needed.\nAnswer: <final answer>"
+	if warnings := parser.CheckPrompt(matching); len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %#v", warnings)
+	}
+
+	drifted := "Action: <tool name>\nThought: <your reasoning>\nAction_Input: <JSON arguments>\nNote: be brief.\nFinel Answer: <final answer>"
+	var kinds, messages []string
+	for _, warning := range parser.CheckPrompt(drifted) {
+		kinds = append(kinds, string(warning.Kind))
+		messages = append(messages, warning.Message)
+	}
+	expected := []string{
+		"Prompt line 3 spells 'Action Input' as 'Action_Input'",
+		"Prompt line 5 spells 'Final Answer' as 'Finel Answer'",
+		"Prompt line 2 instructs 'Thought' after 'Action', but the schema declares it before",
+	}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("unexpected warnings: %q", messages)
+	}
+	if !reflect.DeepEqual(kinds, []string{"prompt-spelling", "prompt-spelling", "prompt-order"}) {
+		t.Errorf("unexpected kinds: %v", kinds)
+	}
+
+	warnings := parser.CheckPrompt("Thought: <your reasoning>")
+	if len(warnings) != 3 || warnings[0].Message != "Prompt never instructs 'Action'" || warnings[0].Line != 0 {
+		t.Errorf("unexpected missing warnings: %#v", warnings)
+	}
+	if warnings := parser.CheckPrompt(""); warnings == nil || len(warnings) != 4 {
+		t.Errorf("expected 4 non-nil warnings, got %#v", warnings)
+	}
+}
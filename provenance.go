@@ -0,0 +1,90 @@
+package arkaineparser
+
+import (
+	"strings"
+
+	"github.com/hlfshell/go-arkaine-parser/parsec"
+)
+
+// MatcherKind names the pathway by which a label's value was found.
+type MatcherKind string
+
+const (
+	MatchLabel       MatcherKind = "label"          // The label's name started a line
+	MatchAlias       MatcherKind = "alias"          // One of the label's Aliases started a line
+	MatchBareLabel   MatcherKind = "bare-label"     // A line held only the label's name or an alias (see WithBareLabels)
+	MatchFenceRouted MatcherKind = "fence-routed"   // A code fence tagged with one of the label's FenceLanguages
+	MatchHTMLCapture MatcherKind = "html-capture"   // An HTML element captured with WithHTMLCapture
+	MatchFallback    MatcherKind = "fallback-label" // No label was found; the whole text went to the fallback label
+)
+
+// Provenance describes how one occurrence of a label was found.
+type Provenance struct {
+	Matcher MatcherKind `json:"matcher"`
+	// Text is the label as written in the output, with its separator
+	// ("Final answer:"), when the model wrote it
+	Text string `json:"text,omitempty"`
+}
+
+// annotate records on the matches the label lines that prepare synthesized.
+func (t preparedText) annotate(matches []lineMatch) {
+	for i, origin := range t.origins {
+		if origin != "" && i < len(matches) && matches[i].label != "" {
+			matches[i].matcher = origin
+		}
+	}
+}
+
+// provenance returns how each label occurrence among matches was found, with
+// an empty slice for labels that never occur.
+func (p *Parser) provenance(matches []lineMatch) map[string][]Provenance {
+	provenance := make(map[string][]Provenance, len(p.labels))
+	for _, label := range p.labels {
+		provenance[label.Name] = []Provenance{}
+	}
+	for _, match := range matches {
+		if match.label == "" {
+			continue
+		}
+		entry := Provenance{Matcher: match.matcher}
+		if entry.Matcher == "" {
+			entry.Text = strings.TrimSpace(match.line[match.span.Start:match.span.End])
+			// parseLine tries a label's name before its aliases, and bare
+			// labels last; a bare label line has no separator to match
+			switch {
+			case parsec.LabelPattern(match.label).MatchString(match.line):
+				entry.Matcher = MatchLabel
+			case p.bareLabels && p.bareLabel(match.line) == match.label:
+				entry.Matcher = MatchBareLabel
+			default:
+				entry.Matcher = MatchAlias
+			}
+		}
+		provenance[match.label] = append(provenance[match.label], entry)
+	}
+	return provenance
+}
+
+// MatcherCounts counts the label occurrences found by each pathway.
+func (d Details) MatcherCounts() map[MatcherKind]int {
+	counts := make(map[MatcherKind]int)
+	for _, entries := range d.Provenance {
+		for _, entry := range entries {
+			counts[entry.Matcher]++
+		}
+	}
+	return counts
+}
+
+// bareLabel returns the canonical label whose name or alias is all line holds,
+// once markdown heading markers and emphasis are removed, or "".
+func (p *Parser) bareLabel(line string) string {
+	if !p.exactUnicode {
+		line = parsec.Normalize(line)
+	}
+	name := strings.TrimSpace(line)
+	name = strings.TrimSpace(strings.TrimLeft(name, "#"))
+	name = strings.Trim(name, "*_")
+	name = strings.Join(strings.Fields(strings.ToLower(name)), " ")
+	return p.names[name]
+}
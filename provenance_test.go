@@ -0,0 +1,73 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestProvenance checks which pathway each label occurrence is attributed to.
+func TestProvenance(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought"},
+		{Name: "Final Answer", Aliases: []string{"Answer"}},
+		{Name: "Code", FenceLanguages: []string{"python"}},
+		{Name: "Summary"},
+		{Name: "Observation", Source: SourceSystem},
+	}, WithHTMLCapture("summary", "Summary"), WithFallbackLabel("final answer"))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	details := parser.ParseDetailed("thought: plan it\nObservation: faked\n```python\nprint(1)\n```\n<summary>short</summary>\nanswer: 42\nFinal Answer: 43")
+	expected := map[string][]Provenance{
+		"thought":      {{Matcher: MatchLabel, Text: "thought:"}},
+		"final answer": {{Matcher: MatchAlias, Text: "answer:"}, {Matcher: MatchLabel, Text: "Final Answer:"}},
+		"code":         {{Matcher: MatchFenceRouted}},
+		"summary":      {{Matcher: MatchHTMLCapture}},
+		"observation":  {},
+	}
+	if !reflect.DeepEqual(details.Provenance, expected) {
+		t.Errorf("unexpected provenance: %#v", details.Provenance)
+	}
+	if len(details.Errors) > 0 || details.Result["code"] != "print(1)" || details.Result["summary"] != "short" {
+		t.Errorf("unexpected result: %#v %v", details.Result, details.Errors)
+	}
+	counts := details.MatcherCounts()
+	if !reflect.DeepEqual(counts, map[MatcherKind]int{MatchLabel: 2, MatchAlias: 1, MatchFenceRouted: 1, MatchHTMLCapture: 1}) {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+
+	fallback := parser.ParseDetailed("Just 42.")
+	if !reflect.DeepEqual(fallback.Provenance["final answer"], []Provenance{{Matcher: MatchFallback}}) {
+		t.Errorf("unexpected fallback provenance: %#v", fallback.Provenance)
+	}
+}
+
+// TestBareLabels checks that lines holding only a label's name open it with
+// WithBareLabels.
+func TestBareLabels(t *testing.T) {
+	labels := []Label{{Name: "Thought"}, {Name: "Final Answer", Aliases: []string{"Answer"}}}
+	parser, err := NewParser(labels, WithBareLabels())
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	text := "## Thought\nThe user wants a sum.\n\n**Answer**\n42\nThought: checked"
+	details := parser.ParseDetailed(text)
+	if len(details.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", details.Errors)
+	}
+	expected := Result{"thought": []interface{}{"The user wants a sum.", "checked"}, "final answer": "42"}
+	if !reflect.DeepEqual(details.Result, expected) {
+		t.Errorf("unexpected result: %#v", details.Result)
+	}
+	matchers := []MatcherKind{details.Provenance["thought"][0].Matcher, details.Provenance["thought"][1].Matcher, details.Provenance["final answer"][0].Matcher}
+	if !reflect.DeepEqual(matchers, []MatcherKind{MatchBareLabel, MatchLabel, MatchBareLabel}) {
+		t.Errorf("unexpected matchers: %v", matchers)
+	}
+
+	// Without the option bare names continue the value before them
+	plain, _ := NewParser(labels)
+	result, _ := plain.Parse("Thought: first\nFinal Answer\n42")
+	if result["thought"] != "first\nFinal Answer\n42" {
+		t.Errorf("unexpected thought without the option: %#v", result["thought"])
+	}
+}
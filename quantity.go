@@ -0,0 +1,224 @@
+package arkaineparser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Quantity is a number parsed with its unit by "quantity", "percent" and
+// "currency" labels, e.g. from "about 3,200 users", "85%" or "$1,499.99".
+type Quantity struct {
+	Value       float64 `json:"value"`                 // The number, with any multiplier ("1.2k") applied
+	Unit        string  `json:"unit,omitempty"`        // "%" for percentages, or the text after the number ("users")
+	Currency    string  `json:"currency,omitempty"`    // ISO 4217 code of an amount of money ("USD")
+	Approximate bool    `json:"approximate,omitempty"` // Hedged with "about", "~", "roughly" and the like
+}
+
+// String formats the quantity so it parses back to the same Quantity.
+func (q Quantity) String() string {
+	var b strings.Builder
+	if q.Approximate {
+		b.WriteString("about ")
+	}
+	b.WriteString(strconv.FormatFloat(q.Value, 'f', -1, 64))
+	if q.Unit == "%" {
+		b.WriteString("%")
+	}
+	if q.Currency != "" {
+		b.WriteString(" " + q.Currency)
+	}
+	if q.Unit != "" && q.Unit != "%" {
+		b.WriteString(" " + q.Unit)
+	}
+	return b.String()
+}
+
+// approximateWords hedge a quantity when they precede it.
+var approximateWords = []string{"approximately", "approx.", "approx", "around", "about", "roughly", "nearly", "almost", "circa", "ca.", "~", "≈"}
+
+// currencySigns maps currency signs and words to ISO 4217 codes, longest
+// first; an empty code stands for the Locale's dollar, as does a bare "$".
+var currencySigns = []struct {
+	sign string
+	code string
+}{
+	{"dollars", ""}, {"dollar", ""}, {"euros", "EUR"}, {"euro", "EUR"},
+	{"US$", "USD"}, {"C$", "CAD"}, {"A$", "AUD"}, {"R$", "BRL"},
+	{"€", "EUR"}, {"£", "GBP"}, {"¥", "JPY"}, {"₹", "INR"}, {"₩", "KRW"}, {"₽", "RUB"}, {"₺", "TRY"}, {"₪", "ILS"},
+}
+
+// currencyCodes are the ISO 4217 codes recognized when written out.
+var currencyCodes = []string{"USD", "EUR", "GBP", "JPY", "CNY", "INR", "CAD", "AUD", "CHF", "SEK", "NOK", "DKK", "BRL", "MXN", "KRW"}
+
+// quantityMultipliers scale a number followed by them ("1.2k", "3 million").
+var quantityMultipliers = []struct {
+	suffix string
+	factor float64
+}{
+	{"thousand", 1e3}, {"million", 1e6}, {"billion", 1e9}, {"trillion", 1e12},
+	{"k", 1e3}, {"K", 1e3}, {"M", 1e6}, {"mn", 1e6}, {"bn", 1e9}, {"B", 1e9}, {"T", 1e12},
+}
+
+// parseQuantity reads a number with its sign, currency, multiplier, percent
+// sign and unit, writing numbers as locale does.
+func parseQuantity(value string, locale Locale) (Quantity, error) {
+	var q Quantity
+	rest := strings.TrimRight(strings.Trim(unwrapValue(value), "*_"), ".")
+	// Hedges come first: "about $3k", "~85%"
+	for hedged := true; hedged; {
+		hedged = false
+		for _, word := range approximateWords {
+			if len(rest) <= len(word) || !strings.EqualFold(rest[:len(word)], word) {
+				continue
+			}
+			// Words must end before the number: "about 5", not "aboutness"
+			if after := rest[len(word):]; !startsWithLetter(after) || !unicode.IsLetter(lastRune(word)) {
+				q.Approximate, hedged = true, true
+				rest = strings.TrimSpace(after)
+			}
+		}
+	}
+	rest, negative := cutSign(rest)
+	rest, q.Currency = cutCurrency(rest, locale, true)
+	if !negative {
+		// "$-5" puts the sign after the currency
+		rest, negative = cutSign(rest)
+	}
+
+	// The number itself, up to the first character that cannot be part of it
+	end := 0
+	for i, r := range rest {
+		if !unicode.IsDigit(r) && r != locale.decimal() && !locale.isThousands(r) && r != '_' {
+			break
+		}
+		end = i + len(string(r))
+	}
+	digits := strings.TrimRight(rest[:end], " \u00a0\u202f")
+	rest = strings.TrimSpace(rest[len(digits):])
+	if digits == "" || !unicode.IsDigit(rune(digits[0])) {
+		return Quantity{}, errors.New("no number in '" + value + "'")
+	}
+	number, err := strconv.ParseFloat(locale.normalizeNumber(digits), 64)
+	if err != nil {
+		return Quantity{}, errors.New("'" + digits + "' is not a number")
+	}
+	for _, multiplier := range quantityMultipliers {
+		if after, ok := strings.CutPrefix(rest, multiplier.suffix); ok && !startsWithLetter(after) {
+			number *= multiplier.factor
+			rest = strings.TrimSpace(after)
+			break
+		}
+	}
+	if negative {
+		number = -number
+	}
+	q.Value = number
+
+	// What follows: a percent sign, a currency, then any unit
+	lower := strings.ToLower(rest)
+	for _, percent := range []string{"%", "percent", "per cent"} {
+		if strings.HasPrefix(lower, percent) {
+			q.Unit = "%"
+			rest = strings.TrimSpace(rest[len(percent):])
+			break
+		}
+	}
+	if q.Currency == "" {
+		rest, q.Currency = cutCurrency(rest, locale, false)
+	}
+	if q.Unit == "" {
+		q.Unit = strings.TrimSpace(rest)
+	}
+	return q, nil
+}
+
+// cutSign removes a leading plus or minus sign.
+func cutSign(text string) (string, bool) {
+	for _, minus := range []string{"-", "−"} {
+		if after, ok := strings.CutPrefix(text, minus); ok {
+			return strings.TrimSpace(after), true
+		}
+	}
+	return strings.TrimSpace(strings.TrimPrefix(text, "+")), false
+}
+
+// cutCurrency removes a currency sign, word or code from the start of text,
+// returning the rest and the ISO 4217 code, or text unchanged and "". Words
+// ("dollars") are only accepted after the number, when prefix is false.
+func cutCurrency(text string, locale Locale, prefix bool) (string, string) {
+	dollar := locale.Currency
+	if dollar == "" {
+		dollar = "USD"
+	}
+	for _, currency := range currencySigns {
+		sign, code := currency.sign, currency.code
+		if prefix && startsWithLetter(sign) {
+			continue
+		}
+		if len(text) >= len(sign) && strings.EqualFold(text[:len(sign)], sign) {
+			if code == "" {
+				code = dollar
+			}
+			return strings.TrimSpace(text[len(sign):]), code
+		}
+	}
+	for _, code := range currencyCodes {
+		if after, ok := strings.CutPrefix(text, code); ok && !startsWithLetter(after) {
+			return strings.TrimSpace(after), code
+		}
+	}
+	if after, ok := strings.CutPrefix(text, "$"); ok {
+		return strings.TrimSpace(after), dollar
+	}
+	return text, ""
+}
+
+// parseQuantityValue converts a "quantity" value to a Quantity.
+func parseQuantityValue(p *Parser, label Label, value string) (interface{}, error) {
+	q, err := parseQuantity(value, p.locale)
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// parsePercentValue converts a "percent" value, which must carry a percent
+// sign, to a Quantity.
+func parsePercentValue(p *Parser, label Label, value string) (interface{}, error) {
+	q, err := parseQuantity(value, p.locale)
+	if err != nil {
+		return nil, err
+	}
+	if q.Unit != "%" {
+		return nil, errors.New("'" + value + "' is not a percentage")
+	}
+	return q, nil
+}
+
+// parseCurrencyValue converts a "currency" value, which must name its
+// currency, to a Quantity.
+func parseCurrencyValue(p *Parser, label Label, value string) (interface{}, error) {
+	q, err := parseQuantity(value, p.locale)
+	if err != nil {
+		return nil, err
+	}
+	if q.Currency == "" {
+		return nil, errors.New("'" + value + "' has no currency")
+	}
+	return q, nil
+}
+
+// startsWithLetter reports whether text starts with a letter.
+func startsWithLetter(text string) bool {
+	r, _ := utf8.DecodeRuneInString(text)
+	return unicode.IsLetter(r)
+}
+
+// lastRune returns the last rune of text.
+func lastRune(text string) rune {
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return r
+}
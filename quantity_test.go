@@ -0,0 +1,65 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestQuantityTypes checks numbers with units, percentages and currencies.
+func TestQuantityTypes(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Amount", DataType: "quantity"},
+		{Name: "Share", DataType: "percent", Max: float64Ptr(100)},
+		{Name: "Price", DataType: "currency"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	tests := []struct {
+		input    string
+		expected Quantity
+	}{
+		{"about 3,200 users", Quantity{Value: 3200, Unit: "users", Approximate: true}},
+		{"1.2k", Quantity{Value: 1200}},
+		{"85%", Quantity{Value: 85, Unit: "%"}},
+		{"$1,499.99", Quantity{Value: 1499.99, Currency: "USD"}},
+		{"~ -€5 per month", Quantity{Value: -5, Currency: "EUR", Unit: "per month", Approximate: true}},
+		{"3 million euros", Quantity{Value: 3e6, Currency: "EUR"}},
+		{"USD 40", Quantity{Value: 40, Currency: "USD"}},
+		{"**12.5 percent**", Quantity{Value: 12.5, Unit: "%"}},
+	}
+	for _, tt := range tests {
+		result, errList := parser.Parse("Amount: " + tt.input)
+		if len(errList) > 0 || result["amount"] != tt.expected {
+			t.Errorf("unexpected result for %q: %#v %v", tt.input, result["amount"], errList)
+		}
+		// String renders a value that parses back to the same quantity
+		again, _ := parser.Parse("Amount: " + tt.expected.String())
+		if again["amount"] != tt.expected {
+			t.Errorf("round trip of %q failed: %#v", tt.expected.String(), again["amount"])
+		}
+	}
+
+	_, errList := parser.Parse("Amount: N/A\nShare: 0.4\nPrice: 40")
+	expected := []string{
+		"Invalid quantity in 'amount': no number in 'N/A'",
+		"Invalid percent in 'share': '0.4' is not a percentage",
+		"Invalid currency in 'price': '40' has no currency",
+	}
+	if !reflect.DeepEqual(errList, expected) {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+	_, errList = parser.Parse("Share: 120%")
+	if len(errList) != 1 || errList[0] != "'share' must be at most 100, got 120" {
+		t.Errorf("unexpected range errors: %v", errList)
+	}
+
+	german, err := NewParser([]Label{{Name: "Price", DataType: "currency"}}, WithLocale(LocaleGerman))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, errList := german.Parse("Price: 1.234,56 €")
+	if len(errList) > 0 || result["price"] != (Quantity{Value: 1234.56, Currency: "EUR"}) {
+		t.Errorf("unexpected German result: %#v %v", result, errList)
+	}
+}
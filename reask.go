@@ -0,0 +1,116 @@
+package arkaineparser
+
+import (
+	"strings"
+)
+
+// ReAskPrompt builds a follow-up prompt asking the model to produce only the
+// given missing or invalid labels, in the format Parse expects. Values already
+// present in context (e.g. the partial result) are shown for reference, except
+// those of the requested labels. Parse the reply and combine it with the
+// partial result using MergeResults.
+func (p *Parser) ReAskPrompt(missing []string, context Result) string {
+	// Resolve the requested labels, keeping declaration order and dropping duplicates
+	requested := make(map[string]bool)
+	for _, name := range missing {
+		if canonical, ok := p.names[strings.ToLower(strings.TrimSpace(name))]; ok {
+			requested[canonical] = true
+		}
+	}
+	var (
+		labels []Label
+		names  []string
+	)
+	for _, label := range p.labels {
+		if requested[label.Name] {
+			labels = append(labels, label)
+			names = append(names, p.display[label.Name])
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("Your previous response was missing or had invalid values for: " + strings.Join(names, ", ") + ".\n")
+
+	// Show what was already provided, so the model stays consistent with it
+	provided := make(map[string]interface{})
+	for key, value := range p.flattenValues(context) {
+		canonical, ok := p.names[strings.ToLower(key)]
+		if ok && !requested[canonical] && !isEmptyValue(value) && p.labelMap[canonical].Source != SourceSystem {
+			provided[canonical] = value
+		}
+	}
+	if text, err := p.Format(provided); err == nil && text != "" {
+		b.WriteString("\nFor reference, you already provided:\n" + text + "\n")
+	}
+
+	b.WriteString("\nRespond with ONLY the following fields, each starting on its own line, in exactly this format:\n")
+	for _, label := range labels {
+		b.WriteString(p.display[label.Name] + ": " + valueHint(label) + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// FailedLabels returns the labels that have errors, in the order their first
+// error was reported, e.g. to pass to ReAskPrompt.
+func (d Details) FailedLabels() []string {
+	var labels []string
+	seen := make(map[string]bool)
+	for _, err := range d.Errors {
+		if err.Label != "" && !seen[err.Label] {
+			seen[err.Label] = true
+			labels = append(labels, err.Label)
+		}
+	}
+	return labels
+}
+
+// valueHint describes the value a label expects, for prompts.
+func valueHint(label Label) string {
+	var hint string
+	switch {
+	case label.IsJSON:
+		hint = "<valid JSON>"
+	case label.DataType == "url":
+		hint = "<URL>"
+	case label.DataType == "path":
+		hint = "<file path>"
+	case label.DataType == "datetime":
+		hint = "<date and time, e.g. 2006-01-02T15:04:05Z>"
+	case label.DataType == "duration":
+		hint = "<duration, e.g. 1h30m>"
+	case label.DataType == "integer":
+		hint = "<whole number>"
+	case label.DataType == "number" || label.DataType == "confidence":
+		hint = "<number>"
+	case len(label.Choices) > 0:
+		hint = "<one of: " + strings.Join(label.Choices, ", ") + ">"
+	case label.DataType == "list":
+		hint = "<comma separated list>"
+	case label.DataType == "nested-list":
+		hint = "<indented bullet list>"
+	case label.DataType == "checkbox":
+		hint = "<[x] or [ ]>"
+	case label.DataType == "status":
+		hint = "<done, failed, pending, in progress, blocked or skipped>"
+	case label.DataType == "percent":
+		hint = "<percentage, e.g. 85%>"
+	case label.DataType == "currency":
+		hint = "<amount with currency, e.g. $12.50>"
+	case label.DataType == "quantity":
+		hint = "<number with unit, e.g. 3 users>"
+	default:
+		hint = "<text>"
+	}
+	switch {
+	case label.Min != nil && label.Max != nil:
+		hint = strings.TrimSuffix(hint, ">") + " between " + formatNumber(*label.Min) + " and " + formatNumber(*label.Max) + ">"
+	case label.Min != nil:
+		hint = strings.TrimSuffix(hint, ">") + " of at least " + formatNumber(*label.Min) + ">"
+	case label.Max != nil:
+		hint = strings.TrimSuffix(hint, ">") + " of at most " + formatNumber(*label.Max) + ">"
+	}
+	if label.MatchPattern != "" {
+		hint = strings.TrimSuffix(hint, ">") + " matching " + label.MatchPattern + ">"
+	}
+	return hint
+}
@@ -0,0 +1,38 @@
+package arkaineparser
+
+import "testing"
+
+// TestReAskPrompt checks the follow-up prompt for failed labels.
+func TestReAskPrompt(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Tool", Required: true},
+		{Name: "Arguments", IsJSON: true, Required: true},
+		{Name: "Confidence", DataType: "number", Min: float64Ptr(0.0), Max: float64Ptr(1.0)},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	details := parser.ParseDetailed("Tool: search\nArguments: {oops\nConfidence: 3")
+	failed := details.FailedLabels()
+	if len(failed) != 2 || failed[0] != "arguments" || failed[1] != "confidence" {
+		t.Fatalf("unexpected failed labels: %v", failed)
+	}
+	prompt := parser.ReAskPrompt(failed, details.Result)
+	expected := "Your previous response was missing or had invalid values for: Arguments, Confidence.\n" +
+		"\nFor reference, you already provided:\nTool: search\n" +
+		"\nRespond with ONLY the following fields, each starting on its own line, in exactly this format:\n" +
+		"Arguments: <valid JSON>\nConfidence: <number between 0 and 1>"
+	if prompt != expected {
+		t.Errorf("unexpected prompt.\nGot:\n%s\nExpected:\n%s", prompt, expected)
+	}
+
+	// The reply parses and merges into the partial result
+	patch, _ := parser.Parse("Arguments: {\"q\": \"go\"}\nConfidence: 0.9")
+	merged := MergeResults(details.Result, patch, MergePreferPatch)
+	if merged["confidence"] != 0.9 || merged["tool"] != "search" {
+		t.Errorf("unexpected merge: %#v", merged)
+	}
+}
+
+// float64Ptr returns a pointer to v, for Min and Max literals.
+func float64Ptr(v float64) *float64 { return &v }
@@ -0,0 +1,215 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Registry holds the Parsers of many named, versioned schemas, such as one
+// per tenant, with metrics per schema. A Registry is safe for concurrent use.
+type Registry struct {
+	opts []Option // Options applied to every schema registered
+
+	mu      sync.RWMutex
+	schemas map[SchemaKey]*registryEntry
+	seq     uint64 // Registration counter, to find the latest version of a name
+}
+
+// SchemaKey identifies a schema in a Registry.
+type SchemaKey struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// SchemaConfig is a schema as loaded by Registry.Load.
+type SchemaConfig struct {
+	Name    string  `json:"name"`
+	Version string  `json:"version"`
+	Labels  []Label `json:"labels"`
+}
+
+// SchemaMetrics counts the parses a Registry ran with one schema.
+type SchemaMetrics struct {
+	Parses   uint64        `json:"parses"`   // Parses run
+	Failures uint64        `json:"failures"` // Parses with at least one error
+	Errors   uint64        `json:"errors"`   // Errors reported, over all parses
+	Duration time.Duration `json:"duration"` // Total time spent parsing
+}
+
+// registryEntry is a registered schema with its metrics.
+type registryEntry struct {
+	parser   *Parser
+	seq      uint64
+	parses   atomic.Uint64
+	failures atomic.Uint64
+	errors   atomic.Uint64
+	duration atomic.Int64
+}
+
+// NewRegistry creates an empty Registry whose schemas are all built with opts.
+func NewRegistry(opts ...Option) *Registry {
+	return &Registry{opts: opts, schemas: make(map[SchemaKey]*registryEntry)}
+}
+
+// Register builds a Parser for labels and stores it under name and version,
+// replacing (and resetting the metrics of) any schema already stored there.
+// opts apply after the Registry's own.
+func (r *Registry) Register(name, version string, labels []Label, opts ...Option) error {
+	if name == "" {
+		return errors.New("Schema name must not be empty")
+	}
+	p, err := NewParser(labels, append(append([]Option{}, r.opts...), opts...)...)
+	if err != nil {
+		return fmt.Errorf("Schema '%s' version '%s': %w", name, version, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store(SchemaKey{name, version}, p)
+	return nil
+}
+
+// store publishes p under key. The caller must hold r.mu.
+func (r *Registry) store(key SchemaKey, p *Parser) {
+	r.seq++
+	r.schemas[key] = &registryEntry{parser: p, seq: r.seq}
+}
+
+// Load registers the schemas of a JSON array of SchemaConfigs. Either all of
+// them are registered or, if any fails to build, none is.
+func (r *Registry) Load(data []byte) error {
+	var configs []SchemaConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return errors.New("Invalid registry config: " + err.Error())
+	}
+	parsers := make([]*Parser, len(configs))
+	for i, config := range configs {
+		if config.Name == "" {
+			return fmt.Errorf("Schema %d of the registry config has no name", i+1)
+		}
+		p, err := NewParser(config.Labels, r.opts...)
+		if err != nil {
+			return fmt.Errorf("Schema '%s' version '%s': %w", config.Name, config.Version, err)
+		}
+		parsers[i] = p
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, config := range configs {
+		r.store(SchemaKey{config.Name, config.Version}, parsers[i])
+	}
+	return nil
+}
+
+// LoadFile registers the schemas of a JSON file, as Load does.
+func (r *Registry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return r.Load(data)
+}
+
+// entry returns the entry stored under name and version; an empty version
+// stands for the version of name registered last.
+func (r *Registry) entry(name, version string) (*registryEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if version != "" {
+		if e, ok := r.schemas[SchemaKey{name, version}]; ok {
+			return e, nil
+		}
+		return nil, wrapError(ErrSchemaNotFound, "Schema '"+name+"' version '"+version+"' is not registered")
+	}
+	var latest *registryEntry
+	for key, e := range r.schemas {
+		if key.Name == name && (latest == nil || e.seq > latest.seq) {
+			latest = e
+		}
+	}
+	if latest == nil {
+		return nil, wrapError(ErrSchemaNotFound, "Schema '"+name+"' is not registered")
+	}
+	return latest, nil
+}
+
+// Get returns the Parser of a schema. An empty version returns the version of
+// name registered last. The error wraps ErrSchemaNotFound.
+func (r *Registry) Get(name, version string) (*Parser, error) {
+	e, err := r.entry(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return e.parser, nil
+}
+
+// Remove deletes a schema, or every version of name when version is empty.
+// Parses already running finish normally. Returns whether anything was removed.
+func (r *Registry) Remove(name, version string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	removed := false
+	for key := range r.schemas {
+		if key.Name == name && (version == "" || key.Version == version) {
+			delete(r.schemas, key)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// Keys returns the registered schemas sorted by name and version.
+func (r *Registry) Keys() []SchemaKey {
+	r.mu.RLock()
+	keys := make([]SchemaKey, 0, len(r.schemas))
+	for key := range r.schemas {
+		keys = append(keys, key)
+	}
+	r.mu.RUnlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Name != keys[j].Name {
+			return keys[i].Name < keys[j].Name
+		}
+		return keys[i].Version < keys[j].Version
+	})
+	return keys
+}
+
+// ParseDetailed parses text with a schema, like Parser.ParseDetailed, and
+// records the parse in the schema's metrics. An empty version uses the
+// version of name registered last.
+func (r *Registry) ParseDetailed(name, version, text string) (Details, error) {
+	e, err := r.entry(name, version)
+	if err != nil {
+		return Details{}, err
+	}
+	start := time.Now()
+	details := e.parser.ParseDetailed(text)
+	e.duration.Add(int64(time.Since(start)))
+	e.parses.Add(1)
+	if len(details.Errors) > 0 {
+		e.failures.Add(1)
+		e.errors.Add(uint64(len(details.Errors)))
+	}
+	return details, nil
+}
+
+// Metrics returns the metrics of a schema. An empty version uses the version
+// of name registered last.
+func (r *Registry) Metrics(name, version string) (SchemaMetrics, error) {
+	e, err := r.entry(name, version)
+	if err != nil {
+		return SchemaMetrics{}, err
+	}
+	return SchemaMetrics{
+		Parses:   e.parses.Load(),
+		Failures: e.failures.Load(),
+		Errors:   e.errors.Load(),
+		Duration: time.Duration(e.duration.Load()),
+	}, nil
+}
@@ -0,0 +1,88 @@
+package arkaineparser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestRegistry checks registration, lookup, loading, removal and metrics.
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register("support", "v1", []Label{{Name: "Answer", Required: true}}); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	config := `[
+		{"name": "support", "version": "v2", "labels": [{"name": "Answer", "required": true}, {"name": "Priority", "choices": ["low", "high"]}]},
+		{"name": "billing", "version": "v1", "labels": [{"name": "Amount", "data_type": "currency"}]}
+	]`
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.LoadFile(path); err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	expectedKeys := []SchemaKey{{"billing", "v1"}, {"support", "v1"}, {"support", "v2"}}
+	if keys := registry.Keys(); !reflect.DeepEqual(keys, expectedKeys) {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	// An empty version is the latest registered one
+	details, err := registry.ParseDetailed("support", "", "Answer: reset it\nPriority: HIGH")
+	if err != nil || details.Result["priority"] != "high" {
+		t.Errorf("unexpected latest parse: %#v %v", details.Result, err)
+	}
+	registry.ParseDetailed("support", "v2", "Priority: low")
+	metrics, err := registry.Metrics("support", "v2")
+	if err != nil || metrics.Parses != 2 || metrics.Failures != 1 || metrics.Errors != 1 || metrics.Duration <= 0 {
+		t.Errorf("unexpected metrics: %+v %v", metrics, err)
+	}
+	if metrics, _ := registry.Metrics("support", "v1"); metrics.Parses != 0 {
+		t.Errorf("v1 metrics changed: %+v", metrics)
+	}
+
+	// A failing config registers nothing
+	err = registry.Load([]byte(`[{"name": "ok", "labels": [{"name": "A"}]}, {"name": "bad", "version": "v1", "labels": [{"name": "A", "data_type": "nope"}]}]`))
+	if err == nil || !strings.HasPrefix(err.Error(), "Schema 'bad' version 'v1': ") || len(registry.Keys()) != 3 {
+		t.Errorf("expected a rejected config, got %v with %v", err, registry.Keys())
+	}
+	if err := registry.Register("", "v1", nil); err == nil {
+		t.Error("expected an error for an empty name")
+	}
+
+	if !registry.Remove("support", "") || registry.Remove("support", "v1") {
+		t.Error("unexpected Remove result")
+	}
+	if _, err := registry.Get("support", ""); !errors.Is(err, ErrSchemaNotFound) || err.Error() != "Schema 'support' is not registered" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := registry.ParseDetailed("billing", "v9", "Amount: $5"); !errors.Is(err, ErrSchemaNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Concurrent parses and registrations
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				registry.ParseDetailed("billing", "v1", "Amount: $5")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			registry.Register("tenant", "v1", []Label{{Name: "Answer"}})
+			registry.Keys()
+		}()
+	}
+	wg.Wait()
+	if metrics, _ := registry.Metrics("billing", ""); metrics.Parses != 160 || metrics.Failures != 0 {
+		t.Errorf("unexpected concurrent metrics: %+v", metrics)
+	}
+}
@@ -0,0 +1,106 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+)
+
+// ReloadableParser wraps a Parser whose label schema can be replaced at
+// runtime, e.g. from a config file watcher or remote config, without
+// restarting the service. Reloads publish the new Parser atomically: each
+// parse runs entirely against the Parser current when it started, so
+// in-flight parses finish against the old schema. A ReloadableParser is safe
+// for concurrent use.
+type ReloadableParser struct {
+	current atomic.Pointer[reloadState]
+	opts    []Option // Options applied to every schema loaded
+}
+
+// reloadState is a published Parser with its generation.
+type reloadState struct {
+	parser     *Parser
+	generation uint64
+}
+
+// NewReloadableParser creates a ReloadableParser for labels. opts apply to
+// the first and every reloaded schema.
+func NewReloadableParser(labels []Label, opts ...Option) (*ReloadableParser, error) {
+	p, err := NewParser(labels, opts...)
+	if err != nil {
+		return nil, err
+	}
+	r := &ReloadableParser{opts: opts}
+	r.current.Store(&reloadState{parser: p, generation: 1})
+	return r, nil
+}
+
+// Reload builds a Parser for labels and publishes it. If the labels are
+// invalid, or an option no longer fits them (such as a fallback label the
+// new schema lacks), the error is returned and the current Parser stays.
+func (r *ReloadableParser) Reload(labels []Label) error {
+	p, err := NewParser(labels, r.opts...)
+	if err != nil {
+		return err
+	}
+	r.Store(p)
+	return nil
+}
+
+// ReloadJSON reloads the schema from labels stored as a JSON array, the
+// format of Label's json tags.
+func (r *ReloadableParser) ReloadJSON(data []byte) error {
+	var labels []Label
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return errors.New("Invalid schema JSON: " + err.Error())
+	}
+	return r.Reload(labels)
+}
+
+// Store publishes an already built Parser, e.g. one built with different
+// options. A nil Parser is ignored.
+func (r *ReloadableParser) Store(p *Parser) {
+	if p == nil {
+		return
+	}
+	// Retry until no concurrent reload published in between, so generations
+	// always increase by one
+	for {
+		old := r.current.Load()
+		if r.current.CompareAndSwap(old, &reloadState{parser: p, generation: old.generation + 1}) {
+			return
+		}
+	}
+}
+
+// Parser returns the current Parser. Use it to run several calls against one
+// schema even if a reload happens in between.
+func (r *ReloadableParser) Parser() *Parser {
+	return r.current.Load().parser
+}
+
+// Generation returns the number of schemas published so far, starting at 1,
+// e.g. to report which schema a result was parsed with.
+func (r *ReloadableParser) Generation() uint64 {
+	return r.current.Load().generation
+}
+
+// Parse behaves like Parser.Parse with the current schema.
+func (r *ReloadableParser) Parse(text string) (map[string]interface{}, []string) {
+	return r.Parser().Parse(text)
+}
+
+// ParseDetailed behaves like Parser.ParseDetailed with the current schema.
+func (r *ReloadableParser) ParseDetailed(text string) Details {
+	return r.Parser().ParseDetailed(text)
+}
+
+// ParseBlocks behaves like Parser.ParseBlocks with the current schema.
+func (r *ReloadableParser) ParseBlocks(text string) ([]map[string]interface{}, []string) {
+	return r.Parser().ParseBlocks(text)
+}
+
+// ParseBlocksDetailed behaves like Parser.ParseBlocksDetailed with the current schema.
+func (r *ReloadableParser) ParseBlocksDetailed(text string) ([]Details, error) {
+	return r.Parser().ParseBlocksDetailed(text)
+}
@@ -0,0 +1,67 @@
+package arkaineparser
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestReloadableParser checks schema swaps, failed reloads and concurrent use.
+func TestReloadableParser(t *testing.T) {
+	r, err := NewReloadableParser([]Label{{Name: "Answer", Required: true}}, WithFallbackLabel("answer"))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	if result, errList := r.Parse("Answer: 42\nScore: 7"); len(errList) > 0 || result["answer"] != "42\nScore: 7" {
+		t.Errorf("unexpected result: %#v %v", result, errList)
+	}
+	old := r.Parser()
+
+	if err := r.ReloadJSON([]byte(`[{"name": "Answer", "required": true}, {"name": "Score", "data_type": "integer"}]`)); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	if result, errList := r.Parse("Answer: 42\nScore: 7"); len(errList) > 0 || result["answer"] != "42" || result["score"] != 7 {
+		t.Errorf("unexpected reloaded result: %#v %v", result, errList)
+	}
+	if r.Generation() != 2 {
+		t.Errorf("expected generation 2, got %d", r.Generation())
+	}
+	// A Parser taken before the reload keeps the old schema
+	if result, _ := old.Parse("Answer: 42\nScore: 7"); result["score"] != nil {
+		t.Errorf("old parser changed: %#v", result)
+	}
+
+	// Failed reloads keep the current schema
+	if err := r.Reload([]Label{{Name: "Score"}}); err == nil || err.Error() != "Fallback label 'answer' is not defined" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := r.ReloadJSON([]byte(`{`)); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+	if r.Generation() != 2 || len(r.Parser().labels) != 2 {
+		t.Errorf("failed reload changed the parser: generation %d", r.Generation())
+	}
+
+	// Concurrent parses and reloads always see a complete schema
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, errList := r.Parse("Answer: 42"); len(errList) > 0 {
+					t.Errorf("unexpected errors: %v", errList)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				r.Reload([]Label{{Name: "Answer", Required: true}})
+			}
+		}()
+	}
+	wg.Wait()
+	if r.Generation() != 82 {
+		t.Errorf("expected generation 82, got %d", r.Generation())
+	}
+}
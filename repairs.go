@@ -0,0 +1,30 @@
+package arkaineparser
+
+// RepairKind classifies a Repair.
+type RepairKind string
+
+const (
+	RepairCodeFence         RepairKind = "code-fence"         // A markdown code fence was stripped
+	RepairInlineCode        RepairKind = "inline-code"        // Inline code backticks were stripped
+	RepairUnclosedStructure RepairKind = "unclosed-structure" // A value's bracket or string never closed, so later lines were matched as labels
+	RepairMissingEndMarker  RepairKind = "missing-end-marker" // A label's EndMarker never appeared, so its value ran to the end of the text
+	RepairEmptyJSON         RepairKind = "empty-json"         // An empty JSON value was replaced by an empty object
+	RepairFallbackLabel     RepairKind = "fallback-label"     // No label was found, so the whole text became the fallback label's value
+	RepairFenceRouted       RepairKind = "fence-routed"       // A code fence outside any value was routed to the label claiming its language
+	RepairHTML              RepairKind = "html"               // HTML tags were converted to text (see WithHTML)
+	RepairQuoted            RepairKind = "quoted"             // A triple-quoted value was unwrapped from its quotes
+	RepairBoilerplate       RepairKind = "boilerplate"        // A chat preamble or sign-off was stripped (see WithBoilerplateStripping)
+	RepairRoleMarker        RepairKind = "role-marker"        // Role markers and other turns were stripped around the answer (see WithRoleMarkers)
+	RepairPromptEcho        RepairKind = "prompt-echo"        // Lines echoing the prompt were stripped (see WithPromptEcho)
+	RepairUnicode           RepairKind = "unicode"            // A label only matched once normalized to NFKC (see WithExactUnicode)
+)
+
+// Repair records a place where a lenient parsing feature changed how the
+// output was interpreted. Comparing repairs across models shows how much
+// fixing each model's output needs.
+type Repair struct {
+	Kind   RepairKind `json:"kind"`            // Which lenient feature applied
+	Label  string     `json:"label,omitempty"` // The label concerned, or "" for text-wide repairs such as cleaning
+	Before string     `json:"before"`          // The original text
+	After  string     `json:"after"`           // The text as it was interpreted
+}
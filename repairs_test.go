@@ -0,0 +1,36 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRepairs checks that lenient interpretations are reported.
+func TestRepairs(t *testing.T) {
+	labels := []Label{{Name: "Action Input", IsJSON: true}, {Name: "Answer", EndMarker: "END"}, {Name: "Thought"}}
+	parser, err := NewParser(labels)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Thought: use `grep`\nAction Input: ```json\n{\"a\": [1,\n```\nThought: again\nAnswer: unterminated"
+	details := parser.ParseDetailed(input)
+	// The fence holds the value of its label, which bounds the malformed JSON
+	expected := []Repair{
+		{Kind: RepairCodeFence, Label: "action input", Before: "```json\n{\"a\": [1,\n```", After: "{\"a\": [1,"},
+		{Kind: RepairInlineCode, Before: "`grep`", After: "grep"},
+		{Kind: RepairMissingEndMarker, Label: "answer", Before: "END"},
+	}
+	if !reflect.DeepEqual(details.Repairs, expected) {
+		t.Errorf("repairs mismatch.\nGot: %#v\nExpected: %#v", details.Repairs, expected)
+	}
+	if !reflect.DeepEqual(details.Result["thought"], []interface{}{"use grep", "again"}) {
+		t.Errorf("unexpected result: %#v", details.Result)
+	}
+
+	// Without a fence, the unclosed structure does not swallow the labels after it
+	details = parser.ParseDetailed("Action Input: {\"a\": [1,\nThought: again")
+	expected = []Repair{{Kind: RepairUnclosedStructure, Label: "action input", Before: "{\"a\": [1,"}}
+	if !reflect.DeepEqual(details.Repairs, expected) || details.Result["thought"] != "again" {
+		t.Errorf("unexpected repairs or result: %#v %#v", details.Repairs, details.Result)
+	}
+}
@@ -0,0 +1,176 @@
+// Package replay records LLM completions and replays them in tests, so unit
+// tests of agent loops run deterministically without network calls. A
+// Cassette stores completions keyed by a hash of their prompt, in a JSON file
+// checked in next to the tests. Set REPLAY_RECORD=1 to run the tests against
+// the live model and re-record their cassettes.
+package replay
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+)
+
+// RecordEnv is the environment variable that makes ForTest record.
+const RecordEnv = "REPLAY_RECORD"
+
+// ErrNotRecorded is wrapped by the errors of replaying a prompt the cassette
+// holds no (further) completion for.
+var ErrNotRecorded = errors.New("Completion is not recorded")
+
+// Completer produces the completion of a prompt, e.g. by calling an LLM.
+type Completer interface {
+	Complete(ctx context.Context, prompt string) (string, error)
+}
+
+// CompleterFunc adapts a function to the Completer interface.
+type CompleterFunc func(ctx context.Context, prompt string) (string, error)
+
+// Complete calls f.
+func (f CompleterFunc) Complete(ctx context.Context, prompt string) (string, error) {
+	return f(ctx, prompt)
+}
+
+// Entry is a recorded completion.
+type Entry struct {
+	Prompt     string `json:"prompt"` // Kept for reading the cassette; only its hash is matched
+	Completion string `json:"completion"`
+}
+
+// Cassette holds recorded completions, keyed by the Hash of their prompt.
+// A prompt completed several times keeps each completion in order, and
+// replays them in that order. A Cassette is safe for concurrent use.
+type Cassette struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string][]Entry
+	next    map[string]int // Index of the next completion to replay per hash
+}
+
+// cassetteFile is the JSON form of a Cassette.
+type cassetteFile struct {
+	Version int                `json:"version"`
+	Entries map[string][]Entry `json:"entries"`
+}
+
+// Hash returns the key a prompt is recorded under: the hex SHA-256 of the prompt.
+func Hash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// Open loads the cassette at path, or starts an empty one if the file does
+// not exist yet.
+func Open(path string) (*Cassette, error) {
+	c := &Cassette{path: path, entries: make(map[string][]Entry), next: make(map[string]int)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.New("Invalid cassette '" + path + "': " + err.Error())
+	}
+	if file.Entries != nil {
+		c.entries = file.Entries
+	}
+	return c, nil
+}
+
+// Save writes the cassette to its path.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(cassetteFile{Version: 1, Entries: c.entries}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0o644)
+}
+
+// Replay returns a Completer answering each prompt with its next recorded
+// completion. The error of a prompt with none left wraps ErrNotRecorded.
+func (c *Cassette) Replay() Completer {
+	return CompleterFunc(func(ctx context.Context, prompt string) (string, error) {
+		hash := Hash(prompt)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		entries, n := c.entries[hash], c.next[hash]
+		if n >= len(entries) {
+			return "", fmt.Errorf("%w for call %d of prompt %s (%d recorded)", ErrNotRecorded, n+1, hash[:12], len(entries))
+		}
+		c.next[hash] = n + 1
+		return entries[n].Completion, nil
+	})
+}
+
+// Record returns a Completer passing prompts to live and recording its
+// completions. Recording starts the cassette afresh; call Save to keep it.
+func (c *Cassette) Record(live Completer) Completer {
+	c.mu.Lock()
+	c.entries = make(map[string][]Entry)
+	c.next = make(map[string]int)
+	c.mu.Unlock()
+	return CompleterFunc(func(ctx context.Context, prompt string) (string, error) {
+		completion, err := live.Complete(ctx, prompt)
+		if err != nil {
+			return "", err
+		}
+		hash := Hash(prompt)
+		c.mu.Lock()
+		c.entries[hash] = append(c.entries[hash], Entry{Prompt: prompt, Completion: completion})
+		c.mu.Unlock()
+		return completion, nil
+	})
+}
+
+// ForTest returns the Completer a test should use: one replaying the
+// cassette at path, or, when RecordEnv is set, one recording live into it,
+// saved when the test ends.
+func ForTest(t testing.TB, path string, live Completer) Completer {
+	t.Helper()
+	c, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open cassette: %v", err)
+	}
+	if os.Getenv(RecordEnv) == "" {
+		return c.Replay()
+	}
+	t.Cleanup(func() {
+		if err := c.Save(); err != nil {
+			t.Errorf("failed to save cassette: %v", err)
+		}
+	})
+	return c.Record(live)
+}
+
+// Parse completes prompt and parses the completion with p.
+func Parse(ctx context.Context, completer Completer, p *arkaineparser.Parser, prompt string) (arkaineparser.Details, error) {
+	completion, err := completer.Complete(ctx, prompt)
+	if err != nil {
+		return arkaineparser.Details{}, err
+	}
+	return p.ParseDetailed(completion), nil
+}
+
+// RunStep completes prompt and runs the completion as an agent step (see
+// arkaineparser.RunStep).
+func RunStep(ctx context.Context, completer Completer, p *arkaineparser.Parser, prompt string, executor arkaineparser.Executor, opts ...arkaineparser.StepOption) (arkaineparser.Step, error) {
+	completion, err := completer.Complete(ctx, prompt)
+	if err != nil {
+		return arkaineparser.Step{}, err
+	}
+	return arkaineparser.RunStep(ctx, p, completion, executor, opts...)
+}
@@ -0,0 +1,93 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+)
+
+// fakeModel answers each prompt with a new completion, counting its calls.
+type fakeModel struct {
+	calls int
+}
+
+// Complete returns the next completion, or an error for the prompt "fail".
+func (m *fakeModel) Complete(ctx context.Context, prompt string) (string, error) {
+	m.calls++
+	if prompt == "fail" {
+		return "", errors.New("rate limited")
+	}
+	return "Action: search\nAction Input: call " + string(rune('0'+m.calls)), nil
+}
+
+// TestRecordReplay checks that recorded completions replay in order.
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "agent.json")
+	cassette, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	model := &fakeModel{}
+	recorder := cassette.Record(model)
+	for _, prompt := range []string{"a", "b", "a"} {
+		recorder.Complete(ctx, prompt)
+	}
+	if _, err := recorder.Complete(ctx, "fail"); err == nil {
+		t.Error("expected the live error")
+	}
+	if err := cassette.Save(); err != nil {
+		t.Fatalf("failed to save: %v", err)
+	}
+
+	cassette, err = Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen: %v", err)
+	}
+	player := cassette.Replay()
+	for _, test := range []struct{ prompt, completion string }{
+		{"a", "Action: search\nAction Input: call 1"},
+		{"b", "Action: search\nAction Input: call 2"},
+		{"a", "Action: search\nAction Input: call 3"},
+	} {
+		if got, err := player.Complete(ctx, test.prompt); err != nil || got != test.completion {
+			t.Errorf("%s: unexpected completion %q, %v", test.prompt, got, err)
+		}
+	}
+	for _, prompt := range []string{"a", "fail"} {
+		if _, err := player.Complete(ctx, prompt); !errors.Is(err, ErrNotRecorded) {
+			t.Errorf("%s: expected ErrNotRecorded, got %v", prompt, err)
+		}
+	}
+	if model.calls != 4 {
+		t.Errorf("expected 4 live calls, got %d", model.calls)
+	}
+}
+
+// TestForTest checks replaying an agent step, and recording when asked to.
+func TestForTest(t *testing.T) {
+	parser, _ := arkaineparser.NewParser([]arkaineparser.Label{{Name: "Action"}, {Name: "Action Input"}, {Name: "Observation"}})
+	executor := arkaineparser.ExecutorFunc(func(ctx context.Context, call arkaineparser.ToolCall) (arkaineparser.Observation, error) {
+		return arkaineparser.Observation{Text: "found " + call.Input}, nil
+	})
+	path := filepath.Join(t.TempDir(), "step.json")
+
+	t.Run("record", func(t *testing.T) {
+		t.Setenv(RecordEnv, "1")
+		completer := ForTest(t, path, &fakeModel{})
+		if details, err := Parse(context.Background(), completer, parser, "prompt"); err != nil || details.Result["action"] != "search" {
+			t.Errorf("unexpected parse: %#v, %v", details.Result, err)
+		}
+	})
+	t.Run("replay", func(t *testing.T) {
+		t.Setenv(RecordEnv, "")
+		completer := ForTest(t, path, nil)
+		step, err := RunStep(context.Background(), completer, parser, "prompt", executor)
+		if err != nil || step.Scratchpad != "Action: search\nAction Input: call 1\nObservation: found call 1" {
+			t.Errorf("unexpected step: %#v, %v", step, err)
+		}
+	})
+}
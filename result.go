@@ -0,0 +1,110 @@
+package arkaineparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Result is a parsed output keyed by lowercase label name, or by the name as
+// declared with WithDeclaredKeys. It has the same shape as the map returned by
+// Parse, so any parse result converts directly:
+//
+//	result, errs := parser.Parse(text)
+//	data, err := json.Marshal(arkaineparser.Result(result))
+type Result map[string]interface{}
+
+// Get returns the value of a label, matching its key case-insensitively, so
+// lookups work with and without WithDeclaredKeys.
+func (r Result) Get(label string) (interface{}, bool) {
+	if value, ok := r[label]; ok {
+		return value, true
+	}
+	for key, value := range r {
+		if strings.EqualFold(key, label) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON encodes the result with keys sorted at every nesting level and
+// without HTML escaping, so identical results always encode to identical bytes.
+func (r Result) MarshalJSON() ([]byte, error) {
+	return marshalStable(map[string]interface{}(r))
+}
+
+// Flat returns a columnar-friendly copy of the result mapping each label to a
+// single string. Plain text values are kept as-is; JSON objects, repeated
+// values and any other non-string values are re-serialized as compact JSON.
+func (r Result) Flat() (map[string]string, error) {
+	flat := make(map[string]string, len(r))
+	for key, value := range r {
+		if str, ok := value.(string); ok {
+			flat[key] = str
+			continue
+		}
+		encoded, err := marshalStable(value)
+		if err != nil {
+			return nil, err
+		}
+		flat[key] = string(encoded)
+	}
+	return flat, nil
+}
+
+// MarshalFlatJSON encodes the Flat form of the result with sorted keys.
+func (r Result) MarshalFlatJSON() ([]byte, error) {
+	flat, err := r.Flat()
+	if err != nil {
+		return nil, err
+	}
+	return marshalStable(flat)
+}
+
+// Columns converts a set of results into a table for bulk loading: the sorted
+// union of all label names, and one row per result holding the Flat value of
+// each column ("" where a result lacks the label).
+func Columns(results []Result) ([]string, [][]string, error) {
+	// Collect the union of all keys
+	seen := make(map[string]bool)
+	for _, result := range results {
+		for key := range result {
+			seen[key] = true
+		}
+	}
+	columns := make([]string, 0, len(seen))
+	for key := range seen {
+		columns = append(columns, key)
+	}
+	sort.Strings(columns)
+
+	// Fill one row per result in column order
+	rows := make([][]string, len(results))
+	for i, result := range results {
+		flat, err := result.Flat()
+		if err != nil {
+			return nil, nil, err
+		}
+		row := make([]string, len(columns))
+		for j, column := range columns {
+			row[j] = flat[column]
+		}
+		rows[i] = row
+	}
+	return columns, rows, nil
+}
+
+// marshalStable encodes v as compact JSON without HTML escaping.
+// encoding/json already writes map keys in sorted order at every level.
+func marshalStable(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encode terminates each value with a newline
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
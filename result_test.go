@@ -0,0 +1,46 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestResultMarshalJSON checks that results encode with sorted keys and no HTML escaping.
+func TestResultMarshalJSON(t *testing.T) {
+	result := Result{
+		"thought":      "a < b",
+		"action input": map[string]interface{}{"z": 1.0, "a": []interface{}{"x"}},
+		"action":       "search",
+	}
+	encoded, err := result.MarshalJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	expected := `{"action":"search","action input":{"a":["x"],"z":1},"thought":"a < b"}`
+	if string(encoded) != expected {
+		t.Errorf("encoding mismatch.\nGot: %s\nExpected: %s", encoded, expected)
+	}
+}
+
+// TestColumns checks the flat columnar representation of several results.
+func TestColumns(t *testing.T) {
+	results := []Result{
+		{"action": "search", "action input": map[string]interface{}{"q": "go"}},
+		{"action": "finish", "notes": []interface{}{"one", "two"}},
+	}
+	columns, rows, err := Columns(results)
+	if err != nil {
+		t.Fatalf("failed to build columns: %v", err)
+	}
+	expectedColumns := []string{"action", "action input", "notes"}
+	expectedRows := [][]string{
+		{"search", `{"q":"go"}`, ""},
+		{"finish", "", `["one","two"]`},
+	}
+	if !reflect.DeepEqual(columns, expectedColumns) {
+		t.Errorf("columns mismatch.\nGot: %#v\nExpected: %#v", columns, expectedColumns)
+	}
+	if !reflect.DeepEqual(rows, expectedRows) {
+		t.Errorf("rows mismatch.\nGot: %#v\nExpected: %#v", rows, expectedRows)
+	}
+}
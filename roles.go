@@ -0,0 +1,143 @@
+package arkaineparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Patterns finding chat roles in model output
+var (
+	// roleLinePattern matches a role written as a label: "Assistant:", "### Response:"
+	roleLinePattern = regexp.MustCompile(`(?i)^\s*(?:#{1,4}\s*)?\**(assistant|ai|model|gpt|bot|response|user|human|system|instruction|input)\**\s*:\s*`)
+	// roleTokenPattern matches a chat template role token: "<|im_start|>assistant", "<|user|>"
+	roleTokenPattern = regexp.MustCompile(`(?i)^\s*<\|(?:im_start\|>)?\s*(assistant|user|system)\s*(?:\|>)?\s*`)
+	// endTokenPattern matches the chat template tokens ending a turn
+	endTokenPattern = regexp.MustCompile(`<\|im_end\|>|<\|end\|>|<\|eot_id\|>|</s>`)
+)
+
+// assistantRoles are the roles whose turns hold the model's own answer.
+var assistantRoles = map[string]bool{"assistant": true, "ai": true, "model": true, "gpt": true, "bot": true, "response": true}
+
+// roleTurn is a part of the output written under one role.
+type roleTurn struct {
+	role  string   // Lowercase role, or "" for text before any marker
+	lines []string // The turn's lines, without its marker
+}
+
+// stripRoles keeps only the model's answer from output wrapped in chat roles
+// or continuing the conversation: the first assistant turn holding a label,
+// else the first unmarked text holding one, else the last assistant turn.
+// Everything else, role markers included, is reported as a RepairRoleMarker
+// repair. Role words that are label names are never taken for roles.
+func (p *Parser) stripRoles(text string, repairs *[]Repair) string {
+	original := text
+	// An instruction closed with [/INST] is followed by the answer
+	if i := strings.LastIndex(text, "[/INST]"); i >= 0 {
+		text = text[i+len("[/INST]"):]
+	}
+	text = endTokenPattern.ReplaceAllString(text, "")
+
+	// Step 1: Split the text into turns at role markers
+	turns := []roleTurn{{}}
+	for _, line := range strings.Split(text, "\n") {
+		role, rest := p.roleMarker(line)
+		if role == "" {
+			turns[len(turns)-1].lines = append(turns[len(turns)-1].lines, line)
+			continue
+		}
+		turn := roleTurn{role: role}
+		if strings.TrimSpace(rest) != "" {
+			turn.lines = []string{rest}
+		}
+		turns = append(turns, turn)
+	}
+	if len(turns) == 1 && text == original {
+		return text
+	}
+
+	// Step 2: Pick the turn holding the answer
+	kept := strings.Join(turns[p.answerTurn(turns)].lines, "\n")
+	if strings.TrimSpace(kept) != strings.TrimSpace(original) {
+		*repairs = append(*repairs, Repair{Kind: RepairRoleMarker, Before: original, After: kept})
+	}
+	return kept
+}
+
+// answerTurn returns the index of the turn holding the model's answer.
+func (p *Parser) answerTurn(turns []roleTurn) int {
+	// The first assistant turn holding a label
+	for i, turn := range turns {
+		if assistantRoles[turn.role] && p.hasLabel(turn.lines) {
+			return i
+		}
+	}
+	// The first text outside any turn holding a label
+	for i, turn := range turns {
+		if turn.role == "" && p.hasLabel(turn.lines) {
+			return i
+		}
+	}
+	// The last assistant turn
+	for i := len(turns) - 1; i > 0; i-- {
+		if assistantRoles[turns[i].role] {
+			return i
+		}
+	}
+	return 0
+}
+
+// roleMarker returns the lowercase role a line opens a turn of and the text
+// following the marker, or "" when the line is no role marker.
+func (p *Parser) roleMarker(line string) (string, string) {
+	for _, pattern := range []*regexp.Regexp{roleTokenPattern, roleLinePattern} {
+		m := pattern.FindStringSubmatchIndex(line)
+		if m == nil {
+			continue
+		}
+		role := strings.ToLower(line[m[2]:m[3]])
+		if _, isLabel := p.names[role]; isLabel {
+			return "", ""
+		}
+		return role, line[m[1]:]
+	}
+	return "", ""
+}
+
+// hasLabel reports whether any of lines starts a label.
+func (p *Parser) hasLabel(lines []string) bool {
+	for _, line := range lines {
+		if label, _, _ := p.parseLine(line); label != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// stripPromptEcho drops the lines at the start of text that repeat lines of
+// the prompt, as models that echo their instructions before answering write
+// them, reporting them as a RepairPromptEcho repair.
+func (p *Parser) stripPromptEcho(text string, repairs *[]Repair) string {
+	promptLines := make(map[string]bool)
+	for _, line := range strings.Split(p.prompt, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			promptLines[line] = true
+		}
+	}
+	lines := strings.Split(text, "\n")
+	echoed := 0
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !promptLines[line] {
+			break
+		}
+		echoed = i + 1
+	}
+	if echoed == 0 {
+		return text
+	}
+	*repairs = append(*repairs, Repair{Kind: RepairPromptEcho, Before: strings.Join(lines[:echoed], "\n")})
+	return strings.Join(lines[echoed:], "\n")
+}
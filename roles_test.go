@@ -0,0 +1,60 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRoleMarkers checks that the answer is kept from output wrapped in chat
+// roles or echoed turns.
+func TestRoleMarkers(t *testing.T) {
+	labels := []Label{{Name: "Thought"}, {Name: "Final Answer"}, {Name: "Input"}}
+	parser, err := NewParser(labels, WithRoleMarkers())
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	expected := Result{"thought": "add", "final answer": "4", "input": ""}
+	tests := []string{
+		"Assistant: Thought: add\nFinal Answer: 4",
+		"System: Reply with Thought: and Final Answer:\nUser: what is 2+2?\nAssistant:\nThought: add\nFinal Answer: 4\nUser: thanks!\nAssistant: You're welcome.",
+		"<|im_start|>assistant\nThought: add\nFinal Answer: 4<|im_end|>",
+		"[INST] Use Thought: and Final Answer: to add 2+2 [/INST] Thought: add\nFinal Answer: 4",
+		"Thought: add\nFinal Answer: 4\nHuman: and 3+3?",
+		"### Instruction:\nAnswer with Final Answer:\n\n### Response:\nThought: add\nFinal Answer: 4",
+	}
+	for _, text := range tests {
+		details := parser.ParseDetailed(text)
+		if len(details.Errors) > 0 {
+			t.Errorf("unexpected errors for %q: %v", text, details.Errors)
+		}
+		if !reflect.DeepEqual(details.Result, expected) {
+			t.Errorf("for %q got %#v", text, details.Result)
+		}
+		if len(details.Repairs) == 0 || details.Repairs[0].Kind != RepairRoleMarker {
+			t.Errorf("expected a role marker repair for %q, got %v", text, details.Repairs)
+		}
+	}
+
+	// A label named like a role is matched as the label
+	result, _ := parser.Parse("Input: the data\nThought: add\nFinal Answer: 4")
+	if result["input"] != "the data" {
+		t.Errorf("label taken for a role: %#v", result)
+	}
+}
+
+// TestPromptEcho checks that echoed prompt lines are stripped.
+func TestPromptEcho(t *testing.T) {
+	prompt := "You are a calculator.\n\nAnswer in this format:\nThought: <reasoning>\nFinal Answer: <number>\n\nWhat is 2+2?"
+	labels := []Label{{Name: "Thought"}, {Name: "Final Answer"}}
+	parser, err := NewParser(labels, WithPromptEcho(prompt))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	details := parser.ParseDetailed("Answer in this format:\nThought: <reasoning>\nFinal Answer: <number>\n\nWhat is 2+2?\nThought: add\nFinal Answer: 4")
+	if !reflect.DeepEqual(details.Result, Result{"thought": "add", "final answer": "4"}) {
+		t.Errorf("unexpected result: %#v", details.Result)
+	}
+	if len(details.Repairs) != 1 || details.Repairs[0].Kind != RepairPromptEcho {
+		t.Errorf("expected a prompt echo repair, got %v", details.Repairs)
+	}
+}
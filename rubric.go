@@ -0,0 +1,127 @@
+package arkaineparser
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// CriterionScore is the judgement of a single criterion of a Rubric.
+type CriterionScore struct {
+	Criterion     string  `json:"criterion"`
+	Score         float64 `json:"score"`
+	Justification string  `json:"justification"`
+	Verdict       string  `json:"verdict"`
+}
+
+// Rubric is a parsed evaluation: the score of each criterion and their aggregates.
+type Rubric struct {
+	Criteria []CriterionScore `json:"criteria"`
+	// Sum and mean of the criterion scores
+	Total float64 `json:"total"`
+	Mean  float64 `json:"mean"`
+	// The last verdict given, usually the judge's overall verdict
+	Verdict string `json:"verdict"`
+}
+
+// RubricLabels returns the labels of a judging output made of criterion blocks,
+// each with a score between min and max, a justification and an optional
+// verdict:
+//
+//	Criterion: Accuracy
+//	Score: 4/5
+//	Justification: One date is wrong.
+//	Verdict: Pass
+func RubricLabels(min, max float64) []Label {
+	return []Label{
+		{Name: "Criterion", IsBlockStart: true, Aliases: []string{"Criteria"}},
+		{Name: "Score", DataType: "score", Required: true, Min: &min, Max: &max, Aliases: []string{"Rating"}},
+		{Name: "Justification", Aliases: []string{"Reasoning", "Rationale"}},
+		{Name: "Verdict"},
+	}
+}
+
+// RubricParser parses judging outputs into Rubrics.
+type RubricParser struct {
+	parser *Parser
+}
+
+// NewRubricParser creates a RubricParser using RubricLabels(min, max).
+func NewRubricParser(min, max float64, opts ...Option) (*RubricParser, error) {
+	parser, err := NewParser(RubricLabels(min, max), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &RubricParser{parser: parser}, nil
+}
+
+// Parse returns the rubric found in text. Criteria with errors, such as a
+// missing or out-of-range score, are reported and left out of the rubric and
+// its aggregates.
+func (r *RubricParser) Parse(text string) (Rubric, []string) {
+	blocks, err := r.parser.ParseBlocksDetailed(text)
+	var errList []string
+	if err != nil {
+		errList = append(errList, err.Error())
+	}
+	var rubric Rubric
+	for _, details := range blocks {
+		if len(details.Errors) > 0 {
+			errList = append(errList, errorStrings(details.Errors)...)
+			continue
+		}
+		score, _ := numericValue(details.Result["score"])
+		criterion := CriterionScore{
+			Criterion:     stringValue(details.Result["criterion"]),
+			Score:         score,
+			Justification: stringValue(details.Result["justification"]),
+			Verdict:       stringValue(details.Result["verdict"]),
+		}
+		rubric.Criteria = append(rubric.Criteria, criterion)
+		rubric.Total += score
+		if criterion.Verdict != "" {
+			rubric.Verdict = criterion.Verdict
+		}
+	}
+	if len(rubric.Criteria) > 0 {
+		rubric.Mean = rubric.Total / float64(len(rubric.Criteria))
+	}
+	return rubric, errList
+}
+
+// WeightedMean returns the mean of the criterion scores weighted by criterion
+// name (case-insensitive). Criteria without a weight count once. Returns 0 if
+// the weights sum to zero.
+func (r Rubric) WeightedMean(weights map[string]float64) float64 {
+	lowered := make(map[string]float64, len(weights))
+	for name, weight := range weights {
+		lowered[strings.ToLower(name)] = weight
+	}
+	sum, total := 0.0, 0.0
+	for _, criterion := range r.Criteria {
+		weight, ok := lowered[strings.ToLower(criterion.Criterion)]
+		if !ok {
+			weight = 1
+		}
+		sum += weight * criterion.Score
+		total += weight
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// scorePattern matches a score written as a number, optionally out of a
+// maximum: "4", "4/5", "4 out of 5", "4.5 / 10".
+var scorePattern = regexp.MustCompile(`(?i)^([-+]?\d+(?:\.\d+)?)\s*(?:(?:/|out\s+of|of)\s*\d+(?:\.\d+)?)?\s*(?:points?)?$`)
+
+// parseScoreValue parses a score as a float64, dropping any "/ max" part.
+func parseScoreValue(p *Parser, label Label, value string) (interface{}, error) {
+	cleaned := strings.TrimRight(strings.Trim(unwrapValue(value), "*_"), ".")
+	m := scorePattern.FindStringSubmatch(strings.TrimSpace(cleaned))
+	if m == nil {
+		return nil, errors.New("'" + cleaned + "' is not a score")
+	}
+	return parseNumberValue(p, label, m[1])
+}
@@ -0,0 +1,44 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRubric checks parsing, validation and aggregation of judging outputs.
+func TestRubric(t *testing.T) {
+	parser, err := NewRubricParser(1, 5)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Criterion: Accuracy\nScore: **4/5**\nJustification: One date is wrong.\n" +
+		"Criterion: Clarity\nScore: 2 out of 5\nReasoning: Rambling.\n" +
+		"Criterion: Tone\nScore: 7\n" +
+		"Criterion: Safety\nScore: 5\nVerdict: Pass"
+	rubric, errList := parser.Parse(input)
+	expectedErrors := []string{"'score' must be at most 5, got 7"}
+	if !reflect.DeepEqual(errList, expectedErrors) {
+		t.Errorf("errors mismatch.\nGot: %#v\nExpected: %#v", errList, expectedErrors)
+	}
+	expected := Rubric{
+		Criteria: []CriterionScore{
+			{Criterion: "Accuracy", Score: 4, Justification: "One date is wrong."},
+			{Criterion: "Clarity", Score: 2, Justification: "Rambling."},
+			{Criterion: "Safety", Score: 5, Verdict: "Pass"},
+		},
+		Total:   11,
+		Mean:    11.0 / 3,
+		Verdict: "Pass",
+	}
+	if !reflect.DeepEqual(rubric, expected) {
+		t.Errorf("rubric mismatch.\nGot: %#v\nExpected: %#v", rubric, expected)
+	}
+	if mean := rubric.WeightedMean(map[string]float64{"accuracy": 2, "Clarity": 0}); mean != 13.0/3 {
+		t.Errorf("unexpected weighted mean: %v", mean)
+	}
+
+	_, errList = parser.Parse("Criterion: Accuracy\nScore: excellent")
+	if !reflect.DeepEqual(errList, []string{"Invalid score in 'score': 'excellent' is not a score"}) {
+		t.Errorf("unexpected errors: %#v", errList)
+	}
+}
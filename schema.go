@@ -0,0 +1,307 @@
+package arkaineparser
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// SchemaBuilder builds a label set fluently, as an alternative to writing
+// []Label literals:
+//
+//	labels, err := NewSchema().
+//		Label("Action").RequiredWith("Action Input").
+//		JSON("Action Input").
+//		BlockStart("Task").
+//		Build()
+//
+// Methods without a name argument modify the most recently added or named
+// label. Mistakes are collected and reported together by Build.
+type SchemaBuilder struct {
+	labels  []Label
+	current int // Index of the label later modifiers apply to, or -1
+	errs    []error
+}
+
+// NewSchema creates an empty SchemaBuilder.
+func NewSchema() *SchemaBuilder {
+	return &SchemaBuilder{current: -1}
+}
+
+// Label adds a new text label and makes it the current label.
+// Adding the same name twice is reported by Build.
+func (b *SchemaBuilder) Label(name string) *SchemaBuilder {
+	b.labels = append(b.labels, Label{Name: name})
+	b.current = len(b.labels) - 1
+	return b
+}
+
+// JSON marks the named label as JSON, adding it if it does not exist yet,
+// and makes it the current label.
+func (b *SchemaBuilder) JSON(name string) *SchemaBuilder {
+	b.use(name).IsJSON = true
+	return b
+}
+
+// BlockStart marks the named label as the block start label, adding it if it
+// does not exist yet, and makes it the current label.
+func (b *SchemaBuilder) BlockStart(name string) *SchemaBuilder {
+	b.use(name).IsBlockStart = true
+	return b
+}
+
+// Required marks the current label as required.
+func (b *SchemaBuilder) Required() *SchemaBuilder {
+	if label := b.currentLabel("Required"); label != nil {
+		label.Required = true
+	}
+	return b
+}
+
+// RequiredWith adds dependencies to the current label.
+func (b *SchemaBuilder) RequiredWith(names ...string) *SchemaBuilder {
+	if label := b.currentLabel("RequiredWith"); label != nil {
+		label.RequiredWith = append(label.RequiredWith, names...)
+	}
+	return b
+}
+
+// DataType sets the data type of the current label.
+func (b *SchemaBuilder) DataType(dataType string) *SchemaBuilder {
+	if label := b.currentLabel("DataType"); label != nil {
+		label.DataType = dataType
+	}
+	return b
+}
+
+// Aliases adds alternative names for the current label.
+func (b *SchemaBuilder) Aliases(aliases ...string) *SchemaBuilder {
+	if label := b.currentLabel("Aliases"); label != nil {
+		label.Aliases = append(label.Aliases, aliases...)
+	}
+	return b
+}
+
+// Range sets inclusive bounds on the numeric values of the current label.
+func (b *SchemaBuilder) Range(min, max float64) *SchemaBuilder {
+	if label := b.currentLabel("Range"); label != nil {
+		label.Min, label.Max = &min, &max
+	}
+	return b
+}
+
+// Min sets an inclusive lower bound on the numeric values of the current label.
+func (b *SchemaBuilder) Min(min float64) *SchemaBuilder {
+	if label := b.currentLabel("Min"); label != nil {
+		label.Min = &min
+	}
+	return b
+}
+
+// Max sets an inclusive upper bound on the numeric values of the current label.
+func (b *SchemaBuilder) Max(max float64) *SchemaBuilder {
+	if label := b.currentLabel("Max"); label != nil {
+		label.Max = &max
+	}
+	return b
+}
+
+// MatchPattern sets a regular expression the whole value of the current label must match.
+func (b *SchemaBuilder) MatchPattern(pattern string) *SchemaBuilder {
+	if label := b.currentLabel("MatchPattern"); label != nil {
+		label.MatchPattern = pattern
+	}
+	return b
+}
+
+// Choices restricts the values of the current label to a fixed set.
+func (b *SchemaBuilder) Choices(choices ...string) *SchemaBuilder {
+	if label := b.currentLabel("Choices"); label != nil {
+		label.Choices = append(label.Choices, choices...)
+	}
+	return b
+}
+
+// FenceLanguages routes code fences tagged with any of languages to the current label.
+func (b *SchemaBuilder) FenceLanguages(languages ...string) *SchemaBuilder {
+	if label := b.currentLabel("FenceLanguages"); label != nil {
+		label.FenceLanguages = append(label.FenceLanguages, languages...)
+	}
+	return b
+}
+
+// Unordered marks the order of the current label's values as meaningless.
+func (b *SchemaBuilder) Unordered() *SchemaBuilder {
+	if label := b.currentLabel("Unordered"); label != nil {
+		label.Unordered = true
+	}
+	return b
+}
+
+// Group adds the current label to the named group of labels assembled into
+// repeated records.
+func (b *SchemaBuilder) Group(group string) *SchemaBuilder {
+	if label := b.currentLabel("Group"); label != nil {
+		label.Group = group
+	}
+	return b
+}
+
+// System marks the current label as written only by the system, never the model.
+func (b *SchemaBuilder) System() *SchemaBuilder {
+	if label := b.currentLabel("System"); label != nil {
+		label.Source = SourceSystem
+	}
+	return b
+}
+
+// EndMarker sets the end marker of the current label.
+func (b *SchemaBuilder) EndMarker(marker string) *SchemaBuilder {
+	if label := b.currentLabel("EndMarker"); label != nil {
+		label.EndMarker = marker
+	}
+	return b
+}
+
+// Build validates the schema and returns a copy of its labels.
+// Returns an error describing every problem found: misuse of the builder and
+// any inconsistency NewParser would reject.
+func (b *SchemaBuilder) Build() ([]Label, error) {
+	errList := append([]error(nil), b.errs...)
+	if err := validateLabels(b.labels); err != nil {
+		errList = append(errList, err)
+	}
+	if len(errList) > 0 {
+		return nil, errors.Join(errList...)
+	}
+	return copyLabels(b.labels), nil
+}
+
+// Parser builds the schema and creates a Parser from it.
+func (b *SchemaBuilder) Parser() (*Parser, error) {
+	labels, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	return NewParser(labels)
+}
+
+// use returns the named label, adding it if needed, and makes it current.
+func (b *SchemaBuilder) use(name string) *Label {
+	for i := range b.labels {
+		if strings.EqualFold(b.labels[i].Name, name) {
+			b.current = i
+			return &b.labels[i]
+		}
+	}
+	b.Label(name)
+	return &b.labels[b.current]
+}
+
+// currentLabel returns the label modifiers apply to, recording an error when
+// method is called before any label was added.
+func (b *SchemaBuilder) currentLabel(method string) *Label {
+	if b.current < 0 {
+		b.errs = append(b.errs, errors.New(method+" called before any label was added"))
+		return nil
+	}
+	return &b.labels[b.current]
+}
+
+// validateLabels checks a label set for empty names, duplicate names, aliases
+// colliding with other names or aliases, RequiredWith targets that are not
+// defined, unknown data types, sources or EmptyJSON policies, Min above Max,
+// invalid MatchPatterns, empty Choices, empty or shared FenceLanguages, groups
+// named like a label, and multiple block start labels.
+// Returns nil or an error joining every problem found.
+func validateLabels(labels []Label) error {
+	var errList []error
+	// Map every lowercase name and alias to the label that declared it
+	defined := make(map[string]string)
+	define := func(name, owner, kind string) {
+		if name == "" {
+			errList = append(errList, errors.New("Label "+kind+"s must not be empty"))
+			return
+		}
+		if existing, taken := defined[name]; taken {
+			if existing == owner && kind == "name" {
+				errList = append(errList, errors.New("Label '"+name+"' is defined more than once"))
+			} else {
+				errList = append(errList, errors.New("Label "+kind+" '"+name+"' of '"+owner+"' collides with label '"+existing+"'"))
+			}
+			return
+		}
+		defined[name] = owner
+	}
+	blockStarts := 0
+	fences := make(map[string]string) // Fence languages to the label claiming them
+	for _, label := range labels {
+		name := strings.ToLower(strings.TrimSpace(label.Name))
+		define(name, name, "name")
+		if name == "" {
+			continue
+		}
+		for _, alias := range label.Aliases {
+			define(strings.ToLower(strings.TrimSpace(alias)), name, "alias")
+		}
+		if !knownDataType(strings.ToLower(strings.TrimSpace(label.DataType))) {
+			errList = append(errList, errors.New("Label '"+name+"' has unknown data type '"+label.DataType+"'"))
+		}
+		switch label.Source {
+		case "", SourceModel, SourceSystem:
+		default:
+			errList = append(errList, errors.New("Label '"+name+"' has unknown source '"+string(label.Source)+"'"))
+		}
+		if label.MatchPattern != "" {
+			if _, err := regexp.Compile(anchorPattern(label.MatchPattern)); err != nil {
+				errList = append(errList, errors.New("Label '"+name+"' has invalid MatchPattern: "+err.Error()))
+			}
+		}
+		for _, choice := range label.Choices {
+			if choiceKey(choice) == "" {
+				errList = append(errList, errors.New("Label '"+name+"' has an empty choice"))
+			}
+		}
+		for _, language := range label.FenceLanguages {
+			language = strings.ToLower(strings.TrimSpace(language))
+			if language == "" {
+				errList = append(errList, errors.New("Label '"+name+"' has an empty fence language"))
+			} else if owner, taken := fences[language]; taken {
+				errList = append(errList, errors.New("Fence language '"+language+"' of '"+name+"' is claimed by label '"+owner+"'"))
+			} else {
+				fences[language] = name
+			}
+		}
+		if label.Min != nil && label.Max != nil && *label.Min > *label.Max {
+			errList = append(errList, errors.New("Label '"+name+"' has Min greater than Max"))
+		}
+		switch label.EmptyJSON {
+		case "", EmptyJSONObject, EmptyJSONNil, EmptyJSONString, EmptyJSONError:
+		default:
+			errList = append(errList, errors.New("Label '"+name+"' has unknown EmptyJSON policy '"+string(label.EmptyJSON)+"'"))
+		}
+		if label.IsBlockStart {
+			blockStarts++
+		}
+	}
+	// Groups become result keys, so they must not collide with labels
+	for _, label := range labels {
+		group := strings.ToLower(strings.TrimSpace(label.Group))
+		if owner, taken := defined[group]; group != "" && taken {
+			errList = append(errList, errors.New("Label group '"+group+"' of '"+strings.ToLower(strings.TrimSpace(label.Name))+"' collides with label '"+owner+"'"))
+		}
+	}
+	for _, label := range labels {
+		for _, dep := range label.RequiredWith {
+			key := strings.ToLower(strings.TrimSpace(dep))
+			// A dotted group such as "action" stands for its nested labels ("action.name")
+			if _, ok := defined[key]; !ok && !isLabelGroup(key, labels) {
+				errList = append(errList, wrapError(ErrDependency, "Label '"+strings.ToLower(label.Name)+"' requires undefined label '"+dep+"'"))
+			}
+		}
+	}
+	if blockStarts > 1 {
+		errList = append(errList, ErrTooManyBlockStarts)
+	}
+	return errors.Join(errList...)
+}
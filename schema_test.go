@@ -0,0 +1,100 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSchemaBuilder checks that the builder produces the equivalent label literals.
+func TestSchemaBuilder(t *testing.T) {
+	labels, err := NewSchema().
+		Label("Action").RequiredWith("Action Input").
+		JSON("Action Input").
+		BlockStart("Task").
+		Label("Result").Required().
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Label{
+		{Name: "Action", RequiredWith: []string{"Action Input"}},
+		{Name: "Action Input", IsJSON: true},
+		{Name: "Task", IsBlockStart: true},
+		{Name: "Result", Required: true},
+	}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("labels mismatch.\nGot: %#v\nExpected: %#v", labels, expected)
+	}
+}
+
+// TestSchemaBuilderValidation checks that mistakes are reported at build time.
+func TestSchemaBuilderValidation(t *testing.T) {
+	_, err := NewSchema().
+		Required().
+		Label("Action").RequiredWith("Action Inptu").
+		Label("action").
+		BlockStart("Task").BlockStart("Step").
+		Build()
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, problem := range []string{
+		"Required called before any label was added",
+		"Label 'action' is defined more than once",
+		"Label 'action' requires undefined label 'Action Inptu'",
+		"Only one block start label is allowed",
+	} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected error to mention %q, got:\n%v", problem, err)
+		}
+	}
+}
+
+// TestNewParserValidation checks that NewParser rejects inconsistent label sets.
+func TestNewParserValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  []Label
+		problem string
+	}{
+		{"typo in RequiredWith", []Label{{Name: "Tool", RequiredWith: []string{"Argumnets"}}, {Name: "Arguments"}},
+			"Label 'tool' requires undefined label 'Argumnets'"},
+		{"duplicate name", []Label{{Name: "Tool"}, {Name: "tool"}}, "Label 'tool' is defined more than once"},
+		{"empty name", []Label{{Name: " "}}, "Label names must not be empty"},
+		{"alias collision", []Label{{Name: "Tool", Aliases: []string{"Call"}}, {Name: "Command", Aliases: []string{"call"}}},
+			"Label alias 'call' of 'command' collides with label 'tool'"},
+		{"unknown policy", []Label{{Name: "Arguments", IsJSON: true, EmptyJSON: "zero"}},
+			"Label 'arguments' has unknown EmptyJSON policy 'zero'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(tt.labels)
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("expected error mentioning %q, got: %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+// TestAliases checks that aliases match and are reported under the canonical name.
+func TestAliases(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Tool", Aliases: []string{"Command"}, RequiredWith: []string{"Args"}},
+		{Name: "Arguments", Aliases: []string{"Args"}, IsJSON: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	result, errList := parser.Parse("Command: search\nArgs: {\"q\": \"go\"}")
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	expected := map[string]interface{}{
+		"tool":      "search",
+		"arguments": map[string]interface{}{"q": "go"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", result, expected)
+	}
+}
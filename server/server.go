@@ -0,0 +1,205 @@
+// Package server serves parsed streams over HTTP, so front-ends consume
+// structured events instead of raw tokens. A Handler accepts an upstream token
+// stream as the body of a POST request, either as raw text or as an upstream
+// Server-Sent Events stream such as an OpenAI or Anthropic streaming
+// completion, and re-emits it as Server-Sent Events: label_start,
+// label_delta, label_complete, block_complete, invalid_json and aborted, as
+// reported by a Stream, then a final done event holding the parse result.
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+)
+
+// EventDone is the name of the last event of every response.
+const EventDone = "done"
+
+// Done is the data of the done event: the result of parsing the whole
+// stream, as Stream.Close reports it.
+type Done struct {
+	Result   arkaineparser.Result       `json:"result"`
+	Errors   []arkaineparser.ParseError `json:"errors"`
+	Warnings []arkaineparser.ParseError `json:"warnings,omitempty"`
+	Aborted  bool                       `json:"aborted,omitempty"` // The stream stopped early (see WithEarlyAbort)
+}
+
+// TokenFunc extracts the text of one upstream Server-Sent Event from its data,
+// reporting false for events carrying no text.
+type TokenFunc func(data string) (string, bool)
+
+// Handler streams parsed events for the token streams posted to it.
+type Handler struct {
+	parser *arkaineparser.Parser
+	opts   []arkaineparser.StreamOption
+	token  TokenFunc
+}
+
+// Option configures a Handler in NewHandler.
+type Option func(*Handler)
+
+// WithStreamOptions applies opts, such as arkaineparser.WithEarlyAbort, to the
+// Stream of every request.
+func WithStreamOptions(opts ...arkaineparser.StreamOption) Option {
+	return func(h *Handler) {
+		h.opts = append(h.opts, opts...)
+	}
+}
+
+// WithTokenFunc sets how the text of upstream events is extracted (default
+// Token).
+func WithTokenFunc(token TokenFunc) Option {
+	return func(h *Handler) {
+		h.token = token
+	}
+}
+
+// NewHandler creates a Handler parsing with p.
+func NewHandler(p *arkaineparser.Parser, opts ...Option) *Handler {
+	h := &Handler{parser: p, token: Token}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// ServeHTTP reads the posted token stream as it arrives and writes the events
+// of parsing it. A body of Content-Type text/event-stream is read as upstream
+// events, others as raw text. Parsing stops when the client goes away.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method must be POST", http.StatusMethodNotAllowed)
+		return
+	}
+	// HTTP/1.1 servers read the whole body before writing unless told
+	// otherwise; HTTP/2 needs no telling and reports ErrNotSupported
+	if err := http.NewResponseController(w).EnableFullDuplex(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		http.Error(w, "Cannot stream the response: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	stream := h.parser.NewStream(h.opts...)
+	write := func(chunk string) bool {
+		for _, event := range stream.Write(chunk) {
+			writeEvent(w, string(event.Kind), event)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return r.Context().Err() == nil && !stream.Aborted()
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		h.readEvents(r.Body, write)
+	} else {
+		readRaw(r.Body, write)
+	}
+
+	events, details := stream.Close()
+	for _, event := range events {
+		writeEvent(w, string(event.Kind), event)
+	}
+	writeEvent(w, EventDone, Done{
+		Result:   details.Result,
+		Errors:   details.Errors,
+		Warnings: details.Warnings,
+		Aborted:  stream.Aborted(),
+	})
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// readRaw hands body to write in the chunks it arrives in, until write
+// returns false or the body ends.
+func readRaw(body io.Reader, write func(string) bool) {
+	buffer := make([]byte, 4096)
+	for {
+		n, err := body.Read(buffer)
+		if n > 0 && !write(string(buffer[:n])) {
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readEvents hands the text of each upstream event of body to write, until
+// write returns false, the body ends or an event's data is "[DONE]".
+func (h *Handler) readEvents(body io.Reader, write func(string) bool) {
+	reader := bufio.NewReader(body)
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && len(data) > 0:
+			// A blank line dispatches the event
+			payload := strings.Join(data, "\n")
+			data = nil
+			if payload == "[DONE]" {
+				return
+			}
+			if text, ok := h.token(payload); ok && !write(text) {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Token extracts the text of an upstream event: the delta content of an
+// OpenAI chat completion chunk, the text of an Anthropic content_block_delta,
+// or else the data itself when it is not JSON.
+func Token(data string) (string, bool) {
+	var chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content string `json:"content"`
+			} `json:"delta"`
+			Text string `json:"text"`
+		} `json:"choices"`
+		Type  string `json:"type"`
+		Delta struct {
+			Text string `json:"text"`
+		} `json:"delta"`
+	}
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return data, true
+	}
+	if chunk.Type == "content_block_delta" {
+		return chunk.Delta.Text, chunk.Delta.Text != ""
+	}
+	if len(chunk.Choices) > 0 {
+		text := chunk.Choices[0].Delta.Content + chunk.Choices[0].Text
+		return text, text != ""
+	}
+	return "", false
+}
+
+// writeEvent writes a named Server-Sent Event with data encoded as JSON.
+func writeEvent(w io.Writer, name string, data interface{}) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		encoded, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, encoded)
+}
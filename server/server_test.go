@@ -0,0 +1,227 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	arkaineparser "github.com/hlfshell/go-arkaine-parser"
+)
+
+// sseEvent is a received Server-Sent Event.
+type sseEvent struct {
+	name string
+	data string
+}
+
+// post sends body to a Handler and returns the events of its response.
+func post(t *testing.T, handler http.Handler, contentType, body string) []sseEvent {
+	t.Helper()
+	return postReader(t, handler, contentType, strings.NewReader(body))
+}
+
+// postReader sends the body read from r to a Handler and returns the events
+// of its response.
+func postReader(t *testing.T, handler http.Handler, contentType string, r io.Reader) []sseEvent {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, "/", r)
+	request.Header.Set("Content-Type", contentType)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected response: %d %v", recorder.Code, recorder.Header())
+	}
+	var events []sseEvent
+	scanner := bufio.NewScanner(recorder.Body)
+	var current sseEvent
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, current)
+			current = sseEvent{}
+		}
+	}
+	return events
+}
+
+// names returns the names of events.
+func names(events []sseEvent) []string {
+	var list []string
+	for _, event := range events {
+		list = append(list, event.name)
+	}
+	return list
+}
+
+// TestHandlerRaw checks that a raw text body is re-emitted as parsed events.
+func TestHandlerRaw(t *testing.T) {
+	parser, err := arkaineparser.NewParser([]arkaineparser.Label{
+		{Name: "Task", IsBlockStart: true},
+		{Name: "Owner", Required: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	events := post(t, NewHandler(parser), "text/plain", "Task: write\nOwner: me\n")
+	expected := []string{
+		"label_start", "label_delta", "label_complete",
+		"label_start", "label_delta", "label_complete", "block_complete", "done",
+	}
+	if !reflect.DeepEqual(names(events), expected) {
+		t.Fatalf("unexpected events: %v", names(events))
+	}
+	var delta arkaineparser.StreamEvent
+	json.Unmarshal([]byte(events[1].data), &delta)
+	if delta != (arkaineparser.StreamEvent{Kind: arkaineparser.StreamLabelDelta, Label: "task", Text: "write"}) {
+		t.Errorf("unexpected delta: %s", events[1].data)
+	}
+	var done Done
+	json.Unmarshal([]byte(events[len(events)-1].data), &done)
+	if done.Result["owner"] != "me" || len(done.Errors) != 0 || done.Aborted {
+		t.Errorf("unexpected done: %s", events[len(events)-1].data)
+	}
+}
+
+// TestHandlerSplitRunes checks that runes cut across reads arrive whole in
+// the deltas.
+func TestHandlerSplitRunes(t *testing.T) {
+	parser, _ := arkaineparser.NewParser([]arkaineparser.Label{{Name: "Answer"}})
+	events := postReader(t, NewHandler(parser), "text/plain", iotest.OneByteReader(strings.NewReader("Answer: café ✓ ok\n")))
+	var text strings.Builder
+	for _, event := range events {
+		if event.name != "label_delta" {
+			continue
+		}
+		var delta arkaineparser.StreamEvent
+		json.Unmarshal([]byte(event.data), &delta)
+		if strings.ContainsRune(delta.Text, '\uFFFD') {
+			t.Errorf("unexpected delta with a cut rune: %q", delta.Text)
+		}
+		text.WriteString(delta.Text)
+	}
+	if text.String() != "café ✓ ok" {
+		t.Errorf("unexpected deltas: %q", text.String())
+	}
+}
+
+// TestHandlerUpstreamEvents checks OpenAI and Anthropic upstream streams.
+func TestHandlerUpstreamEvents(t *testing.T) {
+	parser, _ := arkaineparser.NewParser([]arkaineparser.Label{{Name: "Answer"}})
+	openAI := "data: {\"choices\": [{\"delta\": {\"role\": \"assistant\"}}]}\n\n" +
+		"data: {\"choices\": [{\"delta\": {\"content\": \"Answer: \"}}]}\n\n" +
+		"data: {\"choices\": [{\"delta\": {\"content\": \"42\"}}]}\n\n" +
+		"data: [DONE]\n\n" +
+		"data: {\"choices\": [{\"delta\": {\"content\": \" ignored\"}}]}\n\n"
+	anthropic := "event: message_start\ndata: {\"type\": \"message_start\"}\n\n" +
+		"event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"delta\": {\"type\": \"text_delta\", \"text\": \"Answer: 4\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\": \"content_block_delta\", \"delta\": {\"type\": \"text_delta\", \"text\": \"2\"}}\n\n"
+	for name, body := range map[string]string{"openai": openAI, "anthropic": anthropic} {
+		events := post(t, NewHandler(parser), "text/event-stream; charset=utf-8", body)
+		var done Done
+		json.Unmarshal([]byte(events[len(events)-1].data), &done)
+		if done.Result["answer"] != "42" {
+			t.Errorf("%s: unexpected result: %#v", name, done.Result)
+		}
+	}
+}
+
+// TestHandlerEarlyAbort checks that stream options apply and abort the read.
+func TestHandlerEarlyAbort(t *testing.T) {
+	parser, _ := arkaineparser.NewParser([]arkaineparser.Label{{Name: "Action"}, {Name: "Observation"}})
+	abort := arkaineparser.WithEarlyAbort(func(partial arkaineparser.Result) bool { return partial["action"] != "" })
+	events := post(t, NewHandler(parser, WithStreamOptions(abort)), "text/plain", "Action: search\nObservation: invented\nand more\n")
+	var done Done
+	json.Unmarshal([]byte(events[len(events)-1].data), &done)
+	// The action completes when the observation starts, and the read stops there
+	if !done.Aborted || done.Result["observation"] != "invented" || names(events)[len(events)-2] != "aborted" {
+		t.Errorf("unexpected events: %v, %#v", names(events), done)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	recorder := httptest.NewRecorder()
+	NewHandler(parser).ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", recorder.Code)
+	}
+}
+
+// TestHandlerFullDuplex checks that events arrive over a real connection
+// while the body is still being uploaded.
+func TestHandlerFullDuplex(t *testing.T) {
+	parser, _ := arkaineparser.NewParser([]arkaineparser.Label{{Name: "Thought"}, {Name: "Action"}})
+	server := httptest.NewServer(NewHandler(parser))
+	defer server.Close()
+
+	body, upload := io.Pipe()
+	started := make(chan struct{})
+	late := make(chan bool, 1)
+	go func() {
+		upload.Write([]byte("Thought: hello\n"))
+		// The rest is only sent once the first event has arrived
+		select {
+		case <-started:
+			late <- false
+		case <-time.After(2 * time.Second):
+			late <- true
+		}
+		upload.Write([]byte("Action: go\n"))
+		upload.Close()
+	}()
+	response, err := http.Post(server.URL, "text/plain", body)
+	if err != nil {
+		t.Fatalf("failed to post: %v", err)
+	}
+	defer response.Body.Close()
+
+	scanner := bufio.NewScanner(response.Body)
+	var done Done
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first && strings.HasPrefix(line, "event: label_start") {
+			first = false
+			close(started)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			json.Unmarshal([]byte(data), &done)
+		}
+	}
+	if <-late {
+		t.Error("expected events before the upload ended")
+	}
+	if done.Result["thought"] != "hello" || done.Result["action"] != "go" {
+		t.Errorf("unexpected result: %#v", done.Result)
+	}
+}
+
+// TestToken checks the text extracted from upstream events.
+func TestToken(t *testing.T) {
+	tests := []struct {
+		data string
+		text string
+		ok   bool
+	}{
+		{`{"choices": [{"delta": {"content": "hi"}}]}`, "hi", true},
+		{`{"choices": [{"text": "hi"}]}`, "hi", true},
+		{`{"type": "content_block_delta", "delta": {"text": "hi"}}`, "hi", true},
+		{`{"type": "message_stop"}`, "", false},
+		{"plain text", "plain text", true},
+	}
+	for _, test := range tests {
+		if text, ok := Token(test.data); text != test.text || ok != test.ok {
+			t.Errorf("%s: got %q, %v", test.data, text, ok)
+		}
+	}
+}
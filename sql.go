@@ -0,0 +1,181 @@
+package arkaineparser
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// SQLValidator checks, and may rewrite, a normalized statement of a "sql"
+// label, e.g. to reject writes or to add a LIMIT clause. Returns the statement
+// to keep, or an error reported as a KindType error of the label.
+type SQLValidator func(statement string) (string, error)
+
+// sqlStartPattern matches the first keyword of a statement at the start of a
+// line, used to skip prose the model wrote before the query.
+var sqlStartPattern = regexp.MustCompile(`(?im)^\s*(SELECT|WITH|INSERT|UPDATE|DELETE|MERGE|REPLACE|UPSERT|CREATE|ALTER|DROP|TRUNCATE|GRANT|REVOKE|EXPLAIN|SHOW|DESCRIBE|VALUES|PRAGMA|CALL)\b`)
+
+// parseSQLValue extracts a single SQL statement from a value, normalizes it
+// and runs it through the parser's SQL validators in order.
+func parseSQLValue(p *Parser, label Label, value string) (interface{}, error) {
+	// A fence left in the value holds the statement
+	if start := strings.Index(value, "```"); start >= 0 {
+		value = value[start+3:]
+		if end := strings.Index(value, "```"); end >= 0 {
+			value = value[:end]
+		}
+		// Drop the language tag of the opening fence
+		if newline := strings.IndexByte(value, '\n'); newline >= 0 && !strings.ContainsAny(value[:newline], " \t") {
+			value = value[newline+1:]
+		}
+	}
+	if loc := sqlStartPattern.FindStringIndex(value); loc != nil {
+		value = value[loc[0]:]
+	}
+	statement, err := normalizeSQL(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, validate := range p.sqlValidators {
+		if statement, err = validate(statement); err != nil {
+			return nil, err
+		}
+	}
+	return statement, nil
+}
+
+// normalizeSQL removes comments, collapses whitespace outside of quoted text,
+// and drops the terminating semicolon. Returns an error for empty input,
+// unterminated quotes, or more than one statement.
+func normalizeSQL(value string) (string, error) {
+	var b strings.Builder
+	runes := []rune(value)
+	space, ended := false, false
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			// Line comment
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			space = true
+			continue
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// Block comment
+			end := i + 2
+			for end+1 < len(runes) && (runes[end] != '*' || runes[end+1] != '/') {
+				end++
+			}
+			if end+1 >= len(runes) {
+				return "", errors.New("unterminated comment")
+			}
+			i = end + 1
+			space = true
+			continue
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		}
+		if ended {
+			if r == ';' {
+				continue
+			}
+			return "", errors.New("multiple statements are not allowed")
+		}
+		if r == ';' {
+			ended = true
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		if r == '\'' || r == '"' || r == '`' {
+			// Copy quoted text verbatim; a doubled quote is an escaped quote
+			b.WriteRune(r)
+			closed := false
+			for i++; i < len(runes); i++ {
+				b.WriteRune(runes[i])
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						i++
+						b.WriteRune(r)
+						continue
+					}
+					closed = true
+					break
+				}
+			}
+			if !closed {
+				return "", errors.New("unterminated quote")
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "", errors.New("no SQL statement found")
+	}
+	return b.String(), nil
+}
+
+// sqlWords returns the uppercase words of a normalized statement that are
+// outside quoted text, i.e. its keywords and bare identifiers.
+func sqlWords(statement string) []string {
+	var words []string
+	var quote rune
+	word := strings.Builder{}
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, strings.ToUpper(word.String()))
+			word.Reset()
+		}
+	}
+	for _, r := range statement {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			flush()
+			quote = r
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
+
+// readOnlySQLStarts are the keywords a read-only statement may start with.
+var readOnlySQLStarts = []string{"SELECT", "WITH", "EXPLAIN", "SHOW", "DESCRIBE", "VALUES"}
+
+// sqlWriteKeywords are keywords that modify data, schema or permissions.
+var sqlWriteKeywords = []string{
+	"INSERT", "UPDATE", "DELETE", "MERGE", "REPLACE", "UPSERT", "CREATE", "ALTER", "DROP",
+	"TRUNCATE", "GRANT", "REVOKE", "ATTACH", "DETACH", "COPY", "VACUUM", "INTO",
+}
+
+// ReadOnlySQL is a SQLValidator rejecting statements that may modify the
+// database: the statement must start with SELECT, WITH, EXPLAIN, SHOW,
+// DESCRIBE or VALUES and must not use a write keyword such as INSERT, DROP or
+// SELECT ... INTO anywhere outside quoted text. It is a guardrail against
+// model mistakes; run untrusted queries with a read-only database role too.
+func ReadOnlySQL(statement string) (string, error) {
+	words := sqlWords(statement)
+	if len(words) == 0 || !slices.Contains(readOnlySQLStarts, words[0]) {
+		return "", errors.New("only read-only statements are allowed")
+	}
+	for _, word := range words {
+		if slices.Contains(sqlWriteKeywords, word) {
+			return "", errors.New("read-only statement must not use " + word)
+		}
+	}
+	return statement, nil
+}
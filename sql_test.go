@@ -0,0 +1,59 @@
+package arkaineparser
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSQLType checks extraction, normalization and validation of sql labels.
+func TestSQLType(t *testing.T) {
+	labels := []Label{{Name: "Query", DataType: "sql"}, {Name: "Thought"}}
+	parser, err := NewParser(labels)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	input := "Thought: count users\nQuery: Here is the query:\n```sql\nSELECT name, -- the user\n  count(*)\nFROM users  WHERE note = 'a  b;c' /* active */ AND active;\n```"
+	result, errList := parser.Parse(input)
+	if len(errList) > 0 {
+		t.Fatalf("unexpected errors: %v", errList)
+	}
+	if result["query"] != "SELECT name, count(*) FROM users WHERE note = 'a  b;c' AND active" {
+		t.Errorf("unexpected statement: %q", result["query"])
+	}
+	_, errList = parser.Parse("Query: SELECT 1; DROP TABLE users")
+	if len(errList) != 1 || errList[0] != "Invalid sql in 'query': multiple statements are not allowed" {
+		t.Errorf("unexpected errors: %v", errList)
+	}
+
+	// Validators run in order and may rewrite the statement
+	limit := func(statement string) (string, error) {
+		if !strings.Contains(strings.ToUpper(statement), " LIMIT ") {
+			statement += " LIMIT 100"
+		}
+		return statement, nil
+	}
+	guarded, err := NewParser(labels, WithSQLValidator(ReadOnlySQL), WithSQLValidator(limit))
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	tests := []struct {
+		input    string
+		expected string
+		problem  string
+	}{
+		{"Query: select * from t where name = 'DROP'", "select * from t where name = 'DROP' LIMIT 100", ""},
+		{"Query: DELETE FROM t", "", "only read-only statements are allowed"},
+		{"Query: WITH x AS (DELETE FROM t RETURNING *) SELECT * FROM x", "", "read-only statement must not use DELETE"},
+		{"Query: SELECT * INTO copy FROM t", "", "read-only statement must not use INTO"},
+	}
+	for _, tt := range tests {
+		result, errList := guarded.Parse(tt.input)
+		if tt.problem == "" {
+			if len(errList) > 0 || result["query"] != tt.expected {
+				t.Errorf("unexpected result for %q: %q %v", tt.input, result["query"], errList)
+			}
+		} else if len(errList) != 1 || errList[0] != "Invalid sql in 'query': "+tt.problem {
+			t.Errorf("unexpected errors for %q: %v", tt.input, errList)
+		}
+	}
+}
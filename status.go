@@ -0,0 +1,97 @@
+package arkaineparser
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Statuses produced by "status" labels.
+const (
+	StatusDone       = "done"
+	StatusFailed     = "failed"
+	StatusPending    = "pending"
+	StatusInProgress = "in-progress"
+	StatusBlocked    = "blocked"
+	StatusSkipped    = "skipped"
+)
+
+// statusMarkers maps emoji and checkbox markers to the status they express.
+// Variation selectors are removed from values before matching.
+var statusMarkers = map[string]string{
+	"✅": StatusDone, "✔": StatusDone, "☑": StatusDone, "✓": StatusDone, "🟢": StatusDone,
+	"[x]": StatusDone, "[X]": StatusDone, "(x)": StatusDone,
+	"❌": StatusFailed, "✖": StatusFailed, "✗": StatusFailed, "✘": StatusFailed, "🔴": StatusFailed,
+	"☐": StatusPending, "⬜": StatusPending, "⏳": StatusPending, "🕒": StatusPending, "[ ]": StatusPending, "( )": StatusPending,
+	"🔄": StatusInProgress, "🚧": StatusInProgress, "🟡": StatusInProgress, "[~]": StatusInProgress, "[-]": StatusInProgress,
+	"⛔": StatusBlocked, "🚫": StatusBlocked,
+	"⏭": StatusSkipped,
+}
+
+// statusWords lists the words expressing each status, longest phrases first
+// so "not started" is read before "started".
+var statusWords = []struct {
+	phrase string
+	status string
+}{
+	{"not started", StatusPending}, {"not done", StatusPending}, {"not completed", StatusPending},
+	{"not complete", StatusPending}, {"not applicable", StatusSkipped}, {"in progress", StatusInProgress},
+	{"incomplete", StatusPending}, {"unfinished", StatusPending}, {"to do", StatusPending},
+	{"completed", StatusDone}, {"complete", StatusDone}, {"finished", StatusDone}, {"succeeded", StatusDone},
+	{"success", StatusDone}, {"passed", StatusDone}, {"done", StatusDone}, {"yes", StatusDone}, {"true", StatusDone},
+	{"failed", StatusFailed}, {"failure", StatusFailed}, {"error", StatusFailed}, {"broken", StatusFailed},
+	{"no", StatusPending}, {"false", StatusPending}, {"pending", StatusPending}, {"todo", StatusPending},
+	{"waiting", StatusPending}, {"ongoing", StatusInProgress}, {"working", StatusInProgress},
+	{"started", StatusInProgress}, {"wip", StatusInProgress}, {"blocked", StatusBlocked}, {"stuck", StatusBlocked},
+	{"skipped", StatusSkipped}, {"n/a", StatusSkipped},
+}
+
+// statusWordPattern splits a value into lowercase words, keeping "n/a" whole.
+var statusWordPattern = regexp.MustCompile(`[a-z]+(?:/[a-z]+)?`)
+
+// parseStatus reads the status a value expresses: markers (emoji and
+// checkboxes) take precedence over words, and the first word phrase found
+// wins. Returns an error if there is none or the markers disagree.
+func parseStatus(value string) (string, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), "\uFE0F", "")
+	status := ""
+	for marker, markerStatus := range statusMarkers {
+		if !strings.Contains(value, marker) {
+			continue
+		}
+		if status != "" && status != markerStatus {
+			return "", errors.New("conflicting status markers in '" + value + "'")
+		}
+		status = markerStatus
+	}
+	if status != "" {
+		return status, nil
+	}
+	words := " " + strings.Join(statusWordPattern.FindAllString(strings.ToLower(value), -1), " ") + " "
+	first, found := -1, ""
+	for _, word := range statusWords {
+		if idx := strings.Index(words, " "+word.phrase+" "); idx >= 0 && (first < 0 || idx < first) {
+			first, found = idx, word.status
+		}
+	}
+	if found == "" {
+		return "", errors.New("no status in '" + value + "'")
+	}
+	return found, nil
+}
+
+// parseStatusValue converts a "status" value to one of the Status constants,
+// e.g. "🚧 working on it" is StatusInProgress.
+func parseStatusValue(p *Parser, label Label, value string) (interface{}, error) {
+	return parseStatus(value)
+}
+
+// parseCheckboxValue converts a "checkbox" value to a bool: true only for a
+// done status ("[x]", "✅", "yes", "completed"), false for any other status.
+func parseCheckboxValue(p *Parser, label Label, value string) (interface{}, error) {
+	status, err := parseStatus(value)
+	if err != nil {
+		return nil, err
+	}
+	return status == StatusDone, nil
+}
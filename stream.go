@@ -0,0 +1,408 @@
+package arkaineparser
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hlfshell/go-arkaine-parser/parsec"
+)
+
+// StreamEventKind classifies a StreamEvent.
+type StreamEventKind string
+
+const (
+	StreamLabelStart    StreamEventKind = "label_start"    // A label was found
+	StreamLabelDelta    StreamEventKind = "label_delta"    // Text holds text appended to the label's value
+	StreamLabelComplete StreamEventKind = "label_complete" // Text holds the label's whole value, trimmed
+	// The value of a JSON label can no longer become valid JSON; Text says why
+	StreamInvalidJSON StreamEventKind = "invalid_json"
+	// A block is complete: its block start label appeared again or the stream
+	// ended; Label is the block start label
+	StreamBlockComplete StreamEventKind = "block_complete"
+	// The WithEarlyAbort predicate was satisfied; the rest of the output is ignored
+	StreamAborted StreamEventKind = "aborted"
+)
+
+// StreamEvent reports progress of a Stream.
+type StreamEvent struct {
+	Kind  StreamEventKind `json:"kind"`
+	Label string          `json:"label,omitempty"` // The (lowercase) label the event concerns
+	Text  string          `json:"text,omitempty"`
+}
+
+// Stream parses an output incrementally while the model generates it, so
+// orchestrators and UIs can act on labels as they arrive instead of waiting
+// for the whole completion. Labels are found line by line, as soon as a line
+// starts with one; the lines of other values are reported once they end, and
+// the lines of an unclosed JSON structure as they arrive. Labels match like
+// in Parse, but lines are taken as written, without its cleaning, so the
+// events are a preview: Close parses the whole text like ParseDetailed for
+// the final result. A Stream is not safe for concurrent use.
+type Stream struct {
+	p    *Parser
+	text strings.Builder // Everything written so far
+
+	line    string // The current, unfinished line
+	decided bool   // Whether the current line is known to start a label or continue the value
+	emitted int    // Bytes of the current line already reported
+	offset  int    // Byte offset of the value within the current line
+	label   string // The label whose value is streaming, or ""
+	value   strings.Builder
+	// Bracket and string state of the value, over its finished lines
+	structure parsec.Structure
+	json      *jsonValidator // Validates the value of a JSON label, or nil
+	closed    bool
+
+	inBlock bool                      // Whether a block has started and not completed
+	abort   func(partial Result) bool // Early abort predicate, or nil
+	due     bool                      // A value completed since the predicate last ran
+	aborted bool
+	// Whether the latest value of each label found so far is complete
+	completed map[string]bool
+}
+
+// StreamOption configures a Stream in NewStream.
+type StreamOption func(*Stream)
+
+// WithEarlyAbort stops the stream as soon as done reports that enough of the
+// output is known, e.g. once "Action" and "Action Input" are complete, since
+// the rest of a completion is often ignorable and costs latency and tokens.
+// done is called with the result of parsing the finished lines at the end of
+// each line where a value completed: the next label started, or the JSON of
+// an IsJSON label closed. Once it returns true, the current value is
+// completed, a StreamAborted event is reported and later writes are ignored:
+// the caller should cancel generation. Close then parses the text up to the
+// end of that line.
+func WithEarlyAbort(done func(partial Result) bool) StreamOption {
+	return func(s *Stream) {
+		s.abort = done
+	}
+}
+
+// NewStream starts parsing an output incrementally.
+func (p *Parser) NewStream(opts ...StreamOption) *Stream {
+	s := &Stream{p: p, completed: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Write feeds the next chunk of the output and returns the events it caused.
+// Chunks may be cut anywhere, even within a rune: deltas only ever carry whole
+// runes. Writes after Close or an early abort are ignored.
+func (s *Stream) Write(chunk string) []StreamEvent {
+	if s.closed || s.aborted {
+		return nil
+	}
+	var events []StreamEvent
+	for {
+		end := strings.IndexByte(chunk, '\n')
+		if end < 0 {
+			s.text.WriteString(chunk)
+			s.line += chunk
+			return s.advance(events, false)
+		}
+		s.text.WriteString(chunk[:end+1])
+		s.line += chunk[:end]
+		events = s.advance(events, true)
+		s.line, s.decided, s.emitted, s.offset = "", false, 0, 0
+		if s.abort != nil && s.due {
+			s.due = false
+			if s.abort(s.p.parseDetailed(s.text.String(), nil).Result) {
+				s.aborted = true
+				events = s.completeBlock(s.complete(events))
+				return append(events, StreamEvent{Kind: StreamAborted})
+			}
+		}
+		chunk = chunk[end+1:]
+	}
+}
+
+// Snapshot returns the best-known result at this point of the stream, e.g. for
+// UIs rendering fields as they fill in: the text so far is parsed like
+// ParseDetailed, except for an unfinished line held back because it may still
+// become a label. Values still streaming hold what has arrived of them; use
+// Complete to tell them apart. Snapshots are not recorded by WithJournal.
+func (s *Stream) Snapshot() Result {
+	text := s.text.String()
+	if !s.decided {
+		text = text[:len(text)-len(s.line)]
+	}
+	return s.p.parseDetailed(text, nil).Result
+}
+
+// Complete reports, for each label found so far, whether its latest value is
+// complete. A value completes when the next label starts or the stream ends.
+func (s *Stream) Complete() map[string]bool {
+	complete := make(map[string]bool, len(s.completed))
+	for label, done := range s.completed {
+		complete[label] = done
+	}
+	return complete
+}
+
+// Aborted reports whether the WithEarlyAbort predicate stopped the stream.
+func (s *Stream) Aborted() bool {
+	return s.aborted
+}
+
+// Close ends the output, completing the last value, and parses the whole text
+// like ParseDetailed.
+func (s *Stream) Close() ([]StreamEvent, Details) {
+	var events []StreamEvent
+	if !s.closed && !s.aborted {
+		if s.line != "" {
+			events = s.advance(events, true)
+			s.line, s.decided, s.emitted, s.offset = "", false, 0, 0
+		}
+		events = s.completeBlock(s.complete(events))
+	}
+	s.closed = true
+	return events, s.p.ParseDetailed(s.text.String())
+}
+
+// Text returns everything written so far.
+func (s *Stream) Text() string {
+	return s.text.String()
+}
+
+// advance reports what the current line adds, finished or not. A line that
+// does not start a label yet is held back until it ends, since more text may
+// still make it one, unless it lies inside an unclosed structure.
+func (s *Stream) advance(events []StreamEvent, finished bool) []StreamEvent {
+	line := strings.TrimSuffix(s.line, "\r")
+	if !s.decided {
+		if s.label != "" && s.structure.Open() {
+			s.decided = true
+		} else if match, ok := s.p.matchLabel(line); ok {
+			events = s.complete(events)
+			events = s.start(events, match.Label)
+			s.decided, s.emitted, s.offset = true, match.End, match.End
+		} else if !finished {
+			return events
+		} else if s.label == "" {
+			// Text before the first label is ignored
+			return events
+		} else {
+			s.decided = true
+		}
+		// A continuation line joins the value on a new line
+		if s.emitted == 0 && s.value.Len() > 0 {
+			events = s.delta(events, "\n")
+		}
+	}
+	if s.label == "" {
+		return events
+	}
+	// Whitespace before the value is trimmed
+	for s.value.Len() == 0 && s.emitted < len(line) && (line[s.emitted] == ' ' || line[s.emitted] == '\t') {
+		s.emitted++
+	}
+	// An unfinished line may end within a rune; its bytes wait for the rest
+	end := len(line)
+	if !finished {
+		end = completeRunes(line)
+	}
+	if s.emitted < end {
+		events = s.delta(events, line[s.emitted:end])
+		s.emitted = end
+	}
+	if finished {
+		s.structure.Feed(line[s.offset:])
+		if s.json != nil {
+			events = s.checkJSON(events, "\n")
+		}
+	}
+	return events
+}
+
+// completeRunes returns the length of text without an incomplete UTF-8
+// sequence at its end, as left by a chunk cut within a rune.
+func completeRunes(text string) int {
+	for i := len(text) - 1; i >= 0 && i >= len(text)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(text[i]) {
+			if !utf8.FullRuneInString(text[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(text)
+}
+
+// start opens the value of label, completing the current block first when
+// label starts a new one.
+func (s *Stream) start(events []StreamEvent, label string) []StreamEvent {
+	if s.p.labelMap[label].IsBlockStart {
+		events = s.completeBlock(events)
+		s.inBlock = true
+	}
+	s.label = label
+	s.completed[label] = false
+	s.value.Reset()
+	s.structure = parsec.Structure{}
+	s.json = nil
+	if s.p.labelMap[label].IsJSON {
+		s.structure.Track()
+		s.json = &jsonValidator{}
+	}
+	return append(events, StreamEvent{Kind: StreamLabelStart, Label: label})
+}
+
+// delta appends text to the current value.
+func (s *Stream) delta(events []StreamEvent, text string) []StreamEvent {
+	s.value.WriteString(text)
+	events = append(events, StreamEvent{Kind: StreamLabelDelta, Label: s.label, Text: text})
+	if s.json != nil && text != "\n" {
+		events = s.checkJSON(events, text)
+	}
+	return events
+}
+
+// checkJSON feeds text to the JSON validator, reporting its first problem.
+// The value completes once its top-level JSON value closes.
+func (s *Stream) checkJSON(events []StreamEvent, text string) []StreamEvent {
+	done := s.json.done
+	if problem := s.json.feed(text); problem != "" {
+		events = append(events, StreamEvent{Kind: StreamInvalidJSON, Label: s.label, Text: problem})
+	}
+	s.due = s.due || !done && s.json.done
+	return events
+}
+
+// complete closes the current value, if any.
+func (s *Stream) complete(events []StreamEvent) []StreamEvent {
+	if s.label == "" {
+		return events
+	}
+	if s.json != nil {
+		if problem := s.json.finish(); problem != "" {
+			events = append(events, StreamEvent{Kind: StreamInvalidJSON, Label: s.label, Text: problem})
+		}
+	}
+	events = append(events, StreamEvent{Kind: StreamLabelComplete, Label: s.label, Text: strings.TrimSpace(s.value.String())})
+	s.completed[s.label] = true
+	s.due = true
+	s.label = ""
+	s.value.Reset()
+	return events
+}
+
+// completeBlock reports the end of the current block, if any.
+func (s *Stream) completeBlock(events []StreamEvent) []StreamEvent {
+	if !s.inBlock {
+		return events
+	}
+	s.inBlock = false
+	for _, label := range s.p.labels {
+		if label.IsBlockStart {
+			events = append(events, StreamEvent{Kind: StreamBlockComplete, Label: label.Name})
+		}
+	}
+	return events
+}
+
+// jsonValidator checks a JSON value as it arrives, tracking bracket balance
+// and string state, and reports the first problem that no further text can
+// fix. Lines starting with a backtick, such as code fence markers, are
+// skipped, as cleaning would strip them.
+type jsonValidator struct {
+	stack    []rune // Unclosed '{' and '['
+	inString bool
+	escaped  bool
+	started  bool // The value has begun
+	scalar   bool // Inside a top-level number or literal
+	done     bool // The top-level value is complete
+	skipLine bool // Skipping the rest of a line starting with a backtick
+	failed   bool // A problem was reported
+}
+
+// feed advances the validator over text, returning the first problem found,
+// once, or "".
+func (v *jsonValidator) feed(text string) string {
+	if v.failed {
+		return ""
+	}
+	for _, r := range text {
+		if problem := v.next(r); problem != "" {
+			v.failed = true
+			return problem
+		}
+	}
+	return ""
+}
+
+// next advances the validator over one character.
+func (v *jsonValidator) next(r rune) string {
+	if v.skipLine {
+		v.skipLine = r != '\n'
+		return ""
+	}
+	if v.inString {
+		switch {
+		case v.escaped:
+			v.escaped = false
+			if !strings.ContainsRune(`"\/bfnrtu`, r) {
+				return "invalid escape '\\" + string(r) + "' in a string"
+			}
+		case r == '\\':
+			v.escaped = true
+		case r == '"':
+			v.inString = false
+			v.done = len(v.stack) == 0
+		case r == '\n':
+			return "line break inside a string"
+		}
+		return ""
+	}
+	switch r {
+	case ' ', '\t', '\r', '\n':
+		if v.scalar {
+			v.scalar, v.done = false, true
+		}
+		return ""
+	case '`':
+		v.skipLine = true
+		return ""
+	}
+	if v.done {
+		return "unexpected '" + string(r) + "' after the JSON value"
+	}
+	switch r {
+	case '{', '[':
+		v.stack = append(v.stack, r)
+	case '}', ']':
+		open := '{'
+		if r == ']' {
+			open = '['
+		}
+		if len(v.stack) == 0 || v.stack[len(v.stack)-1] != open {
+			return "unexpected '" + string(r) + "'"
+		}
+		v.stack = v.stack[:len(v.stack)-1]
+		v.done = len(v.stack) == 0
+	case '"':
+		v.inString = true
+	default:
+		if !v.started && !strings.ContainsRune("-0123456789tfn", r) {
+			return "a JSON value cannot start with '" + string(r) + "'"
+		}
+		v.scalar = v.scalar || len(v.stack) == 0
+	}
+	v.started = true
+	return ""
+}
+
+// finish reports a value left incomplete at its end, unless a problem was
+// reported already. An empty value is left to the label's EmptyJSON policy.
+func (v *jsonValidator) finish() string {
+	if v.failed || !v.started {
+		return ""
+	}
+	if v.inString || len(v.stack) > 0 {
+		v.failed = true
+		return "the value ends before its JSON is complete"
+	}
+	return ""
+}
@@ -0,0 +1,268 @@
+package arkaineparser
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// streamAll writes text to a new stream in chunks of size bytes and returns
+// every event and the final details.
+func streamAll(p *Parser, text string, size int) ([]StreamEvent, Details) {
+	stream := p.NewStream()
+	var events []StreamEvent
+	for len(text) > 0 {
+		n := min(size, len(text))
+		events = append(events, stream.Write(text[:n])...)
+		text = text[n:]
+	}
+	closing, details := stream.Close()
+	return append(events, closing...), details
+}
+
+// collapse merges consecutive deltas of the same label, so events compare
+// alike whatever the chunking.
+func collapse(events []StreamEvent) []StreamEvent {
+	var merged []StreamEvent
+	for _, event := range events {
+		last := len(merged) - 1
+		if event.Kind == StreamLabelDelta && last >= 0 && merged[last].Kind == StreamLabelDelta && merged[last].Label == event.Label {
+			merged[last].Text += event.Text
+			continue
+		}
+		merged = append(merged, event)
+	}
+	return merged
+}
+
+// TestStream checks the events of a streamed output, whatever its chunking.
+func TestStream(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Thought"}, {Name: "Action"}, {Name: "Action Input", IsJSON: true}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	text := "Sure!\nThought: first\nsecond\nAction: search\nAction Input: {\n  \"q\": \"Thought: no\"\n}\n"
+	expected := []StreamEvent{
+		{Kind: StreamLabelStart, Label: "thought"},
+		{Kind: StreamLabelDelta, Label: "thought", Text: "first\nsecond"},
+		{Kind: StreamLabelComplete, Label: "thought", Text: "first\nsecond"},
+		{Kind: StreamLabelStart, Label: "action"},
+		{Kind: StreamLabelDelta, Label: "action", Text: "search"},
+		{Kind: StreamLabelComplete, Label: "action", Text: "search"},
+		{Kind: StreamLabelStart, Label: "action input"},
+		{Kind: StreamLabelDelta, Label: "action input", Text: "{\n  \"q\": \"Thought: no\"\n}"},
+		{Kind: StreamLabelComplete, Label: "action input", Text: "{\n  \"q\": \"Thought: no\"\n}"},
+	}
+	for _, size := range []int{1, 3, 7, len(text)} {
+		events, details := streamAll(parser, text, size)
+		if got := collapse(events); !reflect.DeepEqual(got, expected) {
+			t.Errorf("chunks of %d: unexpected events: %#v", size, got)
+		}
+		if len(details.Errors) != 0 || details.Result["action"] != "search" {
+			t.Errorf("chunks of %d: unexpected details: %#v", size, details)
+		}
+	}
+}
+
+// TestStreamEarlyDeltas checks that label lines and open structures stream
+// before their lines end, while other lines wait for theirs.
+func TestStreamEarlyDeltas(t *testing.T) {
+	parser, _ := NewParser([]Label{{Name: "Thought"}, {Name: "Input", IsJSON: true}})
+	stream := parser.NewStream()
+	if events := stream.Write("Thought: I am thin"); len(events) != 2 || events[1].Text != "I am thin" {
+		t.Errorf("expected the label line to stream, got %#v", events)
+	}
+	if events := stream.Write("king\nabout it"); len(events) != 1 || events[0].Text != "king" {
+		t.Errorf("expected the continuation line to wait, got %#v", events)
+	}
+	// Inside the open structure even the line break streams right away
+	if events := stream.Write("\nInput: [1,\n"); events[len(events)-1].Text != "\n" {
+		t.Errorf("expected the line break to stream, got %#v", events)
+	}
+	if events := stream.Write("  2"); len(events) != 1 || events[0].Text != "  2" {
+		t.Errorf("expected the open structure to stream, got %#v", events)
+	}
+}
+
+// TestStreamInvalidJSON checks that broken JSON is reported as soon as it
+// can no longer become valid.
+func TestStreamInvalidJSON(t *testing.T) {
+	parser, _ := NewParser([]Label{{Name: "Action"}, {Name: "Input", IsJSON: true}})
+	tests := []struct {
+		text    string
+		problem string
+		after   string // Text whose write reports the problem
+	}{
+		{"Input: {\"a\": [1, 2}", "unexpected '}'", "}"},
+		{"Input: {\"a\": 1}}", "unexpected '}' after the JSON value", "}"},
+		{"Input: {\"a\": 1} and more", "unexpected 'a' after the JSON value", "a"},
+		{"Input: hello", "a JSON value cannot start with 'h'", "h"},
+		{"Input: {\"a\": \"x\\q\"}", "invalid escape '\\q' in a string", "q"},
+		{"Input: {\"a\": \"x\ny\"}", "line break inside a string", "\n"},
+		{"Input: {\"a\": 1\nAction: done", "the value ends before its JSON is complete", ""},
+	}
+	for _, test := range tests {
+		stream := parser.NewStream()
+		var found []StreamEvent
+		reportedAt := ""
+		for _, r := range test.text {
+			for _, event := range stream.Write(string(r)) {
+				if event.Kind == StreamInvalidJSON {
+					found = append(found, event)
+					reportedAt = string(r)
+				}
+			}
+		}
+		closing, _ := stream.Close()
+		for _, event := range closing {
+			if event.Kind == StreamInvalidJSON {
+				found = append(found, event)
+			}
+		}
+		if len(found) != 1 || found[0].Text != test.problem || found[0].Label != "input" {
+			t.Errorf("%q: unexpected events: %#v", test.text, found)
+		}
+		if reportedAt != test.after {
+			t.Errorf("%q: expected the problem on %q, got %q", test.text, test.after, reportedAt)
+		}
+	}
+
+	// Valid JSON, fenced or not, and empty values are never reported
+	for _, text := range []string{"Input: [1, {\"b\": \"}\"}]", "Input:\n```json\n{\"a\": true}\n```", "Input: 3.5", "Input:\nAction: x"} {
+		events, _ := streamAll(parser, text, 1)
+		for _, event := range events {
+			if event.Kind == StreamInvalidJSON {
+				t.Errorf("%q: unexpected problem %q", text, event.Text)
+			}
+		}
+	}
+	if _, details := streamAll(parser, "Input: {\"a\": 1}", 4); !reflect.DeepEqual(details.Result["input"], map[string]interface{}{"a": 1.0}) {
+		t.Errorf("unexpected final result: %#v", details.Result)
+	}
+}
+
+// TestStreamEarlyAbort checks that the stream stops once the predicate holds.
+func TestStreamEarlyAbort(t *testing.T) {
+	var buf bytes.Buffer
+	parser, _ := NewParser([]Label{{Name: "Thought"}, {Name: "Action"}, {Name: "Action Input", IsJSON: true}, {Name: "Observation"}}, WithJournal(NewJournal(&buf, "v1")))
+	var calls int
+	done := func(partial Result) bool {
+		calls++
+		_, decoded := partial["action input"].(map[string]interface{})
+		return partial["action"] != "" && decoded
+	}
+	stream := parser.NewStream(WithEarlyAbort(done))
+	var events []StreamEvent
+	for _, chunk := range []string{"Thought: go\nAction: search\nAction Input: {\"q\":", " 1}\nObser", "vation: invented\n"} {
+		events = append(events, stream.Write(chunk)...)
+	}
+	last := events[len(events)-2:]
+	expected := []StreamEvent{
+		{Kind: StreamLabelComplete, Label: "action input", Text: `{"q": 1}`},
+		{Kind: StreamAborted},
+	}
+	if !reflect.DeepEqual(last, expected) || !stream.Aborted() {
+		t.Errorf("unexpected events: %#v", events)
+	}
+	// One call per line where a value completed: "Action" when "Action
+	// Input" starts, and "Action Input" when its JSON closes on the same line
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+	closing, details := stream.Close()
+	if len(closing) != 0 || details.Result["observation"] != "" || details.Result["action"] != "search" {
+		t.Errorf("unexpected close: %#v, %#v", closing, details.Result)
+	}
+	if stream.Text() != "Thought: go\nAction: search\nAction Input: {\"q\": 1}\n" {
+		t.Errorf("unexpected text: %q", stream.Text())
+	}
+	// Only the final parse is journaled
+	if entries := strings.Count(buf.String(), "\n"); entries != 1 {
+		t.Errorf("expected 1 journal entry, got %d", entries)
+	}
+
+	// Without the predicate holding, the stream runs to the end
+	stream = parser.NewStream(WithEarlyAbort(func(Result) bool { return false }))
+	stream.Write("Action: a\nObservation: b\n")
+	if _, details := stream.Close(); stream.Aborted() || details.Result["observation"] != "b" {
+		t.Errorf("unexpected result: %#v", details.Result)
+	}
+
+	// The lines of a long value do not call the predicate
+	calls = 0
+	stream = parser.NewStream(WithEarlyAbort(done))
+	stream.Write("Thought: " + strings.Repeat("more\n", 10000))
+	if calls != 0 {
+		t.Errorf("expected no calls, got %d", calls)
+	}
+	stream.Write("Action: search\n")
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+// TestStreamSnapshot checks partial results and completeness flags mid-stream.
+func TestStreamSnapshot(t *testing.T) {
+	var buf bytes.Buffer
+	parser, _ := NewParser([]Label{{Name: "Thought"}, {Name: "Action"}, {Name: "Action Input", IsJSON: true}}, WithJournal(NewJournal(&buf, "v1")))
+	stream := parser.NewStream()
+	stream.Write("Thought: I should look")
+	snapshot := stream.Snapshot()
+	if snapshot["thought"] != "I should look" || snapshot["action"] != "" {
+		t.Errorf("unexpected snapshot: %#v", snapshot)
+	}
+	if !reflect.DeepEqual(stream.Complete(), map[string]bool{"thought": false}) {
+		t.Errorf("unexpected completeness: %#v", stream.Complete())
+	}
+
+	// A line that may still become a label is left out
+	stream.Write(" it up\nAct")
+	if snapshot := stream.Snapshot(); snapshot["thought"] != "I should look it up" {
+		t.Errorf("unexpected snapshot: %#v", snapshot)
+	}
+	stream.Write("ion: search\nAction Input: {\"q\": ")
+	snapshot = stream.Snapshot()
+	if snapshot["action"] != "search" || snapshot["action input"] != `{"q":` {
+		t.Errorf("unexpected snapshot: %#v", snapshot)
+	}
+	expected := map[string]bool{"thought": true, "action": true, "action input": false}
+	if !reflect.DeepEqual(stream.Complete(), expected) {
+		t.Errorf("unexpected completeness: %#v", stream.Complete())
+	}
+
+	stream.Write("\"weather\"}")
+	stream.Close()
+	expected["action input"] = true
+	if !reflect.DeepEqual(stream.Complete(), expected) {
+		t.Errorf("unexpected completeness: %#v", stream.Complete())
+	}
+	if !reflect.DeepEqual(stream.Snapshot()["action input"], map[string]interface{}{"q": "weather"}) {
+		t.Errorf("unexpected final snapshot: %#v", stream.Snapshot())
+	}
+	// Snapshots are previews, never journaled
+	if entries := strings.Count(buf.String(), "\n"); entries != 1 {
+		t.Errorf("expected 1 journal entry, got %d", entries)
+	}
+}
+
+// TestStreamBlocks checks that blocks are reported complete as the next one
+// starts and when the stream ends.
+func TestStreamBlocks(t *testing.T) {
+	parser, _ := NewParser([]Label{{Name: "Task", IsBlockStart: true}, {Name: "Owner"}})
+	events, _ := streamAll(parser, "Task: a\nOwner: me\nTask: b\n", 5)
+	var kinds []StreamEventKind
+	for _, event := range collapse(events) {
+		kinds = append(kinds, event.Kind)
+	}
+	expected := []StreamEventKind{
+		StreamLabelStart, StreamLabelDelta, StreamLabelComplete,
+		StreamLabelStart, StreamLabelDelta, StreamLabelComplete,
+		StreamBlockComplete,
+		StreamLabelStart, StreamLabelDelta, StreamLabelComplete,
+		StreamBlockComplete,
+	}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("unexpected events: %v", kinds)
+	}
+}
@@ -0,0 +1,152 @@
+package arkaineparser
+
+import (
+	"strings"
+	"text/template"
+)
+
+// TemplateFuncs returns text/template functions that embed the parser's
+// schema in a prompt, so the prompt and the parser cannot drift apart:
+//   - {{formatInstructions}}: how to format the response, one line per label
+//   - {{exampleOutput}}: an example response Parse accepts
+//   - {{labelList}}: the label names, comma separated
+//
+// Only model-sourced labels are described.
+func (p *Parser) TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"formatInstructions": p.formatInstructions,
+		"exampleOutput":      p.exampleOutput,
+		"labelList":          p.labelList,
+	}
+}
+
+// RenderPrompt parses promptTemplate with TemplateFuncs and executes it with
+// data, returning the rendered prompt.
+func (p *Parser) RenderPrompt(promptTemplate string, data interface{}) (string, error) {
+	tmpl, err := template.New("prompt").Funcs(p.TemplateFuncs()).Parse(promptTemplate)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// modelLabels returns the labels the model writes, in declaration order.
+func (p *Parser) modelLabels() []Label {
+	var labels []Label
+	for _, label := range p.labels {
+		if label.Source != SourceSystem {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// formatInstructions describes the response format: each label with a hint
+// of its value, then which labels are required and how blocks repeat.
+func (p *Parser) formatInstructions() string {
+	var b strings.Builder
+	b.WriteString("Respond in exactly this format, starting each field on its own line:\n")
+	var required []string
+	blockStart := ""
+	for _, label := range p.modelLabels() {
+		b.WriteString(p.display[label.Name] + ": " + valueHint(label) + "\n")
+		if label.Required {
+			required = append(required, p.display[label.Name])
+		}
+		if label.IsBlockStart {
+			blockStart = p.display[label.Name]
+		}
+	}
+	if len(required) > 0 {
+		b.WriteString("\nAlways include: " + strings.Join(required, ", ") + ".")
+	}
+	if blockStart != "" {
+		b.WriteString("\nRepeat the format for each item, always starting with '" + blockStart + ":'.")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// exampleOutput renders an example response with a plausible value for each
+// label.
+func (p *Parser) exampleOutput() string {
+	var lines []string
+	for _, label := range p.modelLabels() {
+		lines = append(lines, p.display[label.Name]+": "+p.exampleValue(label))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// labelList returns the label names as declared, comma separated.
+func (p *Parser) labelList() string {
+	var names []string
+	for _, label := range p.modelLabels() {
+		names = append(names, p.display[label.Name])
+	}
+	return strings.Join(names, ", ")
+}
+
+// exampleValue returns a value of label that Parse accepts, within its
+// Min/Max range and written with the parser's Locale.
+func (p *Parser) exampleValue(label Label) string {
+	switch {
+	case len(label.Choices) > 0:
+		return label.Choices[0]
+	case label.IsJSON:
+		return "{}"
+	}
+	number := func(fallback float64) string {
+		switch {
+		case label.Min != nil && label.Max != nil:
+			return formatNumber((*label.Min + *label.Max) / 2)
+		case label.Min != nil:
+			return formatNumber(*label.Min)
+		case label.Max != nil:
+			return formatNumber(*label.Max)
+		}
+		return formatNumber(fallback)
+	}
+	if p.locale.decimal() != '.' {
+		plain := number
+		number = func(fallback float64) string {
+			return strings.ReplaceAll(plain(fallback), ".", string(p.locale.decimal()))
+		}
+	}
+	switch label.DataType {
+	case "url":
+		return "https://example.com"
+	case "path":
+		return "path/to/file.txt"
+	case "datetime":
+		return "2006-01-02T15:04:05Z"
+	case "duration":
+		return "1h30m"
+	case "integer":
+		if label.Min != nil && label.Max != nil {
+			return formatNumber(float64(int(*label.Min+*label.Max) / 2))
+		}
+		return number(3)
+	case "number", "score":
+		return number(3.5)
+	case "confidence":
+		return number(0.9)
+	case "list":
+		return "first, second"
+	case "nested-list":
+		return "- first\n  - detail\n- second"
+	case "checkbox":
+		return "[x]"
+	case "status":
+		return StatusDone
+	case "percent":
+		return number(85) + "%"
+	case "currency":
+		return "$" + number(12.5)
+	case "quantity":
+		return number(3) + " items"
+	}
+	return "your " + strings.ToLower(p.display[label.Name]) + " here"
+}
@@ -0,0 +1,64 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestRenderPrompt checks the schema-derived template functions.
+func TestRenderPrompt(t *testing.T) {
+	labels := []Label{
+		{Name: "Task", IsBlockStart: true, Required: true},
+		{Name: "Sentiment", Choices: []string{"positive", "negative"}},
+		{Name: "Confidence", DataType: "confidence", Min: float64Ptr(0), Max: float64Ptr(1)},
+		{Name: "Details", IsJSON: true},
+		{Name: "Observation", Source: SourceSystem},
+	}
+	parser, err := NewParser(labels)
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	prompt, err := parser.RenderPrompt("Classify {{.}} using {{labelList}}.\n\n{{formatInstructions}}\n\nExample:\n{{exampleOutput}}", "the review")
+	if err != nil {
+		t.Fatalf("failed to render: %v", err)
+	}
+	expected := `Classify the review using Task, Sentiment, Confidence, Details.
+
+Respond in exactly this format, starting each field on its own line:
+Task: <text>
+Sentiment: <one of: positive, negative>
+Confidence: <number between 0 and 1>
+Details: <valid JSON>
+
+Always include: Task.
+Repeat the format for each item, always starting with 'Task:'.
+
+Example:
+Task: your task here
+Sentiment: positive
+Confidence: 0.5
+Details: {}`
+	if prompt != expected {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+
+	// The rendered prompt passes CheckPrompt and its example parses cleanly
+	if warnings := parser.CheckPrompt(prompt); len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %#v", warnings)
+	}
+	example := prompt[strings.Index(prompt, "Example:\n")+len("Example:\n"):]
+	result, errList := parser.Parse(example)
+	if len(errList) > 0 || result["confidence"] != 0.5 || !reflect.DeepEqual(result["details"], map[string]interface{}{}) {
+		t.Errorf("example does not parse: %#v %v", result, errList)
+	}
+
+	// Examples are written in the parser's locale
+	german, _ := NewParser([]Label{{Name: "Amount", DataType: "number"}}, WithLocale(LocaleGerman))
+	if example := german.exampleOutput(); example != "Amount: 3,5" {
+		t.Errorf("unexpected German example: %q", example)
+	}
+	if _, err := parser.RenderPrompt("{{unknown}}", nil); err == nil {
+		t.Error("expected an error for an undefined function")
+	}
+}
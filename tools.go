@@ -0,0 +1,175 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// Tool is a function an agent may call, with its arguments declared as labels,
+// so the same definition parses text-format calls ("query: weather") and
+// validates native function calls.
+type Tool struct {
+	Name        string  `json:"name"`                  // Function name: letters, digits, '_' and '-', at most 64
+	Description string  `json:"description,omitempty"` // What the tool does, shown to the model
+	Arguments   []Label `json:"arguments"`             // The arguments, named by their lowercase label names
+}
+
+// toolNamePattern is the function name format OpenAI and Anthropic accept.
+var toolNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ToolRegistry holds the tools of an agent, in registration order, and the
+// Parsers of their arguments. A ToolRegistry is safe for concurrent use.
+type ToolRegistry struct {
+	opts []Option // Options applied to every tool's Parser
+
+	mu     sync.RWMutex
+	tools  []Tool
+	parser map[string]*Parser
+}
+
+// NewToolRegistry creates an empty ToolRegistry whose argument Parsers are all
+// built with opts.
+func NewToolRegistry(opts ...Option) *ToolRegistry {
+	return &ToolRegistry{opts: opts, parser: make(map[string]*Parser)}
+}
+
+// Register adds tool, replacing any tool of the same name in place. Returns an
+// error if the name is malformed or the arguments are not a valid label set;
+// arguments may not include a block start label.
+func (r *ToolRegistry) Register(tool Tool) error {
+	if !toolNamePattern.MatchString(tool.Name) {
+		return errors.New("Tool name '" + tool.Name + "' must be 1 to 64 letters, digits, underscores or hyphens")
+	}
+	p, err := NewParser(tool.Arguments, r.opts...)
+	if err != nil {
+		return fmt.Errorf("Tool '%s': %w", tool.Name, err)
+	}
+	for _, label := range p.labels {
+		if label.IsBlockStart {
+			return errors.New("Tool '" + tool.Name + "' argument '" + label.Name + "' must not start blocks")
+		}
+	}
+	tool.Arguments = copyLabels(tool.Arguments)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.parser[tool.Name]; ok {
+		for i := range r.tools {
+			if r.tools[i].Name == tool.Name {
+				r.tools[i] = tool
+			}
+		}
+	} else {
+		r.tools = append(r.tools, tool)
+	}
+	r.parser[tool.Name] = p
+	return nil
+}
+
+// Tools returns the registered tools in registration order.
+func (r *ToolRegistry) Tools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tools := make([]Tool, len(r.tools))
+	for i, tool := range r.tools {
+		tools[i] = tool
+		tools[i].Arguments = copyLabels(tool.Arguments)
+	}
+	return tools
+}
+
+// Parser returns the Parser of a tool's arguments. The error wraps
+// ErrToolNotFound.
+func (r *ToolRegistry) Parser(name string) (*Parser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.parser[name]
+	if !ok {
+		return nil, wrapError(ErrToolNotFound, "Tool '"+name+"' is not registered")
+	}
+	return p, nil
+}
+
+// ParseArguments parses the text-format arguments of a call to a tool, such
+// as the "Action Input" of a ReAct step written as labeled lines.
+func (r *ToolRegistry) ParseArguments(name, text string) (Details, error) {
+	p, err := r.Parser(name)
+	if err != nil {
+		return Details{}, err
+	}
+	return p.ParseDetailed(text), nil
+}
+
+// ValidateArguments checks the JSON arguments of a native function call, as
+// the model sent them, by rendering them with Format and parsing them back:
+// the Details hold the arguments converted to their data types and any
+// errors, just as for a text-format call. Returns an error if the arguments
+// are not a JSON object or name an argument the tool does not have.
+func (r *ToolRegistry) ValidateArguments(name, arguments string) (Details, error) {
+	p, err := r.Parser(name)
+	if err != nil {
+		return Details{}, err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &values); err != nil {
+		return Details{}, errors.New("Tool '" + name + "' arguments must be a JSON object: " + err.Error())
+	}
+	text, err := p.Format(values)
+	if err != nil {
+		return Details{}, fmt.Errorf("Tool '%s': %w", name, err)
+	}
+	return p.ParseDetailed(text), nil
+}
+
+// OpenAITools returns the tools in the format of the OpenAI "tools" request
+// parameter, each a "function" whose parameters are the JSON Schema of its
+// arguments. Encode it with encoding/json.
+func (r *ToolRegistry) OpenAITools() []map[string]interface{} {
+	var tools []map[string]interface{}
+	for _, tool := range r.definitions() {
+		function := map[string]interface{}{"name": tool.name, "parameters": tool.schema}
+		if tool.description != "" {
+			function["description"] = tool.description
+		}
+		tools = append(tools, map[string]interface{}{"type": "function", "function": function})
+	}
+	return tools
+}
+
+// AnthropicTools returns the tools in the format of the Anthropic Messages API
+// "tools" parameter, each with the JSON Schema of its arguments as its
+// input_schema. Encode it with encoding/json.
+func (r *ToolRegistry) AnthropicTools() []map[string]interface{} {
+	var tools []map[string]interface{}
+	for _, tool := range r.definitions() {
+		definition := map[string]interface{}{"name": tool.name, "input_schema": tool.schema}
+		if tool.description != "" {
+			definition["description"] = tool.description
+		}
+		tools = append(tools, definition)
+	}
+	return tools
+}
+
+// toolDefinition is a tool ready for export.
+type toolDefinition struct {
+	name        string
+	description string
+	schema      map[string]interface{}
+}
+
+// definitions returns the registered tools with the JSON Schema of their
+// arguments, without the "$schema" dialect the tool formats do not take.
+func (r *ToolRegistry) definitions() []toolDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	definitions := make([]toolDefinition, 0, len(r.tools))
+	for _, tool := range r.tools {
+		schema := r.parser[tool.Name].JSONSchema()
+		delete(schema, "$schema")
+		definitions = append(definitions, toolDefinition{name: tool.Name, description: tool.Description, schema: schema})
+	}
+	return definitions
+}
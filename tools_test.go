@@ -0,0 +1,116 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// weatherTool is a tool with a required, a typed and an optional argument.
+func weatherTool() Tool {
+	return Tool{
+		Name:        "get_weather",
+		Description: "Look up the current weather",
+		Arguments: []Label{
+			{Name: "City", Required: true},
+			{Name: "Days", DataType: "integer"},
+			{Name: "Units", Choices: []string{"metric", "imperial"}},
+		},
+	}
+}
+
+// TestToolExport checks the OpenAI and Anthropic tool formats.
+func TestToolExport(t *testing.T) {
+	registry := NewToolRegistry()
+	if err := registry.Register(weatherTool()); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	if err := registry.Register(Tool{Name: "finish", Arguments: []Label{{Name: "Answer"}}}); err != nil {
+		t.Fatalf("failed to register: %v", err)
+	}
+	parameters := `{"type": "object", "required": ["city"], "properties": {
+		"city": {"type": "string"},
+		"days": {"type": "integer"},
+		"units": {"type": "string", "enum": ["metric", "imperial"]}}}`
+	openAI := `[
+		{"type": "function", "function": {"name": "get_weather", "description": "Look up the current weather", "parameters": ` + parameters + `}},
+		{"type": "function", "function": {"name": "finish", "parameters": {"type": "object", "required": [], "properties": {"answer": {"type": "string"}}}}}]`
+	anthropic := `[
+		{"name": "get_weather", "description": "Look up the current weather", "input_schema": ` + parameters + `},
+		{"name": "finish", "input_schema": {"type": "object", "required": [], "properties": {"answer": {"type": "string"}}}}]`
+	assertJSON(t, registry.OpenAITools(), openAI)
+	assertJSON(t, registry.AnthropicTools(), anthropic)
+}
+
+// assertJSON checks that value encodes to the same JSON as expected.
+func assertJSON(t *testing.T, value interface{}, expected string) {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	var got, want interface{}
+	json.Unmarshal(encoded, &got)
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		t.Fatalf("bad expectation: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected JSON: %s", encoded)
+	}
+}
+
+// TestToolArguments checks that text-format and native calls are parsed alike.
+func TestToolArguments(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(weatherTool())
+
+	text, err := registry.ParseArguments("get_weather", "City: Paris\nDays: 3\nUnits: Metric")
+	if err != nil || len(text.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v, %v", err, text.Errors)
+	}
+	native, err := registry.ValidateArguments("get_weather", `{"city": "Paris", "days": 3, "units": "Metric"}`)
+	if err != nil || len(native.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v, %v", err, native.Errors)
+	}
+	if !reflect.DeepEqual(text.Result, native.Result) || native.Result["days"] != 3 || native.Result["units"] != "metric" {
+		t.Errorf("results differ: %#v, %#v", text.Result, native.Result)
+	}
+
+	native, _ = registry.ValidateArguments("get_weather", `{"days": "soon"}`)
+	if len(native.Errors) != 2 || native.Errors[0].Kind != KindType || native.Errors[1].Kind != KindRequired {
+		t.Errorf("unexpected errors: %#v", native.Errors)
+	}
+	if _, err := registry.ValidateArguments("get_weather", `["Paris"]`); err == nil {
+		t.Error("expected an error for arguments that are not an object")
+	}
+	if _, err := registry.ValidateArguments("get_weather", `{"country": "France"}`); err == nil {
+		t.Error("expected an error for an unknown argument")
+	}
+	if _, err := registry.ParseArguments("search", "query: x"); !errors.Is(err, ErrToolNotFound) {
+		t.Errorf("expected ErrToolNotFound, got %v", err)
+	}
+}
+
+// TestToolRegister checks tool validation and replacement.
+func TestToolRegister(t *testing.T) {
+	registry := NewToolRegistry()
+	if err := registry.Register(Tool{Name: "get weather"}); err == nil {
+		t.Error("expected an error for a malformed name")
+	}
+	if err := registry.Register(Tool{Name: "steps", Arguments: []Label{{Name: "Step", IsBlockStart: true}}}); err == nil {
+		t.Error("expected an error for a block start argument")
+	}
+	if err := registry.Register(Tool{Name: "bad", Arguments: []Label{{Name: "A"}, {Name: "a"}}}); err == nil {
+		t.Error("expected an error for duplicate arguments")
+	}
+	registry.Register(weatherTool())
+	registry.Register(Tool{Name: "finish"})
+	replaced := weatherTool()
+	replaced.Description = "Forecast"
+	registry.Register(replaced)
+	tools := registry.Tools()
+	if len(tools) != 2 || tools[0].Description != "Forecast" || tools[1].Name != "finish" {
+		t.Errorf("unexpected tools: %#v", tools)
+	}
+}
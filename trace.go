@@ -0,0 +1,137 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+)
+
+// Trace is a persistable record of every step of a parse: cleaning, line
+// classification, value assembly, and validation. It encodes to JSON for eval
+// dashboards and renders to a standalone HTML page for bug reports.
+type Trace struct {
+	Input    string         `json:"input"`    // The raw text
+	Cleaned  string         `json:"cleaned"`  // The text after cleaning
+	Cleaning []Repair       `json:"cleaning"` // Code fences and inline code removed by cleaning
+	Lines    []LineDecision `json:"lines"`    // One decision per cleaned line
+	Values   []TraceValue   `json:"values"`   // Every assembled value, in order of appearance
+	Result   Result         `json:"result"`   // The final result, as returned by Parse
+	Errors   []ParseError   `json:"errors"`
+	Warnings []ParseError   `json:"warnings"`
+	Repairs  []Repair       `json:"repairs"` // Every repair, including cleaning
+}
+
+// TraceValue records how a single occurrence of a label was assembled.
+type TraceValue struct {
+	Label      string      `json:"label"`
+	Occurrence int         `json:"occurrence"` // 1-based occurrence of Label
+	Lines      []int       `json:"lines"`      // Numbers of the cleaned lines the value was built from
+	Value      interface{} `json:"value"`      // The parsed value of this occurrence
+}
+
+// TraceParse parses text like ParseDetailed and records a Trace of the parse.
+func (p *Parser) TraceParse(text string) Trace {
+	explanation := p.Explain(text)
+	details := explanation.Details
+	trace := Trace{
+		Input:    text,
+		Cleaned:  explanation.Cleaned,
+		Cleaning: []Repair{},
+		Lines:    explanation.Lines,
+		Values:   []TraceValue{},
+		Result:   details.Result,
+		Errors:   details.Errors,
+		Warnings: append([]ParseError{}, details.Warnings...),
+		Repairs:  append([]Repair{}, details.Repairs...),
+	}
+	for _, repair := range details.Repairs {
+		if repair.Kind == RepairCodeFence || repair.Kind == RepairInlineCode {
+			trace.Cleaning = append(trace.Cleaning, repair)
+		}
+	}
+
+	// Group lines into values: each label line starts the next occurrence of its label
+	seen := make(map[string]int)
+	for _, line := range explanation.Lines {
+		switch {
+		case line.Kind == LineLabel:
+			seen[line.Label]++
+			value := TraceValue{Label: line.Label, Occurrence: seen[line.Label], Lines: []int{line.Number}}
+			if occurrences := details.Occurrences[line.Label]; value.Occurrence <= len(occurrences) {
+				value.Value = occurrences[value.Occurrence-1]
+			}
+			trace.Values = append(trace.Values, value)
+		case line.Label != "" && len(trace.Values) > 0:
+			last := &trace.Values[len(trace.Values)-1]
+			last.Lines = append(last.Lines, line.Number)
+		}
+	}
+	return trace
+}
+
+// JSON encodes the trace as indented JSON.
+func (t Trace) JSON() ([]byte, error) {
+	return json.MarshalIndent(t, "", "  ")
+}
+
+// WriteHTML renders the trace as a standalone HTML page.
+func (t Trace) WriteHTML(w io.Writer) error {
+	return traceTemplate.Execute(w, t)
+}
+
+// traceTemplate renders a Trace as HTML; html/template escapes all text.
+var traceTemplate = template.Must(template.New("trace").Funcs(template.FuncMap{
+	"before": func(line LineDecision) string { return line.Text[:line.Span.Start] },
+	"marked": func(line LineDecision) string { return line.Text[line.Span.Start:line.Span.End] },
+	"after":  func(line LineDecision) string { return line.Text[line.Span.End:] },
+	"json": func(v interface{}) string {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err.Error()
+		}
+		return string(encoded)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Parse trace</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td { padding: 2px 8px; vertical-align: top; }
+pre, td.text { font-family: monospace; white-space: pre-wrap; }
+.label { background: #e0f0ff; }
+.literal { background: #fff4d6; }
+.ignored { color: #999; }
+mark { background: #8cc8ff; font-weight: bold; }
+.error { color: #b00020; }
+.warning { color: #a06000; }
+</style>
+</head>
+<body>
+<h1>Parse trace</h1>
+<h2>Input</h2>
+<pre>{{.Input}}</pre>
+{{if .Cleaning}}<h2>Cleaning</h2>
+<ul>{{range .Cleaning}}<li>{{.Kind}}: <code>{{.Before}}</code> &rarr; <code>{{.After}}</code></li>{{end}}</ul>
+{{end}}<h2>Lines</h2>
+<table>
+{{range .Lines}}<tr class="{{.Kind}}"><td>{{.Number}}</td><td>{{.Kind}}</td><td>{{.Label}}</td><td class="text">{{if eq .Kind "label"}}{{before .}}<mark>{{marked .}}</mark>{{after .}}{{else}}{{.Text}}{{end}}{{if .EndsValue}} <em>(ends value)</em>{{end}}</td></tr>
+{{end}}</table>
+<h2>Values</h2>
+<table>
+{{range .Values}}<tr><td>{{.Label}} #{{.Occurrence}}</td><td>lines {{range $i, $n := .Lines}}{{if $i}}, {{end}}{{$n}}{{end}}</td><td class="text">{{json .Value}}</td></tr>
+{{end}}</table>
+<h2>Result</h2>
+<pre>{{json .Result}}</pre>
+<h2>Diagnostics</h2>
+<ul>
+{{range .Errors}}<li class="error">error [{{.Kind}}]: {{.Message}}</li>
+{{end}}{{range .Warnings}}<li class="warning">warning [{{.Kind}}]: {{.Message}}</li>
+{{end}}{{range .Repairs}}<li>repair [{{.Kind}}] {{.Label}}: <code>{{.Before}}</code> &rarr; <code>{{.After}}</code></li>
+{{end}}{{if not (or .Errors .Warnings .Repairs)}}<li>No errors, warnings or repairs</li>
+{{end}}</ul>
+</body>
+</html>
+`))
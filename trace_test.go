@@ -0,0 +1,45 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestTraceParse checks the recorded value assembly and both renderings.
+func TestTraceParse(t *testing.T) {
+	parser, err := NewParser([]Label{{Name: "Thought"}, {Name: "Arguments", IsJSON: true}})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	trace := parser.TraceParse("```\nThought: a <b>\nmore\nArguments: {\"q\":\n\"x\"}\nThought: c\n```")
+	if len(trace.Cleaning) != 1 || trace.Cleaning[0].Kind != RepairCodeFence {
+		t.Errorf("unexpected cleaning: %#v", trace.Cleaning)
+	}
+	expected := []TraceValue{
+		{Label: "thought", Occurrence: 1, Lines: []int{1, 2}, Value: "a <b>\nmore"},
+		{Label: "arguments", Occurrence: 1, Lines: []int{3, 4}, Value: map[string]interface{}{"q": "x"}},
+		{Label: "thought", Occurrence: 2, Lines: []int{5}, Value: "c"},
+	}
+	if !reflect.DeepEqual(trace.Values, expected) {
+		t.Errorf("values mismatch.\nGot: %#v\nExpected: %#v", trace.Values, expected)
+	}
+
+	encoded, err := trace.JSON()
+	if err != nil {
+		t.Fatalf("failed to encode trace: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil || decoded["lines"].([]interface{})[0].(map[string]interface{})["kind"] != "label" {
+		t.Errorf("unexpected JSON trace: %s", encoded)
+	}
+
+	var html strings.Builder
+	if err := trace.WriteHTML(&html); err != nil {
+		t.Fatalf("failed to render trace: %v", err)
+	}
+	if !strings.Contains(html.String(), "<mark>Thought: </mark>a &lt;b&gt;") {
+		t.Errorf("unexpected HTML:\n%s", html.String())
+	}
+}
@@ -0,0 +1,47 @@
+package arkaineparser
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Validate runs only the structural checks of a parse: it reports missing
+// required labels, missing dependencies, malformed JSON and malformed front
+// matter, with the same ParseErrors ParseDetailed would, without building a
+// result. JSON values are syntax checked but never decoded, and data types,
+// patterns, ranges and choices are not checked. Use it to reject bad outputs
+// quickly and fully parse only those that pass. The returned slice is never nil.
+func (p *Parser) Validate(text string) []ParseError {
+	matches, _, front, _ := p.matchText(text, nil)
+	data := p.collectEntries(matches)
+	errList := []ParseError{}
+	if front != nil && p.frontMatterKey != "" {
+		errList = append(errList, front.errors...)
+	}
+	// Values of system-only labels are stripped by a parse, so they do not count
+	for _, label := range p.systemLabels {
+		data[label] = []string{}
+	}
+	// Syntax check JSON values in declaration order, as processResults does
+	for _, label := range p.labels {
+		if !label.IsJSON {
+			continue
+		}
+		for _, entry := range data[label.Name] {
+			if strings.TrimSpace(entry) == "" {
+				if label.EmptyJSON == EmptyJSONError {
+					errList = append(errList, newEmptyJSONError(label.Name))
+				}
+				continue
+			}
+			// Decoding into a RawMessage checks the syntax without building values
+			var raw json.RawMessage
+			if err := json.Unmarshal([]byte(entry), &raw); err != nil {
+				errList = append(errList, newJSONError(label.Name, err))
+			}
+		}
+	}
+	errList = append(errList, p.validateDependencies(data)...)
+	p.renderMessages(errList)
+	return errList
+}
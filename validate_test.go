@@ -0,0 +1,68 @@
+package arkaineparser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestValidate checks that Validate reports the structural errors of a full parse.
+func TestValidate(t *testing.T) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought", Required: true},
+		{Name: "Action", RequiredWith: []string{"Action Input"}},
+		{Name: "Action Input", IsJSON: true},
+		{Name: "Count", DataType: "integer"},
+		{Name: "Observation", Source: SourceSystem},
+	})
+	if err != nil {
+		t.Fatalf("failed to create parser: %v", err)
+	}
+	structural := map[ErrorKind]bool{KindRequired: true, KindDependency: true, KindJSON: true}
+	for _, input := range []string{
+		"Thought: fine\nAction: search\nAction Input: {\"q\": 1}",
+		"Action: search\nAction Input: {\"q\": ",
+		"Thought: a\nAction: search\nThought: b\nAction: lookup\nAction Input: []",
+		"Thought: counting\nCount: many\nObservation: Action Input: faked",
+		"```\nThought: fenced\n```",
+		"",
+	} {
+		var expected []ParseError
+		for _, err := range parser.ParseDetailed(input).Errors {
+			if structural[err.Kind] {
+				expected = append(expected, err)
+			}
+		}
+		got := parser.Validate(input)
+		if got == nil || (len(expected) > 0 || len(got) > 0) && !reflect.DeepEqual(got, expected) {
+			t.Errorf("Validate(%q) = %#v, want %#v", input, got, expected)
+		}
+	}
+}
+
+// BenchmarkValidate measures validating the transcript of BenchmarkParse.
+func BenchmarkValidate(b *testing.B) {
+	parser, err := NewParser([]Label{
+		{Name: "Thought"},
+		{Name: "Action", RequiredWith: []string{"Action Input"}},
+		{Name: "Action Input", IsJSON: true},
+		{Name: "Observation"},
+		{Name: "Final Answer"},
+	})
+	if err != nil {
+		b.Fatalf("failed to create parser: %v", err)
+	}
+	var sb strings.Builder
+	for i := 0; i < 200; i++ {
+		sb.WriteString("Thought: I should look this up\nand think it over\n")
+		sb.WriteString("Action: search\nAction Input: {\"query\": \"step\", \"page\": 1}\n")
+		sb.WriteString("Observation: some results\nmore results here\n")
+	}
+	sb.WriteString("Final Answer: done")
+	input := sb.String()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		parser.Validate(input)
+	}
+}
@@ -0,0 +1,137 @@
+package arkaineparser
+
+import (
+	"errors"
+	"sync"
+)
+
+// MigrationFunc upgrades a result produced by the previous schema version into
+// the shape expected by the version it is registered with.
+type MigrationFunc func(result map[string]interface{}) (map[string]interface{}, error)
+
+// schemaVersion is a single registered version of a label set.
+type schemaVersion struct {
+	version string
+	parser  *Parser
+	migrate MigrationFunc
+}
+
+// VersionedParser holds successive versions of a label schema so outputs
+// produced against older prompts stay parseable. Parse detects which version
+// an output was written for and migrates the result forward to the newest
+// version. A VersionedParser is safe for concurrent use.
+type VersionedParser struct {
+	mu       sync.RWMutex
+	versions []schemaVersion // Ordered oldest to newest
+}
+
+// NewVersionedParser creates a VersionedParser with no versions registered.
+func NewVersionedParser() *VersionedParser {
+	return &VersionedParser{}
+}
+
+// Register adds the next (newest) schema version. migrate converts a result of
+// the previously registered version into this version's shape; it is ignored
+// for the first version and may be nil when no reshaping is needed.
+// Returns error if the version name is empty or already registered, or if the
+// labels are rejected by NewParser.
+func (vp *VersionedParser) Register(version string, labels []Label, migrate MigrationFunc) error {
+	if version == "" {
+		return errors.New("Schema version name must not be empty")
+	}
+	parser, err := NewParser(labels)
+	if err != nil {
+		return err
+	}
+	vp.mu.Lock()
+	defer vp.mu.Unlock()
+	for _, existing := range vp.versions {
+		if existing.version == version {
+			return errors.New("Schema version '" + version + "' is already registered")
+		}
+	}
+	vp.versions = append(vp.versions, schemaVersion{version: version, parser: parser, migrate: migrate})
+	return nil
+}
+
+// Versions returns the registered version names, oldest first.
+func (vp *VersionedParser) Versions() []string {
+	vp.mu.RLock()
+	defer vp.mu.RUnlock()
+	names := make([]string, len(vp.versions))
+	for i, v := range vp.versions {
+		names[i] = v.version
+	}
+	return names
+}
+
+// Detect returns the name of the version whose labels best match text.
+// Versions are ranked by the number of their labels found in the text, then by
+// the fewest validation errors; remaining ties go to the newest version.
+// Returns error if no versions are registered.
+func (vp *VersionedParser) Detect(text string) (string, error) {
+	vp.mu.RLock()
+	defer vp.mu.RUnlock()
+	index, _ := vp.detect(text)
+	if index < 0 {
+		return "", errors.New("No schema versions registered")
+	}
+	return vp.versions[index].version, nil
+}
+
+// detect returns the index of the best matching version, or -1 if there are
+// none, and the parse of text with it. The caller must hold the read lock.
+func (vp *VersionedParser) detect(text string) (int, Details) {
+	best, bestMatched := -1, -1
+	var bestDetails Details
+	for i, v := range vp.versions {
+		matched := len(v.parser.matchedLabels(text))
+		details := v.parser.parseDetailed(text, nil)
+		// Later versions win ties, so compare with >= on equal scores
+		if matched > bestMatched || (matched == bestMatched && len(details.Errors) <= len(bestDetails.Errors)) {
+			best, bestMatched, bestDetails = i, matched, details
+		}
+	}
+	return best, bestDetails
+}
+
+// Parse detects the schema version of text, parses it with that version, and
+// applies each newer version's migration in order so the result always has the
+// newest shape. Returns the migrated result, the detected version name, and the
+// parse errors of the detected version plus any migration errors. When a
+// migration fails, the result of the last successful step is returned.
+func (vp *VersionedParser) Parse(text string) (map[string]interface{}, string, []string) {
+	vp.mu.RLock()
+	defer vp.mu.RUnlock()
+	index, details := vp.detect(text)
+	if index < 0 {
+		return map[string]interface{}{}, "", []string{"No schema versions registered"}
+	}
+	// Reuse the parse detection made with the detected version
+	detected := vp.versions[index]
+	result, errList := map[string]interface{}(details.Result), errorStrings(details.Errors)
+	// Migrate forward one version at a time
+	for _, next := range vp.versions[index+1:] {
+		if next.migrate == nil {
+			continue
+		}
+		migrated, err := next.migrate(result)
+		if err != nil {
+			errList = append(errList, "Migration to version '"+next.version+"' failed: "+err.Error())
+			break
+		}
+		result = migrated
+	}
+	return result, detected.version, errList
+}
+
+// matchedLabels returns the set of label names that appear in text.
+func (p *Parser) matchedLabels(text string) map[string]bool {
+	matched := make(map[string]bool)
+	for _, line := range p.prepare(text).lines {
+		if labelName, _, _ := p.parseLine(line); labelName != "" {
+			matched[labelName] = true
+		}
+	}
+	return matched
+}
@@ -0,0 +1,59 @@
+package arkaineparser
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestVersionedParser checks version detection and forward migration of old outputs.
+func TestVersionedParser(t *testing.T) {
+	vp := NewVersionedParser()
+	if err := vp.Register("v1", []Label{{Name: "Reasoning"}, {Name: "Answer", Required: true}}, nil); err != nil {
+		t.Fatalf("failed to register v1: %v", err)
+	}
+	// v2 renamed both labels
+	rename := func(result map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"thought": result["reasoning"], "final answer": result["answer"]}, nil
+	}
+	if err := vp.Register("v2", []Label{{Name: "Thought"}, {Name: "Final Answer", Required: true}}, rename); err != nil {
+		t.Fatalf("failed to register v2: %v", err)
+	}
+	if err := vp.Register("v2", []Label{{Name: "Other"}}, nil); err == nil {
+		t.Errorf("expected error for duplicate version")
+	}
+
+	// An old output is detected as v1 and migrated to the v2 shape
+	result, version, errs := vp.Parse("Reasoning: simple sum\nAnswer: 42")
+	if len(errs) > 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if version != "v1" {
+		t.Errorf("expected v1 to be detected, got %q", version)
+	}
+	expected := map[string]interface{}{"thought": "simple sum", "final answer": "42"}
+	if !deepEqual(result, expected) {
+		t.Errorf("result mismatch.\nGot: %#v\nExpected: %#v", result, expected)
+	}
+
+	// A current output is parsed as-is
+	result, version, _ = vp.Parse("Thought: easy\nFinal Answer: 7")
+	if version != "v2" || result["final answer"] != "7" {
+		t.Errorf("unexpected v2 parse: %q %#v", version, result)
+	}
+}
+
+// TestVersionedParserMigrationError checks that a failed migration is reported.
+func TestVersionedParserMigrationError(t *testing.T) {
+	vp := NewVersionedParser()
+	vp.Register("v1", []Label{{Name: "Answer"}}, nil)
+	vp.Register("v2", []Label{{Name: "Result"}}, func(map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	result, _, errs := vp.Parse("Answer: 1")
+	if len(errs) != 1 || errs[0] != "Migration to version 'v2' failed: boom" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if result["answer"] != "1" {
+		t.Errorf("expected unmigrated result, got %#v", result)
+	}
+}